@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+)
+
+func approveCmd() *cobra.Command {
+	var runDir string
+	var stageName string
+	var rejectFlag bool
+	var commentFlag string
+	var approverFlag string
+
+	cmd := &cobra.Command{
+		Use:   "approve",
+		Short: "Approve or reject a stage that is waiting for approval",
+		Long: `Records a decision for a stage of a run that is waiting for approval.
+Without --stage, lists the stages the run is waiting on and what each
+asks to approve.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if runDir == "" {
+				return fmt.Errorf("--run is required")
+			}
+			runDir = resolveRunDir(runDir)
+
+			requests, err := evidence.ReadApprovalRequests(runDir)
+			if err != nil {
+				return fmt.Errorf("failed to read approval requests: %w", err)
+			}
+			if stageName == "" {
+				if len(requests) == 0 {
+					fmt.Fprintln(os.Stderr, "No stages are awaiting approval.")
+					return nil
+				}
+				names := make([]string, 0, len(requests))
+				for name := range requests {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					printApprovalRequest(os.Stdout, requests[name])
+				}
+				return nil
+			}
+
+			req, ok := requests[stageName]
+			if !ok {
+				return fmt.Errorf("stage %s is not awaiting approval", stageName)
+			}
+			approver := approverFlag
+			if approver == "" {
+				approver = currentApprover()
+			}
+			decision := evidence.ApprovalRecord{
+				Decision:    evidence.ApprovalApproved,
+				Approver:    approver,
+				Timestamp:   time.Now().UTC(),
+				Comment:     commentFlag,
+				Source:      "cli",
+				RequestHash: req.Hash,
+			}
+			if rejectFlag {
+				decision.Decision = evidence.ApprovalRejected
+			}
+			if err := evidence.WriteApproval(runDir, stageName, decision); err != nil {
+				return fmt.Errorf("failed to record decision: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "Stage %s %s by %s.\n", stageName, decision.Decision, approver)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&runDir, "run", "", "run directory or run ID under .flowgate/runs (required)")
+	cmd.Flags().StringVar(&stageName, "stage", "", "stage to decide on (lists pending stages when omitted)")
+	cmd.Flags().BoolVar(&rejectFlag, "reject", false, "reject instead of approve")
+	cmd.Flags().StringVarP(&commentFlag, "comment", "m", "", "comment recorded with the decision")
+	cmd.Flags().StringVar(&approverFlag, "approver", "", "approver identity (defaults to the current user)")
+
+	return cmd
+}
+
+// resolveRunDir accepts a run directory or the ID of a run under the
+// current directory's .flowgate/runs.
+func resolveRunDir(run string) string {
+	if info, err := os.Stat(run); err == nil && info.IsDir() {
+		return run
+	}
+	return filepath.Join(".flowgate", "runs", run)
+}
+
+// currentApprover identifies the person running flowgate.
+func currentApprover() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+// printApprovalRequest shows what a stage asks to approve.
+func printApprovalRequest(w io.Writer, req *evidence.ApprovalRequest) {
+	fmt.Fprintf(w, "== %s awaits approval ==\n", req.Stage)
+	if req.Message != "" {
+		fmt.Fprintf(w, "%s\n", strings.TrimRight(req.Message, "\n"))
+	}
+	for _, subject := range req.Subjects {
+		fmt.Fprintf(w, "-- %s --\n", subject.Stage)
+		for _, g := range subject.GateResults {
+			status := "pass"
+			if !g.Passed {
+				status = "FAIL"
+			}
+			fmt.Fprintf(w, "gate %s: %s (score %d)\n", g.Name, status, g.Score)
+		}
+		for _, path := range subject.AppliedFiles {
+			fmt.Fprintf(w, "changes %s\n", path)
+		}
+		for _, path := range subject.DeletedFiles {
+			fmt.Fprintf(w, "deletes %s\n", path)
+		}
+		if subject.Output != "" {
+			fmt.Fprintf(w, "%s\n", strings.TrimRight(subject.Output, "\n"))
+		}
+	}
+	fmt.Fprintf(w, "Decide with: flowgate approve --run %s --stage %s [--reject] [-m comment]\n", req.RunDir, req.Stage)
+}
+
+// terminalApprover asks for decisions on the terminal when stdin is one.
+// Otherwise it shows the request and lets the run wait for flowgate approve.
+type terminalApprover struct {
+	mu          sync.Mutex
+	in          *bufio.Reader
+	out         io.Writer
+	interactive bool
+}
+
+func newTerminalApprover() *terminalApprover {
+	interactive := false
+	if info, err := os.Stdin.Stat(); err == nil {
+		interactive = info.Mode()&os.ModeCharDevice != 0
+	}
+	return &terminalApprover{in: bufio.NewReader(os.Stdin), out: os.Stderr, interactive: interactive}
+}
+
+func (a *terminalApprover) approve(ctx context.Context, req evidence.ApprovalRequest) (*evidence.ApprovalRecord, error) {
+	// Stages may wait for approval concurrently; ask one at a time.
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	printApprovalRequest(a.out, &req)
+	if !a.interactive {
+		return nil, nil
+	}
+
+	answer, err := a.ask(ctx, fmt.Sprintf("Approve %s? [y/N] ", req.Stage))
+	if err != nil {
+		return nil, err
+	}
+	decision := &evidence.ApprovalRecord{
+		Decision:  evidence.ApprovalRejected,
+		Approver:  currentApprover(),
+		Timestamp: time.Now().UTC(),
+		Source:    "tty",
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		decision.Decision = evidence.ApprovalApproved
+	}
+	comment, err := a.ask(ctx, "Comment (optional): ")
+	if err != nil {
+		return nil, err
+	}
+	decision.Comment = comment
+	return decision, nil
+}
+
+// ask prints prompt and reads a line, giving up when ctx ends.
+func (a *terminalApprover) ask(ctx context.Context, prompt string) (string, error) {
+	fmt.Fprint(a.out, prompt)
+	type line struct {
+		text string
+		err  error
+	}
+	lines := make(chan line, 1)
+	go func() {
+		text, err := a.in.ReadString('\n')
+		if err == io.EOF && text != "" {
+			err = nil
+		}
+		lines <- line{strings.TrimSpace(text), err}
+	}()
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case l := <-lines:
+		return l.text, l.err
+	}
+}
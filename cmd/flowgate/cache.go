@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/zen-systems/flowgate/pkg/cache"
+	"github.com/zen-systems/flowgate/pkg/config"
+)
+
+// openResponseCache returns the configured response cache, or nil when it is
+// disabled. A cache that cannot be opened is reported and skipped rather than
+// failing the run.
+func openResponseCache(cfg *config.Config, disabled bool) *cache.Cache {
+	if disabled || !cfg.Cache.Enabled {
+		return nil
+	}
+	c, err := cache.New(cfg.Cache.Dir, cache.Options{TTL: cfg.Cache.TTL, MaxBytes: cfg.Cache.MaxBytes})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: response cache disabled: %v\n", err)
+		return nil
+	}
+	return c
+}
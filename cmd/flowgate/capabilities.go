@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zen-systems/flowgate/pkg/config"
+	"github.com/zen-systems/flowgate/pkg/gate"
+)
+
+// repoCapabilitiesFile is the repo-level capabilities file, relative to the
+// workspace.
+const repoCapabilitiesFile = ".flowgate/capabilities.yaml"
+
+// loadCapabilities loads the command gate policy for a workspace from the
+// user and repo capabilities files.
+func loadCapabilities(cfg *config.Config, workspace string) (*gate.CapabilitySet, error) {
+	if workspace == "" {
+		workspace = "."
+	}
+	userPath := ""
+	if cfg.ConfigDir != "" {
+		userPath = filepath.Join(cfg.ConfigDir, "capabilities.yaml")
+	}
+	set, err := gate.LoadCapabilities(userPath, filepath.Join(workspace, repoCapabilitiesFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load capabilities: %w", err)
+	}
+	return set, nil
+}
+
+func capabilitiesCmd() *cobra.Command {
+	var workspaceFlag string
+
+	cmd := &cobra.Command{
+		Use:   "capabilities [name...]",
+		Short: "Show the command gate capability policy",
+		Long: `Lists the capabilities command gates may use, merged from the built-in
+set, ~/.flowgate/capabilities.yaml and the workspace's
+.flowgate/capabilities.yaml, and explains what each template allows.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			set, err := loadCapabilities(cfg, workspaceFlag)
+			if err != nil {
+				return err
+			}
+			return printCapabilities(os.Stdout, set, args)
+		},
+	}
+
+	cmd.Flags().StringVar(&workspaceFlag, "workspace", "", "workspace whose .flowgate/capabilities.yaml applies (defaults to the current directory)")
+
+	return cmd
+}
+
+func printCapabilities(w io.Writer, set *gate.CapabilitySet, names []string) error {
+	fmt.Fprintf(w, "User file: %s\n", sourceOrNone(set.UserFile))
+	fmt.Fprintf(w, "Repo file: %s\n", sourceOrNone(set.RepoFile))
+	fmt.Fprintf(w, "Repo policy: %s\n", set.RepoPolicy)
+
+	if len(names) == 0 {
+		names = set.Names()
+	}
+	for _, name := range names {
+		c, ok := set.Lookup(name)
+		if !ok {
+			return fmt.Errorf("unknown capability %s", name)
+		}
+		fmt.Fprintf(w, "\n%s (%s)\n", c.Name, c.Source)
+		if c.Description != "" {
+			fmt.Fprintf(w, "  %s\n", c.Description)
+		}
+		used := make(map[string]bool)
+		for _, tmpl := range c.Templates {
+			fmt.Fprintf(w, "  $ %s\n", tmpl.String())
+			for _, arg := range tmpl.Args {
+				used[arg] = true
+			}
+		}
+		if used[gate.PlaceholderPath] {
+			fmt.Fprintf(w, "  %s: a relative path inside the workspace\n", gate.PlaceholderPath)
+		}
+		if used[gate.PlaceholderPkg] {
+			fmt.Fprintf(w, "  %s: one of %s\n", gate.PlaceholderPkg, strings.Join(c.EffectivePackages(), ", "))
+		}
+		if used[gate.PlaceholderTestRegex] {
+			fmt.Fprintf(w, "  %s: a valid regular expression not starting with '-'\n", gate.PlaceholderTestRegex)
+		}
+		if used[gate.PlaceholderFileGlob] {
+			fmt.Fprintf(w, "  %s: a workspace file matching %s\n", gate.PlaceholderFileGlob, strings.Join(c.FileGlobs, ", "))
+		}
+	}
+	return nil
+}
+
+func sourceOrNone(path string) string {
+	if path == "" {
+		return "(none)"
+	}
+	return path
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+)
+
+// useCassette wraps adapters for --record or replaces them for --replay. The
+// returned save function writes a recording and is a no-op otherwise.
+func useCassette(adapters map[string]adapter.Adapter, recordPath, replayPath string) (map[string]adapter.Adapter, func() error, error) {
+	noop := func() error { return nil }
+	switch {
+	case recordPath != "" && replayPath != "":
+		return nil, nil, fmt.Errorf("--record and --replay are mutually exclusive")
+
+	case recordPath != "":
+		cassette := adapter.NewCassette()
+		wrapped := make(map[string]adapter.Adapter, len(adapters))
+		for name, a := range adapters {
+			wrapped[name] = adapter.NewRecordingAdapter(a, cassette)
+		}
+		return wrapped, func() error { return cassette.Save(recordPath) }, nil
+
+	case replayPath != "":
+		cassette, err := adapter.LoadCassette(replayPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		replay := cassette.ReplayAdapters()
+		if _, ok := replay["mock"]; !ok {
+			replay["mock"] = adapter.NewMockAdapter()
+		}
+		return replay, noop, nil
+	}
+	return adapters, noop, nil
+}
@@ -10,6 +10,7 @@ import (
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/zen-systems/flowgate/pkg/adapter"
@@ -20,6 +21,7 @@ import (
 	"github.com/zen-systems/flowgate/pkg/crypto"
 	"github.com/zen-systems/flowgate/pkg/curator"
 	"github.com/zen-systems/flowgate/pkg/curator/sources"
+	"github.com/zen-systems/flowgate/pkg/evidence"
 	"github.com/zen-systems/flowgate/pkg/pipeline"
 	"github.com/zen-systems/flowgate/pkg/policy"
 	"github.com/zen-systems/flowgate/pkg/router"
@@ -63,6 +65,10 @@ func main() {
 	rootCmd.AddCommand(modelsCmd())
 	rootCmd.AddCommand(validateCmd())
 	rootCmd.AddCommand(runCmd())
+	rootCmd.AddCommand(resumeCmd())
+	rootCmd.AddCommand(rollbackCmd())
+	rootCmd.AddCommand(approveCmd())
+	rootCmd.AddCommand(capabilitiesCmd())
 	rootCmd.AddCommand(attestCmd())
 	rootCmd.AddCommand(verifyCmd())
 
@@ -75,6 +81,7 @@ func askCmd() *cobra.Command {
 	var deepFlag bool
 	var gateFlag string
 	var maxRetries int
+	var streamFlag bool
 
 	cmd := &cobra.Command{
 		Use:   "ask [prompt]",
@@ -169,12 +176,24 @@ func askCmd() *cobra.Command {
 				p.Stages[0].Gates = []string{"hollowcheck"}
 			}
 
-			result, err := pipeline.Run(context.Background(), p, pipeline.RunOptions{Input: prompt, RoutingConfig: cfg.RoutingConfig})
+			opts := pipeline.RunOptions{Input: prompt, RoutingConfig: cfg.RoutingConfig}
+			var printer *streamPrinter
+			if streamFlag {
+				printer = newStreamPrinter(os.Stdout, os.Stderr, false)
+				opts.OnStream = printer.handle
+			}
+
+			result, err := pipeline.Run(context.Background(), p, opts)
+			if printer != nil {
+				printer.finish()
+			}
 			if err != nil {
 				return err
 			}
 
-			fmt.Println(result.Stages["ask"].Artifact.Content)
+			if printer == nil {
+				fmt.Println(result.Stages["ask"].Artifact.Content)
+			}
 			return nil
 		},
 	}
@@ -184,6 +203,7 @@ func askCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&deepFlag, "deep", false, "use context curator for complex queries")
 	cmd.Flags().StringVar(&gateFlag, "gate", "", "enable quality gate with contract file (e.g., hollowcheck.yaml)")
 	cmd.Flags().IntVar(&maxRetries, "retries", 3, "max repair attempts when gate fails")
+	cmd.Flags().BoolVar(&streamFlag, "stream", true, "print output as it is generated")
 
 	return cmd
 }
@@ -305,6 +325,16 @@ func modelsCmd() *cobra.Command {
 				}
 				fmt.Fprintf(w, "%s\t%s\t%s\n", provider, models, status)
 			}
+			for _, entry := range cfg.OpenAICompatible {
+				if len(aliases.GetProviderModels(entry.Name)) > 0 {
+					continue
+				}
+				status := "no key"
+				if entry.Available() {
+					status = "ready"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", entry.Name, formatList(entry.Models), status)
+			}
 
 			return w.Flush()
 		},
@@ -347,6 +377,9 @@ func validateAliases(cfg *config.Config) error {
 		return nil
 	}
 
+	for _, entry := range cfg.OpenAICompatible {
+		aliases.AddProvider(entry.Name, entry.Models)
+	}
 	errors := aliases.ValidateRoutingConfig(cfg.RoutingConfig)
 	if len(errors) == 0 {
 		fmt.Println("All models in routing.yaml are valid.")
@@ -399,6 +432,12 @@ func runCmd() *cobra.Command {
 	var applyFlag bool
 	var approveFlag bool
 	var maxBudgetUSD float64
+	var maxParallel int
+	var streamFlag bool
+	var recordFlag string
+	var replayFlag string
+	var noCacheFlag bool
+	var timeoutFlag time.Duration
 
 	cmd := &cobra.Command{
 		Use:   "run",
@@ -432,19 +471,53 @@ func runCmd() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("failed to create adapters: %w", err)
 			}
+			adapters, saveCassette, err := useCassette(adapters, recordFlag, replayFlag)
+			if err != nil {
+				return err
+			}
 			p.Adapters = adapters
 
-			result, err := pipeline.Run(context.Background(), p, pipeline.RunOptions{
+			workspace := workspaceFlag
+			if workspace == "" {
+				workspace = p.Workspace.Path
+			}
+			capabilities, err := loadCapabilities(cfg, workspace)
+			if err != nil {
+				return err
+			}
+
+			opts := pipeline.RunOptions{
 				Input:           input,
 				WorkspacePath:   workspaceFlag,
 				EvidenceDir:     outFlag,
 				PipelinePath:    pipelineFile,
 				RoutingConfig:   cfg.RoutingConfig,
+				Aliases:         aliases,
 				MaxBudgetUSD:    maxBudgetUSD,
 				ApplyForReal:    applyFlag,
 				ApplyApproved:   approveFlag,
+				MaxParallel:     maxParallel,
+				Timeout:         timeoutFlag,
+				Capabilities:    capabilities,
+				Approve:         newTerminalApprover().approve,
 				VTPOrchestrator: vtpOrchestrator, // Pass the global orchestrator
-			})
+			}
+			// Cassettes must see every call, so the cache is bypassed while
+			// recording or replaying.
+			opts.Cache = openResponseCache(cfg, noCacheFlag || recordFlag != "" || replayFlag != "")
+			var printer *streamPrinter
+			if streamFlag {
+				printer = newStreamPrinter(os.Stderr, os.Stderr, true)
+				opts.OnStream = printer.handle
+			}
+
+			result, err := pipeline.Run(context.Background(), p, opts)
+			if printer != nil {
+				printer.finish()
+			}
+			if saveErr := saveCassette(); saveErr != nil {
+				return fmt.Errorf("failed to save cassette: %w", saveErr)
+			}
 			if err != nil {
 				return err
 			}
@@ -461,6 +534,161 @@ func runCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&applyFlag, "apply", false, "apply changes to the real workspace")
 	cmd.Flags().BoolVar(&approveFlag, "yes", false, "approve applying changes to the real workspace")
 	cmd.Flags().Float64Var(&maxBudgetUSD, "max-budget-usd", 0, "maximum USD budget for adapter calls (0 disables)")
+	cmd.Flags().IntVar(&maxParallel, "parallel", 0, "maximum number of stages to run concurrently (defaults to max_parallel or 4)")
+	cmd.Flags().BoolVar(&streamFlag, "stream", true, "print stage output to stderr as it is generated")
+	cmd.Flags().StringVar(&recordFlag, "record", "", "record every adapter call to a cassette file")
+	cmd.Flags().StringVar(&replayFlag, "replay", "", "answer adapter calls from a cassette file instead of the network")
+	cmd.Flags().BoolVar(&noCacheFlag, "no-cache", false, "bypass the response cache")
+	cmd.Flags().DurationVar(&timeoutFlag, "timeout", 0, "abort the run after this long, e.g. 30m (0 disables)")
+
+	return cmd
+}
+
+func resumeCmd() *cobra.Command {
+	var runDir string
+	var pipelineFile string
+	var inputFlag string
+	var workspaceFlag string
+	var applyFlag bool
+	var approveFlag bool
+	var maxBudgetUSD float64
+	var maxParallel int
+	var streamFlag bool
+	var noCacheFlag bool
+	var timeoutFlag time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Resume an interrupted pipeline run",
+		Long: `Continues a run from its evidence bundle. Stages that completed are
+reused after their output hashes are verified; the remaining stages run
+again and append to the same run directory.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if runDir == "" {
+				return fmt.Errorf("--run is required")
+			}
+
+			runRecord, err := evidence.ReadRun(runDir)
+			if err != nil {
+				return fmt.Errorf("failed to read run: %w", err)
+			}
+			if pipelineFile == "" {
+				pipelineFile = runRecord.PipelineFile
+			}
+			if pipelineFile == "" {
+				return fmt.Errorf("run does not record a pipeline file; pass --file")
+			}
+
+			p, err := pipeline.LoadManifest(pipelineFile)
+			if err != nil {
+				return err
+			}
+
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			adapters, err := createAdapters(cfg)
+			if err != nil {
+				return fmt.Errorf("failed to create adapters: %w", err)
+			}
+			p.Adapters = adapters
+
+			workspace := workspaceFlag
+			if workspace == "" {
+				workspace = runRecord.Workspace
+			}
+			if workspace == "" {
+				workspace = p.Workspace.Path
+			}
+			capabilities, err := loadCapabilities(cfg, workspace)
+			if err != nil {
+				return err
+			}
+
+			opts := pipeline.RunOptions{
+				Input:           inputFlag,
+				WorkspacePath:   workspaceFlag,
+				PipelinePath:    pipelineFile,
+				RoutingConfig:   cfg.RoutingConfig,
+				Aliases:         aliases,
+				MaxBudgetUSD:    maxBudgetUSD,
+				ApplyForReal:    applyFlag,
+				ApplyApproved:   approveFlag,
+				MaxParallel:     maxParallel,
+				Logger:          log.Printf,
+				Timeout:         timeoutFlag,
+				Cache:           openResponseCache(cfg, noCacheFlag),
+				Capabilities:    capabilities,
+				Approve:         newTerminalApprover().approve,
+				VTPOrchestrator: vtpOrchestrator,
+			}
+			var printer *streamPrinter
+			if streamFlag {
+				printer = newStreamPrinter(os.Stderr, os.Stderr, true)
+				opts.OnStream = printer.handle
+			}
+
+			result, err := pipeline.Resume(context.Background(), p, runDir, opts)
+			if printer != nil {
+				printer.finish()
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(os.Stderr, "Run complete. Evidence: %s\n", result.EvidenceDir)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&runDir, "run", "", "run directory to resume (required)")
+	cmd.Flags().StringVarP(&pipelineFile, "file", "f", "", "pipeline manifest path (defaults to the one recorded in run.json)")
+	cmd.Flags().StringVarP(&inputFlag, "input", "i", "", "original input, required only for runs that did not record it")
+	cmd.Flags().StringVar(&workspaceFlag, "workspace", "", "workspace path (defaults to the recorded workspace)")
+	cmd.Flags().BoolVar(&applyFlag, "apply", false, "apply changes to the real workspace")
+	cmd.Flags().BoolVar(&approveFlag, "yes", false, "approve applying changes to the real workspace")
+	cmd.Flags().Float64Var(&maxBudgetUSD, "max-budget-usd", 0, "maximum USD budget for adapter calls, including calls made before the resume (0 keeps the recorded budget)")
+	cmd.Flags().IntVar(&maxParallel, "parallel", 0, "maximum number of stages to run concurrently (defaults to max_parallel or 4)")
+	cmd.Flags().BoolVar(&streamFlag, "stream", true, "print stage output to stderr as it is generated")
+	cmd.Flags().BoolVar(&noCacheFlag, "no-cache", false, "bypass the response cache")
+	cmd.Flags().DurationVar(&timeoutFlag, "timeout", 0, "abort the run after this long, e.g. 30m (0 disables)")
+
+	return cmd
+}
+
+func rollbackCmd() *cobra.Command {
+	var runDir string
+	var workspaceFlag string
+
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Undo the changes a run applied to the real workspace",
+		Long: `Restores the files a run changed with --apply to their previous
+contents, using the apply journal in its evidence bundle. Fails without
+changing anything if a file was modified after the run.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if runDir == "" {
+				return fmt.Errorf("--run is required")
+			}
+
+			result, err := pipeline.Rollback(resolveRunDir(runDir), workspaceFlag)
+			if err != nil {
+				return err
+			}
+			for _, path := range result.Restored {
+				fmt.Fprintf(os.Stderr, "Restored %s\n", path)
+			}
+			for _, path := range result.Removed {
+				fmt.Fprintf(os.Stderr, "Removed %s\n", path)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&runDir, "run", "", "run directory or run ID under .flowgate/runs to roll back (required)")
+	cmd.Flags().StringVar(&workspaceFlag, "workspace", "", "workspace path (defaults to the recorded workspace)")
 
 	return cmd
 }
@@ -577,6 +805,24 @@ func createAdapters(cfg *config.Config) (map[string]adapter.Adapter, error) {
 		adapters["deepseek"] = a
 	}
 
+	for _, entry := range cfg.OpenAICompatible {
+		if !entry.Available() {
+			continue
+		}
+		a, err := adapter.NewOpenAICompatibleAdapter(adapter.OpenAICompatibleOptions{
+			Name:           entry.Name,
+			BaseURL:        entry.BaseURL,
+			APIKey:         entry.APIKey(),
+			Models:         entry.Models,
+			Headers:        entry.Headers,
+			ResponseFormat: entry.ResponseFormat,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s adapter: %w", entry.Name, err)
+		}
+		adapters[entry.Name] = a
+	}
+
 	adapters["mock"] = adapter.NewMockAdapter()
 
 	return adapters, nil
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/zen-systems/flowgate/pkg/pipeline"
+)
+
+// streamPrinter renders stage output as it arrives. A header is written to
+// status whenever output switches to a different stage or attempt, so that
+// interleaved parallel stages stay readable, and a marker whenever output is
+// discarded because a call is retried or an attempt is repaired.
+type streamPrinter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	status  io.Writer
+	headers bool
+
+	stage   string
+	attempt int
+	sample  int
+	midLine bool
+}
+
+func newStreamPrinter(out, status io.Writer, headers bool) *streamPrinter {
+	return &streamPrinter{out: out, status: status, headers: headers}
+}
+
+func (p *streamPrinter) handle(event pipeline.StreamEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if event.Stage != p.stage || event.Attempt != p.attempt || event.Sample != p.sample {
+		if p.midLine {
+			fmt.Fprintln(p.out)
+		}
+		switch {
+		case p.headers && event.Sample > 0:
+			fmt.Fprintf(p.status, "== %s (attempt %d, sample %d) ==\n", event.Stage, event.Attempt, event.Sample)
+		case p.headers:
+			fmt.Fprintf(p.status, "== %s (attempt %d) ==\n", event.Stage, event.Attempt)
+		case event.Sample > 0:
+			fmt.Fprintf(p.status, "-- sample %d --\n", event.Sample)
+		case event.Attempt > 1:
+			fmt.Fprintf(p.status, "-- repair attempt %d --\n", event.Attempt)
+		}
+		p.stage = event.Stage
+		p.attempt = event.Attempt
+		p.sample = event.Sample
+		p.midLine = false
+	}
+
+	if event.Reset {
+		if p.midLine {
+			fmt.Fprintln(p.out)
+			p.midLine = false
+		}
+		fmt.Fprintln(p.status, "-- output above discarded --")
+		return
+	}
+	if event.Delta == "" {
+		return
+	}
+	fmt.Fprint(p.out, event.Delta)
+	p.midLine = event.Delta[len(event.Delta)-1] != '\n'
+}
+
+// finish terminates a partially written line.
+func (p *streamPrinter) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.midLine {
+		fmt.Fprintln(p.out)
+		p.midLine = false
+	}
+}
@@ -39,21 +39,71 @@ func (a *AnthropicAdapter) Models() []string {
 
 // Generate sends a prompt to Claude and returns the response as an artifact.
 func (a *AnthropicAdapter) Generate(ctx context.Context, model string, prompt string) (*Response, error) {
-	resp, err := a.client.Messages.New(ctx, anthropic.MessageNewParams{
-		Model:     anthropic.Model(model),
-		MaxTokens: 4096,
-		Messages: []anthropic.MessageParam{
-			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
-		},
-	})
+	return a.Chat(ctx, model, UserRequest(prompt))
+}
+
+// Chat sends a structured request to Claude.
+func (a *AnthropicAdapter) Chat(ctx context.Context, model string, req Request) (*Response, error) {
+	resp, err := a.client.Messages.New(ctx, anthropicParams(model, req))
 	if err != nil {
-		var apiErr *anthropic.Error
-		if errors.As(err, &apiErr) {
-			return nil, &AdapterError{Status: apiErr.StatusCode, Temporary: apiErr.StatusCode == 429 || apiErr.StatusCode >= 500, Err: err}
+		return nil, anthropicError(err)
+	}
+	return a.response(model, req, resp), nil
+}
+
+// Stream sends a structured request to Claude and reports text deltas.
+func (a *AnthropicAdapter) Stream(ctx context.Context, model string, req Request, onDelta StreamFunc) (*Response, error) {
+	stream := a.client.Messages.NewStreaming(ctx, anthropicParams(model, req))
+	defer stream.Close()
+
+	var message anthropic.Message
+	for stream.Next() {
+		event := stream.Current()
+		if err := message.Accumulate(event); err != nil {
+			return nil, fmt.Errorf("anthropic stream error: %w", err)
+		}
+		if delta, ok := event.AsAny().(anthropic.ContentBlockDeltaEvent); ok && delta.Delta.Type == "text_delta" && delta.Delta.Text != "" {
+			onDelta(delta.Delta.Text)
 		}
-		return nil, fmt.Errorf("anthropic API error: %w", err)
 	}
+	if err := stream.Err(); err != nil {
+		return nil, anthropicError(err)
+	}
+	return a.response(model, req, &message), nil
+}
+
+func anthropicParams(model string, req Request) anthropic.MessageNewParams {
+	params := anthropic.MessageNewParams{
+		Model:         anthropic.Model(model),
+		MaxTokens:     int64(req.MaxTokensOrDefault()),
+		StopSequences: req.Stop,
+	}
+	if req.System != "" {
+		params.System = []anthropic.TextBlockParam{{Text: req.System}}
+	}
+	if req.Temperature != nil {
+		params.Temperature = anthropic.Float(*req.Temperature)
+	}
+	for _, msg := range req.Messages {
+		block := anthropic.NewTextBlock(msg.Content)
+		if msg.Role == RoleAssistant {
+			params.Messages = append(params.Messages, anthropic.NewAssistantMessage(block))
+		} else {
+			params.Messages = append(params.Messages, anthropic.NewUserMessage(block))
+		}
+	}
+	return params
+}
+
+func anthropicError(err error) error {
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) {
+		return &AdapterError{Status: apiErr.StatusCode, Temporary: apiErr.StatusCode == 429 || apiErr.StatusCode >= 500, Err: err}
+	}
+	return fmt.Errorf("anthropic API error: %w", err)
+}
 
+func (a *AnthropicAdapter) response(model string, req Request, resp *anthropic.Message) *Response {
 	var content string
 	for _, block := range resp.Content {
 		if block.Type == "text" {
@@ -61,11 +111,11 @@ func (a *AnthropicAdapter) Generate(ctx context.Context, model string, prompt st
 		}
 	}
 
-	art := artifact.New(content, a.Name(), model, prompt)
+	art := artifact.New(content, a.Name(), model, req.Flatten())
 	usage := &Usage{
 		PromptTokens:     int(resp.Usage.InputTokens),
 		CompletionTokens: int(resp.Usage.OutputTokens),
 		TotalTokens:      int(resp.Usage.InputTokens + resp.Usage.OutputTokens),
 	}
-	return &Response{Artifact: art, Usage: usage}, nil
+	return &Response{Artifact: art, Usage: usage}
 }
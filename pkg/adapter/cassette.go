@@ -0,0 +1,259 @@
+package adapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/zen-systems/flowgate/pkg/artifact"
+)
+
+// CassetteVersion is the current cassette file format version.
+const CassetteVersion = 1
+
+// ErrCassetteMiss is returned when a replayed request was never recorded.
+var ErrCassetteMiss = errors.New("no recorded interaction")
+
+// Cassette holds recorded adapter interactions for offline replay.
+type Cassette struct {
+	Version      int                 `json:"version"`
+	Adapters     map[string][]string `json:"adapters"`
+	Interactions []Interaction       `json:"interactions"`
+
+	mu     sync.Mutex
+	cursor map[string]int
+}
+
+// Interaction is a single recorded adapter call. Failed calls are recorded
+// too so that retries and fallbacks replay the same way.
+type Interaction struct {
+	Adapter     string `json:"adapter"`
+	Model       string `json:"model"`
+	RequestHash string `json:"request_hash"`
+	Prompt      string `json:"prompt"`
+	Response    string `json:"response,omitempty"`
+	Usage       *Usage `json:"usage,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	Temporary   bool   `json:"temporary,omitempty"`
+}
+
+// NewCassette creates an empty cassette for recording.
+func NewCassette() *Cassette {
+	return &Cassette{
+		Version:  CassetteVersion,
+		Adapters: make(map[string][]string),
+	}
+}
+
+// LoadCassette reads a cassette file for replay.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	c := NewCassette()
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	if c.Version != CassetteVersion {
+		return nil, fmt.Errorf("unsupported cassette version %d", c.Version)
+	}
+	if c.Adapters == nil {
+		c.Adapters = make(map[string][]string)
+	}
+	return c, nil
+}
+
+// Save writes the cassette to path.
+func (c *Cassette) Save(path string) error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create cassette dir: %w", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+func (c *Cassette) record(a Adapter, model string, req Request, resp *Response, callErr error) {
+	interaction := Interaction{
+		Adapter:     a.Name(),
+		Model:       model,
+		RequestHash: RequestHash(req),
+		Prompt:      req.Flatten(),
+	}
+	if callErr != nil {
+		interaction.Error = callErr.Error()
+		interaction.Temporary = IsTransient(callErr)
+		var adapterErr *AdapterError
+		if errors.As(callErr, &adapterErr) {
+			interaction.Status = adapterErr.Status
+		}
+	} else if resp != nil {
+		if resp.Artifact != nil {
+			interaction.Response = resp.Artifact.Content
+		}
+		interaction.Usage = resp.Usage
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.Adapters[a.Name()]; !ok {
+		c.Adapters[a.Name()] = append([]string(nil), a.Models()...)
+	}
+	c.Interactions = append(c.Interactions, interaction)
+}
+
+// next returns the next recorded interaction for the key. Identical requests
+// replay in recording order; once exhausted the last one repeats.
+func (c *Cassette) next(adapterName, model, hash string) (Interaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := adapterName + "/" + model + "/" + hash
+	if c.cursor == nil {
+		c.cursor = make(map[string]int)
+	}
+	var matches []int
+	for i, interaction := range c.Interactions {
+		if interaction.Adapter == adapterName && interaction.Model == model && interaction.RequestHash == hash {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) == 0 {
+		return Interaction{}, false
+	}
+	idx := c.cursor[key]
+	if idx >= len(matches) {
+		idx = len(matches) - 1
+	}
+	c.cursor[key] = idx + 1
+	return c.Interactions[matches[idx]], true
+}
+
+// RequestHash returns the key used to match a request during replay.
+func RequestHash(req Request) string {
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordingAdapter passes calls through to an adapter and records them.
+type RecordingAdapter struct {
+	inner    Adapter
+	cassette *Cassette
+}
+
+// NewRecordingAdapter wraps inner so that every call is added to cassette.
+func NewRecordingAdapter(inner Adapter, cassette *Cassette) *RecordingAdapter {
+	return &RecordingAdapter{inner: inner, cassette: cassette}
+}
+
+// Name returns the wrapped adapter's identifier.
+func (a *RecordingAdapter) Name() string {
+	return a.inner.Name()
+}
+
+// Models returns the wrapped adapter's models.
+func (a *RecordingAdapter) Models() []string {
+	return a.inner.Models()
+}
+
+// Generate records a single-prompt call.
+func (a *RecordingAdapter) Generate(ctx context.Context, model string, prompt string) (*Response, error) {
+	return a.Chat(ctx, model, UserRequest(prompt))
+}
+
+// Chat records a structured call.
+func (a *RecordingAdapter) Chat(ctx context.Context, model string, req Request) (*Response, error) {
+	resp, err := Chat(ctx, a.inner, model, req)
+	a.cassette.record(a.inner, model, req, resp, err)
+	return resp, err
+}
+
+// Stream records a streamed call once it completes.
+func (a *RecordingAdapter) Stream(ctx context.Context, model string, req Request, onDelta StreamFunc) (*Response, error) {
+	resp, err := Stream(ctx, a.inner, model, req, onDelta)
+	a.cassette.record(a.inner, model, req, resp, err)
+	return resp, err
+}
+
+// ReplayAdapter answers calls from a cassette without network access.
+type ReplayAdapter struct {
+	name     string
+	models   []string
+	cassette *Cassette
+}
+
+// ReplayAdapters returns a replay adapter for every adapter in the cassette.
+func (c *Cassette) ReplayAdapters() map[string]Adapter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	adapters := make(map[string]Adapter, len(c.Adapters))
+	for name, models := range c.Adapters {
+		adapters[name] = &ReplayAdapter{name: name, models: models, cassette: c}
+	}
+	return adapters
+}
+
+// Name returns the recorded adapter identifier.
+func (a *ReplayAdapter) Name() string {
+	return a.name
+}
+
+// Models returns the models the recorded adapter reported.
+func (a *ReplayAdapter) Models() []string {
+	return a.models
+}
+
+// Generate replays a single-prompt call.
+func (a *ReplayAdapter) Generate(ctx context.Context, model string, prompt string) (*Response, error) {
+	return a.Chat(ctx, model, UserRequest(prompt))
+}
+
+// Chat replays a structured call, failing with ErrCassetteMiss when the
+// request was not recorded.
+func (a *ReplayAdapter) Chat(ctx context.Context, model string, req Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	hash := RequestHash(req)
+	interaction, ok := a.cassette.next(a.name, model, hash)
+	if !ok {
+		return nil, fmt.Errorf("cassette: %w for %s/%s (request %s): %q", ErrCassetteMiss, a.name, model, hash[:12], truncatePrompt(req.Flatten(), 80))
+	}
+	if interaction.Error != "" {
+		return nil, &AdapterError{Status: interaction.Status, Temporary: interaction.Temporary, Err: errors.New(interaction.Error)}
+	}
+
+	art := artifact.New(interaction.Response, a.name, model, req.Flatten())
+	return &Response{Artifact: art, Usage: interaction.Usage}, nil
+}
+
+func truncatePrompt(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,105 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// failingAdapter returns err on the first call and then answers "recovered".
+type failingAdapter struct {
+	err   error
+	calls int
+}
+
+func (a *failingAdapter) Generate(ctx context.Context, model string, prompt string) (*Response, error) {
+	a.calls++
+	if a.calls == 1 {
+		return nil, a.err
+	}
+	return NewMockAdapterWithResponses(map[string]string{prompt: "recovered"}, "").Generate(ctx, model, prompt)
+}
+
+func (a *failingAdapter) Name() string { return "flaky" }
+
+func (a *failingAdapter) Models() []string { return []string{"flaky-1"} }
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	cassette := NewCassette()
+	mock := NewMockAdapterWithResponses(map[string]string{"hello": "world"}, "default")
+	rec := NewRecordingAdapter(mock, cassette)
+
+	req := UserRequest("hello")
+	req.System = "be nice"
+	recorded, err := rec.Chat(context.Background(), "mock-1", req)
+	if err != nil {
+		t.Fatalf("record chat: %v", err)
+	}
+	var streamed string
+	if _, err := rec.Stream(context.Background(), "mock-1", UserRequest("hello"), func(d string) { streamed += d }); err != nil {
+		t.Fatalf("record stream: %v", err)
+	}
+	if streamed != "world" {
+		t.Fatalf("recording should pass deltas through, got %q", streamed)
+	}
+
+	path := filepath.Join(t.TempDir(), "run.cassette.json")
+	if err := cassette.Save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	loaded, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	replay := loaded.ReplayAdapters()["mock"]
+	if replay == nil {
+		t.Fatal("expected replay adapter for recorded adapter")
+	}
+	if models := replay.Models(); len(models) != len(mock.Models()) {
+		t.Fatalf("expected recorded models, got %v", models)
+	}
+	resp, err := Chat(context.Background(), replay, "mock-1", req)
+	if err != nil {
+		t.Fatalf("replay chat: %v", err)
+	}
+	if resp.Artifact.Content != recorded.Artifact.Content || resp.Artifact.Adapter != "mock" {
+		t.Fatalf("unexpected replayed artifact %+v", resp.Artifact)
+	}
+
+	_, err = replay.Generate(context.Background(), "mock-1", "something new")
+	if !errors.Is(err, ErrCassetteMiss) {
+		t.Fatalf("expected cassette miss, got %v", err)
+	}
+	_, err = replay.Generate(context.Background(), "other-model", "hello")
+	if !errors.Is(err, ErrCassetteMiss) {
+		t.Fatalf("expected cassette miss for a different model, got %v", err)
+	}
+}
+
+func TestCassetteReplaysErrorsInOrder(t *testing.T) {
+	cassette := NewCassette()
+	rec := NewRecordingAdapter(&failingAdapter{err: &AdapterError{Status: 503, Err: errors.New("overloaded")}}, cassette)
+	if _, err := rec.Generate(context.Background(), "flaky-1", "hi"); err == nil {
+		t.Fatal("expected first call to fail")
+	}
+	if _, err := rec.Generate(context.Background(), "flaky-1", "hi"); err != nil {
+		t.Fatalf("second call: %v", err)
+	}
+
+	replay := cassette.ReplayAdapters()["flaky"]
+	_, err := replay.Generate(context.Background(), "flaky-1", "hi")
+	if err == nil || !IsTransient(err) {
+		t.Fatalf("expected recorded transient error, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := replay.Generate(context.Background(), "flaky-1", "hi")
+		if err != nil {
+			t.Fatalf("replay %d: %v", i, err)
+		}
+		if resp.Artifact.Content != "recovered" {
+			t.Fatalf("unexpected replay content %q", resp.Artifact.Content)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// DefaultMaxTokens is used when a request does not set MaxTokens.
+const DefaultMaxTokens = 4096
+
+// responseSchemaName labels Request.ResponseSchema for providers that require
+// structured output formats to be named.
+const responseSchemaName = "output"
+
+// Message roles.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is a single turn in a conversation.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Request is a provider-neutral chat request.
+type Request struct {
+	System      string    `json:"system,omitempty"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Stop        []string  `json:"stop,omitempty"`
+	// ResponseSchema asks for JSON output matching this JSON Schema. Adapters
+	// whose provider supports structured output enforce it natively; others
+	// rely on the instructions in the prompt.
+	ResponseSchema json.RawMessage `json:"response_schema,omitempty"`
+}
+
+// ChatAdapter is implemented by adapters that accept structured requests.
+type ChatAdapter interface {
+	Adapter
+
+	// Chat sends a structured request to the model.
+	Chat(ctx context.Context, model string, req Request) (*Response, error)
+}
+
+// UserRequest wraps a single prompt in a request.
+func UserRequest(prompt string) Request {
+	return Request{Messages: []Message{{Role: RoleUser, Content: prompt}}}
+}
+
+// Append returns a copy of the request with msgs added to the conversation.
+func (r Request) Append(msgs ...Message) Request {
+	out := r
+	out.Messages = append(append([]Message(nil), r.Messages...), msgs...)
+	out.Stop = append([]string(nil), r.Stop...)
+	return out
+}
+
+// MaxTokensOrDefault returns MaxTokens, or DefaultMaxTokens when unset.
+func (r Request) MaxTokensOrDefault() int {
+	if r.MaxTokens > 0 {
+		return r.MaxTokens
+	}
+	return DefaultMaxTokens
+}
+
+// Flatten renders the request as a single prompt for adapters that only
+// implement Generate. A lone user message is returned unchanged.
+func (r Request) Flatten() string {
+	if r.System == "" && len(r.Messages) == 1 && r.Messages[0].Role == RoleUser {
+		return r.Messages[0].Content
+	}
+
+	var sb strings.Builder
+	if r.System != "" {
+		sb.WriteString("System:\n")
+		sb.WriteString(r.System)
+	}
+	for _, msg := range r.Messages {
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		switch msg.Role {
+		case RoleAssistant:
+			sb.WriteString("Assistant:\n")
+		default:
+			sb.WriteString("User:\n")
+		}
+		sb.WriteString(msg.Content)
+	}
+	return sb.String()
+}
+
+// Chat sends req through a ChatAdapter when available, falling back to
+// Generate with the flattened request otherwise. Sampling options are ignored
+// by the fallback.
+func Chat(ctx context.Context, a Adapter, model string, req Request) (*Response, error) {
+	if chat, ok := a.(ChatAdapter); ok {
+		return chat.Chat(ctx, model, req)
+	}
+	return a.Generate(ctx, model, req.Flatten())
+}
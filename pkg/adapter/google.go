@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/zen-systems/flowgate/pkg/artifact"
 	"google.golang.org/genai"
@@ -47,13 +48,15 @@ func (a *GoogleAdapter) Models() []string {
 
 // Generate sends a prompt to Gemini and returns the response as an artifact.
 func (a *GoogleAdapter) Generate(ctx context.Context, model string, prompt string) (*Response, error) {
-	resp, err := a.client.Models.GenerateContent(ctx, model, genai.Text(prompt), nil)
+	return a.Chat(ctx, model, UserRequest(prompt))
+}
+
+// Chat sends a structured request to Gemini.
+func (a *GoogleAdapter) Chat(ctx context.Context, model string, req Request) (*Response, error) {
+	contents, config := googleRequest(req)
+	resp, err := a.client.Models.GenerateContent(ctx, model, contents, config)
 	if err != nil {
-		var apiErr *genai.APIError
-		if errors.As(err, &apiErr) {
-			return nil, &AdapterError{Status: apiErr.Code, Temporary: apiErr.Code == 429 || apiErr.Code >= 500, Err: err}
-		}
-		return nil, fmt.Errorf("google API error: %w", err)
+		return nil, googleError(err)
 	}
 
 	if resp == nil || len(resp.Candidates) == 0 {
@@ -69,11 +72,74 @@ func (a *GoogleAdapter) Generate(ctx context.Context, model string, prompt strin
 		}
 	}
 
-	art := artifact.New(content, a.Name(), model, prompt)
+	art := artifact.New(content, a.Name(), model, req.Flatten())
 	usage := usageFromGoogle(resp)
 	return &Response{Artifact: art, Usage: usage}, nil
 }
 
+// Stream sends a structured request to Gemini and reports text deltas.
+func (a *GoogleAdapter) Stream(ctx context.Context, model string, req Request, onDelta StreamFunc) (*Response, error) {
+	contents, config := googleRequest(req)
+
+	var sb strings.Builder
+	var last *genai.GenerateContentResponse
+	for chunk, err := range a.client.Models.GenerateContentStream(ctx, model, contents, config) {
+		if err != nil {
+			return nil, googleError(err)
+		}
+		if chunk == nil {
+			continue
+		}
+		last = chunk
+		if text := chunk.Text(); text != "" {
+			sb.WriteString(text)
+			onDelta(text)
+		}
+	}
+	if last == nil {
+		return nil, fmt.Errorf("google returned no candidates")
+	}
+
+	art := artifact.New(sb.String(), a.Name(), model, req.Flatten())
+	return &Response{Artifact: art, Usage: usageFromGoogle(last)}, nil
+}
+
+func googleError(err error) error {
+	var apiErr *genai.APIError
+	if errors.As(err, &apiErr) {
+		return &AdapterError{Status: apiErr.Code, Temporary: apiErr.Code == 429 || apiErr.Code >= 500, Err: err}
+	}
+	return fmt.Errorf("google API error: %w", err)
+}
+
+func googleRequest(req Request) ([]*genai.Content, *genai.GenerateContentConfig) {
+	contents := make([]*genai.Content, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		role := genai.Role(genai.RoleUser)
+		if msg.Role == RoleAssistant {
+			role = genai.RoleModel
+		}
+		contents = append(contents, genai.NewContentFromText(msg.Content, role))
+	}
+
+	config := &genai.GenerateContentConfig{
+		MaxOutputTokens: int32(req.MaxTokensOrDefault()),
+		StopSequences:   req.Stop,
+	}
+	if req.System != "" {
+		config.SystemInstruction = genai.NewContentFromText(req.System, genai.RoleUser)
+	}
+	if req.Temperature != nil {
+		temperature := float32(*req.Temperature)
+		config.Temperature = &temperature
+	}
+	if len(req.ResponseSchema) > 0 {
+		config.ResponseMIMEType = "application/json"
+		config.ResponseJsonSchema = req.ResponseSchema
+	}
+	return contents, config
+}
+
 func usageFromGoogle(resp *genai.GenerateContentResponse) *Usage {
 	if resp == nil || resp.UsageMetadata == nil {
 		return nil
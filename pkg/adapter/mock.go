@@ -40,6 +40,30 @@ func (a *MockAdapter) Models() []string {
 	return []string{"mock-1"}
 }
 
+// mockChunkSize is the number of bytes emitted per streamed delta.
+const mockChunkSize = 16
+
+// Stream returns the same artifact as Generate, delivering it in fixed-size
+// chunks.
+func (a *MockAdapter) Stream(ctx context.Context, model string, req Request, onDelta StreamFunc) (*Response, error) {
+	resp, err := a.Generate(ctx, model, req.Flatten())
+	if err != nil {
+		return nil, err
+	}
+	content := resp.Artifact.Content
+	for start := 0; start < len(content); start += mockChunkSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := start + mockChunkSize
+		if end > len(content) {
+			end = len(content)
+		}
+		onDelta(content[start:end])
+	}
+	return resp, nil
+}
+
 // Generate returns a deterministic artifact for the prompt.
 func (a *MockAdapter) Generate(_ context.Context, model string, prompt string) (*Response, error) {
 	if model == "" {
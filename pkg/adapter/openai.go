@@ -41,27 +41,89 @@ func (a *OpenAIAdapter) Models() []string {
 
 // Generate sends a prompt to OpenAI and returns the response as an artifact.
 func (a *OpenAIAdapter) Generate(ctx context.Context, model string, prompt string) (*Response, error) {
-	resp, err := a.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Model: openai.ChatModel(model),
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.UserMessage(prompt),
-		},
-		MaxCompletionTokens: openai.Int(4096),
-	})
+	return a.Chat(ctx, model, UserRequest(prompt))
+}
+
+// Chat sends a structured request to OpenAI.
+func (a *OpenAIAdapter) Chat(ctx context.Context, model string, req Request) (*Response, error) {
+	resp, err := a.client.Chat.Completions.New(ctx, openaiParams(model, req))
 	if err != nil {
-		var apiErr *openai.Error
-		if errors.As(err, &apiErr) {
-			return nil, &AdapterError{Status: apiErr.StatusCode, Temporary: apiErr.StatusCode == 429 || apiErr.StatusCode >= 500, Err: err}
+		return nil, openaiError(err)
+	}
+	return a.response(model, req, resp)
+}
+
+// Stream sends a structured request to OpenAI and reports content deltas.
+func (a *OpenAIAdapter) Stream(ctx context.Context, model string, req Request, onDelta StreamFunc) (*Response, error) {
+	params := openaiParams(model, req)
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+
+	stream := a.client.Chat.Completions.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	acc := openai.ChatCompletionAccumulator{}
+	for stream.Next() {
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			onDelta(chunk.Choices[0].Delta.Content)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, openaiError(err)
+	}
+	return a.response(model, req, &acc.ChatCompletion)
+}
+
+func openaiParams(model string, req Request) openai.ChatCompletionNewParams {
+	params := openai.ChatCompletionNewParams{
+		Model:               openai.ChatModel(model),
+		MaxCompletionTokens: openai.Int(int64(req.MaxTokensOrDefault())),
+	}
+	if req.System != "" {
+		params.Messages = append(params.Messages, openai.SystemMessage(req.System))
+	}
+	for _, msg := range req.Messages {
+		if msg.Role == RoleAssistant {
+			params.Messages = append(params.Messages, openai.AssistantMessage(msg.Content))
+		} else {
+			params.Messages = append(params.Messages, openai.UserMessage(msg.Content))
 		}
-		return nil, fmt.Errorf("openai API error: %w", err)
 	}
+	if req.Temperature != nil {
+		params.Temperature = openai.Float(*req.Temperature)
+	}
+	if len(req.Stop) > 0 {
+		params.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: req.Stop}
+	}
+	if len(req.ResponseSchema) > 0 {
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+				JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   responseSchemaName,
+					Schema: req.ResponseSchema,
+				},
+			},
+		}
+	}
+	return params
+}
+
+func openaiError(err error) error {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return &AdapterError{Status: apiErr.StatusCode, Temporary: apiErr.StatusCode == 429 || apiErr.StatusCode >= 500, Err: err}
+	}
+	return fmt.Errorf("openai API error: %w", err)
+}
 
+func (a *OpenAIAdapter) response(model string, req Request, resp *openai.ChatCompletion) (*Response, error) {
 	if len(resp.Choices) == 0 {
 		return nil, fmt.Errorf("openai returned no choices")
 	}
 
 	content := resp.Choices[0].Message.Content
-	art := artifact.New(content, a.Name(), model, prompt)
+	art := artifact.New(content, a.Name(), model, req.Flatten())
 	usage := &Usage{
 		PromptTokens:     int(resp.Usage.PromptTokens),
 		CompletionTokens: int(resp.Usage.CompletionTokens),
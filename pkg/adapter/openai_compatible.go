@@ -0,0 +1,346 @@
+package adapter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/zen-systems/flowgate/pkg/artifact"
+)
+
+// OpenAICompatibleAdapter implements the Adapter interface for any endpoint
+// speaking the OpenAI chat completions API, such as vLLM, Ollama, LM Studio
+// or an internal gateway.
+type OpenAICompatibleAdapter struct {
+	name       string
+	apiKey     string
+	baseURL    string
+	models     []string
+	headers    map[string]string
+	format     string
+	httpClient *http.Client
+}
+
+// Structured output modes for OpenAICompatibleOptions.ResponseFormat.
+const (
+	// ResponseFormatJSONSchema sends the schema as a json_schema response
+	// format. It is the default.
+	ResponseFormatJSONSchema = "json_schema"
+	// ResponseFormatJSONObject only requests JSON output, for servers that do
+	// not accept schemas.
+	ResponseFormatJSONObject = "json_object"
+	// ResponseFormatNone sends no response format at all.
+	ResponseFormatNone = "none"
+)
+
+// OpenAICompatibleOptions configures an OpenAICompatibleAdapter.
+type OpenAICompatibleOptions struct {
+	// Name identifies the adapter in routing, fallback chains and pricing.
+	Name string
+	// BaseURL is the API root; /chat/completions is appended to it.
+	BaseURL string
+	// APIKey is sent as a bearer token when set. Local servers often need none.
+	APIKey string
+	// Models lists the models served by the endpoint.
+	Models []string
+	// Headers are added to every request.
+	Headers map[string]string
+	// ResponseFormat selects how Request.ResponseSchema is passed on. It
+	// defaults to ResponseFormatJSONSchema.
+	ResponseFormat string
+	// HTTPClient defaults to a plain http.Client.
+	HTTPClient *http.Client
+}
+
+// compatRequest represents the chat completions request format.
+type compatRequest struct {
+	Model       string          `json:"model"`
+	Messages    []compatMessage `json:"messages"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	Stop        []string        `json:"stop,omitempty"`
+	Stream      bool            `json:"stream,omitempty"`
+
+	StreamOptions  *compatStreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *compatResponseFormat `json:"response_format,omitempty"`
+}
+
+// compatResponseFormat requests structured output.
+type compatResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *compatJSONSchema `json:"json_schema,omitempty"`
+}
+
+// compatJSONSchema names the schema a json_schema response must follow.
+type compatJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// compatStreamOptions requests a final usage chunk when streaming.
+type compatStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// compatMessage represents a chat message.
+type compatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// compatResponse represents the chat completions response format.
+type compatResponse struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index   int `json:"index"`
+		Message struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+	} `json:"error,omitempty"`
+}
+
+// NewOpenAICompatibleAdapter creates a new OpenAI-compatible adapter.
+func NewOpenAICompatibleAdapter(opts OpenAICompatibleOptions) (*OpenAICompatibleAdapter, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("adapter name is required")
+	}
+	if opts.BaseURL == "" {
+		return nil, fmt.Errorf("%s: base URL is required", opts.Name)
+	}
+	if len(opts.Models) == 0 {
+		return nil, fmt.Errorf("%s: at least one model is required", opts.Name)
+	}
+	format := opts.ResponseFormat
+	switch format {
+	case "":
+		format = ResponseFormatJSONSchema
+	case ResponseFormatJSONSchema, ResponseFormatJSONObject, ResponseFormatNone:
+	default:
+		return nil, fmt.Errorf("%s: unknown response format %q", opts.Name, format)
+	}
+
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{}
+	}
+	headers := make(map[string]string, len(opts.Headers))
+	for k, v := range opts.Headers {
+		headers[k] = v
+	}
+
+	return &OpenAICompatibleAdapter{
+		name:       opts.Name,
+		apiKey:     opts.APIKey,
+		baseURL:    strings.TrimRight(opts.BaseURL, "/"),
+		models:     append([]string(nil), opts.Models...),
+		headers:    headers,
+		format:     format,
+		httpClient: client,
+	}, nil
+}
+
+// Name returns the adapter identifier.
+func (a *OpenAICompatibleAdapter) Name() string {
+	return a.name
+}
+
+// Models returns the configured models.
+func (a *OpenAICompatibleAdapter) Models() []string {
+	return a.models
+}
+
+// Generate sends a prompt to the endpoint and returns the response as an artifact.
+func (a *OpenAICompatibleAdapter) Generate(ctx context.Context, model string, prompt string) (*Response, error) {
+	return a.Chat(ctx, model, UserRequest(prompt))
+}
+
+// Chat sends a structured request to the endpoint.
+func (a *OpenAICompatibleAdapter) Chat(ctx context.Context, model string, req Request) (*Response, error) {
+	resp, err := a.do(ctx, model, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var compatResp compatResponse
+	if err := json.Unmarshal(body, &compatResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			wrapped := fmt.Errorf("%s API returned status %d: %s", a.name, resp.StatusCode, string(body))
+			return nil, &AdapterError{Status: resp.StatusCode, Temporary: resp.StatusCode == 429 || resp.StatusCode >= 500, Err: wrapped}
+		}
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if compatResp.Error != nil {
+		wrapped := fmt.Errorf("%s API error: %s (type: %s, code: %s)",
+			a.name, compatResp.Error.Message, compatResp.Error.Type, compatResp.Error.Code)
+		return nil, &AdapterError{Status: resp.StatusCode, Temporary: resp.StatusCode == 429 || resp.StatusCode >= 500, Err: wrapped}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		wrapped := fmt.Errorf("%s API returned status %d: %s", a.name, resp.StatusCode, string(body))
+		return nil, &AdapterError{Status: resp.StatusCode, Temporary: resp.StatusCode == 429 || resp.StatusCode >= 500, Err: wrapped}
+	}
+
+	if len(compatResp.Choices) == 0 {
+		return nil, fmt.Errorf("%s returned no choices", a.name)
+	}
+
+	content := compatResp.Choices[0].Message.Content
+	art := artifact.New(content, a.Name(), model, req.Flatten())
+	usage := &Usage{
+		PromptTokens:     compatResp.Usage.PromptTokens,
+		CompletionTokens: compatResp.Usage.CompletionTokens,
+		TotalTokens:      compatResp.Usage.TotalTokens,
+	}
+	return &Response{Artifact: art, Usage: usage}, nil
+}
+
+// Stream sends a structured request to the endpoint and reports content deltas
+// parsed from the server-sent event stream.
+func (a *OpenAICompatibleAdapter) Stream(ctx context.Context, model string, req Request, onDelta StreamFunc) (*Response, error) {
+	resp, err := a.do(ctx, model, req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		wrapped := fmt.Errorf("%s API returned status %d: %s", a.name, resp.StatusCode, string(body))
+		return nil, &AdapterError{Status: resp.StatusCode, Temporary: resp.StatusCode == 429 || resp.StatusCode >= 500, Err: wrapped}
+	}
+
+	var sb strings.Builder
+	var usage *Usage
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk compatStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("%s API error: %s (type: %s, code: %s)", a.name, chunk.Error.Message, chunk.Error.Type, chunk.Error.Code)
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			sb.WriteString(chunk.Choices[0].Delta.Content)
+			onDelta(chunk.Choices[0].Delta.Content)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	art := artifact.New(sb.String(), a.Name(), model, req.Flatten())
+	return &Response{Artifact: art, Usage: usage}, nil
+}
+
+// compatStreamChunk is a single server-sent event payload.
+type compatStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+	} `json:"error,omitempty"`
+}
+
+func (a *OpenAICompatibleAdapter) do(ctx context.Context, model string, req Request, stream bool) (*http.Response, error) {
+	reqBody := compatRequest{
+		Model:       model,
+		MaxTokens:   req.MaxTokensOrDefault(),
+		Temperature: req.Temperature,
+		Stop:        req.Stop,
+		Stream:      stream,
+	}
+	if stream {
+		reqBody.StreamOptions = &compatStreamOptions{IncludeUsage: true}
+	}
+	if len(req.ResponseSchema) > 0 {
+		switch a.format {
+		case ResponseFormatJSONSchema:
+			reqBody.ResponseFormat = &compatResponseFormat{
+				Type:       ResponseFormatJSONSchema,
+				JSONSchema: &compatJSONSchema{Name: responseSchemaName, Schema: req.ResponseSchema},
+			}
+		case ResponseFormatJSONObject:
+			reqBody.ResponseFormat = &compatResponseFormat{Type: ResponseFormatJSONObject}
+		}
+	}
+	if req.System != "" {
+		reqBody.Messages = append(reqBody.Messages, compatMessage{Role: "system", Content: req.System})
+	}
+	for _, msg := range req.Messages {
+		reqBody.Messages = append(reqBody.Messages, compatMessage{Role: msg.Role, Content: msg.Content})
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/chat/completions", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if a.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+	for k, v := range a.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := a.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%s API request failed: %w", a.name, err)
+	}
+	return resp, nil
+}
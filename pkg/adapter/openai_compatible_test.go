@@ -0,0 +1,174 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newCompatTestAdapter(t *testing.T, handler http.HandlerFunc, apiKey string) *OpenAICompatibleAdapter {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	a, err := NewOpenAICompatibleAdapter(OpenAICompatibleOptions{
+		Name:    "local",
+		BaseURL: server.URL + "/v1/",
+		APIKey:  apiKey,
+		Models:  []string{"llama3"},
+		Headers: map[string]string{"X-Team": "platform"},
+	})
+	if err != nil {
+		t.Fatalf("new adapter: %v", err)
+	}
+	return a
+}
+
+func TestOpenAICompatibleChat(t *testing.T) {
+	var got compatRequest
+	a := newCompatTestAdapter(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected no authorization header without a key")
+		}
+		if r.Header.Get("X-Team") != "platform" {
+			t.Errorf("expected configured header, got %q", r.Header.Get("X-Team"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"hi there"}}],"usage":{"prompt_tokens":7,"completion_tokens":2,"total_tokens":9}}`)
+	}, "")
+
+	temperature := 0.5
+	req := UserRequest("hello")
+	req.System = "be brief"
+	req.Temperature = &temperature
+	resp, err := a.Chat(context.Background(), "llama3", req)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if resp.Artifact.Content != "hi there" || resp.Artifact.Adapter != "local" {
+		t.Fatalf("unexpected artifact %+v", resp.Artifact)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 9 {
+		t.Fatalf("unexpected usage %+v", resp.Usage)
+	}
+	if got.Model != "llama3" || len(got.Messages) != 2 || got.Messages[0].Role != "system" || got.Messages[1].Content != "hello" {
+		t.Fatalf("unexpected request body %+v", got)
+	}
+	if got.Temperature == nil || *got.Temperature != 0.5 || got.Stream {
+		t.Fatalf("unexpected sampling options %+v", got)
+	}
+}
+
+func TestOpenAICompatibleStream(t *testing.T) {
+	a := newCompatTestAdapter(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"choices":[{"delta":{"content":"hel"}}]}`,
+			`{"choices":[{"delta":{"content":"lo"}}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}, "secret")
+
+	var deltas []string
+	resp, err := a.Stream(context.Background(), "llama3", UserRequest("hi"), func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if strings.Join(deltas, "|") != "hel|lo" {
+		t.Fatalf("unexpected deltas %q", deltas)
+	}
+	if resp.Artifact.Content != "hello" || resp.Usage == nil || resp.Usage.TotalTokens != 5 {
+		t.Fatalf("unexpected response %+v usage %+v", resp.Artifact, resp.Usage)
+	}
+}
+
+func TestOpenAICompatibleServerErrorIsTransient(t *testing.T) {
+	a := newCompatTestAdapter(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}, "")
+
+	_, err := a.Chat(context.Background(), "llama3", UserRequest("hi"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !IsTransient(err) {
+		t.Fatalf("expected transient error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "local API returned status 503") {
+		t.Fatalf("expected adapter name in error, got %v", err)
+	}
+}
+
+func TestOpenAICompatibleResponseFormat(t *testing.T) {
+	schema := json.RawMessage(`{"type":"object","required":["steps"]}`)
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantRaw bool
+	}{
+		{name: "default", want: ResponseFormatJSONSchema, wantRaw: true},
+		{name: "json object", format: ResponseFormatJSONObject, want: ResponseFormatJSONObject},
+		{name: "none", format: ResponseFormatNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got compatRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decode request: %v", err)
+				}
+				fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"{}"}}]}`)
+			}))
+			defer server.Close()
+			a, err := NewOpenAICompatibleAdapter(OpenAICompatibleOptions{
+				Name:           "local",
+				BaseURL:        server.URL,
+				Models:         []string{"llama3"},
+				ResponseFormat: tt.format,
+			})
+			if err != nil {
+				t.Fatalf("new adapter: %v", err)
+			}
+
+			req := UserRequest("plan")
+			req.ResponseSchema = schema
+			if _, err := a.Chat(context.Background(), "llama3", req); err != nil {
+				t.Fatalf("chat: %v", err)
+			}
+			if tt.want == "" {
+				if got.ResponseFormat != nil {
+					t.Fatalf("expected no response format, got %+v", got.ResponseFormat)
+				}
+				return
+			}
+			if got.ResponseFormat == nil || got.ResponseFormat.Type != tt.want {
+				t.Fatalf("expected %s response format, got %+v", tt.want, got.ResponseFormat)
+			}
+			if tt.wantRaw && (got.ResponseFormat.JSONSchema == nil || string(got.ResponseFormat.JSONSchema.Schema) != string(schema)) {
+				t.Fatalf("expected schema to be forwarded, got %+v", got.ResponseFormat.JSONSchema)
+			}
+		})
+	}
+
+	if _, err := NewOpenAICompatibleAdapter(OpenAICompatibleOptions{Name: "local", BaseURL: "http://x", Models: []string{"m"}, ResponseFormat: "xml"}); err == nil {
+		t.Fatal("expected unknown response format to be rejected")
+	}
+}
@@ -0,0 +1,34 @@
+package adapter
+
+import "context"
+
+// StreamFunc receives output deltas as they arrive.
+type StreamFunc func(delta string)
+
+// StreamingAdapter is implemented by adapters that can stream output.
+type StreamingAdapter interface {
+	Adapter
+
+	// Stream sends a request and reports output deltas to onDelta as they
+	// arrive. The returned response holds the assembled artifact and usage.
+	Stream(ctx context.Context, model string, req Request, onDelta StreamFunc) (*Response, error)
+}
+
+// Stream uses the adapter's streaming support when available. Otherwise it
+// falls back to Chat and reports the complete output as a single delta.
+func Stream(ctx context.Context, a Adapter, model string, req Request, onDelta StreamFunc) (*Response, error) {
+	if onDelta == nil {
+		return Chat(ctx, a, model, req)
+	}
+	if streamer, ok := a.(StreamingAdapter); ok {
+		return streamer.Stream(ctx, model, req, onDelta)
+	}
+	resp, err := Chat(ctx, a, model, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp != nil && resp.Artifact != nil && resp.Artifact.Content != "" {
+		onDelta(resp.Artifact.Content)
+	}
+	return resp, nil
+}
@@ -25,6 +25,7 @@ type CallReport struct {
 	Cost         Cost   `json:"cost"`
 	Retries      int    `json:"retries"`
 	FallbackUsed bool   `json:"fallback_used"`
+	Cached       bool   `json:"cached,omitempty"`
 	Error        string `json:"error,omitempty"`
 }
 
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/zen-systems/flowgate/pkg/evidence"
 )
@@ -36,6 +37,24 @@ type Claim struct {
 	GateCount int         `json:"gate_count"`
 	Gated     bool        `json:"gated"`
 	Gates     []GateClaim `json:"gates"`
+	// Resumed is set when the run was resumed after an interruption; Reused
+	// marks a stage whose output was carried over from an earlier execution.
+	Resumed bool `json:"resumed,omitempty"`
+	Reused  bool `json:"reused,omitempty"`
+	// Skipped marks a stage that did not execute; it never claims a pass.
+	Skipped    bool   `json:"skipped,omitempty"`
+	SkipReason string `json:"skip_reason,omitempty"`
+	// Approval is the human decision on a stage that required one. A
+	// rejected stage never claims a pass.
+	Approval *ApprovalClaim `json:"approval,omitempty"`
+}
+
+// ApprovalClaim records who decided on a stage, how and when.
+type ApprovalClaim struct {
+	Decision  string    `json:"decision"`
+	Approver  string    `json:"approver"`
+	Timestamp time.Time `json:"timestamp"`
+	Comment   string    `json:"comment,omitempty"`
 }
 
 // GateClaim summarizes a gate outcome.
@@ -48,7 +67,7 @@ type GateClaim struct {
 
 // Evidence references run artifacts.
 type Evidence struct {
-	RunJSON   string   `json:"run_json"`
+	RunJSON   string   `json:"run_json,omitempty"`
 	StageJSON string   `json:"stage_json"`
 	Blobs     []string `json:"blobs"`
 	GateLogs  []string `json:"gate_logs"`
@@ -97,6 +116,13 @@ func BuildAttestation(runDir, stageName string) (*AttestationV0, error) {
 			passed = false
 		}
 	}
+	if stageRecord.Skipped {
+		passed = false
+	}
+	approval := approvalClaim(stageRecord.Approval)
+	if approval != nil && approval.Decision != evidence.ApprovalApproved {
+		passed = false
+	}
 	sort.Slice(gateClaims, func(i, j int) bool {
 		if gateClaims[i].Name == gateClaims[j].Name {
 			return gateClaims[i].Kind < gateClaims[j].Kind
@@ -157,10 +183,15 @@ func BuildAttestation(runDir, stageName string) (*AttestationV0, error) {
 			Stage:        stageName,
 		},
 		Claim: Claim{
-			Passed:    passed,
-			GateCount: len(gateClaims),
-			Gated:     len(gateClaims) > 0,
-			Gates:     gateClaims,
+			Passed:     passed,
+			GateCount:  len(gateClaims),
+			Gated:      len(gateClaims) > 0,
+			Gates:      gateClaims,
+			Resumed:    len(runRecord.Resumes) > 0,
+			Reused:     stageReused(runRecord, stageName),
+			Skipped:    stageRecord.Skipped,
+			SkipReason: stageRecord.SkipReason,
+			Approval:   approval,
 		},
 		Evidence: Evidence{
 			RunJSON:   "run.json",
@@ -172,6 +203,34 @@ func BuildAttestation(runDir, stageName string) (*AttestationV0, error) {
 	}, nil
 }
 
+// BuildStageAttestation builds a v0 attestation that covers only a stage's
+// own evidence: its record, blobs and gate logs. It leaves out run.json,
+// which keeps changing while the run continues, and with it the run-level
+// resumed and reused claims, so it still verifies after the run moves on.
+func BuildStageAttestation(runDir, stageName string) (*AttestationV0, error) {
+	att, err := BuildAttestation(runDir, stageName)
+	if err != nil {
+		return nil, err
+	}
+	delete(att.Hashes, "run.json")
+	att.Evidence.RunJSON = ""
+	att.Claim.Resumed = false
+	att.Claim.Reused = false
+	return att, nil
+}
+
+func approvalClaim(record *evidence.ApprovalRecord) *ApprovalClaim {
+	if record == nil {
+		return nil
+	}
+	return &ApprovalClaim{
+		Decision:  record.Decision,
+		Approver:  record.Approver,
+		Timestamp: record.Timestamp,
+		Comment:   record.Comment,
+	}
+}
+
 func collectStageBlobs(record evidence.StageRecord) []string {
 	blobs := make([]string, 0, 4)
 	if record.PromptRef != "" {
@@ -180,7 +239,7 @@ func collectStageBlobs(record evidence.StageRecord) []string {
 	if record.OutputRef != "" {
 		blobs = append(blobs, record.OutputRef)
 	}
-	for _, attempt := range record.Attempts {
+	for _, attempt := range append(append([]evidence.AttemptRecord{}, record.PriorAttempts...), record.Attempts...) {
 		if attempt.PromptRef != "" {
 			blobs = append(blobs, attempt.PromptRef)
 		}
@@ -206,6 +265,25 @@ func collectStageBlobs(record evidence.StageRecord) []string {
 	return unique
 }
 
+// stageReused reports whether the latest resume of the run reused the stage's
+// recorded output instead of executing it again.
+func stageReused(run evidence.RunRecord, stageName string) bool {
+	reused := false
+	for _, event := range run.Resumes {
+		for _, name := range event.ReusedStages {
+			if name == stageName {
+				reused = true
+			}
+		}
+		for _, name := range event.RerunStages {
+			if name == stageName {
+				reused = false
+			}
+		}
+	}
+	return reused
+}
+
 func findGateLogs(runDir, stageName string) ([]string, error) {
 	gatesDir := filepath.Join(runDir, "gates")
 	entries, err := os.ReadDir(gatesDir)
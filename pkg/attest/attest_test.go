@@ -137,3 +137,43 @@ func assertHashBytes(t *testing.T, hashes map[string]string, rel string, content
 		t.Fatalf("hash mismatch for %s", rel)
 	}
 }
+
+func TestBuildAttestationReportsResume(t *testing.T) {
+	runDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(runDir, "stages"), 0755); err != nil {
+		t.Fatalf("mkdir stages: %v", err)
+	}
+
+	runRecord := evidence.RunRecord{
+		ID: "run-1",
+		Resumes: []evidence.ResumeEvent{
+			{ReusedStages: []string{"research"}, RerunStages: []string{"draft"}},
+		},
+	}
+	writeJSONFile(t, filepath.Join(runDir, "run.json"), runRecord)
+	writeJSONFile(t, filepath.Join(runDir, "stages", "research.json"), evidence.StageRecord{Name: "research"})
+	writeJSONFile(t, filepath.Join(runDir, "stages", "draft.json"), evidence.StageRecord{Name: "draft"})
+
+	research, err := BuildAttestation(runDir, "research")
+	if err != nil {
+		t.Fatalf("build research attestation: %v", err)
+	}
+	if !research.Claim.Resumed || !research.Claim.Reused {
+		t.Fatalf("expected research to be reported as reused: %+v", research.Claim)
+	}
+	draft, err := BuildAttestation(runDir, "draft")
+	if err != nil {
+		t.Fatalf("build draft attestation: %v", err)
+	}
+	if !draft.Claim.Resumed || draft.Claim.Reused {
+		t.Fatalf("expected draft to be reported as re-run: %+v", draft.Claim)
+	}
+	if err := VerifyAttestation(draft, runDir); err != nil {
+		t.Fatalf("verify draft attestation: %v", err)
+	}
+
+	draft.Claim.Reused = true
+	if err := VerifyAttestation(draft, runDir); err == nil {
+		t.Fatalf("expected tampered resume claim to fail verification")
+	}
+}
@@ -58,6 +58,26 @@ func VerifyAttestation(att *AttestationV0, runDir string) error {
 		return err
 	}
 
+	// Stage attestations make no claims about the run.
+	if att.Evidence.RunJSON == "" {
+		return nil
+	}
+	runPath, err := safeJoin(runDir, "run.json")
+	if err != nil {
+		return err
+	}
+	runData, err := os.ReadFile(runPath)
+	if err != nil {
+		return fmt.Errorf("read run json: %w", err)
+	}
+	var runRecord evidence.RunRecord
+	if err := json.Unmarshal(runData, &runRecord); err != nil {
+		return fmt.Errorf("parse run json: %w", err)
+	}
+	if att.Claim.Resumed != (len(runRecord.Resumes) > 0) || att.Claim.Reused != stageReused(runRecord, att.Subject.Stage) {
+		return fmt.Errorf("claim resume mismatch")
+	}
+
 	return nil
 }
 
@@ -74,6 +94,19 @@ func VerifyAttestationFile(attestationPath, runDir string) error {
 }
 
 func verifyClaim(att *AttestationV0, stageRecord evidence.StageRecord, mode schemaMode) error {
+	if att.Claim.Skipped != stageRecord.Skipped || att.Claim.SkipReason != stageRecord.SkipReason {
+		return fmt.Errorf("claim skip mismatch")
+	}
+	if stageRecord.Skipped {
+		if att.Claim.Passed {
+			return fmt.Errorf("claim.passed mismatch")
+		}
+		if len(att.Claim.Gates) != 0 {
+			return fmt.Errorf("claim.gates mismatch")
+		}
+		return nil
+	}
+
 	expectedGateCount := len(stageRecord.GateResults)
 	claimPassed := true
 	for _, g := range stageRecord.GateResults {
@@ -82,6 +115,11 @@ func verifyClaim(att *AttestationV0, stageRecord evidence.StageRecord, mode sche
 			break
 		}
 	}
+	approval := approvalClaim(stageRecord.Approval)
+	if !approvalClaimsEqual(approval, att.Claim.Approval) {
+		return fmt.Errorf("claim approval mismatch")
+	}
+	rejected := approval != nil && approval.Decision != evidence.ApprovalApproved
 
 	if mode == schemaModeV0 {
 		expectedGated := expectedGateCount > 0
@@ -91,6 +129,9 @@ func verifyClaim(att *AttestationV0, stageRecord evidence.StageRecord, mode sche
 		if !expectedGated {
 			claimPassed = true
 		}
+		if rejected {
+			claimPassed = false
+		}
 		if att.Claim.Passed != claimPassed {
 			return fmt.Errorf("claim.passed mismatch")
 		}
@@ -102,7 +143,7 @@ func verifyClaim(att *AttestationV0, stageRecord evidence.StageRecord, mode sche
 		} else {
 			lastAttemptSucceeded = len(stageRecord.GateResults) > 0
 		}
-		if !lastAttemptSucceeded {
+		if !lastAttemptSucceeded || rejected {
 			claimPassed = false
 		}
 		if att.Claim.Passed != claimPassed {
@@ -149,6 +190,13 @@ func verifyClaim(att *AttestationV0, stageRecord evidence.StageRecord, mode sche
 	return nil
 }
 
+func approvalClaimsEqual(a, b *ApprovalClaim) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Decision == b.Decision && a.Approver == b.Approver && a.Comment == b.Comment && a.Timestamp.Equal(b.Timestamp)
+}
+
 type schemaMode int
 
 const (
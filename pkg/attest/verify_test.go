@@ -1,9 +1,11 @@
 package attest
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/zen-systems/flowgate/pkg/evidence"
 )
@@ -58,6 +60,29 @@ func TestVerifyAttestationHashMismatch(t *testing.T) {
 	}
 }
 
+func TestVerifyStageAttestationIgnoresRunRecord(t *testing.T) {
+	runDir := t.TempDir()
+	setupRunDir(t, runDir)
+
+	att, err := BuildStageAttestation(runDir, "build")
+	if err != nil {
+		t.Fatalf("build stage attestation: %v", err)
+	}
+	if _, ok := att.Hashes["run.json"]; ok || att.Evidence.RunJSON != "" {
+		t.Fatalf("expected run.json to be left out: %+v", att)
+	}
+
+	// The run continues and rewrites its record.
+	runRecord := evidence.RunRecord{ID: "run", Resumes: []evidence.ResumeEvent{{Timestamp: time.Now().UTC()}}}
+	data, _ := json.Marshal(runRecord)
+	if err := os.WriteFile(filepath.Join(runDir, "run.json"), data, 0644); err != nil {
+		t.Fatalf("rewrite run.json: %v", err)
+	}
+	if err := VerifyAttestation(att, runDir); err != nil {
+		t.Fatalf("verify stage attestation: %v", err)
+	}
+}
+
 func TestVerifyAttestationUnknownSchema(t *testing.T) {
 	runDir := t.TempDir()
 	setupRunDir(t, runDir)
@@ -92,6 +117,43 @@ func TestVerifyAttestationRejectsTraversal(t *testing.T) {
 	}
 }
 
+func TestVerifyAttestationApproval(t *testing.T) {
+	runDir := t.TempDir()
+	setupRunDir(t, runDir)
+	stagePath := filepath.Join(runDir, "stages", "build.json")
+	var record evidence.StageRecord
+	data, err := os.ReadFile(stagePath)
+	if err != nil {
+		t.Fatalf("read stage: %v", err)
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("unmarshal stage: %v", err)
+	}
+	record.Approval = &evidence.ApprovalRecord{
+		Decision:  evidence.ApprovalRejected,
+		Approver:  "alice",
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Comment:   "too risky",
+	}
+	writeJSONFile(t, stagePath, record)
+
+	att, err := BuildAttestation(runDir, "build")
+	if err != nil {
+		t.Fatalf("build attestation: %v", err)
+	}
+	if att.Claim.Passed || att.Claim.Approval == nil || att.Claim.Approval.Approver != "alice" || att.Claim.Approval.Comment != "too risky" {
+		t.Fatalf("expected a rejected approval claim: %+v", att.Claim)
+	}
+	if err := VerifyAttestation(att, runDir); err != nil {
+		t.Fatalf("verify attestation: %v", err)
+	}
+
+	att.Claim.Approval.Decision = evidence.ApprovalApproved
+	if err := VerifyAttestation(att, runDir); err == nil {
+		t.Fatalf("expected approval mismatch")
+	}
+}
+
 func setupRunDir(t *testing.T, runDir string) {
 	t.Helper()
 	if err := os.MkdirAll(filepath.Join(runDir, "stages"), 0755); err != nil {
@@ -0,0 +1,200 @@
+// Package cache provides an on-disk, content-addressed byte store with
+// expiry and a total size limit.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL is how long entries stay valid when Options.TTL is unset.
+	DefaultTTL = 7 * 24 * time.Hour
+	// DefaultMaxBytes caps the cache size when Options.MaxBytes is unset.
+	DefaultMaxBytes int64 = 256 << 20
+)
+
+// Options configures a Cache.
+type Options struct {
+	// TTL is measured from when an entry was written.
+	TTL time.Duration
+	// MaxBytes bounds the total size of stored entries. The oldest entries
+	// are evicted first once it is exceeded.
+	MaxBytes int64
+}
+
+// Cache stores values in files named by their key.
+type Cache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	now      func() time.Time
+
+	mu sync.Mutex
+}
+
+// New opens a cache rooted at dir, creating it if needed.
+func New(dir string, opts Options) (*Cache, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("cache dir is required")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	maxBytes := opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return &Cache{dir: dir, ttl: ttl, maxBytes: maxBytes, now: time.Now}, nil
+}
+
+// Key derives a cache key from its parts.
+func Key(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Dir returns the cache root.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get returns the value for key. Expired entries are removed and reported as
+// misses.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	path, err := c.path(key)
+	if err != nil {
+		return nil, false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if c.expired(info) {
+		_ = os.Remove(path)
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Put stores data under key and evicts entries beyond the size limit.
+func (c *Cache) Put(key string, data []byte) error {
+	path, err := c.path(key)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > c.maxBytes {
+		return fmt.Errorf("cache entry of %d bytes exceeds limit of %d", len(data), c.maxBytes)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create cache dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	return c.prune()
+}
+
+// prune removes expired entries, then the oldest entries until the cache
+// fits within its size limit.
+func (c *Cache) prune() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var entries []entry
+	var total int64
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if c.expired(info) {
+			_ = os.Remove(path)
+			return nil
+		}
+		entries = append(entries, entry{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan cache: %w", err)
+	}
+
+	if total <= c.maxBytes {
+		return nil
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	for _, e := range entries {
+		if total <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to evict cache entry: %w", err)
+		}
+		total -= e.size
+	}
+	return nil
+}
+
+func (c *Cache) expired(info fs.FileInfo) bool {
+	return c.now().Sub(info.ModTime()) > c.ttl
+}
+
+func (c *Cache) path(key string) (string, error) {
+	if len(key) < 3 || strings.ContainsAny(key, `/\.`) {
+		return "", fmt.Errorf("invalid cache key %q", key)
+	}
+	return filepath.Join(c.dir, key[:2], key), nil
+}
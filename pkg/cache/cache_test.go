@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCachePutGet(t *testing.T) {
+	c, err := New(t.TempDir(), Options{})
+	if err != nil {
+		t.Fatalf("new cache: %v", err)
+	}
+
+	key := Key("anthropic", "claude", "hash")
+	if _, ok := c.Get(key); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+	if err := c.Put(key, []byte("value")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	data, ok := c.Get(key)
+	if !ok || !bytes.Equal(data, []byte("value")) {
+		t.Fatalf("expected hit, got %q %v", data, ok)
+	}
+	if Key("a", "bc") == Key("ab", "c") {
+		t.Fatal("expected key parts to be separated")
+	}
+}
+
+func TestCacheExpiresEntries(t *testing.T) {
+	c, err := New(t.TempDir(), Options{TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("new cache: %v", err)
+	}
+	key := Key("entry")
+	if err := c.Put(key, []byte("value")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, ok := c.Get(key); ok {
+		t.Fatal("expected expired entry to miss")
+	}
+}
+
+func TestCacheEvictsOldestOverLimit(t *testing.T) {
+	c, err := New(t.TempDir(), Options{MaxBytes: 10})
+	if err != nil {
+		t.Fatalf("new cache: %v", err)
+	}
+
+	keys := []string{Key("one"), Key("two"), Key("three")}
+	for i, key := range keys {
+		if err := c.Put(key, []byte("1234")); err != nil {
+			t.Fatalf("put %d: %v", i, err)
+		}
+		// Backdate entries so eviction order does not depend on timer resolution.
+		path, _ := c.path(key)
+		mtime := time.Now().Add(time.Duration(i-len(keys)) * time.Minute)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+
+	if _, ok := c.Get(keys[0]); ok {
+		t.Fatal("expected oldest entry to be evicted")
+	}
+	for _, key := range keys[1:] {
+		if _, ok := c.Get(key); !ok {
+			t.Fatalf("expected %s to remain", key)
+		}
+	}
+
+	if err := c.Put(Key("big"), make([]byte, 11)); err == nil {
+		t.Fatal("expected oversized entry to be rejected")
+	}
+}
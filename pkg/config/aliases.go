@@ -139,6 +139,20 @@ func (a *ModelAliases) GetProviderModels(provider string) []string {
 	return a.Providers[provider]
 }
 
+// AddProvider registers models for a provider that is not described by the
+// aliases file, such as an openai_compatible adapter.
+func (a *ModelAliases) AddProvider(provider string, models []string) {
+	if a == nil {
+		return
+	}
+	if a.Providers == nil {
+		a.Providers = make(map[string][]string)
+	}
+	if _, ok := a.Providers[provider]; !ok {
+		a.Providers[provider] = append([]string(nil), models...)
+	}
+}
+
 // GetProviderForModel returns the provider name for a canonical model.
 func (a *ModelAliases) GetProviderForModel(model string) string {
 	if a == nil || a.Providers == nil {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -16,13 +17,73 @@ type Config struct {
 	DeepSeekAPIKey  string
 	RoutingConfig   *RoutingConfig
 	ConfigDir       string
+
+	// OpenAICompatible lists adapters for OpenAI-compatible endpoints
+	// declared in config.yaml.
+	OpenAICompatible []OpenAICompatibleConfig
+
+	// Cache configures the adapter response cache.
+	Cache CacheConfig
+}
+
+// CacheConfig holds resolved response cache settings.
+type CacheConfig struct {
+	Enabled  bool
+	Dir      string
+	TTL      time.Duration
+	MaxBytes int64
 }
 
 // FileConfig represents the structure of ~/.flowgate/config.yaml
 type FileConfig struct {
-	APIKeys APIKeysConfig `yaml:"api_keys"`
+	APIKeys  APIKeysConfig   `yaml:"api_keys"`
+	Adapters AdaptersConfig  `yaml:"adapters"`
+	Cache    CacheFileConfig `yaml:"cache"`
+}
+
+// CacheFileConfig holds response cache configuration from file.
+type CacheFileConfig struct {
+	Enabled   *bool  `yaml:"enabled,omitempty"`
+	Dir       string `yaml:"dir,omitempty"`
+	TTL       string `yaml:"ttl,omitempty"`
+	MaxSizeMB int64  `yaml:"max_size_mb,omitempty"`
+}
+
+// resolve applies defaults and parses durations.
+func (c CacheFileConfig) resolve(configDir string) (CacheConfig, error) {
+	resolved := CacheConfig{
+		Enabled:  true,
+		Dir:      filepath.Join(configDir, "cache"),
+		TTL:      defaultCacheTTL,
+		MaxBytes: defaultCacheMaxSizeMB << 20,
+	}
+	if c.Enabled != nil {
+		resolved.Enabled = *c.Enabled
+	}
+	if c.Dir != "" {
+		resolved.Dir = c.Dir
+	}
+	if c.TTL != "" {
+		ttl, err := time.ParseDuration(c.TTL)
+		if err != nil || ttl <= 0 {
+			return CacheConfig{}, fmt.Errorf("cache: invalid ttl %q", c.TTL)
+		}
+		resolved.TTL = ttl
+	}
+	if c.MaxSizeMB < 0 {
+		return CacheConfig{}, fmt.Errorf("cache: max_size_mb must not be negative")
+	}
+	if c.MaxSizeMB > 0 {
+		resolved.MaxBytes = c.MaxSizeMB << 20
+	}
+	return resolved, nil
 }
 
+const (
+	defaultCacheTTL             = 7 * 24 * time.Hour
+	defaultCacheMaxSizeMB int64 = 256
+)
+
 // APIKeysConfig holds API key configuration from file.
 type APIKeysConfig struct {
 	Anthropic string `yaml:"anthropic"`
@@ -31,6 +92,48 @@ type APIKeysConfig struct {
 	DeepSeek  string `yaml:"deepseek"`
 }
 
+// AdaptersConfig holds adapter definitions from file.
+type AdaptersConfig struct {
+	OpenAICompatible []OpenAICompatibleConfig `yaml:"openai_compatible"`
+}
+
+// OpenAICompatibleConfig defines an adapter for an endpoint speaking the
+// OpenAI chat completions API. The API key is read from the environment
+// variable named by APIKeyEnv, never from the file itself.
+type OpenAICompatibleConfig struct {
+	Name      string            `yaml:"name"`
+	BaseURL   string            `yaml:"base_url"`
+	APIKeyEnv string            `yaml:"api_key_env,omitempty"`
+	Models    []string          `yaml:"models"`
+	Headers   map[string]string `yaml:"headers,omitempty"`
+	// ResponseFormat is json_schema (default), json_object or none, matching
+	// what the server accepts for structured output.
+	ResponseFormat string `yaml:"response_format,omitempty"`
+}
+
+// APIKey returns the key from the configured environment variable.
+func (c OpenAICompatibleConfig) APIKey() string {
+	if c.APIKeyEnv == "" {
+		return ""
+	}
+	return os.Getenv(c.APIKeyEnv)
+}
+
+// Available reports whether the adapter can be used: either it needs no key
+// or its key variable is set.
+func (c OpenAICompatibleConfig) Available() bool {
+	return c.APIKeyEnv == "" || c.APIKey() != ""
+}
+
+// builtinAdapters cannot be redefined by openai_compatible entries.
+var builtinAdapters = map[string]bool{
+	"anthropic": true,
+	"openai":    true,
+	"google":    true,
+	"deepseek":  true,
+	"mock":      true,
+}
+
 // Load reads configuration from config files and environment variables.
 // Environment variables take precedence over file configuration.
 func Load() (*Config, error) {
@@ -39,13 +142,9 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to get config directory: %w", err)
 	}
 
-	// Build config from environment only; file-based API keys are ignored.
-	cfg := &Config{
-		AnthropicAPIKey: os.Getenv("ANTHROPIC_API_KEY"),
-		OpenAIAPIKey:    os.Getenv("OPENAI_API_KEY"),
-		GoogleAPIKey:    os.Getenv("GOOGLE_API_KEY"),
-		DeepSeekAPIKey:  os.Getenv("DEEPSEEK_API_KEY"),
-		ConfigDir:       configDir,
+	cfg, err := newConfig(configDir)
+	if err != nil {
+		return nil, err
 	}
 
 	// Load routing config
@@ -70,7 +169,23 @@ func LoadWithRoutingFile(routingPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to get config directory: %w", err)
 	}
 
-	// Build config from environment only; file-based API keys are ignored.
+	cfg, err := newConfig(configDir)
+	if err != nil {
+		return nil, err
+	}
+
+	routing, err := LoadRoutingConfig(routingPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load routing config from %s: %w", routingPath, err)
+	}
+	cfg.RoutingConfig = routing
+
+	return cfg, nil
+}
+
+// newConfig builds config from environment API keys and the adapter and
+// cache settings in config.yaml. File-based API keys are ignored.
+func newConfig(configDir string) (*Config, error) {
 	cfg := &Config{
 		AnthropicAPIKey: os.Getenv("ANTHROPIC_API_KEY"),
 		OpenAIAPIKey:    os.Getenv("OPENAI_API_KEY"),
@@ -79,15 +194,69 @@ func LoadWithRoutingFile(routingPath string) (*Config, error) {
 		ConfigDir:       configDir,
 	}
 
-	routing, err := LoadRoutingConfig(routingPath)
+	file, err := loadSettings(filepath.Join(configDir, "config.yaml"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to load routing config from %s: %w", routingPath, err)
+		return nil, err
+	}
+	cfg.OpenAICompatible = file.Adapters.OpenAICompatible
+	cfg.Cache, err = file.Cache.resolve(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
 	}
-	cfg.RoutingConfig = routing
 
 	return cfg, nil
 }
 
+// loadSettings reads adapter and cache settings from the config file. Unlike
+// API keys, malformed settings are reported rather than ignored.
+func loadSettings(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &FileConfig{}, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var file FileConfig
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if err := file.Adapters.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid adapters in %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// Validate checks adapter definitions for missing fields and name clashes.
+func (a AdaptersConfig) Validate() error {
+	seen := make(map[string]bool)
+	for i, entry := range a.OpenAICompatible {
+		if entry.Name == "" {
+			return fmt.Errorf("openai_compatible[%d]: name is required", i)
+		}
+		if builtinAdapters[entry.Name] {
+			return fmt.Errorf("openai_compatible %q: name is reserved for a built-in adapter", entry.Name)
+		}
+		if seen[entry.Name] {
+			return fmt.Errorf("openai_compatible %q: duplicate name", entry.Name)
+		}
+		seen[entry.Name] = true
+		if entry.BaseURL == "" {
+			return fmt.Errorf("openai_compatible %q: base_url is required", entry.Name)
+		}
+		if len(entry.Models) == 0 {
+			return fmt.Errorf("openai_compatible %q: at least one model is required", entry.Name)
+		}
+		switch entry.ResponseFormat {
+		case "", "json_schema", "json_object", "none":
+		default:
+			return fmt.Errorf("openai_compatible %q: response_format must be json_schema, json_object or none", entry.Name)
+		}
+	}
+	return nil
+}
+
 // HasAdapter returns true if the API key for the given adapter is configured.
 func (c *Config) HasAdapter(name string) bool {
 	switch name {
@@ -99,9 +268,13 @@ func (c *Config) HasAdapter(name string) bool {
 		return c.GoogleAPIKey != ""
 	case "deepseek":
 		return c.DeepSeekAPIKey != ""
-	default:
-		return false
 	}
+	for _, entry := range c.OpenAICompatible {
+		if entry.Name == name {
+			return entry.Available()
+		}
+	}
+	return false
 }
 
 // loadFileConfig reads the config file, returning empty config if not found.
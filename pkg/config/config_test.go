@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 )
 
 func TestConfigIgnoresFileAPIKeys(t *testing.T) {
@@ -53,6 +54,111 @@ func TestConfigUsesEnvAPIKeys(t *testing.T) {
 	}
 }
 
+func TestConfigLoadsOpenAICompatibleAdapters(t *testing.T) {
+	home := t.TempDir()
+	setHomeEnv(t, home)
+
+	configDir := filepath.Join(home, ".flowgate")
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		t.Fatalf("mkdir config dir: %v", err)
+	}
+	data := []byte(`adapters:
+  openai_compatible:
+    - name: ollama
+      base_url: http://localhost:11434/v1
+      models: [llama3.1]
+    - name: gateway
+      base_url: https://llm.internal/v1
+      api_key_env: GATEWAY_TOKEN
+      models: [gpt-4o]
+      headers:
+        X-Team: platform
+`)
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), data, 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("GATEWAY_TOKEN", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(cfg.OpenAICompatible) != 2 {
+		t.Fatalf("expected 2 adapters, got %d", len(cfg.OpenAICompatible))
+	}
+	if !cfg.HasAdapter("ollama") {
+		t.Fatalf("expected keyless adapter to be available")
+	}
+	if cfg.HasAdapter("gateway") {
+		t.Fatalf("expected gateway to be unavailable without its key")
+	}
+
+	t.Setenv("GATEWAY_TOKEN", "secret")
+	if !cfg.HasAdapter("gateway") || cfg.OpenAICompatible[1].APIKey() != "secret" {
+		t.Fatalf("expected gateway key from environment")
+	}
+	if cfg.OpenAICompatible[1].Headers["X-Team"] != "platform" {
+		t.Fatalf("expected headers to be loaded")
+	}
+}
+
+func TestConfigRejectsInvalidOpenAICompatibleAdapters(t *testing.T) {
+	tests := map[string]string{
+		"missing base_url": "adapters:\n  openai_compatible:\n    - name: local\n      models: [m]\n",
+		"builtin name":     "adapters:\n  openai_compatible:\n    - name: openai\n      base_url: http://x\n      models: [m]\n",
+		"no models":        "adapters:\n  openai_compatible:\n    - name: local\n      base_url: http://x\n",
+		"duplicate":        "adapters:\n  openai_compatible:\n    - {name: a, base_url: http://x, models: [m]}\n    - {name: a, base_url: http://y, models: [m]}\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			home := t.TempDir()
+			setHomeEnv(t, home)
+			configDir := filepath.Join(home, ".flowgate")
+			if err := os.MkdirAll(configDir, 0700); err != nil {
+				t.Fatalf("mkdir config dir: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(data), 0600); err != nil {
+				t.Fatalf("write config: %v", err)
+			}
+			if _, err := Load(); err == nil {
+				t.Fatalf("expected load to fail")
+			}
+		})
+	}
+}
+
+func TestConfigCacheSettings(t *testing.T) {
+	home := t.TempDir()
+	setHomeEnv(t, home)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !cfg.Cache.Enabled || cfg.Cache.Dir != filepath.Join(home, ".flowgate", "cache") || cfg.Cache.TTL != 7*24*time.Hour {
+		t.Fatalf("unexpected cache defaults %+v", cfg.Cache)
+	}
+
+	data := []byte("cache:\n  enabled: false\n  ttl: 2h\n  max_size_mb: 16\n")
+	if err := os.WriteFile(filepath.Join(home, ".flowgate", "config.yaml"), data, 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Cache.Enabled || cfg.Cache.TTL != 2*time.Hour || cfg.Cache.MaxBytes != 16<<20 {
+		t.Fatalf("unexpected cache settings %+v", cfg.Cache)
+	}
+
+	if err := os.WriteFile(filepath.Join(home, ".flowgate", "config.yaml"), []byte("cache:\n  ttl: soon\n"), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := Load(); err == nil {
+		t.Fatal("expected invalid ttl to fail")
+	}
+}
+
 func setHomeEnv(t *testing.T, home string) {
 	t.Helper()
 	t.Setenv("HOME", home)
@@ -0,0 +1,138 @@
+package evidence
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Approval decisions.
+const (
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// ApprovalRequest is what a stage waiting for approval asks a human to
+// decide. Hash fingerprints the stage, message and subjects; a decision only
+// answers the request whose hash it names.
+type ApprovalRequest struct {
+	RunDir      string            `json:"run_dir"`
+	Stage       string            `json:"stage"`
+	Message     string            `json:"message,omitempty"`
+	Subjects    []ApprovalSubject `json:"subjects,omitempty"`
+	RequestedAt time.Time         `json:"requested_at"`
+	Hash        string            `json:"hash"`
+}
+
+// ApprovalSubject is a stage output under review. Output is a truncated
+// preview; OutputRef holds the full output.
+type ApprovalSubject struct {
+	Stage        string       `json:"stage"`
+	Output       string       `json:"output,omitempty"`
+	OutputRef    string       `json:"output_ref,omitempty"`
+	OutputHash   string       `json:"output_hash,omitempty"`
+	GateResults  []GateRecord `json:"gate_results,omitempty"`
+	AppliedFiles []string     `json:"applied_files,omitempty"`
+	DeletedFiles []string     `json:"deleted_files,omitempty"`
+}
+
+// ApprovalRecord is a human decision on an approval request. Source is "tty"
+// for an interactive answer and "cli" for one recorded with flowgate approve.
+type ApprovalRecord struct {
+	Decision    string    `json:"decision"`
+	Approver    string    `json:"approver"`
+	Timestamp   time.Time `json:"timestamp"`
+	Comment     string    `json:"comment,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	RequestHash string    `json:"request_hash"`
+}
+
+// Approved reports whether the decision approves the request.
+func (r *ApprovalRecord) Approved() bool {
+	return r != nil && r.Decision == ApprovalApproved
+}
+
+func approvalPath(runDir, stageName, suffix string) string {
+	return filepath.Join(runDir, "approvals", stageName+suffix)
+}
+
+// WriteApprovalRequest writes approvals/<stage>.request.json.
+func (w *Writer) WriteApprovalRequest(req ApprovalRequest) error {
+	if req.Stage == "" {
+		return fmt.Errorf("stage name is required")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := os.MkdirAll(filepath.Join(w.runDir, "approvals"), 0700); err != nil {
+		return err
+	}
+	return writeJSON(approvalPath(w.runDir, req.Stage, ".request.json"), req)
+}
+
+// RemoveApproval deletes a stage's approval request and decision once the
+// decision has been recorded in the stage record.
+func (w *Writer) RemoveApproval(stageName string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, suffix := range []string{".request.json", ".json"} {
+		if err := os.Remove(approvalPath(w.runDir, stageName, suffix)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadApprovalRequests loads the approval requests a run is waiting on,
+// keyed by stage name.
+func ReadApprovalRequests(runDir string) (map[string]*ApprovalRequest, error) {
+	paths, err := filepath.Glob(filepath.Join(runDir, "approvals", "*.request.json"))
+	if err != nil {
+		return nil, err
+	}
+	requests := make(map[string]*ApprovalRequest, len(paths))
+	for _, path := range paths {
+		var req ApprovalRequest
+		if err := readJSON(path, &req); err != nil {
+			return nil, err
+		}
+		if req.Stage == "" {
+			req.Stage = strings.TrimSuffix(filepath.Base(path), ".request.json")
+		}
+		requests[req.Stage] = &req
+	}
+	return requests, nil
+}
+
+// WriteApproval records a decision in approvals/<stage>.json. The file is
+// replaced atomically, so a waiting run never reads a partial decision.
+func WriteApproval(runDir, stageName string, record ApprovalRecord) error {
+	if stageName == "" {
+		return fmt.Errorf("stage name is required")
+	}
+	if err := os.MkdirAll(filepath.Join(runDir, "approvals"), 0700); err != nil {
+		return err
+	}
+	path := approvalPath(runDir, stageName, ".json")
+	tmp := path + ".tmp"
+	if err := writeJSON(tmp, record); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReadApproval returns the decision recorded for a stage, or nil when there
+// is none.
+func ReadApproval(runDir, stageName string) (*ApprovalRecord, error) {
+	var record ApprovalRecord
+	err := readJSON(approvalPath(runDir, stageName, ".json"), &record)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/zen-systems/flowgate/pkg/adapter"
@@ -20,10 +21,49 @@ type RunRecord struct {
 	Timestamp       time.Time         `json:"timestamp"`
 	PipelineFile    string            `json:"pipeline_file"`
 	InputHash       string            `json:"input_hash"`
+	InputRef        string            `json:"input_ref,omitempty"`
+	PipelineHash    string            `json:"pipeline_hash,omitempty"`
 	Workspace       string            `json:"workspace"`
 	ToolVersions    map[string]string `json:"tool_versions,omitempty"`
 	CostReport      *RunCostReport    `json:"cost_report,omitempty"`
 	RoutingDecision *router.Decision  `json:"routing_decision,omitempty"`
+	Resumes         []ResumeEvent     `json:"resumes,omitempty"`
+	Rollbacks       []RollbackEvent   `json:"rollbacks,omitempty"`
+	Git             *GitRecord        `json:"git,omitempty"`
+}
+
+// GitRecord describes the run branch of a git-mode workspace.
+type GitRecord struct {
+	Branch  string      `json:"branch"`
+	Base    string      `json:"base"`
+	Commits []GitCommit `json:"commits,omitempty"`
+}
+
+// GitCommit records the commit made for an applied stage and the
+// attestation named in its trailer.
+type GitCommit struct {
+	Stage           string `json:"stage"`
+	Commit          string `json:"commit"`
+	AttestationRef  string `json:"attestation_ref"`
+	AttestationHash string `json:"attestation_hash"`
+}
+
+// ResumeEvent records a resumption of an interrupted run.
+type ResumeEvent struct {
+	Timestamp       time.Time `json:"timestamp"`
+	PipelineHash    string    `json:"pipeline_hash,omitempty"`
+	PipelineChanged bool      `json:"pipeline_changed,omitempty"`
+	ReusedStages    []string  `json:"reused_stages,omitempty"`
+	RerunStages     []string  `json:"rerun_stages,omitempty"`
+}
+
+// RollbackEvent records a rollback of the changes a run applied to the real
+// workspace.
+type RollbackEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Workspace string    `json:"workspace"`
+	Restored  []string  `json:"restored,omitempty"`
+	Removed   []string  `json:"removed,omitempty"`
 }
 
 // RunCostReport captures aggregated cost/usage information.
@@ -45,8 +85,10 @@ type BudgetStatus struct {
 // StageRecord captures evidence for a single stage.
 type StageRecord struct {
 	Name           string            `json:"name"`
+	DefinitionHash string            `json:"definition_hash,omitempty"`
 	Adapter        string            `json:"adapter"`
 	Model          string            `json:"model"`
+	System         string            `json:"system,omitempty"`
 	Prompt         string            `json:"prompt,omitempty"`
 	PromptRef      string            `json:"prompt_ref,omitempty"`
 	PromptHash     string            `json:"prompt_hash,omitempty"`
@@ -59,14 +101,91 @@ type StageRecord struct {
 	GateResults    []GateRecord      `json:"gate_results,omitempty"`
 	ApplyResult    *ApplyRecord      `json:"apply_result,omitempty"`
 	DurationMillis int64             `json:"duration_ms"`
+	Skipped        bool              `json:"skipped,omitempty"`
+	SkipReason     string            `json:"skip_reason,omitempty"`
+	Error          string            `json:"error,omitempty"`
 	Attempts       []AttemptRecord   `json:"attempts,omitempty"`
+	PriorAttempts  []AttemptRecord   `json:"prior_attempts,omitempty"`
+	// Items lists the per-item records of a for_each stage.
+	Items []ItemRecord `json:"items,omitempty"`
+	// Approval is the human decision on an approval stage or a stage with
+	// require_approval.
+	Approval *ApprovalRecord `json:"approval,omitempty"`
+	// RoutingDecision is how a stage without an explicit adapter was routed.
+	RoutingDecision *router.Decision `json:"routing_decision,omitempty"`
+	// Escalations lists the escalations of the stage's repair loop in order.
+	Escalations []EscalationRecord `json:"escalations,omitempty"`
+}
+
+// EscalationRecord is an escalation of a stage's repair loop after a failed
+// attempt: an escalate_on policy, or the built-in handling of repeated
+// output (trigger repeat_output). Adapter and Model are the target of the
+// next attempt; Prompt previews the prompt a prompt action restarted with.
+type EscalationRecord struct {
+	Attempt int    `json:"attempt"`
+	Trigger string `json:"trigger"`
+	Reason  string `json:"reason,omitempty"`
+	Action  string `json:"action"`
+	Adapter string `json:"adapter,omitempty"`
+	Model   string `json:"model,omitempty"`
+	Prompt  string `json:"prompt,omitempty"`
+}
+
+// ItemRecord summarizes one item of a for_each stage. Item is the item as
+// JSON; Stage names the item's own stage record.
+type ItemRecord struct {
+	Index     int    `json:"index"`
+	Item      string `json:"item"`
+	Stage     string `json:"stage"`
+	Succeeded bool   `json:"succeeded"`
+	Error     string `json:"error,omitempty"`
 }
 
 // ApplyRecord captures workspace apply behavior.
 type ApplyRecord struct {
-	AppliedFiles    []string `json:"applied_files,omitempty"`
-	DeletedFiles    []string `json:"deleted_files,omitempty"`
-	UsedUnifiedDiff bool     `json:"used_unified_diff"`
+	AppliedFiles    []string          `json:"applied_files,omitempty"`
+	DeletedFiles    []string          `json:"deleted_files,omitempty"`
+	UsedUnifiedDiff bool              `json:"used_unified_diff"`
+	Mode            string            `json:"mode,omitempty"`
+	Adjustments     []ApplyAdjustment `json:"adjustments,omitempty"`
+}
+
+// ApplyAdjustment records a hunk or file block that was applied at an
+// offset, with fuzz, or by three-way merge.
+type ApplyAdjustment struct {
+	File   string `json:"file"`
+	Hunk   int    `json:"hunk,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+	Fuzz   int    `json:"fuzz,omitempty"`
+	Merged bool   `json:"merged,omitempty"`
+}
+
+// ApplyJournal records the files an apply to the real workspace changed, so
+// the apply can be rolled back.
+type ApplyJournal struct {
+	AppliedAt time.Time    `json:"applied_at"`
+	Changes   []FileChange `json:"changes"`
+}
+
+// FileChange records one file changed by an apply. OldRef is the blob
+// holding the previous content when the file existed.
+type FileChange struct {
+	Path    string      `json:"path"`
+	Existed bool        `json:"existed"`
+	OldMode os.FileMode `json:"old_mode,omitempty"`
+	OldHash string      `json:"old_hash,omitempty"`
+	OldRef  string      `json:"old_ref,omitempty"`
+	Deleted bool        `json:"deleted,omitempty"`
+	NewMode os.FileMode `json:"new_mode,omitempty"`
+	NewHash string      `json:"new_hash,omitempty"`
+}
+
+// ApplyReject records a hunk or file block that could not be applied.
+type ApplyReject struct {
+	File   string `json:"file"`
+	Hunk   int    `json:"hunk,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Reason string `json:"reason"`
 }
 
 // GateRecord captures gate evaluation results.
@@ -79,6 +198,10 @@ type GateRecord struct {
 	Kind           string          `json:"kind,omitempty"`
 	Diagnostics    json.RawMessage `json:"diagnostics,omitempty"`
 	Error          string          `json:"error,omitempty"`
+	Severity       string          `json:"severity,omitempty"`
+	Skipped        bool            `json:"skipped,omitempty"`
+	SkipReason     string          `json:"skip_reason,omitempty"`
+	Members        []GateRecord    `json:"members,omitempty"`
 	DurationMillis int64           `json:"duration_ms"`
 }
 
@@ -93,22 +216,33 @@ type Violation struct {
 
 // AttemptRecord captures each attempt to satisfy gates.
 type AttemptRecord struct {
-	Attempt        int          `json:"attempt"`
-	PromptHash     string       `json:"prompt_hash,omitempty"`
-	PromptRef      string       `json:"prompt_ref,omitempty"`
-	OutputRef      string       `json:"output_ref,omitempty"`
-	OutputHash     string       `json:"output_hash,omitempty"`
-	OutputLen      int          `json:"output_len,omitempty"`
-	WorkspaceUsed  string       `json:"workspace_used,omitempty"`
-	WorkspaceMode  string       `json:"workspace_mode,omitempty"`
-	GateResults    []GateRecord `json:"gate_results,omitempty"`
-	ApplyError     string       `json:"apply_error,omitempty"`
-	Succeeded      bool         `json:"succeeded"`
-	DurationMillis int64        `json:"duration_ms"`
-}
-
-// Writer writes evidence bundles to disk.
+	Attempt int `json:"attempt"`
+	// Sample numbers the candidates of a stage with samples, from 1. The
+	// candidates share attempt 1 and the one the stage kept is Selected.
+	Sample         int           `json:"sample,omitempty"`
+	Selected       bool          `json:"selected,omitempty"`
+	Adapter        string        `json:"adapter,omitempty"`
+	Model          string        `json:"model,omitempty"`
+	Cost           *adapter.Cost `json:"cost,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	PromptHash     string        `json:"prompt_hash,omitempty"`
+	PromptRef      string        `json:"prompt_ref,omitempty"`
+	OutputRef      string        `json:"output_ref,omitempty"`
+	OutputHash     string        `json:"output_hash,omitempty"`
+	OutputLen      int           `json:"output_len,omitempty"`
+	WorkspaceUsed  string        `json:"workspace_used,omitempty"`
+	WorkspaceMode  string        `json:"workspace_mode,omitempty"`
+	GateResults    []GateRecord  `json:"gate_results,omitempty"`
+	ApplyError     string        `json:"apply_error,omitempty"`
+	ApplyRejects   []ApplyReject `json:"apply_rejects,omitempty"`
+	ApplyJournal   *ApplyJournal `json:"apply_journal,omitempty"`
+	Succeeded      bool          `json:"succeeded"`
+	DurationMillis int64         `json:"duration_ms"`
+}
+
+// Writer writes evidence bundles to disk. It is safe for concurrent use.
 type Writer struct {
+	mu      sync.Mutex
 	baseDir string
 	runDir  string
 }
@@ -139,6 +273,26 @@ func NewWriter(baseDir, runID string) (*Writer, error) {
 	return &Writer{baseDir: baseDir, runDir: runDir}, nil
 }
 
+// OpenWriter reopens an existing run directory so a run can be resumed.
+func OpenWriter(runDir string) (*Writer, error) {
+	if runDir == "" {
+		return nil, fmt.Errorf("run directory is required")
+	}
+	info, err := os.Stat(filepath.Join(runDir, "run.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open run %s: %w", runDir, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("open run %s: run.json is a directory", runDir)
+	}
+	for _, sub := range []string{"stages", "gates", "blobs"} {
+		if err := os.MkdirAll(filepath.Join(runDir, sub), 0700); err != nil {
+			return nil, err
+		}
+	}
+	return &Writer{baseDir: filepath.Dir(runDir), runDir: runDir}, nil
+}
+
 // RunDir returns the run directory path.
 func (w *Writer) RunDir() string {
 	return w.runDir
@@ -146,12 +300,16 @@ func (w *Writer) RunDir() string {
 
 // WriteRun writes run metadata to run.json.
 func (w *Writer) WriteRun(record RunRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return writeJSON(filepath.Join(w.runDir, "run.json"), record)
 }
 
 // WriteStage writes a stage record to stages/<stage>.json.
 func (w *Writer) WriteStage(record StageRecord) error {
 	path := filepath.Join(w.runDir, "stages", fmt.Sprintf("%s.json", record.Name))
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return writeJSON(path, record)
 }
 
@@ -161,6 +319,8 @@ func (w *Writer) WriteGateLog(stageName, gateName, content string) error {
 		return fmt.Errorf("stage name and gate name are required")
 	}
 	path := filepath.Join(w.runDir, "gates", fmt.Sprintf("%s-%s.log", stageName, gateName))
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return os.WriteFile(path, []byte(content), 0600)
 }
 
@@ -173,6 +333,8 @@ func (w *Writer) WriteBlob(kind string, content []byte) (ref string, sha string,
 	ref = filepath.ToSlash(filepath.Join("blobs", filename))
 	path := filepath.Join(w.runDir, ref)
 
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if _, err := os.Stat(path); err == nil {
 		return ref, sha, nil
 	} else if !os.IsNotExist(err) {
@@ -186,6 +348,67 @@ func (w *Writer) WriteBlob(kind string, content []byte) (ref string, sha string,
 	return ref, sha, nil
 }
 
+// ReadRun loads run.json from a run directory.
+func ReadRun(runDir string) (*RunRecord, error) {
+	var record RunRecord
+	if err := readJSON(filepath.Join(runDir, "run.json"), &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ReadStages loads every stage record in a run directory, keyed by stage name.
+func ReadStages(runDir string) (map[string]*StageRecord, error) {
+	paths, err := filepath.Glob(filepath.Join(runDir, "stages", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string]*StageRecord, len(paths))
+	for _, path := range paths {
+		var record StageRecord
+		if err := readJSON(path, &record); err != nil {
+			return nil, err
+		}
+		if record.Name == "" {
+			record.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+		}
+		records[record.Name] = &record
+	}
+	return records, nil
+}
+
+// ReadStage loads the record of one stage.
+func ReadStage(runDir, stageName string) (*StageRecord, error) {
+	var record StageRecord
+	if err := readJSON(filepath.Join(runDir, "stages", stageName+".json"), &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ReadBlob returns the content of a blob reference and verifies it against
+// the expected sha256 when one is given.
+func ReadBlob(runDir, ref, expectedSha string) ([]byte, error) {
+	if ref == "" {
+		return nil, fmt.Errorf("blob reference is required")
+	}
+	clean := filepath.Clean(filepath.FromSlash(ref))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("blob reference %s escapes run directory", ref)
+	}
+	data, err := os.ReadFile(filepath.Join(runDir, clean))
+	if err != nil {
+		return nil, err
+	}
+	if expectedSha != "" {
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); got != expectedSha {
+			return nil, fmt.Errorf("blob %s hash mismatch: expected %s, got %s", ref, expectedSha, got)
+		}
+	}
+	return data, nil
+}
+
 func sanitizeKind(kind string) string {
 	if kind == "" {
 		return "blob"
@@ -216,3 +439,14 @@ func writeJSON(path string, value any) error {
 	}
 	return os.WriteFile(path, data, 0600)
 }
+
+func readJSON(path string, value any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
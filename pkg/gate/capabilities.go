@@ -0,0 +1,328 @@
+package gate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Command template placeholders.
+const (
+	PlaceholderPath      = "{path}"
+	PlaceholderPkg       = "{pkg}"
+	PlaceholderTestRegex = "{test_regex}"
+	PlaceholderFileGlob  = "{file_glob}"
+)
+
+// Repo policies for the repo-level capabilities file.
+const (
+	// RepoPolicyMerge lets the repo file add, replace and disable
+	// capabilities.
+	RepoPolicyMerge = "merge"
+	// RepoPolicyNarrow lets the repo file only disable capabilities or
+	// restrict them to a subset of their templates, packages and globs.
+	RepoPolicyNarrow = "narrow"
+)
+
+// CapabilitySourceBuiltin is the source of the built-in capabilities.
+const CapabilitySourceBuiltin = "builtin"
+
+// defaultPackages are the {pkg} values allowed when a capability does not
+// list its own.
+var defaultPackages = []string{"./...", "./pkg/...", "./cmd/..."}
+
+// Capability is a named set of command templates a command gate may run.
+type Capability struct {
+	Name        string
+	Description string
+	Templates   []CommandTemplate
+	// Packages lists the values accepted for {pkg}; nil means the defaults.
+	Packages []string
+	// FileGlobs lists the patterns {file_glob} arguments must match.
+	FileGlobs []string
+	// Source is "builtin" or the file that defined the capability.
+	Source string
+}
+
+// EffectivePackages returns the values accepted for {pkg}.
+func (c *Capability) EffectivePackages() []string {
+	if c.Packages == nil {
+		return defaultPackages
+	}
+	return c.Packages
+}
+
+// BoundTemplates returns the templates with the capability's placeholder
+// allowlists attached, ready for matching.
+func (c *Capability) BoundTemplates() []CommandTemplate {
+	out := make([]CommandTemplate, 0, len(c.Templates))
+	for _, tmpl := range c.Templates {
+		tmpl.Packages = c.EffectivePackages()
+		tmpl.FileGlobs = c.FileGlobs
+		out = append(out, tmpl)
+	}
+	return out
+}
+
+// CapabilitySet is the effective capability policy.
+type CapabilitySet struct {
+	// RepoPolicy is RepoPolicyMerge or RepoPolicyNarrow.
+	RepoPolicy string
+	// UserFile and RepoFile are the files that were loaded, if any.
+	UserFile string
+	RepoFile string
+
+	capabilities map[string]*Capability
+}
+
+// DefaultCapabilities returns the built-in capabilities.
+func DefaultCapabilities() *CapabilitySet {
+	set := &CapabilitySet{RepoPolicy: RepoPolicyMerge, capabilities: make(map[string]*Capability)}
+	for _, c := range []*Capability{
+		{
+			Name:        "go_test",
+			Description: "Run Go tests, optionally filtered with -run.",
+			Templates: []CommandTemplate{
+				{Exec: "go", Args: []string{"test", PlaceholderPkg}},
+				{Exec: "go", Args: []string{"test", "-run", PlaceholderTestRegex, PlaceholderPkg}},
+			},
+		},
+		{
+			Name:        "go_vet",
+			Description: "Run go vet on the whole module.",
+			Templates:   []CommandTemplate{{Exec: "go", Args: []string{"vet", PlaceholderPkg}}},
+			Packages:    []string{"./..."},
+		},
+		{
+			Name:        "gofmt",
+			Description: "Format a file in the workspace in place.",
+			Templates:   []CommandTemplate{{Exec: "gofmt", Args: []string{"-w", PlaceholderPath}}},
+		},
+	} {
+		c.Source = CapabilitySourceBuiltin
+		set.capabilities[c.Name] = c
+	}
+	return set
+}
+
+// Lookup returns the named capability.
+func (s *CapabilitySet) Lookup(name string) (*Capability, bool) {
+	if s == nil {
+		return nil, false
+	}
+	c, ok := s.capabilities[name]
+	return c, ok
+}
+
+// Names returns the capability names in sorted order.
+func (s *CapabilitySet) Names() []string {
+	if s == nil {
+		return nil
+	}
+	names := make([]string, 0, len(s.capabilities))
+	for name := range s.capabilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type capabilitiesFile struct {
+	RepoPolicy   string                         `yaml:"repo_policy,omitempty"`
+	Capabilities map[string]capabilityFileEntry `yaml:"capabilities"`
+}
+
+type capabilityFileEntry struct {
+	Description string `yaml:"description,omitempty"`
+	Templates   []struct {
+		Exec string   `yaml:"exec"`
+		Args []string `yaml:"args,omitempty"`
+	} `yaml:"templates,omitempty"`
+	Packages  []string `yaml:"packages,omitempty"`
+	FileGlobs []string `yaml:"file_globs,omitempty"`
+	Disabled  bool     `yaml:"disabled,omitempty"`
+}
+
+// LoadCapabilities builds the effective policy from the built-in
+// capabilities, the user file and the repo file, in that order. Missing
+// files are skipped. A capability defined in a later file replaces the
+// earlier definition as a whole; `disabled: true` removes it. Only the user
+// file may set repo_policy; under RepoPolicyNarrow the repo file may not add
+// capabilities or widen existing ones.
+func LoadCapabilities(userPath, repoPath string) (*CapabilitySet, error) {
+	set := DefaultCapabilities()
+
+	user, err := readCapabilitiesFile(userPath)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		switch user.RepoPolicy {
+		case "":
+		case RepoPolicyMerge, RepoPolicyNarrow:
+			set.RepoPolicy = user.RepoPolicy
+		default:
+			return nil, fmt.Errorf("%s: repo_policy must be %s or %s", userPath, RepoPolicyMerge, RepoPolicyNarrow)
+		}
+		if err := set.apply(userPath, user, false); err != nil {
+			return nil, err
+		}
+		set.UserFile = userPath
+	}
+
+	repo, err := readCapabilitiesFile(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if repo != nil {
+		if repo.RepoPolicy != "" {
+			return nil, fmt.Errorf("%s: repo_policy can only be set in the user capabilities file", repoPath)
+		}
+		if err := set.apply(repoPath, repo, set.RepoPolicy == RepoPolicyNarrow); err != nil {
+			return nil, err
+		}
+		set.RepoFile = repoPath
+	}
+	return set, nil
+}
+
+func readCapabilitiesFile(path string) (*capabilitiesFile, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read capabilities: %w", err)
+	}
+	var file capabilitiesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+func (s *CapabilitySet) apply(source string, file *capabilitiesFile, narrow bool) error {
+	names := make([]string, 0, len(file.Capabilities))
+	for name := range file.Capabilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		entry := file.Capabilities[name]
+		if entry.Disabled {
+			delete(s.capabilities, name)
+			continue
+		}
+		c, err := entry.capability(name, source)
+		if err != nil {
+			return fmt.Errorf("%s: capability %s: %w", source, name, err)
+		}
+		if narrow {
+			if err := checkNarrows(s.capabilities[name], c); err != nil {
+				return fmt.Errorf("%s: capability %s widens the user policy: %w", source, name, err)
+			}
+		}
+		s.capabilities[name] = c
+	}
+	return nil
+}
+
+func (e capabilityFileEntry) capability(name, source string) (*Capability, error) {
+	if len(e.Templates) == 0 {
+		return nil, fmt.Errorf("no templates")
+	}
+	c := &Capability{
+		Name:        name,
+		Description: e.Description,
+		Packages:    e.Packages,
+		FileGlobs:   e.FileGlobs,
+		Source:      source,
+	}
+	usesFileGlob := false
+	for _, t := range e.Templates {
+		if t.Exec == "" || strings.HasPrefix(t.Exec, "{") {
+			return nil, fmt.Errorf("template exec must be a fixed command")
+		}
+		for _, arg := range t.Args {
+			if strings.HasPrefix(arg, "{") && strings.HasSuffix(arg, "}") {
+				switch arg {
+				case PlaceholderPath, PlaceholderPkg, PlaceholderTestRegex:
+				case PlaceholderFileGlob:
+					usesFileGlob = true
+				default:
+					return nil, fmt.Errorf("unknown placeholder %s", arg)
+				}
+			}
+		}
+		c.Templates = append(c.Templates, CommandTemplate{Exec: t.Exec, Args: t.Args})
+	}
+	if usesFileGlob && len(c.FileGlobs) == 0 {
+		return nil, fmt.Errorf("%s requires file_globs", PlaceholderFileGlob)
+	}
+	for _, pkg := range c.Packages {
+		if pkg == "" || strings.HasPrefix(pkg, "-") || filepath.IsAbs(pkg) || hasParentSegment(pkg) {
+			return nil, fmt.Errorf("invalid package pattern %q", pkg)
+		}
+	}
+	for _, glob := range c.FileGlobs {
+		if _, err := filepath.Match(glob, ""); err != nil || filepath.IsAbs(glob) || hasParentSegment(glob) {
+			return nil, fmt.Errorf("invalid file glob %q", glob)
+		}
+	}
+	return c, nil
+}
+
+// checkNarrows reports how next allows more than base.
+func checkNarrows(base, next *Capability) error {
+	if base == nil {
+		return fmt.Errorf("the repo file may not add capabilities")
+	}
+	var allowed []string
+	for _, tmpl := range base.Templates {
+		allowed = append(allowed, tmpl.String())
+	}
+	for _, tmpl := range next.Templates {
+		if !hasString(allowed, tmpl.String()) {
+			return fmt.Errorf("template %q is not allowed", tmpl.String())
+		}
+	}
+	if missing := notIn(next.EffectivePackages(), base.EffectivePackages()); missing != "" {
+		return fmt.Errorf("package %q is not allowed", missing)
+	}
+	if missing := notIn(next.FileGlobs, base.FileGlobs); missing != "" {
+		return fmt.Errorf("file glob %q is not allowed", missing)
+	}
+	return nil
+}
+
+func notIn(values, allowed []string) string {
+	for _, v := range values {
+		if !hasString(allowed, v) {
+			return v
+		}
+	}
+	return ""
+}
+
+func hasParentSegment(path string) bool {
+	for _, seg := range strings.Split(filepath.ToSlash(path), "/") {
+		if seg == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the template as a command line.
+func (t CommandTemplate) String() string {
+	return strings.TrimSpace(t.Exec + " " + strings.Join(t.Args, " "))
+}
@@ -0,0 +1,194 @@
+package gate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCapabilities(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "capabilities.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write capabilities: %v", err)
+	}
+	return path
+}
+
+func TestLoadCapabilitiesMissingFilesUsesBuiltins(t *testing.T) {
+	dir := t.TempDir()
+	set, err := LoadCapabilities(filepath.Join(dir, "user.yaml"), filepath.Join(dir, "repo.yaml"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := strings.Join(set.Names(), ","); got != "go_test,go_vet,gofmt" {
+		t.Fatalf("names = %s", got)
+	}
+	if set.UserFile != "" || set.RepoFile != "" || set.RepoPolicy != RepoPolicyMerge {
+		t.Fatalf("unexpected set: %+v", set)
+	}
+}
+
+func TestLoadCapabilitiesMerge(t *testing.T) {
+	user := writeCapabilities(t, t.TempDir(), `
+capabilities:
+  go_vet:
+    disabled: true
+  lint:
+    description: Run the linter.
+    templates:
+      - exec: golangci-lint
+        args: [run, "{pkg}"]
+    packages: ["./internal/..."]
+`)
+	repo := writeCapabilities(t, t.TempDir(), `
+capabilities:
+  go_test:
+    templates:
+      - exec: go
+        args: [test, "{pkg}"]
+    packages: ["./api/..."]
+`)
+	set, err := LoadCapabilities(user, repo)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if _, ok := set.Lookup("go_vet"); ok {
+		t.Fatalf("expected go_vet to be disabled")
+	}
+	lint, ok := set.Lookup("lint")
+	if !ok || lint.Source != user {
+		t.Fatalf("expected lint from user file, got %+v", lint)
+	}
+	goTest, _ := set.Lookup("go_test")
+	if goTest.Source != repo || len(goTest.Templates) != 1 {
+		t.Fatalf("expected repo go_test to replace builtin, got %+v", goTest)
+	}
+
+	templates, _ := set.TemplatesFor("go_test")
+	if ok, _ := matchTemplates([]string{"go", "test", "./api/..."}, templates, "/workspace", ""); !ok {
+		t.Fatalf("expected repo package to be allowed")
+	}
+	if ok, _ := matchTemplates([]string{"go", "test", "./..."}, templates, "/workspace", ""); ok {
+		t.Fatalf("expected default package to be replaced")
+	}
+}
+
+func TestLoadCapabilitiesNarrow(t *testing.T) {
+	user := writeCapabilities(t, t.TempDir(), "repo_policy: narrow\n")
+
+	narrowing := writeCapabilities(t, t.TempDir(), `
+capabilities:
+  gofmt:
+    disabled: true
+  go_test:
+    templates:
+      - exec: go
+        args: [test, "{pkg}"]
+    packages: ["./pkg/..."]
+`)
+	set, err := LoadCapabilities(user, narrowing)
+	if err != nil {
+		t.Fatalf("expected narrowing repo file to load: %v", err)
+	}
+	if set.RepoPolicy != RepoPolicyNarrow {
+		t.Fatalf("repo policy = %s", set.RepoPolicy)
+	}
+
+	tests := map[string]string{
+		"new capability": `
+capabilities:
+  shell:
+    templates:
+      - exec: sh
+`,
+		"new template": `
+capabilities:
+  go_test:
+    templates:
+      - exec: go
+        args: [test, -v, "{pkg}"]
+`,
+		"new package": `
+capabilities:
+  go_vet:
+    templates:
+      - exec: go
+        args: [vet, "{pkg}"]
+    packages: ["./pkg/..."]
+`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			repo := writeCapabilities(t, t.TempDir(), content)
+			_, err := LoadCapabilities(user, repo)
+			if err == nil || !strings.Contains(err.Error(), "widens") {
+				t.Fatalf("expected widening error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadCapabilitiesRejectsInvalid(t *testing.T) {
+	tests := map[string]string{
+		"repo policy in repo file": "repo_policy: merge\n",
+		"unknown placeholder": `
+capabilities:
+  x:
+    templates:
+      - exec: make
+        args: ["{target}"]
+`,
+		"file glob without globs": `
+capabilities:
+  x:
+    templates:
+      - exec: gofmt
+        args: [-l, "{file_glob}"]
+`,
+		"package traversal": `
+capabilities:
+  x:
+    templates:
+      - exec: go
+        args: [build, "{pkg}"]
+    packages: ["../..."]
+`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			repo := writeCapabilities(t, t.TempDir(), content)
+			if _, err := LoadCapabilities("", repo); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestTemplatePlaceholders(t *testing.T) {
+	workspace := t.TempDir()
+	templates := []CommandTemplate{
+		{Exec: "go", Args: []string{"test", "-run", PlaceholderTestRegex, PlaceholderPkg}, Packages: []string{"./..."}},
+		{Exec: "gofmt", Args: []string{"-l", PlaceholderFileGlob}, FileGlobs: []string{"*.go"}},
+	}
+
+	tests := []struct {
+		command []string
+		allowed bool
+	}{
+		{[]string{"go", "test", "-run", "TestFoo|TestBar", "./..."}, true},
+		{[]string{"go", "test", "-run", "-exec=rm", "./..."}, false},
+		{[]string{"go", "test", "-run", "Test(", "./..."}, false},
+		{[]string{"go", "test", "-run", "TestFoo", "./pkg/..."}, false},
+		{[]string{"gofmt", "-l", "pkg/gate/command.go"}, true},
+		{[]string{"gofmt", "-l", "README.md"}, false},
+		{[]string{"gofmt", "-l", "../other/main.go"}, false},
+	}
+	for _, tt := range tests {
+		ok, reason := matchTemplates(tt.command, templates, workspace, "")
+		if ok != tt.allowed {
+			t.Errorf("%v: allowed = %v, want %v (%s)", tt.command, ok, tt.allowed, reason)
+		}
+	}
+}
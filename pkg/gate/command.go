@@ -1,9 +1,9 @@
 package gate
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -14,18 +14,27 @@ import (
 
 // CommandDiagnostics captures execution details for a command gate.
 type CommandDiagnostics struct {
-	Command        []string `json:"command"`
-	Workdir        string   `json:"workdir,omitempty"`
-	Stdout         string   `json:"stdout,omitempty"`
-	Stderr         string   `json:"stderr,omitempty"`
-	ExitCode       int      `json:"exit_code"`
-	DurationMillis int64    `json:"duration_ms"`
-	Error          string   `json:"error,omitempty"`
-	BlockedReason  string   `json:"blocked_reason,omitempty"`
-	PolicyMode     string   `json:"policy_mode,omitempty"`
-	Capability     string   `json:"capability,omitempty"`
+	Command         []string        `json:"command"`
+	Workdir         string          `json:"workdir,omitempty"`
+	Stdout          string          `json:"stdout,omitempty"`
+	Stderr          string          `json:"stderr,omitempty"`
+	ExitCode        int             `json:"exit_code"`
+	DurationMillis  int64           `json:"duration_ms"`
+	Error           string          `json:"error,omitempty"`
+	BlockedReason   string          `json:"blocked_reason,omitempty"`
+	PolicyMode      string          `json:"policy_mode,omitempty"`
+	Capability      string          `json:"capability,omitempty"`
+	TimedOut        bool            `json:"timed_out,omitempty"`
+	Parser          string          `json:"parser,omitempty"`
+	ParseError      string          `json:"parse_error,omitempty"`
+	Sandbox         *SandboxProfile `json:"sandbox,omitempty"`
+	OutputTruncated bool            `json:"output_truncated,omitempty"`
 }
 
+// commandWaitDelay bounds how long Evaluate waits for output pipes after the
+// process group has been killed.
+const commandWaitDelay = 2 * time.Second
+
 // CommandGate executes a local command as a gate.
 type CommandGate struct {
 	name          string
@@ -38,6 +47,9 @@ type CommandGate struct {
 	policyMode    string
 	capability    string
 	shellApproved bool
+	parserConfig  ParserConfig
+	parser        OutputParser
+	sandbox       *SandboxConfig
 }
 
 // CommandGateConfig defines configuration for a command gate.
@@ -69,6 +81,18 @@ func NewCommandGate(name string, command []string, workdir string, allowed []str
 	}, nil
 }
 
+// SetParser makes failed runs report the violations parsed from the command
+// output instead of a single exit status violation.
+func (g *CommandGate) SetParser(cfg ParserConfig) error {
+	parser, err := NewOutputParser(cfg)
+	if err != nil {
+		return err
+	}
+	g.parserConfig = cfg
+	g.parser = parser
+	return nil
+}
+
 // Name returns the gate identifier.
 func (g *CommandGate) Name() string {
 	return g.name
@@ -95,19 +119,68 @@ func (g *CommandGate) Evaluate(ctx context.Context, _ *artifact.Artifact) (*Gate
 		return result, nil
 	}
 
-	cmd := exec.CommandContext(ctx, g.command[0], g.command[1:]...)
-	if g.workdir != "" {
-		cmd.Dir = g.workdir
+	var cmd *exec.Cmd
+	var profile *SandboxProfile
+	outputLimit := 0
+	if g.sandbox != nil {
+		profile = g.sandboxProfile()
+		outputLimit = g.sandbox.MaxOutputBytes
+		sandboxed, cleanup, err := sandboxCommand(ctx, g.command, g.workdir, sandboxEnv(g.sandbox), profile)
+		if err != nil {
+			result := g.resultFromDiagnostics(CommandDiagnostics{
+				Command:    append([]string{}, g.command...),
+				Workdir:    g.workdir,
+				ExitCode:   1,
+				Error:      err.Error(),
+				PolicyMode: g.policyMode,
+				Capability: g.capability,
+				Sandbox:    profile,
+			}, false)
+			result.Violations = []Violation{sandboxViolation(err.Error())}
+			return result, nil
+		}
+		defer cleanup()
+		cmd = sandboxed
+	} else {
+		cmd = exec.CommandContext(ctx, g.command[0], g.command[1:]...)
+		if g.workdir != "" {
+			cmd.Dir = g.workdir
+		}
+		configureProcessGroup(cmd)
 	}
+	cmd.WaitDelay = commandWaitDelay
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	stdout := &cappedBuffer{limit: outputLimit}
+	stderr := &cappedBuffer{limit: outputLimit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 
 	start := time.Now()
 	err := cmd.Run()
 	duration := time.Since(start)
 
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		diag := CommandDiagnostics{
+			Command:         append([]string{}, g.command...),
+			Workdir:         g.workdir,
+			Stdout:          stdout.String(),
+			Stderr:          stderr.String(),
+			ExitCode:        -1,
+			DurationMillis:  duration.Milliseconds(),
+			PolicyMode:      g.policyMode,
+			Capability:      g.capability,
+			TimedOut:        true,
+			Sandbox:         profile,
+			OutputTruncated: stdout.truncated || stderr.truncated,
+		}
+		if err != nil {
+			diag.Error = err.Error()
+		}
+		result := g.resultFromDiagnostics(diag, false)
+		result.Violations = []Violation{TimeoutViolation("command", duration)}
+		return result, nil
+	}
+
 	exitCode := 0
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
@@ -123,6 +196,7 @@ func (g *CommandGate) Evaluate(ctx context.Context, _ *artifact.Artifact) (*Gate
 				Error:          err.Error(),
 				PolicyMode:     g.policyMode,
 				Capability:     g.capability,
+				Sandbox:        profile,
 			}, false)
 			result.Violations = []Violation{
 				{
@@ -131,23 +205,49 @@ func (g *CommandGate) Evaluate(ctx context.Context, _ *artifact.Artifact) (*Gate
 					Message:  "command failed to start",
 				},
 			}
+			if profile != nil {
+				// Namespace creation fails here when the kernel does not
+				// allow unprivileged user namespaces.
+				result.Violations = []Violation{sandboxViolation(err.Error())}
+			}
 			return result, nil
 		}
 	}
 
 	passed := exitCode == 0
-	result := g.resultFromDiagnostics(CommandDiagnostics{
-		Command:        append([]string{}, g.command...),
-		Workdir:        g.workdir,
-		Stdout:         stdout.String(),
-		Stderr:         stderr.String(),
-		ExitCode:       exitCode,
-		DurationMillis: duration.Milliseconds(),
-		PolicyMode:     g.policyMode,
-		Capability:     g.capability,
-	}, passed)
+	diag := CommandDiagnostics{
+		Command:         append([]string{}, g.command...),
+		Workdir:         g.workdir,
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		ExitCode:        exitCode,
+		DurationMillis:  duration.Milliseconds(),
+		PolicyMode:      g.policyMode,
+		Capability:      g.capability,
+		Parser:          g.parserConfig.Type,
+		Sandbox:         profile,
+		OutputTruncated: stdout.truncated || stderr.truncated,
+	}
+	if profile != nil && exitCode == sandboxExitCode && strings.HasPrefix(diag.Stderr, sandboxErrorPrefix) {
+		result := g.resultFromDiagnostics(diag, false)
+		result.Violations = []Violation{sandboxViolation(strings.TrimSpace(strings.TrimPrefix(diag.Stderr, sandboxErrorPrefix)))}
+		return result, nil
+	}
+	var parsed []Violation
+	if !passed && g.parser != nil {
+		var parseErr error
+		parsed, parseErr = g.parseOutput(diag.Stdout, diag.Stderr)
+		if parseErr != nil {
+			diag.ParseError = parseErr.Error()
+		}
+	}
+	result := g.resultFromDiagnostics(diag, passed)
 
 	if !passed {
+		if len(parsed) > 0 {
+			result.Violations = parsed
+			return result, nil
+		}
 		result.Violations = []Violation{
 			{
 				Rule:     "command_failed",
@@ -163,6 +263,19 @@ func (g *CommandGate) Evaluate(ctx context.Context, _ *artifact.Artifact) (*Gate
 	return result, nil
 }
 
+// parseOutput runs the configured parser over the command output, or over
+// the report file when one is configured.
+func (g *CommandGate) parseOutput(stdout, stderr string) ([]Violation, error) {
+	if g.parserConfig.Report != "" {
+		report, err := readReport(g.parserConfig.Report, g.workdir)
+		if err != nil {
+			return nil, err
+		}
+		stdout = report
+	}
+	return g.parser.Parse(stdout, stderr)
+}
+
 func (g *CommandGate) resultFromDiagnostics(diag CommandDiagnostics, passed bool) *GateResult {
 	payload, _ := json.Marshal(diag)
 	score := 0
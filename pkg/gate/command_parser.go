@@ -0,0 +1,530 @@
+package gate
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Output parsers for command gates.
+const (
+	ParserGoTestJSON   = "go_test_json"
+	ParserGoVet        = "go_vet"
+	ParserGolangciLint = "golangci_lint"
+	ParserJUnitXML     = "junit_xml"
+	ParserSARIF        = "sarif"
+	ParserRegex        = "regex"
+)
+
+// Violation rules reported by the output parsers. golangci_lint and sarif
+// report the linter or rule id instead.
+const (
+	TestFailedRule = "test_failed"
+	BuildErrorRule = "build_error"
+	VetRule        = "vet"
+	OutputRule     = "command_output"
+)
+
+// maxParsedViolations caps the violations taken from one command run so a
+// broken build does not flood the repair prompt.
+const maxParsedViolations = 50
+
+// ParserConfig selects how a command gate reads its output.
+type ParserConfig struct {
+	// Type is one of the Parser* constants.
+	Type string
+	// Pattern is the regular expression for the regex parser. Named groups
+	// file, line, col, message, rule and severity are recognised.
+	Pattern string
+	// Report is a file the command writes its results to (junit_xml, sarif).
+	// Relative paths resolve against the gate's workdir. When empty, stdout
+	// is parsed.
+	Report string
+}
+
+// OutputParser turns command output into violations.
+type OutputParser interface {
+	Parse(stdout, stderr string) ([]Violation, error)
+}
+
+// NewOutputParser returns the parser for cfg.
+func NewOutputParser(cfg ParserConfig) (OutputParser, error) {
+	switch cfg.Type {
+	case ParserGoTestJSON:
+		return goTestJSONParser{}, nil
+	case ParserGoVet:
+		return compilerStyleParser{rule: VetRule}, nil
+	case ParserGolangciLint:
+		return golangciLintParser{}, nil
+	case ParserJUnitXML:
+		return junitParser{}, nil
+	case ParserSARIF:
+		return sarifParser{}, nil
+	case ParserRegex:
+		if cfg.Pattern == "" {
+			return nil, fmt.Errorf("regex parser requires a pattern")
+		}
+		re, err := regexp.Compile(cfg.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("regex parser: %w", err)
+		}
+		if re.SubexpIndex("message") < 0 && re.SubexpIndex("file") < 0 {
+			return nil, fmt.Errorf("regex parser pattern needs a file or message group")
+		}
+		return regexParser{re: re}, nil
+	default:
+		return nil, fmt.Errorf("unknown parser %q", cfg.Type)
+	}
+}
+
+// goTestJSONParser reads `go test -json` events.
+type goTestJSONParser struct{}
+
+type goTestEvent struct {
+	Action  string `json:"Action"`
+	Package string `json:"Package"`
+	Test    string `json:"Test"`
+	Output  string `json:"Output"`
+}
+
+// goFileLine matches the file:line prefix of t.Error output and of
+// compiler diagnostics.
+var goFileLine = regexp.MustCompile(`^\s*(\S+\.go):(\d+)(?::\d+)?:\s*(.*)$`)
+
+type goTestKey struct {
+	pkg, test string
+}
+
+func (goTestJSONParser) Parse(stdout, stderr string) ([]Violation, error) {
+	output := make(map[goTestKey][]string)
+	var failed []goTestKey
+	var violations []Violation
+
+	scanner := bufio.NewScanner(strings.NewReader(stdout))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var ev goTestEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			continue
+		}
+		key := goTestKey{ev.Package, ev.Test}
+		switch ev.Action {
+		case "output":
+			output[key] = append(output[key], ev.Output)
+		case "build-output":
+			if v, ok := compilerViolation(ev.Output, BuildErrorRule); ok {
+				violations = append(violations, v)
+			}
+		case "fail":
+			failed = append(failed, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	failedTests := make(map[string]bool)
+	for _, key := range failed {
+		if key.test != "" {
+			failedTests[key.pkg] = true
+		}
+	}
+	for _, key := range failed {
+		lines := output[key]
+		if key.test == "" {
+			// A package failing without a failing test did not build or
+			// crashed outside a test.
+			if failedTests[key.pkg] {
+				continue
+			}
+			for _, line := range lines {
+				if v, ok := compilerViolation(line, BuildErrorRule); ok {
+					violations = append(violations, v)
+				}
+			}
+			continue
+		}
+		if hasFailedSubtest(key.pkg, key.test, failed) {
+			continue
+		}
+		violations = append(violations, testFailure(key.pkg, key.test, lines))
+	}
+	return capViolations(violations), nil
+}
+
+// hasFailedSubtest reports whether a failing subtest of test is recorded,
+// in which case the subtest is the more precise report.
+func hasFailedSubtest(pkg, test string, failed []goTestKey) bool {
+	for _, key := range failed {
+		if key.pkg == pkg && strings.HasPrefix(key.test, test+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func testFailure(pkg, test string, lines []string) Violation {
+	v := Violation{Rule: TestFailedRule, Severity: "error", Location: pkg + "." + test}
+	var details []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ") {
+			continue
+		}
+		if m := goFileLine.FindStringSubmatch(trimmed); m != nil && v.Location == pkg+"."+test {
+			v.Location = m[1] + ":" + m[2]
+			trimmed = m[3]
+		}
+		details = append(details, trimmed)
+	}
+	v.Message = fmt.Sprintf("%s failed", test)
+	if len(details) > 0 {
+		v.Message += ": " + truncateLines(details, 10)
+	}
+	return v
+}
+
+// compilerStyleParser reads `file:line:col: message` diagnostics such as
+// those printed by go vet and the compiler.
+type compilerStyleParser struct {
+	rule string
+}
+
+func (p compilerStyleParser) Parse(stdout, stderr string) ([]Violation, error) {
+	var violations []Violation
+	for _, line := range strings.Split(stderr+"\n"+stdout, "\n") {
+		if v, ok := compilerViolation(line, p.rule); ok {
+			violations = append(violations, v)
+		}
+	}
+	return capViolations(violations), nil
+}
+
+func compilerViolation(line, rule string) (Violation, bool) {
+	m := goFileLine.FindStringSubmatch(strings.TrimRight(line, "\n"))
+	if m == nil || m[3] == "" {
+		return Violation{}, false
+	}
+	return Violation{
+		Rule:     rule,
+		Severity: "error",
+		Message:  m[3],
+		Location: strings.TrimPrefix(m[1], "./") + ":" + m[2],
+	}, true
+}
+
+// golangciLintParser reads golangci-lint JSON output (--out-format json) and
+// falls back to its line output, `file:line:col: message (linter)`.
+type golangciLintParser struct{}
+
+var lintSuffix = regexp.MustCompile(`^(.*)\s+\((\w[\w-]*)\)$`)
+
+func (golangciLintParser) Parse(stdout, stderr string) ([]Violation, error) {
+	trimmed := strings.TrimSpace(stdout)
+	if strings.HasPrefix(trimmed, "{") {
+		var report struct {
+			Issues []struct {
+				FromLinter string `json:"FromLinter"`
+				Text       string `json:"Text"`
+				Severity   string `json:"Severity"`
+				Pos        struct {
+					Filename string `json:"Filename"`
+					Line     int    `json:"Line"`
+				} `json:"Pos"`
+			} `json:"Issues"`
+		}
+		if err := json.Unmarshal([]byte(firstLine(trimmed)), &report); err != nil {
+			return nil, fmt.Errorf("parse golangci-lint json: %w", err)
+		}
+		var violations []Violation
+		for _, issue := range report.Issues {
+			violations = append(violations, Violation{
+				Rule:     issue.FromLinter,
+				Severity: lintSeverity(issue.Severity),
+				Message:  issue.Text,
+				Location: fmt.Sprintf("%s:%d", issue.Pos.Filename, issue.Pos.Line),
+			})
+		}
+		return capViolations(violations), nil
+	}
+
+	var violations []Violation
+	for _, line := range strings.Split(stdout+"\n"+stderr, "\n") {
+		v, ok := compilerViolation(line, "lint")
+		if !ok {
+			continue
+		}
+		if m := lintSuffix.FindStringSubmatch(v.Message); m != nil {
+			v.Message, v.Rule = m[1], m[2]
+		}
+		violations = append(violations, v)
+	}
+	return capViolations(violations), nil
+}
+
+// junitParser reads JUnit XML test reports.
+type junitParser struct{}
+
+type junitSuites struct {
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name   string       `xml:"name,attr"`
+	Cases  []junitCase  `xml:"testcase"`
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+type junitCase struct {
+	Name      string         `xml:"name,attr"`
+	Classname string         `xml:"classname,attr"`
+	File      string         `xml:"file,attr"`
+	Line      string         `xml:"line,attr"`
+	Failures  []junitFailure `xml:"failure"`
+	Errors    []junitFailure `xml:"error"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+func (junitParser) Parse(stdout, _ string) ([]Violation, error) {
+	data := []byte(strings.TrimSpace(stdout))
+	var suites []junitSuite
+	var root junitSuites
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parse junit xml: %w", err)
+	}
+	suites = root.Suites
+	if len(suites) == 0 {
+		// The document root may be a single <testsuite>.
+		var single junitSuite
+		if err := xml.Unmarshal(data, &single); err == nil {
+			suites = []junitSuite{single}
+		}
+	}
+
+	var violations []Violation
+	var walk func([]junitSuite)
+	walk = func(suites []junitSuite) {
+		for _, suite := range suites {
+			for _, tc := range suite.Cases {
+				for _, f := range append(tc.Failures, tc.Errors...) {
+					violations = append(violations, junitViolation(suite, tc, f))
+				}
+			}
+			walk(suite.Suites)
+		}
+	}
+	walk(suites)
+	return capViolations(violations), nil
+}
+
+func junitViolation(suite junitSuite, tc junitCase, f junitFailure) Violation {
+	class := tc.Classname
+	if class == "" {
+		class = suite.Name
+	}
+	location := tc.Name
+	if class != "" {
+		location = class + "." + tc.Name
+	}
+	if tc.File != "" {
+		location = tc.File
+		if tc.Line != "" {
+			location += ":" + tc.Line
+		}
+	}
+	message := strings.TrimSpace(f.Message)
+	if body := strings.TrimSpace(f.Body); body != "" {
+		if message != "" {
+			message += ": "
+		}
+		message += truncateLines(strings.Split(body, "\n"), 10)
+	}
+	return Violation{
+		Rule:     TestFailedRule,
+		Severity: "error",
+		Message:  fmt.Sprintf("%s failed: %s", tc.Name, message),
+		Location: location,
+	}
+}
+
+// sarifParser reads SARIF 2.1 logs.
+type sarifParser struct{}
+
+func (sarifParser) Parse(stdout, _ string) ([]Violation, error) {
+	var log struct {
+		Runs []struct {
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &log); err != nil {
+		return nil, fmt.Errorf("parse sarif: %w", err)
+	}
+
+	var violations []Violation
+	for _, run := range log.Runs {
+		for _, res := range run.Results {
+			v := Violation{
+				Rule:     res.RuleID,
+				Severity: sarifSeverity(res.Level),
+				Message:  res.Message.Text,
+			}
+			if v.Rule == "" {
+				v.Rule = OutputRule
+			}
+			if len(res.Locations) > 0 {
+				loc := res.Locations[0].PhysicalLocation
+				v.Location = strings.TrimPrefix(loc.ArtifactLocation.URI, "file://")
+				if loc.Region.StartLine > 0 {
+					v.Location += ":" + strconv.Itoa(loc.Region.StartLine)
+				}
+			}
+			violations = append(violations, v)
+		}
+	}
+	// Put errors before warnings so the cap keeps the important ones.
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Severity == "error" && violations[j].Severity != "error"
+	})
+	return capViolations(violations), nil
+}
+
+// regexParser matches a user-supplied pattern against each output line.
+type regexParser struct {
+	re *regexp.Regexp
+}
+
+func (p regexParser) Parse(stdout, stderr string) ([]Violation, error) {
+	group := func(m []string, name string) string {
+		if idx := p.re.SubexpIndex(name); idx >= 0 {
+			return strings.TrimSpace(m[idx])
+		}
+		return ""
+	}
+
+	var violations []Violation
+	for _, line := range strings.Split(stdout+"\n"+stderr, "\n") {
+		m := p.re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		v := Violation{
+			Rule:     group(m, "rule"),
+			Severity: group(m, "severity"),
+			Message:  group(m, "message"),
+			Location: group(m, "file"),
+		}
+		if v.Rule == "" {
+			v.Rule = OutputRule
+		}
+		switch strings.ToLower(v.Severity) {
+		case "warning", "warn":
+			v.Severity = "warning"
+		case "info", "note":
+			v.Severity = "info"
+		default:
+			v.Severity = "error"
+		}
+		if v.Message == "" {
+			v.Message = strings.TrimSpace(line)
+		}
+		if lineNo := group(m, "line"); lineNo != "" && v.Location != "" {
+			v.Location += ":" + lineNo
+		}
+		violations = append(violations, v)
+	}
+	return capViolations(violations), nil
+}
+
+// readReport returns the contents of a report file, resolving relative paths
+// against workdir.
+func readReport(path, workdir string) (string, error) {
+	if !filepath.IsAbs(path) && workdir != "" {
+		path = filepath.Join(workdir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read report: %w", err)
+	}
+	return string(data), nil
+}
+
+func capViolations(violations []Violation) []Violation {
+	if len(violations) <= maxParsedViolations {
+		return violations
+	}
+	dropped := len(violations) - maxParsedViolations
+	violations = append(violations[:maxParsedViolations], Violation{
+		Rule:     OutputRule,
+		Severity: "info",
+		Message:  fmt.Sprintf("%d further issues omitted", dropped),
+	})
+	return violations
+}
+
+func truncateLines(lines []string, limit int) string {
+	if len(lines) > limit {
+		lines = append(lines[:limit:limit], "...")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func firstLine(s string) string {
+	if idx := strings.IndexByte(s, '\n'); idx >= 0 {
+		return s[:idx]
+	}
+	return s
+}
+
+func lintSeverity(level string) string {
+	switch strings.ToLower(level) {
+	case "warning", "warn":
+		return "warning"
+	case "info":
+		return "info"
+	default:
+		return "error"
+	}
+}
+
+func sarifSeverity(level string) string {
+	switch level {
+	case "warning":
+		return "warning"
+	case "note", "none":
+		return "info"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,158 @@
+package gate
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputParsers(t *testing.T) {
+	goTestJSON := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/store","Test":"TestLoad"}`,
+		`{"Action":"output","Package":"example.com/store","Test":"TestLoad","Output":"=== RUN   TestLoad\n"}`,
+		`{"Action":"output","Package":"example.com/store","Test":"TestLoad","Output":"    store_test.go:14: got 1, want 2\n"}`,
+		`{"Action":"output","Package":"example.com/store","Test":"TestLoad","Output":"--- FAIL: TestLoad (0.00s)\n"}`,
+		`{"Action":"fail","Package":"example.com/store","Test":"TestLoad","Elapsed":0}`,
+		`{"Action":"output","Package":"example.com/store","Test":"TestSave/empty","Output":"    save_test.go:30: missing file\n"}`,
+		`{"Action":"fail","Package":"example.com/store","Test":"TestSave/empty","Elapsed":0}`,
+		`{"Action":"fail","Package":"example.com/store","Test":"TestSave","Elapsed":0}`,
+		`{"Action":"pass","Package":"example.com/store","Test":"TestClose","Elapsed":0}`,
+		`{"Action":"fail","Package":"example.com/store","Elapsed":0.1}`,
+		`{"ImportPath":"example.com/api","Action":"build-output","Output":"api/handler.go:9:2: undefined: serve\n"}`,
+		`{"Action":"fail","Package":"example.com/api","Elapsed":0}`,
+	}, "\n")
+
+	junit := `<?xml version="1.0"?>
+<testsuites>
+  <testsuite name="api">
+    <testcase classname="api.Handler" name="returns 404"><failure message="expected 404">got 500</failure></testcase>
+    <testcase classname="api.Handler" name="returns 200"/>
+    <testcase name="login" file="src/login.test.ts" line="12"><error message="TypeError"/></testcase>
+  </testsuite>
+</testsuites>`
+
+	sarif := `{"runs": [{"results": [
+  {"ruleId": "G104", "level": "warning", "message": {"text": "errors unhandled"}, "locations": [{"physicalLocation": {"artifactLocation": {"uri": "main.go"}, "region": {"startLine": 7}}}]},
+  {"ruleId": "G401", "level": "error", "message": {"text": "weak hash"}, "locations": [{"physicalLocation": {"artifactLocation": {"uri": "hash.go"}, "region": {"startLine": 3}}}]}
+]}]}`
+
+	tests := []struct {
+		name   string
+		cfg    ParserConfig
+		stdout string
+		stderr string
+		want   []string // rule@location
+	}{
+		{
+			name:   "go test json",
+			cfg:    ParserConfig{Type: ParserGoTestJSON},
+			stdout: goTestJSON,
+			want:   []string{"build_error@api/handler.go:9", "test_failed@store_test.go:14", "test_failed@save_test.go:30"},
+		},
+		{
+			name:   "go vet",
+			cfg:    ParserConfig{Type: ParserGoVet},
+			stderr: "# example.com/store\n./store.go:12:2: fmt.Printf format %d has arg name of wrong type string\n",
+			want:   []string{"vet@store.go:12"},
+		},
+		{
+			name:   "golangci-lint text",
+			cfg:    ParserConfig{Type: ParserGolangciLint},
+			stdout: "store.go:8:9: Error return value of `f.Close` is not checked (errcheck)\n1 issues.\n",
+			want:   []string{"errcheck@store.go:8"},
+		},
+		{
+			name:   "golangci-lint json",
+			cfg:    ParserConfig{Type: ParserGolangciLint},
+			stdout: `{"Issues":[{"FromLinter":"unused","Text":"func helper is unused","Pos":{"Filename":"util.go","Line":4}}]}`,
+			want:   []string{"unused@util.go:4"},
+		},
+		{
+			name:   "junit xml",
+			cfg:    ParserConfig{Type: ParserJUnitXML},
+			stdout: junit,
+			want:   []string{"test_failed@api.Handler.returns 404", "test_failed@src/login.test.ts:12"},
+		},
+		{
+			name:   "sarif",
+			cfg:    ParserConfig{Type: ParserSARIF},
+			stdout: sarif,
+			want:   []string{"G401@hash.go:3", "G104@main.go:7"},
+		},
+		{
+			name:   "regex",
+			cfg:    ParserConfig{Type: ParserRegex, Pattern: `^(?P<file>[\w/.]+):(?P<line>\d+) (?P<severity>\w+) (?P<message>.+)$`},
+			stdout: "lib/app.rb:3 warning line too long\nnoise\n",
+			want:   []string{"command_output@lib/app.rb:3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewOutputParser(tt.cfg)
+			if err != nil {
+				t.Fatalf("new parser: %v", err)
+			}
+			violations, err := parser.Parse(tt.stdout, tt.stderr)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			var got []string
+			for _, v := range violations {
+				if v.Message == "" {
+					t.Fatalf("violation without message: %+v", v)
+				}
+				got = append(got, v.Rule+"@"+v.Location)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("expected %v, got %+v", tt.want, violations)
+			}
+		})
+	}
+
+	if _, err := NewOutputParser(ParserConfig{Type: ParserRegex, Pattern: `(\d+)`}); err == nil {
+		t.Fatal("expected regex without named groups to be rejected")
+	}
+}
+
+func TestCommandGateUsesParser(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	dir := t.TempDir()
+	report := `<testsuite name="unit"><testcase classname="calc" name="adds"><failure message="1 + 1 = 3"/></testcase></testsuite>`
+	if err := os.WriteFile(filepath.Join(dir, "report.xml"), []byte(report), 0644); err != nil {
+		t.Fatalf("write report: %v", err)
+	}
+
+	g, err := NewCommandGate("unit", []string{"sh", "-c", "exit 1"}, dir, nil, false, "", nil, "none", "", true)
+	if err != nil {
+		t.Fatalf("new gate: %v", err)
+	}
+	if err := g.SetParser(ParserConfig{Type: ParserJUnitXML, Report: "report.xml"}); err != nil {
+		t.Fatalf("set parser: %v", err)
+	}
+	result, err := g.Evaluate(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if result.Passed || len(result.Violations) != 1 || result.Violations[0].Location != "calc.adds" || !strings.Contains(result.Violations[0].Message, "1 + 1 = 3") {
+		t.Fatalf("expected parsed junit failure, got %+v", result)
+	}
+
+	// Output the parser cannot read falls back to the exit status.
+	g, _ = NewCommandGate("vet", []string{"sh", "-c", "echo boom >&2; exit 2"}, dir, nil, false, "", nil, "none", "", true)
+	if err := g.SetParser(ParserConfig{Type: ParserGoVet}); err != nil {
+		t.Fatalf("set parser: %v", err)
+	}
+	result, err = g.Evaluate(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if len(result.Violations) != 1 || result.Violations[0].Message != "command exited with status 2" {
+		t.Fatalf("expected exit status fallback, got %+v", result.Violations)
+	}
+}
@@ -2,7 +2,9 @@ package gate
 
 import (
 	"fmt"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
@@ -10,39 +12,27 @@ import (
 type CommandTemplate struct {
 	Exec string
 	Args []string
+	// Packages and FileGlobs are the allowlists for {pkg} and {file_glob},
+	// filled in from the capability.
+	Packages  []string
+	FileGlobs []string
 }
 
-var capabilityTemplates = map[string][]CommandTemplate{
-	"go_test": {
-		{Exec: "go", Args: []string{"test", "./..."}},
-		{Exec: "go", Args: []string{"test", "./pkg/..."}},
-		{Exec: "go", Args: []string{"test", "./cmd/..."}},
-	},
-	"go_vet": {
-		{Exec: "go", Args: []string{"vet", "./..."}},
-	},
-	"gofmt": {
-		{Exec: "gofmt", Args: []string{"-w", "{path}"}},
-	},
-}
+// maxTestRegexLen bounds {test_regex} arguments.
+const maxTestRegexLen = 256
 
-var allowedPkgArgs = map[string]struct{}{
-	"./...":     {},
-	"./pkg/...": {},
-	"./cmd/...": {},
+// TemplatesForCapability returns templates for a built-in capability name.
+func TemplatesForCapability(name string) ([]CommandTemplate, bool) {
+	return DefaultCapabilities().TemplatesFor(name)
 }
 
-func templatesForCapability(name string) ([]CommandTemplate, bool) {
-	templates, ok := capabilityTemplates[name]
+// TemplatesFor returns the templates for a capability in the set.
+func (s *CapabilitySet) TemplatesFor(name string) ([]CommandTemplate, bool) {
+	c, ok := s.Lookup(name)
 	if !ok {
 		return nil, false
 	}
-	return templates, true
-}
-
-// TemplatesForCapability returns templates for a capability name.
-func TemplatesForCapability(name string) ([]CommandTemplate, bool) {
-	return templatesForCapability(name)
+	return c.BoundTemplates(), true
 }
 
 func matchTemplates(command []string, templates []CommandTemplate, workspaceRoot, workdir string) (bool, string) {
@@ -64,19 +54,35 @@ func matchTemplates(command []string, templates []CommandTemplate, workspaceRoot
 		for i, arg := range tmpl.Args {
 			value := command[i+1]
 			switch arg {
-			case "{path}":
+			case PlaceholderPath:
 				ok, reason := isWorkspaceConfined(workdir, workspaceRoot, value)
 				if !ok {
 					matched = false
 					lastReason = reason
 					break
 				}
-			case "{pkg}":
-				if _, ok := allowedPkgArgs[value]; !ok {
+			case PlaceholderPkg:
+				if !hasString(tmpl.Packages, value) {
 					matched = false
 					lastReason = "package argument not allowed"
 					break
 				}
+			case PlaceholderTestRegex:
+				if ok, reason := validTestRegex(value); !ok {
+					matched = false
+					lastReason = reason
+					break
+				}
+			case PlaceholderFileGlob:
+				ok, reason := isWorkspaceConfined(workdir, workspaceRoot, value)
+				if ok && !matchesAnyGlob(tmpl.FileGlobs, value) {
+					ok, reason = false, "file does not match an allowed glob"
+				}
+				if !ok {
+					matched = false
+					lastReason = reason
+					break
+				}
 			default:
 				if value != arg {
 					matched = false
@@ -94,6 +100,45 @@ func matchTemplates(command []string, templates []CommandTemplate, workspaceRoot
 	return false, "command does not match any allowed template"
 }
 
+func validTestRegex(value string) (bool, string) {
+	if value == "" || strings.HasPrefix(value, "-") {
+		return false, "invalid test regex"
+	}
+	if len(value) > maxTestRegexLen {
+		return false, "test regex too long"
+	}
+	if _, err := regexp.Compile(value); err != nil {
+		return false, "invalid test regex"
+	}
+	return true, ""
+}
+
+// matchesAnyGlob reports whether the slash-separated path matches one of the
+// globs, either in full or by its base name for globs without a separator.
+func matchesAnyGlob(globs []string, value string) bool {
+	clean := filepath.ToSlash(filepath.Clean(value))
+	for _, glob := range globs {
+		glob = filepath.ToSlash(glob)
+		target := clean
+		if !strings.Contains(glob, "/") {
+			target = path.Base(clean)
+		}
+		if ok, _ := path.Match(glob, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasString(values []string, v string) bool {
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
+
 // isWorkspaceConfined validates that arg stays within workspace.
 func isWorkspaceConfined(workdir, workspace, arg string) (bool, string) {
 	if workspace == "" {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"os/exec"
 	"testing"
+	"time"
 )
 
 func TestCommandGateCapturesOutput(t *testing.T) {
@@ -62,3 +63,39 @@ func TestCommandGateAllowedExact(t *testing.T) {
 		t.Fatalf("expected command with extra args to be denied")
 	}
 }
+
+func TestCommandGateTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	gate, err := NewCommandGate("slow", []string{"sh", "-c", "echo started; sleep 30"}, "", nil, false, "", nil, "none", "", true)
+	if err != nil {
+		t.Fatalf("new gate: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result, err := gate.Evaluate(ctx, nil)
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("expected evaluation to stop at the deadline, took %s", elapsed)
+	}
+	if result.Passed {
+		t.Fatalf("expected timeout to fail the gate")
+	}
+	if len(result.Violations) != 1 || result.Violations[0].Rule != TimeoutRule {
+		t.Fatalf("expected timeout violation, got %+v", result.Violations)
+	}
+
+	var diag CommandDiagnostics
+	if err := json.Unmarshal(result.Diagnostics, &diag); err != nil {
+		t.Fatalf("unmarshal diagnostics: %v", err)
+	}
+	if !diag.TimedOut || diag.Stdout != "started\n" {
+		t.Fatalf("unexpected diagnostics %+v", diag)
+	}
+}
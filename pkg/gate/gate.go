@@ -3,6 +3,8 @@ package gate
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/zen-systems/flowgate/pkg/artifact"
 )
@@ -52,3 +54,17 @@ func NewFailingResult(score int, violations []Violation, hints []string) *GateRe
 		RepairHints: hints,
 	}
 }
+
+// TimeoutRule is the violation rule recorded when a gate exceeds its time
+// limit.
+const TimeoutRule = "timeout"
+
+// TimeoutViolation describes subject running for longer than after.
+func TimeoutViolation(subject string, after time.Duration) Violation {
+	return Violation{
+		Rule:       TimeoutRule,
+		Severity:   "error",
+		Message:    fmt.Sprintf("%s did not finish within %s", subject, after.Round(time.Millisecond)),
+		Suggestion: "Look for infinite loops, deadlocks, or code that waits on input or the network.",
+	}
+}
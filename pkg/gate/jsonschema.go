@@ -0,0 +1,90 @@
+package gate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/jsonschema"
+)
+
+// Violation rules reported by JSONSchemaGate.
+const (
+	InvalidJSONRule = "invalid_json"
+	JSONSchemaRule  = "json_schema"
+)
+
+// JSONSchemaGate checks that an artifact is JSON matching a schema.
+type JSONSchemaGate struct {
+	name   string
+	schema *jsonschema.Schema
+}
+
+// NewJSONSchemaGate creates a gate validating artifacts against schema.
+func NewJSONSchemaGate(name string, schema *jsonschema.Schema) *JSONSchemaGate {
+	return &JSONSchemaGate{name: name, schema: schema}
+}
+
+// Name returns the gate identifier.
+func (g *JSONSchemaGate) Name() string {
+	return g.name
+}
+
+// Evaluate parses the artifact as JSON and reports each schema mismatch with
+// its JSON pointer as the violation location.
+func (g *JSONSchemaGate) Evaluate(_ context.Context, a *artifact.Artifact) (*GateResult, error) {
+	if g.schema == nil {
+		return nil, fmt.Errorf("json_schema gate %s has no schema", g.name)
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(ExtractJSON(a.Content)), &value); err != nil {
+		result := NewFailingResult(100, []Violation{{
+			Rule:       InvalidJSONRule,
+			Severity:   "error",
+			Message:    fmt.Sprintf("output is not valid JSON: %v", err),
+			Suggestion: "Respond with a single JSON document and no surrounding prose.",
+		}}, nil)
+		result.Kind = "json_schema"
+		return result, nil
+	}
+
+	errs := g.schema.Validate(value)
+	if len(errs) == 0 {
+		result := NewPassingResult(0)
+		result.Kind = "json_schema"
+		return result, nil
+	}
+
+	violations := make([]Violation, 0, len(errs))
+	for _, e := range errs {
+		violations = append(violations, Violation{
+			Rule:     JSONSchemaRule,
+			Severity: "error",
+			Message:  e.Message,
+			Location: e.Pointer,
+		})
+	}
+	hints := []string{"Keep the JSON structure and fix only the values at the reported locations."}
+	result := NewFailingResult(100, violations, hints)
+	result.Kind = "json_schema"
+	return result, nil
+}
+
+var jsonFence = regexp.MustCompile("(?s)```(?:json)?[ \t]*\n(.*?)\n?```")
+
+// ExtractJSON returns the JSON document in model output, unwrapping a fenced
+// code block when the model added one.
+func ExtractJSON(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return trimmed
+	}
+	if m := jsonFence.FindStringSubmatch(trimmed); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	return trimmed
+}
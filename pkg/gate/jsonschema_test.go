@@ -0,0 +1,56 @@
+package gate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/jsonschema"
+)
+
+func TestJSONSchemaGate(t *testing.T) {
+	schema, err := jsonschema.Compile([]byte(`{
+		"type": "object",
+		"required": ["steps"],
+		"properties": {"steps": {"type": "array", "items": {"type": "string"}}}
+	}`))
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	g := NewJSONSchemaGate("plan_schema", schema)
+
+	tests := []struct {
+		name     string
+		content  string
+		passed   bool
+		rule     string
+		location string
+	}{
+		{name: "valid", content: `{"steps": ["a", "b"]}`, passed: true},
+		{name: "fenced", content: "Here is the plan:\n```json\n{\"steps\": []}\n```", passed: true},
+		{name: "wrong item type", content: `{"steps": ["a", 2]}`, rule: JSONSchemaRule, location: "/steps/1"},
+		{name: "missing property", content: `{}`, rule: JSONSchemaRule, location: "/steps"},
+		{name: "not json", content: "steps: a, b", rule: InvalidJSONRule},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := g.Evaluate(context.Background(), artifact.New(tt.content, "mock", "mock-1", ""))
+			if err != nil {
+				t.Fatalf("evaluate: %v", err)
+			}
+			if result.Passed != tt.passed {
+				t.Fatalf("expected passed=%v, got %+v", tt.passed, result)
+			}
+			if tt.passed {
+				return
+			}
+			if len(result.Violations) != 1 {
+				t.Fatalf("expected one violation, got %+v", result.Violations)
+			}
+			v := result.Violations[0]
+			if v.Rule != tt.rule || v.Location != tt.location {
+				t.Fatalf("expected %s at %q, got %+v", tt.rule, tt.location, v)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package gate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/artifact"
+	"gopkg.in/yaml.v3"
+)
+
+// JudgeCriterionRule is the violation rule for a rubric criterion that scored
+// below its threshold. The criterion name is the violation location.
+const JudgeCriterionRule = "judge_criterion"
+
+// defaultPassScore is used when a rubric does not set pass_score.
+const defaultPassScore = 70
+
+// Rubric describes how a judge model grades an artifact.
+type Rubric struct {
+	Instructions string            `yaml:"instructions,omitempty"`
+	PassScore    int               `yaml:"pass_score,omitempty"`
+	Criteria     []RubricCriterion `yaml:"criteria"`
+}
+
+// RubricCriterion is one graded aspect of an artifact. Scores run from 0 to
+// 100; MinScore, when set, fails the gate regardless of the weighted total.
+type RubricCriterion struct {
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description"`
+	Weight      float64 `yaml:"weight,omitempty"`
+	MinScore    *int    `yaml:"min_score,omitempty"`
+}
+
+// LoadRubric reads and validates a YAML rubric file.
+func LoadRubric(path string) (*Rubric, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rubric: %w", err)
+	}
+	var rubric Rubric
+	if err := yaml.Unmarshal(data, &rubric); err != nil {
+		return nil, fmt.Errorf("parse rubric %s: %w", path, err)
+	}
+	if err := rubric.Validate(); err != nil {
+		return nil, fmt.Errorf("rubric %s: %w", path, err)
+	}
+	return &rubric, nil
+}
+
+// Validate checks the rubric and fills in default weights and pass score.
+func (r *Rubric) Validate() error {
+	if len(r.Criteria) == 0 {
+		return fmt.Errorf("rubric has no criteria")
+	}
+	if r.PassScore == 0 {
+		r.PassScore = defaultPassScore
+	}
+	if r.PassScore < 0 || r.PassScore > 100 {
+		return fmt.Errorf("pass_score must be between 0 and 100")
+	}
+	seen := make(map[string]bool, len(r.Criteria))
+	for i := range r.Criteria {
+		c := &r.Criteria[i]
+		if c.Name == "" {
+			return fmt.Errorf("criterion %d has no name", i+1)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("duplicate criterion %s", c.Name)
+		}
+		seen[c.Name] = true
+		if c.Weight < 0 {
+			return fmt.Errorf("criterion %s: weight must not be negative", c.Name)
+		}
+		if c.Weight == 0 {
+			c.Weight = 1
+		}
+		if c.MinScore != nil && (*c.MinScore < 0 || *c.MinScore > 100) {
+			return fmt.Errorf("criterion %s: min_score must be between 0 and 100", c.Name)
+		}
+	}
+	return nil
+}
+
+// JudgeFunc sends a judge request to a model and returns its raw reply.
+type JudgeFunc func(ctx context.Context, req adapter.Request) (string, error)
+
+// JudgeGate asks a model to grade an artifact against a rubric.
+type JudgeGate struct {
+	name   string
+	rubric *Rubric
+	judge  JudgeFunc
+}
+
+// NewJudgeGate creates a gate that grades artifacts with judge.
+func NewJudgeGate(name string, rubric *Rubric, judge JudgeFunc) *JudgeGate {
+	return &JudgeGate{name: name, rubric: rubric, judge: judge}
+}
+
+// Name returns the gate identifier.
+func (g *JudgeGate) Name() string {
+	return g.name
+}
+
+// JudgeVerdict is the structured reply expected from the judge model.
+type JudgeVerdict struct {
+	Criteria []CriterionVerdict `json:"criteria"`
+	Summary  string             `json:"summary,omitempty"`
+}
+
+// CriterionVerdict is the judge's score for one rubric criterion.
+type CriterionVerdict struct {
+	Name       string `json:"name"`
+	Score      int    `json:"score"`
+	Reason     string `json:"reason"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// JudgeDiagnostics is recorded as the gate result diagnostics.
+type JudgeDiagnostics struct {
+	WeightedScore int                `json:"weighted_score"`
+	PassScore     int                `json:"pass_score"`
+	Criteria      []CriterionVerdict `json:"criteria"`
+	Summary       string             `json:"summary,omitempty"`
+}
+
+// verdictSchema constrains the judge reply on adapters with structured output.
+var verdictSchema = json.RawMessage(`{
+  "type": "object",
+  "required": ["criteria"],
+  "properties": {
+    "criteria": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["name", "score", "reason"],
+        "properties": {
+          "name": {"type": "string"},
+          "score": {"type": "integer", "minimum": 0, "maximum": 100},
+          "reason": {"type": "string"},
+          "suggestion": {"type": "string"}
+        }
+      }
+    },
+    "summary": {"type": "string"}
+  }
+}`)
+
+const judgeSystemPrompt = `You are a strict reviewer grading work against a rubric.
+Score every criterion from 0 (absent or wrong) to 100 (excellent) and give a
+one or two sentence reason. For scores below 100, suggest the most important
+improvement. Respond with JSON only, in the form:
+{"criteria": [{"name": "...", "score": 0, "reason": "...", "suggestion": "..."}], "summary": "..."}`
+
+// Evaluate sends the artifact and rubric to the judge and turns the verdict
+// into a gate result. The score is 100 minus the weighted criterion score, so
+// lower is better like every other gate.
+func (g *JudgeGate) Evaluate(ctx context.Context, a *artifact.Artifact) (*GateResult, error) {
+	if g.rubric == nil || g.judge == nil {
+		return nil, fmt.Errorf("judge gate %s is not configured", g.name)
+	}
+
+	req := adapter.UserRequest(g.prompt(a))
+	req.System = judgeSystemPrompt
+	req.ResponseSchema = verdictSchema
+	temperature := 0.0
+	req.Temperature = &temperature
+
+	reply, err := g.judge(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("judge call: %w", err)
+	}
+	var verdict JudgeVerdict
+	if err := json.Unmarshal([]byte(ExtractJSON(reply)), &verdict); err != nil {
+		return nil, fmt.Errorf("judge returned an invalid verdict: %w", err)
+	}
+	scores := make(map[string]CriterionVerdict, len(verdict.Criteria))
+	for _, cv := range verdict.Criteria {
+		scores[cv.Name] = cv
+	}
+
+	var weighted, total float64
+	ordered := make([]CriterionVerdict, 0, len(g.rubric.Criteria))
+	for _, c := range g.rubric.Criteria {
+		cv, ok := scores[c.Name]
+		if !ok {
+			return nil, fmt.Errorf("judge verdict is missing criterion %s", c.Name)
+		}
+		cv.Score = clampScore(cv.Score)
+		ordered = append(ordered, cv)
+		weighted += c.Weight * float64(cv.Score)
+		total += c.Weight
+	}
+	overall := int(weighted/total + 0.5)
+
+	passed := overall >= g.rubric.PassScore
+	var violations []Violation
+	for i, c := range g.rubric.Criteria {
+		cv := ordered[i]
+		threshold := g.rubric.PassScore
+		if c.MinScore != nil {
+			threshold = *c.MinScore
+			if cv.Score < threshold {
+				passed = false
+			}
+		}
+		if cv.Score >= threshold {
+			continue
+		}
+		violations = append(violations, Violation{
+			Rule:       JudgeCriterionRule,
+			Message:    fmt.Sprintf("%s scored %d/100 (needs %d): %s", c.Name, cv.Score, threshold, cv.Reason),
+			Location:   c.Name,
+			Suggestion: cv.Suggestion,
+		})
+	}
+	// The lowest-scoring criteria come first so repair prompts lead with
+	// the biggest problems.
+	sort.SliceStable(violations, func(i, j int) bool {
+		return scores[violations[i].Location].Score < scores[violations[j].Location].Score
+	})
+
+	severity := "warning"
+	if !passed {
+		severity = "error"
+	}
+	var hints []string
+	for i := range violations {
+		violations[i].Severity = severity
+		if violations[i].Suggestion != "" {
+			hints = append(hints, fmt.Sprintf("%s: %s", violations[i].Location, violations[i].Suggestion))
+		}
+	}
+
+	var result *GateResult
+	if passed {
+		result = NewPassingResult(100 - overall)
+		result.Violations = violations
+	} else {
+		result = NewFailingResult(100-overall, violations, hints)
+	}
+	result.Kind = "judge"
+	result.Diagnostics, _ = json.Marshal(JudgeDiagnostics{
+		WeightedScore: overall,
+		PassScore:     g.rubric.PassScore,
+		Criteria:      ordered,
+		Summary:       verdict.Summary,
+	})
+	return result, nil
+}
+
+func (g *JudgeGate) prompt(a *artifact.Artifact) string {
+	var b strings.Builder
+	if g.rubric.Instructions != "" {
+		b.WriteString(strings.TrimSpace(g.rubric.Instructions))
+		b.WriteString("\n\n")
+	}
+	fmt.Fprintf(&b, "Rubric (pass score %d):\n", g.rubric.PassScore)
+	for _, c := range g.rubric.Criteria {
+		fmt.Fprintf(&b, "- %s (weight %g", c.Name, c.Weight)
+		if c.MinScore != nil {
+			fmt.Fprintf(&b, ", minimum %d", *c.MinScore)
+		}
+		fmt.Fprintf(&b, "): %s\n", strings.TrimSpace(c.Description))
+	}
+	if a.Prompt != "" {
+		fmt.Fprintf(&b, "\nTask given to the author:\n<task>\n%s\n</task>\n", a.Prompt)
+	}
+	fmt.Fprintf(&b, "\nWork to grade:\n<work>\n%s\n</work>\n", a.Content)
+	return b.String()
+}
+
+func clampScore(score int) int {
+	switch {
+	case score < 0:
+		return 0
+	case score > 100:
+		return 100
+	}
+	return score
+}
@@ -0,0 +1,97 @@
+package gate
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/artifact"
+)
+
+func judgeRubric() *Rubric {
+	floor := 60
+	r := &Rubric{
+		PassScore: 70,
+		Criteria: []RubricCriterion{
+			{Name: "accuracy", Description: "Claims are correct.", Weight: 3},
+			{Name: "tone", Description: "Neutral tone.", MinScore: &floor},
+		},
+	}
+	if err := r.Validate(); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func staticJudge(reply string) JudgeFunc {
+	return func(context.Context, adapter.Request) (string, error) {
+		return reply, nil
+	}
+}
+
+func TestJudgeGateScoresCriteria(t *testing.T) {
+	tests := []struct {
+		name       string
+		reply      string
+		passed     bool
+		score      int
+		violations []string
+	}{
+		{
+			name:   "passes",
+			reply:  `{"criteria": [{"name": "accuracy", "score": 90, "reason": "ok"}, {"name": "tone", "score": 70, "reason": "ok"}]}`,
+			passed: true,
+			score:  15,
+		},
+		{
+			name:       "weighted total below pass score",
+			reply:      "```json\n{\"criteria\": [{\"name\": \"tone\", \"score\": 100, \"reason\": \"ok\"}, {\"name\": \"accuracy\", \"score\": 50, \"reason\": \"wrong\"}]}\n```",
+			score:      37,
+			violations: []string{"accuracy"},
+		},
+		{
+			name:       "criterion floor",
+			reply:      `{"criteria": [{"name": "accuracy", "score": 100, "reason": "ok"}, {"name": "tone", "score": 40, "reason": "hostile"}]}`,
+			score:      15,
+			violations: []string{"tone"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewJudgeGate("review", judgeRubric(), staticJudge(tt.reply)).Evaluate(context.Background(), artifact.New("essay", "mock", "mock-1", ""))
+			if err != nil {
+				t.Fatalf("evaluate: %v", err)
+			}
+			if result.Passed != tt.passed || result.Score != tt.score || result.Kind != "judge" {
+				t.Fatalf("expected passed=%v score=%d, got %+v", tt.passed, tt.score, result)
+			}
+			var got []string
+			for _, v := range result.Violations {
+				if v.Rule != JudgeCriterionRule || v.Severity != "error" {
+					t.Fatalf("unexpected violation %+v", v)
+				}
+				got = append(got, v.Location)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.violations, ",") {
+				t.Fatalf("expected violations %v, got %+v", tt.violations, result.Violations)
+			}
+			var diag JudgeDiagnostics
+			if err := json.Unmarshal(result.Diagnostics, &diag); err != nil || len(diag.Criteria) != 2 || diag.WeightedScore != 100-tt.score {
+				t.Fatalf("unexpected diagnostics %s (%v)", result.Diagnostics, err)
+			}
+		})
+	}
+}
+
+func TestJudgeGateRejectsIncompleteVerdict(t *testing.T) {
+	for _, reply := range []string{"looks good to me", `{"criteria": [{"name": "accuracy", "score": 90, "reason": "ok"}]}`} {
+		if _, err := NewJudgeGate("review", judgeRubric(), staticJudge(reply)).Evaluate(context.Background(), artifact.New("essay", "mock", "mock-1", "")); err == nil {
+			t.Fatalf("expected error for verdict %q", reply)
+		}
+	}
+	if err := (&Rubric{}).Validate(); err == nil {
+		t.Fatal("expected empty rubric to be rejected")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package gate
+
+import "os/exec"
+
+// configureProcessGroup is a no-op on platforms without process groups; the
+// default cancellation kills only the command itself.
+func configureProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,194 @@
+package gate
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SandboxConfig restricts what a command gate can reach. The sandbox is
+// available on Linux only: the command runs in fresh user, mount, PID, IPC,
+// UTS and (unless Network is set) network namespaces, sees the host
+// filesystem read-only with the user's home directory hidden, and can write
+// only to its workdir, the workspace, a private /tmp and Writable.
+type SandboxConfig struct {
+	// Network keeps the host network. By default only loopback is available.
+	Network bool
+	// Env lists variables passed to the command, either NAME (copied from
+	// the host) or NAME=value. PATH, LANG, LC_ALL and TERM are always
+	// copied; HOME and TMPDIR point at /tmp.
+	Env []string
+	// ReadOnly lists paths made visible again inside hidden paths, such as a
+	// module cache under the home directory.
+	ReadOnly []string
+	// Writable lists extra writable paths.
+	Writable []string
+	// Hide lists extra paths replaced by empty directories.
+	Hide []string
+	// CPUSeconds, MemoryMB and MaxProcesses set RLIMIT_CPU, RLIMIT_AS and
+	// RLIMIT_NPROC. Zero leaves the limit alone.
+	CPUSeconds   int
+	MemoryMB     int
+	MaxProcesses int
+	// MaxOutputBytes caps the captured stdout and stderr, each.
+	MaxOutputBytes int
+}
+
+// SandboxProfile records how a sandboxed command was run.
+type SandboxProfile struct {
+	Namespaces     []string `json:"namespaces"`
+	Network        bool     `json:"network"`
+	Env            []string `json:"env"`
+	Writable       []string `json:"writable"`
+	ReadOnly       []string `json:"read_only,omitempty"`
+	Hidden         []string `json:"hidden"`
+	CPUSeconds     int      `json:"cpu_seconds,omitempty"`
+	MemoryMB       int      `json:"memory_mb,omitempty"`
+	MaxProcesses   int      `json:"max_processes,omitempty"`
+	MaxOutputBytes int      `json:"max_output_bytes,omitempty"`
+}
+
+// SandboxRule is the violation rule recorded when the sandbox cannot be set
+// up. The command did not run.
+const SandboxRule = "sandbox_failed"
+
+// sandboxErrorPrefix marks setup errors written by the sandbox helper, which
+// then exits with sandboxExitCode.
+const (
+	sandboxErrorPrefix = "flowgate sandbox: "
+	sandboxExitCode    = 125
+)
+
+// sandboxTmp is the private temporary directory inside the sandbox.
+const sandboxTmp = "/tmp"
+
+var sandboxBaseEnv = []string{"PATH", "LANG", "LC_ALL", "TERM"}
+
+// SetSandbox runs the command inside a sandbox built from cfg.
+func (g *CommandGate) SetSandbox(cfg SandboxConfig) {
+	g.sandbox = &cfg
+}
+
+// sandboxProfile resolves cfg for a command running in workdir.
+func (g *CommandGate) sandboxProfile() *SandboxProfile {
+	cfg := g.sandbox
+	home, _ := os.UserHomeDir()
+	base := g.workspaceRoot
+	if base == "" {
+		base = g.workdir
+	}
+	resolve := func(paths []string) []string {
+		var out []string
+		for _, p := range paths {
+			if p == "" {
+				continue
+			}
+			if p == "~" || strings.HasPrefix(p, "~/") {
+				if home == "" {
+					continue
+				}
+				p = filepath.Join(home, strings.TrimPrefix(p, "~"))
+			} else if !filepath.IsAbs(p) {
+				p = filepath.Join(base, p)
+			}
+			out = append(out, filepath.Clean(p))
+		}
+		return uniqueSorted(out)
+	}
+
+	profile := &SandboxProfile{
+		Namespaces:     []string{"user", "mount", "pid", "ipc", "uts"},
+		Network:        cfg.Network,
+		Writable:       resolve(append([]string{g.workdir, g.workspaceRoot}, cfg.Writable...)),
+		ReadOnly:       resolve(cfg.ReadOnly),
+		Hidden:         resolve(cfg.Hide),
+		CPUSeconds:     cfg.CPUSeconds,
+		MemoryMB:       cfg.MemoryMB,
+		MaxProcesses:   cfg.MaxProcesses,
+		MaxOutputBytes: cfg.MaxOutputBytes,
+	}
+	if !cfg.Network {
+		profile.Namespaces = append(profile.Namespaces, "net")
+	}
+	if home != "" && home != "/" {
+		profile.Hidden = uniqueSorted(append(profile.Hidden, filepath.Clean(home)))
+	}
+	for _, entry := range append(append([]string{}, sandboxBaseEnv...), cfg.Env...) {
+		name, _, _ := strings.Cut(entry, "=")
+		profile.Env = append(profile.Env, name)
+	}
+	profile.Env = uniqueSorted(append(profile.Env, "HOME", "TMPDIR"))
+	return profile
+}
+
+// sandboxEnv builds the command environment from the host environment and
+// the allowlist.
+func sandboxEnv(cfg *SandboxConfig) []string {
+	values := make(map[string]string)
+	for _, entry := range append(append([]string{}, sandboxBaseEnv...), cfg.Env...) {
+		if name, value, ok := strings.Cut(entry, "="); ok {
+			values[name] = value
+		} else if value, ok := os.LookupEnv(entry); ok {
+			values[entry] = value
+		}
+	}
+	values["HOME"] = sandboxTmp
+	values["TMPDIR"] = sandboxTmp
+
+	env := make([]string, 0, len(values))
+	for name, value := range values {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+func sandboxViolation(reason string) Violation {
+	return Violation{
+		Rule:     SandboxRule,
+		Severity: "error",
+		Message:  "sandbox setup failed: " + reason,
+	}
+}
+
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if v == "" || v == "." || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// cappedBuffer keeps the first limit bytes written to it. A zero limit keeps
+// everything. It deliberately has no ReadFrom, so io.Copy goes through Write.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.limit <= 0 {
+		return b.buf.Write(p)
+	}
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) String() string { return b.buf.String() }
+
+func (b *cappedBuffer) Len() int { return b.buf.Len() }
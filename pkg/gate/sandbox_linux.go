@@ -0,0 +1,370 @@
+//go:build linux
+
+package gate
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// The sandbox helper is this binary re-executed in the new namespaces. It
+// finishes the setup that has to happen inside them (mounts, loopback,
+// rlimits) and then execs the gate command.
+const (
+	sandboxHelperArg = "flowgate-sandbox-init"
+	sandboxSpecEnv   = "FLOWGATE_SANDBOX_SPEC"
+)
+
+// rlimitNproc is RLIMIT_NPROC, which package syscall does not define. The
+// value holds on every Linux architecture except alpha, mips and sparc.
+const rlimitNproc = 6
+
+// Statfs flags carried over when remounting read-only; a user namespace may
+// not clear them.
+const (
+	stNosuid     = 0x2
+	stNodev      = 0x4
+	stNoexec     = 0x8
+	stNoatime    = 0x400
+	stNodiratime = 0x800
+	stRelatime   = 0x1000
+)
+
+// sandboxSpec is passed from the gate to the helper.
+type sandboxSpec struct {
+	Root    string          `json:"root"`
+	Dir     string          `json:"dir"`
+	Command []string        `json:"command"`
+	Profile *SandboxProfile `json:"profile"`
+}
+
+func init() {
+	if len(os.Args) > 0 && os.Args[0] == sandboxHelperArg && os.Getenv(sandboxSpecEnv) != "" {
+		runSandboxHelper()
+	}
+}
+
+// sandboxCommand returns a command that runs command inside the sandbox
+// described by profile, and a cleanup function for the staging directory.
+func sandboxCommand(ctx context.Context, command []string, dir string, env []string, profile *SandboxProfile) (*exec.Cmd, func(), error) {
+	root, err := os.MkdirTemp("", "flowgate-sandbox-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("create sandbox root: %w", err)
+	}
+	cleanup := func() { os.Remove(root) }
+	if dir == "" {
+		if dir, err = os.Getwd(); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+	spec, err := json.Marshal(sandboxSpec{Root: root, Dir: dir, Command: command, Profile: profile})
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, "/proc/self/exe")
+	cmd.Args = []string{sandboxHelperArg}
+	cmd.Env = append(append([]string{}, env...), sandboxSpecEnv+"="+string(spec))
+	configureProcessGroup(cmd)
+
+	flags := uintptr(syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS)
+	if !profile.Network {
+		flags |= syscall.CLONE_NEWNET
+	}
+	cmd.SysProcAttr.Cloneflags = flags
+	// Root inside the namespace is needed to mount; it has no privileges
+	// outside it.
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+	cmd.SysProcAttr.GidMappingsEnableSetgroups = false
+	return cmd, cleanup, nil
+}
+
+func runSandboxHelper() {
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(os.Getenv(sandboxSpecEnv)), &spec); err != nil {
+		sandboxExit(fmt.Errorf("read spec: %w", err))
+	}
+	os.Unsetenv(sandboxSpecEnv)
+	if len(spec.Command) == 0 || spec.Profile == nil {
+		sandboxExit(fmt.Errorf("incomplete spec"))
+	}
+
+	if err := setupSandboxFS(spec); err != nil {
+		sandboxExit(err)
+	}
+	if !spec.Profile.Network {
+		if err := loopbackUp(); err != nil {
+			sandboxExit(fmt.Errorf("bring up loopback: %w", err))
+		}
+	}
+	_ = syscall.Sethostname([]byte("flowgate-sandbox"))
+
+	path, err := exec.LookPath(spec.Command[0])
+	if err != nil {
+		sandboxExit(err)
+	}
+	if err := setSandboxLimits(spec.Profile); err != nil {
+		sandboxExit(err)
+	}
+	sandboxExit(syscall.Exec(path, spec.Command, os.Environ()))
+}
+
+func sandboxExit(err error) {
+	fmt.Fprintf(os.Stderr, "%s%v\n", sandboxErrorPrefix, err)
+	os.Exit(sandboxExitCode)
+}
+
+// setupSandboxFS builds the sandbox filesystem under spec.Root and pivots
+// into it.
+func setupSandboxFS(spec sandboxSpec) error {
+	root := spec.Root
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+	if err := syscall.Mount("/", root, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind root: %w", err)
+	}
+
+	mounts, err := mountPoints(root)
+	if err != nil {
+		return err
+	}
+	for _, mp := range mounts {
+		rel := strings.TrimPrefix(mp, root)
+		// Device nodes must stay writable (/dev/null) and /proc is replaced
+		// below.
+		if underPath(rel, "/dev") || underPath(rel, "/proc") {
+			continue
+		}
+		if err := remountReadOnly(mp); err != nil {
+			return fmt.Errorf("remount %s read-only: %w", displayPath(rel), err)
+		}
+	}
+
+	for _, hidden := range spec.Profile.Hidden {
+		if err := hidePath(filepath.Join(root, hidden)); err != nil {
+			return fmt.Errorf("hide %s: %w", hidden, err)
+		}
+	}
+	if err := syscall.Mount("tmpfs", filepath.Join(root, sandboxTmp), "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("mount %s: %w", sandboxTmp, err)
+	}
+	for _, path := range spec.Profile.ReadOnly {
+		if err := bindPath(path, filepath.Join(root, path), true); err != nil {
+			return fmt.Errorf("bind %s read-only: %w", path, err)
+		}
+	}
+	for _, path := range spec.Profile.Writable {
+		if err := bindPath(path, filepath.Join(root, path), false); err != nil {
+			return fmt.Errorf("bind %s: %w", path, err)
+		}
+	}
+
+	// A fresh /proc shows only the sandbox's processes. Some container
+	// runtimes forbid it; the host /proc is kept then.
+	_ = syscall.Mount("proc", filepath.Join(root, "proc"), "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, "")
+
+	if err := syscall.Chdir(root); err != nil {
+		return err
+	}
+	if err := syscall.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("pivot root: %w", err)
+	}
+	if err := syscall.Unmount(".", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("detach host root: %w", err)
+	}
+	if err := syscall.Chdir(spec.Dir); err != nil {
+		return fmt.Errorf("enter workdir: %w", err)
+	}
+	return nil
+}
+
+// mountPoints lists the mount points at or below root, parents first.
+func mountPoints(root string) ([]string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		mp := unescapeMountPath(fields[4])
+		if underPath(mp, root) {
+			out = append(out, mp)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// unescapeMountPath decodes the octal escapes used in mountinfo.
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func remountReadOnly(path string) error {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return err
+	}
+	flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY)
+	for _, carry := range []struct{ st, ms uintptr }{
+		{stNosuid, syscall.MS_NOSUID},
+		{stNodev, syscall.MS_NODEV},
+		{stNoexec, syscall.MS_NOEXEC},
+		{stNoatime, syscall.MS_NOATIME},
+		{stNodiratime, syscall.MS_NODIRATIME},
+		{stRelatime, syscall.MS_RELATIME},
+	} {
+		if uintptr(st.Flags)&carry.st != 0 {
+			flags |= carry.ms
+		}
+	}
+	return syscall.Mount("", path, "", flags, "")
+}
+
+// hidePath covers a directory with an empty tmpfs and a file with
+// /dev/null. Missing paths are ignored.
+func hidePath(target string) error {
+	info, err := os.Lstat(target)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		if err := syscall.Mount("/dev/null", target, "", syscall.MS_BIND, ""); err != nil {
+			return err
+		}
+		return remountReadOnly(target)
+	}
+	if err := syscall.Mount("tmpfs", target, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=0755,size=1m"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// bindPath binds a host path into the sandbox, creating the mount point in
+// a hidden directory when needed. Missing sources are ignored.
+func bindPath(source, target string, readOnly bool) error {
+	info, err := os.Stat(source)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		err = os.MkdirAll(target, 0755)
+	} else if err = os.MkdirAll(filepath.Dir(target), 0755); err == nil {
+		var f *os.File
+		if f, err = os.OpenFile(target, os.O_CREATE|os.O_RDONLY, 0644); err == nil {
+			f.Close()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := syscall.Mount(source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return err
+	}
+	if readOnly {
+		return remountReadOnly(target)
+	}
+	return nil
+}
+
+// loopbackUp brings up lo in the new network namespace so tests can still
+// listen on 127.0.0.1.
+func loopbackUp() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	var req struct {
+		name  [syscall.IFNAMSIZ]byte
+		flags uint16
+		_     [22]byte
+	}
+	copy(req.name[:], "lo")
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.SIOCGIFFLAGS, uintptr(unsafe.Pointer(&req))); errno != 0 {
+		return errno
+	}
+	req.flags |= syscall.IFF_UP
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.SIOCSIFFLAGS, uintptr(unsafe.Pointer(&req))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func setSandboxLimits(profile *SandboxProfile) error {
+	limits := []struct {
+		name     string
+		resource int
+		value    uint64
+	}{
+		{"cpu_seconds", syscall.RLIMIT_CPU, uint64(profile.CPUSeconds)},
+		{"memory_mb", syscall.RLIMIT_AS, uint64(profile.MemoryMB) << 20},
+		{"max_processes", rlimitNproc, uint64(profile.MaxProcesses)},
+	}
+	for _, limit := range limits {
+		if limit.value == 0 {
+			continue
+		}
+		rlimit := &syscall.Rlimit{Cur: limit.value, Max: limit.value}
+		if err := syscall.Setrlimit(limit.resource, rlimit); err != nil {
+			return fmt.Errorf("set %s: %w", limit.name, err)
+		}
+	}
+	return nil
+}
+
+func underPath(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")
+}
+
+func displayPath(rel string) string {
+	if rel == "" {
+		return "/"
+	}
+	return rel
+}
@@ -0,0 +1,113 @@
+//go:build linux
+
+package gate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runSandboxed runs script in a sandboxed command gate rooted at workspace.
+// It skips the test when the kernel does not allow the namespaces.
+func runSandboxed(t *testing.T, workspace, script string, cfg SandboxConfig) (*GateResult, CommandDiagnostics) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	g, err := NewCommandGate("sandboxed", []string{"sh", "-c", script}, workspace, nil, false, workspace, nil, "none", "", true)
+	if err != nil {
+		t.Fatalf("new gate: %v", err)
+	}
+	g.SetSandbox(cfg)
+	result, err := g.Evaluate(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	var diag CommandDiagnostics
+	if err := json.Unmarshal(result.Diagnostics, &diag); err != nil {
+		t.Fatalf("unmarshal diagnostics: %v", err)
+	}
+	if len(result.Violations) > 0 && result.Violations[0].Rule == SandboxRule {
+		t.Skipf("sandbox unavailable: %s", result.Violations[0].Message)
+	}
+	return result, diag
+}
+
+func TestSandboxIsolatesCommand(t *testing.T) {
+	home := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	key := filepath.Join(home, ".ssh", "id_ed25519")
+	if err := os.WriteFile(key, []byte("secret"), 0600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("FLOWGATE_TEST_SECRET", "leaked")
+
+	outside, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	probe := filepath.Join(outside, ".sandbox-probe")
+	t.Cleanup(func() { os.Remove(probe) })
+
+	workspace := t.TempDir()
+	script := strings.Join([]string{
+		"cat " + key + " && echo read-key",
+		"touch " + probe + " && echo wrote-outside",
+		"echo \"secret=$FLOWGATE_TEST_SECRET home=$HOME\"",
+		"touch result.txt && echo wrote-workspace",
+		"touch /tmp/scratch && echo wrote-tmp",
+		"ls /sys/class/net",
+	}, "; ")
+	result, diag := runSandboxed(t, workspace, script, SandboxConfig{})
+	if !result.Passed {
+		t.Fatalf("expected script to run, got %+v\nstderr: %s", result, diag.Stderr)
+	}
+
+	for _, forbidden := range []string{"read-key", "wrote-outside", "leaked"} {
+		if strings.Contains(diag.Stdout, forbidden) {
+			t.Fatalf("sandbox allowed %s:\n%s", forbidden, diag.Stdout)
+		}
+	}
+	for _, want := range []string{"secret= home=/tmp", "wrote-workspace", "wrote-tmp"} {
+		if !strings.Contains(diag.Stdout, want) {
+			t.Fatalf("expected %q in output:\n%s\nstderr: %s", want, diag.Stdout, diag.Stderr)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "result.txt")); err != nil {
+		t.Fatalf("expected workspace write to reach the host: %v", err)
+	}
+	if diag.Sandbox == nil || diag.Sandbox.Network || !strings.Contains(strings.Join(diag.Sandbox.Namespaces, ","), "net") {
+		t.Fatalf("expected sandbox profile in diagnostics, got %+v", diag.Sandbox)
+	}
+}
+
+func TestSandboxLimits(t *testing.T) {
+	workspace := t.TempDir()
+	result, diag := runSandboxed(t, workspace, "head -c 4096 /dev/zero | tr '\\0' x", SandboxConfig{
+		MaxOutputBytes: 100,
+		MaxProcesses:   64,
+		Env:            []string{"GREETING=hi"},
+	})
+	if !result.Passed {
+		t.Fatalf("expected success, got %+v\nstderr: %s", result, diag.Stderr)
+	}
+	if len(diag.Stdout) != 100 || !diag.OutputTruncated {
+		t.Fatalf("expected output capped at 100 bytes, got %d (truncated=%v)", len(diag.Stdout), diag.OutputTruncated)
+	}
+	if diag.Sandbox.MaxProcesses != 64 || !strings.Contains(strings.Join(diag.Sandbox.Env, ","), "GREETING") {
+		t.Fatalf("unexpected profile %+v", diag.Sandbox)
+	}
+
+	result, diag = runSandboxed(t, workspace, "grep 'Max processes' /proc/self/limits", SandboxConfig{MaxProcesses: 64})
+	if !result.Passed || !strings.Contains(diag.Stdout, " 64 ") {
+		t.Fatalf("expected process limit to apply, got %q (%+v)", diag.Stdout, result)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package gate
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// sandboxCommand is unavailable: the sandbox relies on Linux namespaces.
+func sandboxCommand(_ context.Context, _ []string, _ string, _ []string, _ *SandboxProfile) (*exec.Cmd, func(), error) {
+	return nil, nil, fmt.Errorf("sandbox requires Linux")
+}
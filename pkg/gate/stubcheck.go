@@ -0,0 +1,435 @@
+package gate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/workspace"
+)
+
+// Rules reported by StubCheckGate. They match the hollowcheck rule names so
+// generateRepairHint produces the same hints for both gates.
+const (
+	StubRuleForbiddenPattern = "forbidden_pattern"
+	StubRuleStubFunction     = "stub_function"
+	StubRuleIgnoredError     = "ignored_error"
+	StubRuleMockData         = "mock_data"
+)
+
+// StubCheckGate detects placeholder code without external tools. Go sources
+// are parsed with go/ast; other files are scanned line by line.
+type StubCheckGate struct {
+	name          string
+	workspacePath string
+}
+
+// StubCheckDiagnostics is stored as the gate result diagnostics.
+type StubCheckDiagnostics struct {
+	FilesScanned []string `json:"files_scanned"`
+	Errors       int      `json:"errors"`
+	Warnings     int      `json:"warnings"`
+}
+
+// stubSource is one file to scan. When changed is set only findings on those
+// lines are reported, so diffs are not blamed for existing code.
+type stubSource struct {
+	path    string
+	content string
+	changed map[int]bool
+	// fragment marks sources assembled from diff lines; they are not
+	// complete files and are never parsed as Go.
+	fragment bool
+}
+
+// NewStubCheckGate creates a stub detector. workspacePath is used to read the
+// patched files when the artifact is a unified diff; it may be empty.
+func NewStubCheckGate(name, workspacePath string) *StubCheckGate {
+	if name == "" {
+		name = "stubcheck"
+	}
+	return &StubCheckGate{name: name, workspacePath: workspacePath}
+}
+
+// Name returns the gate identifier.
+func (g *StubCheckGate) Name() string {
+	return g.name
+}
+
+// Evaluate scans the artifact for stubs and placeholder code.
+func (g *StubCheckGate) Evaluate(_ context.Context, a *artifact.Artifact) (*GateResult, error) {
+	sources := g.sources(a)
+
+	var violations []Violation
+	var hints []string
+	diag := StubCheckDiagnostics{FilesScanned: []string{}}
+	for _, src := range sources {
+		diag.FilesScanned = append(diag.FilesScanned, src.path)
+		for _, issue := range scanSource(src) {
+			if src.changed != nil && !src.changed[issue.Line] {
+				continue
+			}
+			location := fmt.Sprintf("%s:%d", issue.File, issue.Line)
+			violations = append(violations, Violation{
+				Rule:     issue.Rule,
+				Severity: issue.Severity,
+				Message:  issue.Message,
+				Location: location,
+			})
+			if hint := generateRepairHint(issue); hint != "" {
+				hints = append(hints, hint)
+			}
+			if issue.Severity == "error" {
+				diag.Errors++
+			} else {
+				diag.Warnings++
+			}
+		}
+	}
+
+	// Like hollowcheck, the score measures hollowness: 0 is clean.
+	score := 10*diag.Errors + 3*diag.Warnings
+	if score > 100 {
+		score = 100
+	}
+	var result *GateResult
+	if diag.Errors == 0 {
+		result = NewPassingResult(score)
+		result.Violations = violations
+	} else {
+		result = NewFailingResult(score, violations, hints)
+	}
+	result.Kind = "stubcheck"
+	result.Diagnostics, _ = json.Marshal(diag)
+	return result, nil
+}
+
+// sources splits an artifact into files: a unified diff, file blocks, or a
+// single file named after the artifact's extension metadata.
+func (g *StubCheckGate) sources(a *artifact.Artifact) []stubSource {
+	content := unfence(a.Content)
+
+	if patches, err := workspace.ParseUnifiedDiff(content); err == nil {
+		var sources []stubSource
+		for _, patch := range patches {
+			if src, ok := g.patchSource(patch); ok {
+				sources = append(sources, src)
+			}
+		}
+		return sources
+	}
+
+	if files := parseMultiFileContent(content); len(files) > 0 {
+		paths := make([]string, 0, len(files))
+		for path := range files {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		sources := make([]stubSource, 0, len(paths))
+		for _, path := range paths {
+			sources = append(sources, stubSource{path: path, content: files[path]})
+		}
+		return sources
+	}
+
+	ext := ".go"
+	if e, ok := a.Metadata["extension"]; ok {
+		ext = e
+	}
+	return []stubSource{{path: "artifact" + ext, content: content}}
+}
+
+// patchSource prefers the patched file from the workspace so Go code can be
+// parsed whole; otherwise it falls back to the added lines alone. The
+// workspace file is used only when it holds the added lines, since a stage
+// that does not apply its diff leaves the old file there.
+func (g *StubCheckGate) patchSource(patch workspace.FilePatch) (stubSource, bool) {
+	path := strings.TrimPrefix(strings.TrimPrefix(patch.NewPath, "b/"), "a/")
+	if path == "" || path == "/dev/null" {
+		return stubSource{}, false
+	}
+
+	changed := make(map[int]bool)
+	added := make(map[int]string)
+	for _, hunk := range patch.Hunks {
+		line := hunk.NewStart
+		for _, l := range hunk.Lines {
+			switch {
+			case strings.HasPrefix(l, "+"):
+				changed[line] = true
+				added[line] = l[1:]
+				line++
+			case strings.HasPrefix(l, "-"):
+			default:
+				line++
+			}
+		}
+	}
+
+	if g.workspacePath != "" && filepath.IsLocal(path) {
+		if data, err := os.ReadFile(filepath.Join(g.workspacePath, path)); err == nil && holdsLines(string(data), added) {
+			return stubSource{path: path, content: string(data), changed: changed}, true
+		}
+	}
+
+	// Rebuild the added lines at their original line numbers.
+	maxLine := 0
+	for line := range added {
+		if line > maxLine {
+			maxLine = line
+		}
+	}
+	lines := make([]string, maxLine)
+	for line, text := range added {
+		if line > 0 {
+			lines[line-1] = text
+		}
+	}
+	return stubSource{path: path, content: strings.Join(lines, "\n"), changed: changed, fragment: true}, true
+}
+
+var (
+	todoPattern           = regexp.MustCompile(`\b(TODO|FIXME)\b`)
+	notImplementedPattern = regexp.MustCompile(`(?i)(panic|raise|throw)\b.*not[ _]?implemented|NotImplementedError|\bunimplemented!\(|\btodo!\(`)
+	mockDataPattern       = regexp.MustCompile(`(?i)lorem ipsum|\b(john|jane) doe\b|\b(dummy|fake|mock|sample)[ _-]?(data|value|user|response)\b|\b555-\d{4}\b|\b123-45-6789\b`)
+	commentPattern        = regexp.MustCompile(`^\s*(//|#|/\*|\*|--|<!--)`)
+	goIgnoredErrPattern   = regexp.MustCompile(`(^|,)\s*_\s*:?=\s*[\w.]+\(`)
+	emptyCatchPattern     = regexp.MustCompile(`catch\s*(\([^)]*\))?\s*\{\s*\}`)
+	exceptPassPattern     = regexp.MustCompile(`^\s*except\b[^:]*:\s*pass\s*$`)
+	pyDefPattern          = regexp.MustCompile(`^\s*(async\s+)?def\s+\w+.*:\s*$`)
+	pyPassPattern         = regexp.MustCompile(`^\s*(pass|\.\.\.)\s*$`)
+)
+
+func scanSource(src stubSource) []hollowcheckIssue {
+	if strings.HasSuffix(src.path, ".go") && !src.fragment {
+		if issues, ok := scanGo(src); ok {
+			return issues
+		}
+	}
+	return scanLines(src)
+}
+
+// scanGo inspects a Go file. It reports false when the file does not parse,
+// in which case the caller falls back to line heuristics.
+func scanGo(src stubSource) ([]hollowcheckIssue, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, src.path, src.content, parser.ParseComments)
+	if err != nil {
+		return nil, false
+	}
+
+	var issues []hollowcheckIssue
+	add := func(pos token.Pos, rule, severity, message string) {
+		issues = append(issues, hollowcheckIssue{
+			Rule:     rule,
+			Severity: severity,
+			File:     src.path,
+			Line:     fset.Position(pos).Line,
+			Message:  message,
+		})
+	}
+
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if m := todoPattern.FindString(c.Text); m != "" {
+				add(c.Pos(), StubRuleForbiddenPattern, "error", m+" comment left in code")
+			}
+		}
+	}
+
+	isTest := strings.HasSuffix(src.path, "_test.go")
+	constrained := hasBuildConstraint(file)
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncDecl:
+			// Empty methods satisfy interfaces and empty functions behind
+			// build constraints are platform no-ops.
+			if node.Recv == nil && !constrained && node.Body != nil && len(node.Body.List) == 0 && !hasCommentWithin(file, node.Body) {
+				add(node.Pos(), StubRuleStubFunction, "error", fmt.Sprintf("function %s has an empty body", node.Name.Name))
+			}
+		case *ast.CallExpr:
+			if isNotImplementedPanic(node) {
+				add(node.Pos(), StubRuleForbiddenPattern, "error", `panic("not implemented") stub`)
+			}
+		case *ast.AssignStmt:
+			if len(node.Rhs) == 1 && len(node.Lhs) > 0 {
+				if _, ok := node.Rhs[0].(*ast.CallExpr); ok && isBlank(node.Lhs[len(node.Lhs)-1]) {
+					// Without type information the discarded value may not be
+					// an error, so this does not fail the gate.
+					add(node.Pos(), StubRuleIgnoredError, "warning", "error return value ignored with _")
+				}
+			}
+		case *ast.IfStmt:
+			if isErrNilCheck(node.Cond) && len(node.Body.List) == 0 && !hasCommentWithin(file, node.Body) {
+				add(node.Pos(), StubRuleIgnoredError, "error", "error checked but ignored in empty if block")
+			}
+		case *ast.BasicLit:
+			if !isTest && node.Kind == token.STRING {
+				if value, err := strconv.Unquote(node.Value); err == nil && mockDataPattern.MatchString(value) {
+					add(node.Pos(), StubRuleMockData, "warning", fmt.Sprintf("hard-coded mock data %q", truncateLiteral(value)))
+				}
+			}
+		}
+		return true
+	})
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return issues, true
+}
+
+// scanLines applies language-agnostic heuristics.
+func scanLines(src stubSource) []hollowcheckIssue {
+	var issues []hollowcheckIssue
+	add := func(line int, rule, severity, message string) {
+		issues = append(issues, hollowcheckIssue{Rule: rule, Severity: severity, File: src.path, Line: line, Message: message})
+	}
+
+	isGo := strings.HasSuffix(src.path, ".go")
+	isTest := strings.Contains(filepath.Base(src.path), "test")
+	pendingDef := 0
+	for i, line := range strings.Split(src.content, "\n") {
+		n := i + 1
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		if commentPattern.MatchString(line) || strings.Contains(line, "//") || strings.Contains(line, " #") {
+			if m := todoPattern.FindString(line); m != "" {
+				add(n, StubRuleForbiddenPattern, "error", m+" comment left in code")
+			}
+		}
+		if notImplementedPattern.MatchString(line) {
+			if isGo {
+				add(n, StubRuleForbiddenPattern, "error", `panic("not implemented") stub`)
+			} else {
+				add(n, StubRuleForbiddenPattern, "error", "not implemented stub: "+trimmed)
+			}
+		}
+		if isGo && goIgnoredErrPattern.MatchString(line) {
+			add(n, StubRuleIgnoredError, "warning", "error return value ignored with _")
+		}
+		if emptyCatchPattern.MatchString(line) || exceptPassPattern.MatchString(line) {
+			add(n, StubRuleIgnoredError, "error", "exception caught and ignored")
+		}
+		if pendingDef > 0 && pyPassPattern.MatchString(line) {
+			add(pendingDef, StubRuleStubFunction, "error", "function body is only "+trimmed)
+		}
+		pendingDef = 0
+		if pyDefPattern.MatchString(line) {
+			pendingDef = n
+		}
+		if !isTest && mockDataPattern.MatchString(line) && !commentPattern.MatchString(line) {
+			add(n, StubRuleMockData, "warning", "hard-coded mock data: "+truncateLiteral(trimmed))
+		}
+	}
+	return issues
+}
+
+func isNotImplementedPanic(call *ast.CallExpr) bool {
+	ident, ok := call.Fun.(*ast.Ident)
+	if !ok || ident.Name != "panic" || len(call.Args) != 1 {
+		return false
+	}
+	return strings.Contains(strings.ToLower(literalText(call.Args[0])), "not implemented")
+}
+
+// literalText returns the string literals within expr, joined, so that
+// panic(fmt.Sprintf("not implemented: %s", x)) is recognized too.
+func literalText(expr ast.Expr) string {
+	var sb strings.Builder
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			if value, err := strconv.Unquote(lit.Value); err == nil {
+				sb.WriteString(value)
+			}
+		}
+		return true
+	})
+	return sb.String()
+}
+
+// holdsLines reports whether content has the given lines at their line
+// numbers.
+func holdsLines(content string, lines map[int]string) bool {
+	fileLines := strings.Split(content, "\n")
+	for n, text := range lines {
+		if n < 1 || n > len(fileLines) || strings.TrimSuffix(fileLines[n-1], "\r") != text {
+			return false
+		}
+	}
+	return true
+}
+
+// hasBuildConstraint reports whether a Go file has a build constraint.
+func hasBuildConstraint(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if constraint.IsGoBuild(c.Text) || constraint.IsPlusBuild(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isBlank(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == "_"
+}
+
+func isErrNilCheck(expr ast.Expr) bool {
+	bin, ok := expr.(*ast.BinaryExpr)
+	if !ok || bin.Op != token.NEQ {
+		return false
+	}
+	ident, ok := bin.X.(*ast.Ident)
+	nilIdent, nilOK := bin.Y.(*ast.Ident)
+	return ok && nilOK && nilIdent.Name == "nil" && (ident.Name == "err" || strings.HasSuffix(ident.Name, "Err"))
+}
+
+// hasCommentWithin reports whether a block contains a comment, which marks an
+// intentionally empty body.
+func hasCommentWithin(file *ast.File, block *ast.BlockStmt) bool {
+	for _, group := range file.Comments {
+		if group.Pos() > block.Lbrace && group.End() < block.Rbrace {
+			return true
+		}
+	}
+	return false
+}
+
+// unfence strips a markdown code fence wrapping the whole artifact.
+func unfence(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") || len(trimmed) < 6 {
+		return content
+	}
+	body := strings.TrimSuffix(trimmed, "```")
+	newline := strings.Index(body, "\n")
+	if newline < 0 {
+		return content
+	}
+	return body[newline+1:]
+}
+
+func truncateLiteral(value string) string {
+	if len(value) > 60 {
+		return value[:60] + "..."
+	}
+	return value
+}
@@ -0,0 +1,177 @@
+package gate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/artifact"
+)
+
+func TestStubCheckGoSource(t *testing.T) {
+	content := `package store
+
+import "os"
+
+// TODO: add caching
+func Load(path string) ([]byte, error) {
+	panic("not implemented")
+}
+
+func Save(path string, data []byte) {}
+
+func Close() {
+	// Nothing to release.
+}
+
+func Remove(path string) {
+	_ = os.Remove(path)
+	if err := os.Chmod(path, 0600); err != nil {
+	}
+}
+
+var owner = "John Doe"
+`
+	g := NewStubCheckGate("", "")
+	result, err := g.Evaluate(context.Background(), artifact.New(content, "mock", "mock-1", ""))
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if result.Passed || result.Kind != "stubcheck" {
+		t.Fatalf("expected failing stubcheck result, got %+v", result)
+	}
+
+	got := make(map[string]string)
+	for _, v := range result.Violations {
+		got[v.Location] = v.Rule
+	}
+	want := map[string]string{
+		"artifact.go:5":  StubRuleForbiddenPattern,
+		"artifact.go:7":  StubRuleForbiddenPattern,
+		"artifact.go:10": StubRuleStubFunction,
+		"artifact.go:17": StubRuleIgnoredError,
+		"artifact.go:18": StubRuleIgnoredError,
+		"artifact.go:22": StubRuleMockData,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d violations, got %+v", len(want), result.Violations)
+	}
+	for location, rule := range want {
+		if got[location] != rule {
+			t.Fatalf("expected %s at %s, got %+v", rule, location, result.Violations)
+		}
+	}
+
+	hints := strings.Join(result.RepairHints, "\n")
+	for _, hint := range []string{
+		"Remove TODO comment at artifact.go:5",
+		`Replace panic("not implemented") with real implementation at artifact.go:7`,
+		"Implement stub function at artifact.go:10",
+		"Handle error properly at artifact.go:17",
+	} {
+		if !strings.Contains(hints, hint) {
+			t.Fatalf("missing hint %q in:\n%s", hint, hints)
+		}
+	}
+}
+
+func TestStubCheckOtherLanguages(t *testing.T) {
+	content := "// file: app.py\n" +
+		"def handler(event):\n" +
+		"    pass\n" +
+		"\n" +
+		"def parse(raw):\n" +
+		"    raise NotImplementedError\n" +
+		"// file: app.js\n" +
+		"try { run() } catch (e) {}\n" +
+		"// FIXME: retry\n"
+	g := NewStubCheckGate("stubs", "")
+	result, err := g.Evaluate(context.Background(), artifact.New(content, "mock", "mock-1", ""))
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if result.Passed {
+		t.Fatalf("expected failure, got %+v", result)
+	}
+	want := []string{"app.js:1", "app.js:2", "app.py:1", "app.py:5"}
+	var got []string
+	for _, v := range result.Violations {
+		got = append(got, v.Location)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected violations at %v, got %+v", want, result.Violations)
+	}
+}
+
+func TestStubCheckDiffOnlyReportsAddedLines(t *testing.T) {
+	dir := t.TempDir()
+	patched := "package main\n\n// TODO: existing note\nfunc main() {\n\trun()\n}\n\nfunc run() {}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(patched), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	diff := "--- a/main.go\n+++ b/main.go\n@@ -3,4 +3,6 @@\n // TODO: existing note\n func main() {\n \trun()\n }\n+\n+func run() {}\n"
+
+	result, err := NewStubCheckGate("", dir).Evaluate(context.Background(), artifact.New(diff, "mock", "mock-1", ""))
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if result.Passed || len(result.Violations) != 1 || result.Violations[0].Location != "main.go:8" {
+		t.Fatalf("expected only the added stub to be reported, got %+v", result.Violations)
+	}
+
+	clean := "package main\n\nfunc main() {\n\tprintln(\"ok\")\n}\n"
+	result, err = NewStubCheckGate("", "").Evaluate(context.Background(), artifact.New(clean, "mock", "mock-1", ""))
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if !result.Passed || result.Score != 0 {
+		t.Fatalf("expected clean source to pass, got %+v", result)
+	}
+}
+
+func TestStubCheckAllowsIdiomaticGo(t *testing.T) {
+	content := "// file: proc_other.go\n" +
+		"//go:build !unix\n" +
+		"\n" +
+		"package proc\n" +
+		"\n" +
+		"func configure() {}\n" +
+		"// file: sink.go\n" +
+		"package proc\n" +
+		"\n" +
+		"import \"encoding/json\"\n" +
+		"\n" +
+		"type sink struct{ data []byte }\n" +
+		"\n" +
+		"func (sink) Close() {}\n" +
+		"\n" +
+		"func (s *sink) Write(v any) {\n" +
+		"\ts.data, _ = json.Marshal(v)\n" +
+		"}\n"
+	result, err := NewStubCheckGate("", "").Evaluate(context.Background(), artifact.New(content, "mock", "mock-1", ""))
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if !result.Passed || len(result.Violations) != 1 || result.Violations[0].Location != "sink.go:10" || result.Violations[0].Severity != "warning" {
+		t.Fatalf("expected only a warning for the discarded result, got %+v", result)
+	}
+}
+
+func TestStubCheckDiffIgnoresUnpatchedWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	original := "package main\n\nfunc main() {}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(original), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	diff := "--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,5 @@\n package main\n \n func main() {}\n+\n+func B() { panic(\"not implemented\") }\n"
+
+	result, err := NewStubCheckGate("", dir).Evaluate(context.Background(), artifact.New(diff, "mock", "mock-1", ""))
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if result.Passed || len(result.Violations) != 1 || result.Violations[0].Location != "main.go:5" {
+		t.Fatalf("expected the added stub to be reported, got %+v", result.Violations)
+	}
+}
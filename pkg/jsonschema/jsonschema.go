@@ -0,0 +1,560 @@
+// Package jsonschema validates decoded JSON values against a practical subset
+// of JSON Schema (draft 2020-12 keywords without remote references or
+// formats).
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	root *node
+	raw  json.RawMessage
+}
+
+// ValidationError describes one place where a value does not match the
+// schema. Pointer is an RFC 6901 JSON pointer to the offending value.
+type ValidationError struct {
+	Pointer string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Pointer == "" {
+		return e.Message
+	}
+	return e.Pointer + ": " + e.Message
+}
+
+type node struct {
+	// always is set for the boolean schemas true and false.
+	always *bool
+
+	types            []string
+	properties       map[string]*node
+	required         []string
+	additional       *node
+	items            *node
+	prefixItems      []*node
+	enum             []any
+	constValue       any
+	hasConst         bool
+	minLength        *int
+	maxLength        *int
+	pattern          *regexp.Regexp
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+	minItems         *int
+	maxItems         *int
+	uniqueItems      bool
+	minProperties    *int
+	maxProperties    *int
+	allOf            []*node
+	anyOf            []*node
+	oneOf            []*node
+	not              *node
+	ref              *node
+}
+
+// Compile parses a JSON Schema document.
+func Compile(data []byte) (*Schema, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid schema JSON: %w", err)
+	}
+	c := &compiler{doc: doc, refs: make(map[string]*node)}
+	root, err := c.compile(doc, "")
+	if err != nil {
+		return nil, err
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return nil, fmt.Errorf("invalid schema JSON: %w", err)
+	}
+	return &Schema{root: root, raw: compact.Bytes()}, nil
+}
+
+// Raw returns the schema document in compact form.
+func (s *Schema) Raw() json.RawMessage {
+	return append(json.RawMessage(nil), s.raw...)
+}
+
+// Validate checks a value decoded by encoding/json and returns every
+// mismatch found, ordered by pointer.
+func (s *Schema) Validate(value any) []ValidationError {
+	var errs []ValidationError
+	s.root.validate(value, "", &errs)
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Pointer < errs[j].Pointer
+	})
+	return errs
+}
+
+// ValidateJSON decodes data and validates it.
+func (s *Schema) ValidateJSON(data []byte) ([]ValidationError, error) {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return s.Validate(value), nil
+}
+
+type compiler struct {
+	doc  any
+	refs map[string]*node
+}
+
+func (c *compiler) compile(v any, at string) (*node, error) {
+	switch s := v.(type) {
+	case bool:
+		return &node{always: &s}, nil
+	case map[string]any:
+		return c.compileObject(s, at)
+	default:
+		return nil, fmt.Errorf("schema at %q must be an object or boolean", pointerOrRoot(at))
+	}
+}
+
+func (c *compiler) compileObject(s map[string]any, at string) (*node, error) {
+	n := &node{}
+	var err error
+
+	if ref, ok := s["$ref"]; ok {
+		str, ok := ref.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: $ref must be a string", pointerOrRoot(at))
+		}
+		if n.ref, err = c.resolve(str); err != nil {
+			return nil, err
+		}
+	}
+
+	switch t := s["type"].(type) {
+	case nil:
+	case string:
+		n.types = []string{t}
+	case []any:
+		for _, item := range t {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: type entries must be strings", pointerOrRoot(at))
+			}
+			n.types = append(n.types, str)
+		}
+	default:
+		return nil, fmt.Errorf("%s: type must be a string or array", pointerOrRoot(at))
+	}
+	for _, t := range n.types {
+		switch t {
+		case "null", "boolean", "object", "array", "number", "integer", "string":
+		default:
+			return nil, fmt.Errorf("%s: unknown type %q", pointerOrRoot(at), t)
+		}
+	}
+
+	if props, ok := s["properties"].(map[string]any); ok {
+		n.properties = make(map[string]*node, len(props))
+		for name, sub := range props {
+			if n.properties[name], err = c.compile(sub, at+"/properties/"+escape(name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if req, ok := s["required"].([]any); ok {
+		for _, item := range req {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: required entries must be strings", pointerOrRoot(at))
+			}
+			n.required = append(n.required, str)
+		}
+	}
+	if sub, ok := s["additionalProperties"]; ok {
+		if n.additional, err = c.compile(sub, at+"/additionalProperties"); err != nil {
+			return nil, err
+		}
+	}
+	if sub, ok := s["items"]; ok {
+		if n.items, err = c.compile(sub, at+"/items"); err != nil {
+			return nil, err
+		}
+	}
+	if n.prefixItems, err = c.compileList(s, "prefixItems", at); err != nil {
+		return nil, err
+	}
+	if n.allOf, err = c.compileList(s, "allOf", at); err != nil {
+		return nil, err
+	}
+	if n.anyOf, err = c.compileList(s, "anyOf", at); err != nil {
+		return nil, err
+	}
+	if n.oneOf, err = c.compileList(s, "oneOf", at); err != nil {
+		return nil, err
+	}
+	if sub, ok := s["not"]; ok {
+		if n.not, err = c.compile(sub, at+"/not"); err != nil {
+			return nil, err
+		}
+	}
+
+	if enum, ok := s["enum"]; ok {
+		list, ok := enum.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: enum must be an array", pointerOrRoot(at))
+		}
+		n.enum = list
+	}
+	if value, ok := s["const"]; ok {
+		n.constValue = value
+		n.hasConst = true
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		if n.pattern, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern: %w", pointerOrRoot(at), err)
+		}
+	}
+
+	n.minLength = intKeyword(s, "minLength")
+	n.maxLength = intKeyword(s, "maxLength")
+	n.minItems = intKeyword(s, "minItems")
+	n.maxItems = intKeyword(s, "maxItems")
+	n.minProperties = intKeyword(s, "minProperties")
+	n.maxProperties = intKeyword(s, "maxProperties")
+	n.minimum = numberKeyword(s, "minimum")
+	n.maximum = numberKeyword(s, "maximum")
+	n.exclusiveMinimum = numberKeyword(s, "exclusiveMinimum")
+	n.exclusiveMaximum = numberKeyword(s, "exclusiveMaximum")
+	n.multipleOf = numberKeyword(s, "multipleOf")
+	n.uniqueItems, _ = s["uniqueItems"].(bool)
+
+	return n, nil
+}
+
+func (c *compiler) compileList(s map[string]any, key, at string) ([]*node, error) {
+	raw, ok := s[key]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: %s must be an array", pointerOrRoot(at), key)
+	}
+	nodes := make([]*node, 0, len(list))
+	for i, sub := range list {
+		n, err := c.compile(sub, fmt.Sprintf("%s/%s/%d", at, key, i))
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// resolve compiles a local reference such as "#/$defs/step". Targets are
+// memoized before compilation so recursive schemas terminate.
+func (c *compiler) resolve(ref string) (*node, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %q: only local references are allowed", ref)
+	}
+	if n, ok := c.refs[ref]; ok {
+		return n, nil
+	}
+	target, err := lookup(c.doc, strings.TrimPrefix(ref, "#"))
+	if err != nil {
+		return nil, fmt.Errorf("unresolved $ref %q: %w", ref, err)
+	}
+	n := &node{}
+	c.refs[ref] = n
+	compiled, err := c.compile(target, strings.TrimPrefix(ref, "#"))
+	if err != nil {
+		return nil, err
+	}
+	*n = *compiled
+	return n, nil
+}
+
+func lookup(doc any, pointer string) (any, error) {
+	if pointer == "" {
+		return doc, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid pointer %q", pointer)
+	}
+	current := doc
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch v := current.(type) {
+		case map[string]any:
+			next, ok := v[token]
+			if !ok {
+				return nil, fmt.Errorf("%q not found", token)
+			}
+			current = next
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("index %q out of range", token)
+			}
+			current = v[i]
+		default:
+			return nil, fmt.Errorf("%q not found", token)
+		}
+	}
+	return current, nil
+}
+
+func (n *node) validate(value any, at string, errs *[]ValidationError) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, ValidationError{Pointer: at, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if n.always != nil {
+		if !*n.always {
+			fail("value is not allowed")
+		}
+		return
+	}
+	if n.ref != nil {
+		n.ref.validate(value, at, errs)
+	}
+
+	if len(n.types) > 0 && !matchesAnyType(value, n.types) {
+		fail("expected %s, got %s", strings.Join(n.types, " or "), typeName(value))
+		return
+	}
+	if len(n.enum) > 0 {
+		found := false
+		for _, candidate := range n.enum {
+			if equal(value, candidate) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value must be one of %s", compactJSON(n.enum))
+		}
+	}
+	if n.hasConst && !equal(value, n.constValue) {
+		fail("value must be %s", compactJSON(n.constValue))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if n.minLength != nil && length < *n.minLength {
+			fail("string is shorter than %d characters", *n.minLength)
+		}
+		if n.maxLength != nil && length > *n.maxLength {
+			fail("string is longer than %d characters", *n.maxLength)
+		}
+		if n.pattern != nil && !n.pattern.MatchString(v) {
+			fail("string does not match pattern %q", n.pattern.String())
+		}
+	case float64:
+		if n.minimum != nil && v < *n.minimum {
+			fail("%v is less than minimum %v", v, *n.minimum)
+		}
+		if n.maximum != nil && v > *n.maximum {
+			fail("%v is greater than maximum %v", v, *n.maximum)
+		}
+		if n.exclusiveMinimum != nil && v <= *n.exclusiveMinimum {
+			fail("%v must be greater than %v", v, *n.exclusiveMinimum)
+		}
+		if n.exclusiveMaximum != nil && v >= *n.exclusiveMaximum {
+			fail("%v must be less than %v", v, *n.exclusiveMaximum)
+		}
+		if n.multipleOf != nil && *n.multipleOf > 0 {
+			if q := v / *n.multipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+				fail("%v is not a multiple of %v", v, *n.multipleOf)
+			}
+		}
+	case []any:
+		if n.minItems != nil && len(v) < *n.minItems {
+			fail("array has fewer than %d items", *n.minItems)
+		}
+		if n.maxItems != nil && len(v) > *n.maxItems {
+			fail("array has more than %d items", *n.maxItems)
+		}
+		if n.uniqueItems {
+			for i := range v {
+				for j := 0; j < i; j++ {
+					if equal(v[i], v[j]) {
+						fail("items %d and %d are equal", j, i)
+					}
+				}
+			}
+		}
+		for i, item := range v {
+			itemAt := at + "/" + strconv.Itoa(i)
+			if i < len(n.prefixItems) {
+				n.prefixItems[i].validate(item, itemAt, errs)
+			} else if n.items != nil {
+				n.items.validate(item, itemAt, errs)
+			}
+		}
+	case map[string]any:
+		if n.minProperties != nil && len(v) < *n.minProperties {
+			fail("object has fewer than %d properties", *n.minProperties)
+		}
+		if n.maxProperties != nil && len(v) > *n.maxProperties {
+			fail("object has more than %d properties", *n.maxProperties)
+		}
+		for _, name := range n.required {
+			if _, ok := v[name]; !ok {
+				// Point at the missing member so callers know where to add it.
+				*errs = append(*errs, ValidationError{Pointer: at + "/" + escape(name), Message: fmt.Sprintf("missing required property %q", name)})
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			propAt := at + "/" + escape(name)
+			if sub, ok := n.properties[name]; ok {
+				sub.validate(v[name], propAt, errs)
+				continue
+			}
+			if n.additional == nil {
+				continue
+			}
+			if n.additional.always != nil && !*n.additional.always {
+				*errs = append(*errs, ValidationError{Pointer: propAt, Message: fmt.Sprintf("property %q is not allowed", name)})
+				continue
+			}
+			n.additional.validate(v[name], propAt, errs)
+		}
+	}
+
+	for _, sub := range n.allOf {
+		sub.validate(value, at, errs)
+	}
+	if len(n.anyOf) > 0 {
+		var best []ValidationError
+		matched := false
+		for _, sub := range n.anyOf {
+			var subErrs []ValidationError
+			sub.validate(value, at, &subErrs)
+			if len(subErrs) == 0 {
+				matched = true
+				break
+			}
+			if best == nil || len(subErrs) < len(best) {
+				best = subErrs
+			}
+		}
+		if !matched {
+			// Report the closest alternative; it is usually the intended one.
+			*errs = append(*errs, best...)
+		}
+	}
+	if len(n.oneOf) > 0 {
+		matches := 0
+		for _, sub := range n.oneOf {
+			var subErrs []ValidationError
+			sub.validate(value, at, &subErrs)
+			if len(subErrs) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			fail("value must match exactly one schema in oneOf, matched %d", matches)
+		}
+	}
+	if n.not != nil {
+		var subErrs []ValidationError
+		n.not.validate(value, at, &subErrs)
+		if len(subErrs) == 0 {
+			fail("value must not match the schema in not")
+		}
+	}
+}
+
+func matchesAnyType(value any, types []string) bool {
+	actual := typeName(value)
+	for _, t := range types {
+		if t == actual {
+			return true
+		}
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func equal(a, b any) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func intKeyword(s map[string]any, key string) *int {
+	v, ok := s[key].(float64)
+	if !ok {
+		return nil
+	}
+	i := int(v)
+	return &i
+}
+
+func numberKeyword(s map[string]any, key string) *float64 {
+	v, ok := s[key].(float64)
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+func compactJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func escape(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+func pointerOrRoot(at string) string {
+	if at == "" {
+		return "schema root"
+	}
+	return at
+}
@@ -0,0 +1,124 @@
+package jsonschema
+
+import (
+	"strings"
+	"testing"
+)
+
+const planSchema = `{
+  "type": "object",
+  "required": ["title", "steps"],
+  "additionalProperties": false,
+  "properties": {
+    "title": {"type": "string", "minLength": 1},
+    "steps": {"type": "array", "minItems": 1, "items": {"$ref": "#/$defs/step"}}
+  },
+  "$defs": {
+    "step": {
+      "type": "object",
+      "required": ["id", "kind"],
+      "properties": {
+        "id": {"type": "integer", "minimum": 1},
+        "kind": {"enum": ["edit", "test"]},
+        "children": {"type": "array", "items": {"$ref": "#/$defs/step"}}
+      }
+    }
+  }
+}`
+
+func TestValidateReportsPointers(t *testing.T) {
+	schema, err := Compile([]byte(planSchema))
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	errs, err := schema.ValidateJSON([]byte(`{
+		"title": "plan",
+		"steps": [
+			{"id": 1, "kind": "edit"},
+			{"id": 0, "kind": "deploy", "children": [{"kind": "test"}]}
+		],
+		"a/b": true
+	}`))
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	want := map[string]string{
+		"/a~1b":                  "not allowed",
+		"/steps/1/id":            "less than minimum",
+		"/steps/1/kind":          "one of",
+		"/steps/1/children/0/id": `missing required property "id"`,
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %+v", len(want), errs)
+	}
+	for _, e := range errs {
+		fragment, ok := want[e.Pointer]
+		if !ok || !strings.Contains(e.Message, fragment) {
+			t.Fatalf("unexpected error %s", e.Error())
+		}
+	}
+}
+
+func TestValidateAcceptsConformingValue(t *testing.T) {
+	schema, err := Compile([]byte(planSchema))
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	errs, err := schema.ValidateJSON([]byte(`{"title": "plan", "steps": [{"id": 1, "kind": "test"}]}`))
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if len(errs) != 0 {
+		t.Fatalf("expected no errors, got %+v", errs)
+	}
+}
+
+func TestValidateTypesAndCombinators(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		valid  bool
+	}{
+		{name: "integer rejects fraction", schema: `{"type": "integer"}`, value: `1.5`},
+		{name: "number accepts integer", schema: `{"type": "number"}`, value: `2`, valid: true},
+		{name: "nullable", schema: `{"type": ["string", "null"]}`, value: `null`, valid: true},
+		{name: "pattern", schema: `{"type": "string", "pattern": "^[a-z]+$"}`, value: `"ABC"`},
+		{name: "const", schema: `{"const": "v1"}`, value: `"v1"`, valid: true},
+		{name: "anyOf", schema: `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, value: `true`},
+		{name: "oneOf ambiguous", schema: `{"oneOf": [{"type": "number"}, {"type": "integer"}]}`, value: `3`},
+		{name: "unique items", schema: `{"uniqueItems": true}`, value: `[1, 2, 1]`},
+		{name: "false schema", schema: `false`, value: `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := Compile([]byte(tt.schema))
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			errs, err := schema.ValidateJSON([]byte(tt.value))
+			if err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			if (len(errs) == 0) != tt.valid {
+				t.Fatalf("expected valid=%v, got %+v", tt.valid, errs)
+			}
+		})
+	}
+}
+
+func TestCompileRejectsInvalidSchemas(t *testing.T) {
+	for _, schema := range []string{
+		`[]`,
+		`{"type": "text"}`,
+		`{"pattern": "("}`,
+		`{"$ref": "https://example.com/schema.json"}`,
+		`{"$ref": "#/$defs/missing"}`,
+	} {
+		if _, err := Compile([]byte(schema)); err == nil {
+			t.Fatalf("expected %s to be rejected", schema)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/cache"
 	"github.com/zen-systems/flowgate/pkg/config"
 )
 
@@ -14,16 +15,42 @@ type callTarget struct {
 	Model   string
 }
 
+// streamSink receives the output of a call as it is generated. onReset is
+// called when the output streamed so far is discarded because the call is
+// retried or falls back to another target.
+type streamSink struct {
+	onDelta adapter.StreamFunc
+	onReset func()
+}
+
 func callAdapterWithPolicy(
 	ctx context.Context,
 	adapters map[string]adapter.Adapter,
 	adapterName string,
 	model string,
-	prompt string,
+	req adapter.Request,
 	cfg *config.RoutingConfig,
 	tracker *costTracker,
+	responses *cache.Cache,
+	stream *streamSink,
 ) (*adapter.Response, []adapter.CallReport, error) {
 	targets := buildTargets(adapterName, model, cfg)
+	if resp, report, ok := lookupCachedResponse(responses, targets, req); ok {
+		if stream != nil && resp.Artifact.Content != "" {
+			stream.onDelta(resp.Artifact.Content)
+		}
+		return resp, []adapter.CallReport{report}, nil
+	}
+
+	var onDelta adapter.StreamFunc
+	streamed := false
+	if stream != nil {
+		onDelta = func(delta string) {
+			streamed = true
+			stream.onDelta(delta)
+		}
+	}
+
 	retryCfg := retrySettings(cfg)
 	var reports []adapter.CallReport
 	var lastErr error
@@ -41,7 +68,11 @@ func callAdapterWithPolicy(
 				}
 			}
 
-			resp, err := adapterImpl.Generate(ctx, target.Model, prompt)
+			if streamed {
+				stream.onReset()
+				streamed = false
+			}
+			resp, err := adapter.Stream(ctx, adapterImpl, target.Model, req, onDelta)
 			if err == nil {
 				usage := normalizeUsage(resp.Usage)
 				cost, _ := estimateCost(cfgPricing(cfg), target.Adapter, target.Model, usage)
@@ -53,6 +84,7 @@ func callAdapterWithPolicy(
 					Retries:      attempt,
 					FallbackUsed: idx > 0,
 				})
+				storeCachedResponse(responses, target, req, resp)
 				return resp, reports, nil
 			}
 
@@ -0,0 +1,174 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+)
+
+// stageTypeApproval is the type of stages that wait for a human decision.
+const stageTypeApproval = "approval"
+
+// Approver asks a human to decide on an approval request. It returns nil
+// without error when it cannot ask, and the run then waits for a decision
+// recorded with flowgate approve.
+type Approver func(ctx context.Context, req evidence.ApprovalRequest) (*evidence.ApprovalRecord, error)
+
+// approvalPollInterval is how often a waiting run checks for a decision.
+var approvalPollInterval = 500 * time.Millisecond
+
+// runApproval runs an approval stage: it asks for a decision on the outputs
+// of the stage's dependencies and fails when they are rejected. Its output
+// is the approver's comment.
+func runApproval(
+	ctx context.Context,
+	env *stageEnv,
+	stage *Stage,
+	artifacts map[string]ArtifactTemplateData,
+	stagesLegacy map[string]map[string]string,
+) (*StageResult, *evidence.StageRecord, error) {
+	start := time.Now()
+	stageRecord := &evidence.StageRecord{}
+
+	message := ""
+	if stage.Prompt != "" {
+		var err error
+		message, err = renderPrompt(stage.Prompt, env.input, artifacts, stagesLegacy)
+		if err != nil {
+			return nil, stageRecord, fmt.Errorf("render prompt for stage %s: %w", stage.Name, err)
+		}
+		stageRecord.Prompt = truncateForEvidence(message, 4096)
+	}
+
+	var subjects []evidence.ApprovalSubject
+	for _, dep := range env.graph[stage.Name] {
+		record, err := evidence.ReadStage(env.writer.RunDir(), dep)
+		if err != nil {
+			return nil, stageRecord, fmt.Errorf("stage %s: read stage %s: %w", stage.Name, dep, err)
+		}
+		if record.Skipped || record.Error != "" {
+			continue
+		}
+		subjects = append(subjects, approvalSubject(dep, record))
+	}
+
+	decision, err := awaitApproval(ctx, env, stage.Name, message, subjects)
+	stageRecord.Approval = decision
+	stageRecord.DurationMillis = time.Since(start).Milliseconds()
+	if err != nil {
+		return nil, stageRecord, err
+	}
+
+	output := decision.Comment
+	if output == "" {
+		output = decision.Decision
+	}
+	outputRef, outputSha, err := env.writer.WriteBlob("output", []byte(output))
+	if err != nil {
+		return nil, stageRecord, fmt.Errorf("write output blob for stage %s: %w", stage.Name, err)
+	}
+	art := artifact.New(output, "", "", message)
+	stageRecord.Output = truncateForEvidence(output, 4096)
+	stageRecord.OutputRef = outputRef
+	stageRecord.OutputHash = outputSha
+	stageRecord.OutputLen = len(output)
+	stageRecord.Artifacts = map[string]string{
+		"text": output,
+		"hash": art.Hash,
+	}
+	return &StageResult{
+		Name:     stage.Name,
+		Artifact: art,
+		Duration: time.Since(start),
+	}, stageRecord, nil
+}
+
+// approvalSubject describes a stage output from its record.
+func approvalSubject(stageName string, record *evidence.StageRecord) evidence.ApprovalSubject {
+	subject := evidence.ApprovalSubject{
+		Stage:       stageName,
+		Output:      record.Output,
+		OutputRef:   record.OutputRef,
+		OutputHash:  record.OutputHash,
+		GateResults: record.GateResults,
+	}
+	if record.ApplyResult != nil {
+		subject.AppliedFiles = record.ApplyResult.AppliedFiles
+		subject.DeletedFiles = record.ApplyResult.DeletedFiles
+	}
+	return subject
+}
+
+// awaitApproval publishes an approval request in the run directory and
+// waits for a decision on it, from env.approve or from a decision file
+// written by flowgate approve. A rejection is returned with an error.
+func awaitApproval(ctx context.Context, env *stageEnv, stageName, message string, subjects []evidence.ApprovalSubject) (*evidence.ApprovalRecord, error) {
+	fingerprint, err := json.Marshal(map[string]any{"stage": stageName, "message": message, "subjects": subjects})
+	if err != nil {
+		return nil, err
+	}
+	req := evidence.ApprovalRequest{
+		RunDir:      env.writer.RunDir(),
+		Stage:       stageName,
+		Message:     message,
+		Subjects:    subjects,
+		RequestedAt: time.Now().UTC(),
+		Hash:        hashString(string(fingerprint)),
+	}
+	if err := env.writer.WriteApprovalRequest(req); err != nil {
+		return nil, fmt.Errorf("stage %s: write approval request: %w", stageName, err)
+	}
+	// The decision is kept in the stage record; a stage that runs again
+	// asks again.
+	defer env.writer.RemoveApproval(stageName)
+
+	decision, err := waitForDecision(ctx, env, req)
+	if err != nil {
+		return nil, fmt.Errorf("stage %s awaiting approval: %w", stageName, err)
+	}
+	decision.RequestHash = req.Hash
+	if decision.Timestamp.IsZero() {
+		decision.Timestamp = time.Now().UTC()
+	}
+	switch decision.Decision {
+	case evidence.ApprovalApproved:
+		return decision, nil
+	case evidence.ApprovalRejected:
+		if decision.Comment != "" {
+			return decision, fmt.Errorf("stage %s rejected by %s: %s", stageName, decision.Approver, decision.Comment)
+		}
+		return decision, fmt.Errorf("stage %s rejected by %s", stageName, decision.Approver)
+	default:
+		return decision, fmt.Errorf("stage %s: unknown approval decision %q", stageName, decision.Decision)
+	}
+}
+
+func waitForDecision(ctx context.Context, env *stageEnv, req evidence.ApprovalRequest) (*evidence.ApprovalRecord, error) {
+	if env.approve != nil {
+		decision, err := env.approve(ctx, req)
+		if err != nil || decision != nil {
+			return decision, err
+		}
+	}
+	ticker := time.NewTicker(approvalPollInterval)
+	defer ticker.Stop()
+	for {
+		decision, err := evidence.ReadApproval(req.RunDir, req.Stage)
+		if err != nil {
+			return nil, err
+		}
+		// A decision on an earlier request for the stage does not count.
+		if decision != nil && decision.RequestHash == req.Hash {
+			return decision, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
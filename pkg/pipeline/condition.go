@@ -0,0 +1,169 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/zen-systems/flowgate/pkg/evidence"
+	"github.com/zen-systems/flowgate/pkg/router"
+)
+
+// Stage status values exposed to `when` expressions via .Status.
+const (
+	stageStatusPending   = "pending"
+	stageStatusSucceeded = "succeeded"
+	stageStatusFailed    = "failed"
+	stageStatusSkipped   = "skipped"
+)
+
+// GateTemplateData exposes a gate outcome to `when` expressions.
+type GateTemplateData struct {
+	Passed     bool
+	Score      int
+	Violations []string
+}
+
+var conditionFuncs = template.FuncMap{
+	"contains":  strings.Contains,
+	"hasPrefix": strings.HasPrefix,
+	"hasSuffix": strings.HasSuffix,
+	"lower":     strings.ToLower,
+}
+
+// parseCondition compiles a `when` expression. Bare expressions such as
+// `eq .Status.build "failed"` are wrapped in an action.
+func parseCondition(expr string) (*template.Template, error) {
+	if !strings.Contains(expr, "{{") {
+		expr = "{{ " + expr + " }}"
+	}
+	return template.New("when").Funcs(conditionFuncs).Option("missingkey=zero").Parse(expr)
+}
+
+// evaluateCondition renders a `when` expression and interprets the result.
+// Empty output, "false", "0" and "no" are false; anything else is true.
+func evaluateCondition(expr string, data map[string]any) (bool, error) {
+	tmpl, err := parseCondition(expr)
+	if err != nil {
+		return false, err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(sb.String())) {
+	case "", "false", "0", "no", "<no value>":
+		return false, nil
+	}
+	return true, nil
+}
+
+// failureHandlers maps each on_failure target to the stages that route to it.
+func failureHandlers(stages []*Stage) map[string][]string {
+	handlers := make(map[string][]string)
+	for _, stage := range stages {
+		if stage.OnFailure != "" {
+			handlers[stage.OnFailure] = append(handlers[stage.OnFailure], stage.Name)
+		}
+	}
+	for _, sources := range handlers {
+		sort.Strings(sources)
+	}
+	return handlers
+}
+
+// conditionData builds the template data available to `when` expressions.
+func (s *runState) conditionData(stages []*Stage) map[string]any {
+	status := make(map[string]string, len(stages))
+	for _, stage := range stages {
+		status[stage.Name] = stageStatusPending
+	}
+	for name, value := range s.status {
+		status[name] = value
+	}
+
+	gates := make(map[string]map[string]GateTemplateData, len(s.records))
+	for name, record := range s.records {
+		gateRecords := record.GateResults
+		if len(record.Attempts) > 0 {
+			gateRecords = record.Attempts[len(record.Attempts)-1].GateResults
+		}
+		gates[name] = gateTemplateData(gateRecords)
+	}
+
+	routing := router.Decision{}
+	if s.record.RoutingDecision != nil {
+		routing = *s.record.RoutingDecision
+	}
+
+	return map[string]any{
+		"Input":     s.input,
+		"input":     s.input,
+		"Artifacts": s.artifacts,
+		"artifacts": s.artifacts,
+		"Stages":    s.stagesLegacy,
+		"stages":    s.stagesLegacy,
+		"Status":    status,
+		"Errors":    s.errors,
+		"Gates":     gates,
+		"Routing":   routing,
+	}
+}
+
+func gateTemplateData(records []evidence.GateRecord) map[string]GateTemplateData {
+	out := make(map[string]GateTemplateData, len(records))
+	for _, record := range records {
+		data := GateTemplateData{Passed: record.Passed && record.Error == "", Score: record.Score}
+		for _, v := range record.Violations {
+			data.Violations = append(data.Violations, v.Message)
+		}
+		out[record.Name] = data
+		// Group members are addressable by their own names.
+		for name, member := range gateTemplateData(record.Members) {
+			if _, ok := out[name]; !ok {
+				out[name] = member
+			}
+		}
+	}
+	return out
+}
+
+// skipReason decides whether a ready stage should be skipped. An on_failure
+// target runs only when one of its sources failed. Otherwise a `when`
+// expression decides; without one, the stage is skipped if any dependency
+// did not succeed.
+func (s *runState) skipReason(stage *Stage, stages []*Stage, deps []string, handlers map[string][]string) (string, error) {
+	triggeredBy := make(map[string]bool)
+	if sources, ok := handlers[stage.Name]; ok {
+		for _, source := range sources {
+			if s.status[source] == stageStatusFailed {
+				triggeredBy[source] = true
+			}
+		}
+		if len(triggeredBy) == 0 {
+			return fmt.Sprintf("on_failure target not triggered by %s", strings.Join(sources, ", ")), nil
+		}
+	}
+
+	if stage.When != "" {
+		ok, err := evaluateCondition(stage.When, s.conditionData(stages))
+		if err != nil {
+			return "", fmt.Errorf("evaluate when for stage %s: %w", stage.Name, err)
+		}
+		if !ok {
+			return fmt.Sprintf("when condition is false: %s", stage.When), nil
+		}
+		return "", nil
+	}
+
+	for _, dep := range deps {
+		if triggeredBy[dep] {
+			continue
+		}
+		if status := s.status[dep]; status != stageStatusSucceeded {
+			return fmt.Sprintf("dependency %s %s", dep, status), nil
+		}
+	}
+	return "", nil
+}
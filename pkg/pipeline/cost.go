@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/zen-systems/flowgate/pkg/adapter"
 	"github.com/zen-systems/flowgate/pkg/config"
@@ -9,6 +10,7 @@ import (
 )
 
 type costTracker struct {
+	mu            sync.Mutex
 	pricing       config.PricingConfig
 	totalUsage    adapter.Usage
 	totalAmount   float64
@@ -35,6 +37,8 @@ func (t *costTracker) checkBudget(adapterName, model string) error {
 	if t == nil || t.maxBudgetUSD <= 0 {
 		return nil
 	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if t.budgetStatus == nil {
 		t.budgetStatus = &evidence.BudgetStatus{MaxAmount: t.maxBudgetUSD}
 	}
@@ -66,6 +70,8 @@ func (t *costTracker) recordReports(reports []adapter.CallReport) {
 	if t == nil {
 		return
 	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	for _, report := range reports {
 		t.calls = append(t.calls, report)
 		if report.Error != "" {
@@ -73,14 +79,32 @@ func (t *costTracker) recordReports(reports []adapter.CallReport) {
 		}
 		t.totalAmount += report.Cost.Amount
 		t.totalUsage = addUsage(t.totalUsage, report.Usage)
+		if report.Cached {
+			// Cache hits carry no usage and would understate projections.
+			continue
+		}
 		t.lastUsageHint = &report.Usage
 	}
 }
 
+// restore seeds the tracker with calls recorded by an earlier execution of the
+// same run, so budgets apply across resumes.
+func (t *costTracker) restore(report *evidence.RunCostReport) {
+	if t == nil || report == nil {
+		return
+	}
+	t.recordReports(report.Calls)
+	if report.Budget != nil && t.maxBudgetUSD <= 0 {
+		t.maxBudgetUSD = report.Budget.MaxAmount
+	}
+}
+
 func (t *costTracker) report() *evidence.RunCostReport {
 	if t == nil {
 		return nil
 	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if t.budgetStatus == nil && t.maxBudgetUSD > 0 {
 		t.budgetStatus = &evidence.BudgetStatus{MaxAmount: t.maxBudgetUSD}
 	}
@@ -88,7 +112,7 @@ func (t *costTracker) report() *evidence.RunCostReport {
 		Currency:    t.currency,
 		TotalAmount: t.totalAmount,
 		TotalUsage:  t.totalUsage,
-		Calls:       t.calls,
+		Calls:       append([]adapter.CallReport(nil), t.calls...),
 		Budget:      t.budgetStatus,
 	}
 }
@@ -181,9 +181,11 @@ func TestRetryWithTransientErrors(t *testing.T) {
 		map[string]adapter.Adapter{"transient": adapterImpl},
 		"transient",
 		"mock-1",
-		"prompt",
+		adapter.UserRequest("prompt"),
 		cfg,
 		newCostTracker(cfg, 0),
+		nil,
+		nil,
 	)
 	if err != nil {
 		t.Fatalf("expected success, got %v", err)
@@ -218,9 +220,11 @@ func TestFallbackAdapterUsedOnFailure(t *testing.T) {
 		},
 		"primary",
 		"mock-1",
-		"prompt",
+		adapter.UserRequest("prompt"),
 		cfg,
 		newCostTracker(cfg, 0),
+		nil,
+		nil,
 	)
 	if err != nil {
 		t.Fatalf("expected fallback success, got %v", err)
@@ -0,0 +1,354 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+const defaultMaxParallel = 4
+
+// artifactRoots are template fields keyed by stage name.
+var artifactRoots = map[string]struct{}{
+	"Artifacts": {},
+	"artifacts": {},
+	"Stages":    {},
+	"stages":    {},
+	"Status":    {},
+	"Errors":    {},
+	"Gates":     {},
+}
+
+// buildStageGraph returns the dependencies of every stage, combining explicit
+// depends_on entries and on_failure edges with references found in the
+// prompt, system, escalate_on prompt and when templates. Stages that apply
+// changes to the workspace are additionally ordered after the previous apply
+// stage so that writes never race.
+func buildStageGraph(p *Pipeline) (map[string][]string, error) {
+	index := make(map[string]int, len(p.Stages))
+	for i, stage := range p.Stages {
+		index[stage.Name] = i
+	}
+
+	for _, stage := range p.Stages {
+		if stage.OnFailure == "" {
+			continue
+		}
+		if stage.OnFailure == stage.Name {
+			return nil, fmt.Errorf("stage %s cannot be its own on_failure target", stage.Name)
+		}
+		if _, ok := index[stage.OnFailure]; !ok {
+			return nil, fmt.Errorf("stage %s has unknown on_failure target %s", stage.Name, stage.OnFailure)
+		}
+	}
+	handlers := failureHandlers(p.Stages)
+
+	graph := make(map[string][]string, len(p.Stages))
+	lastApply := ""
+	for i, stage := range p.Stages {
+		deps := make(map[string]struct{})
+		for _, dep := range stage.DependsOn {
+			if dep == stage.Name {
+				return nil, fmt.Errorf("stage %s depends on itself", stage.Name)
+			}
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Errorf("stage %s depends on unknown stage %s", stage.Name, dep)
+			}
+			deps[dep] = struct{}{}
+		}
+		if stage.ForEach != nil {
+			source := stage.ForEach.Stage
+			if source == stage.Name {
+				return nil, fmt.Errorf("stage %s cannot iterate over its own output", stage.Name)
+			}
+			if _, ok := index[source]; !ok {
+				return nil, fmt.Errorf("stage %s has unknown for_each stage %s", stage.Name, source)
+			}
+			deps[source] = struct{}{}
+		}
+		// An on_failure target waits for the stages that can trigger it.
+		for _, source := range handlers[stage.Name] {
+			deps[source] = struct{}{}
+		}
+
+		refs, all, err := templateStageRefs(stage.Prompt)
+		if err != nil {
+			return nil, fmt.Errorf("parse prompt for stage %s: %w", stage.Name, err)
+		}
+		if stage.System != "" {
+			systemRefs, systemAll, err := templateStageRefs(stage.System)
+			if err != nil {
+				return nil, fmt.Errorf("parse system prompt for stage %s: %w", stage.Name, err)
+			}
+			refs = append(refs, systemRefs...)
+			all = all || systemAll
+		}
+		for _, policy := range stage.EscalateOn {
+			if policy.Prompt == "" {
+				continue
+			}
+			policyRefs, policyAll, err := templateStageRefs(policy.Prompt)
+			if err != nil {
+				return nil, fmt.Errorf("parse escalate_on prompt for stage %s: %w", stage.Name, err)
+			}
+			refs = append(refs, policyRefs...)
+			all = all || policyAll
+		}
+		if stage.When != "" {
+			tmpl, err := parseCondition(stage.When)
+			if err != nil {
+				return nil, fmt.Errorf("parse when for stage %s: %w", stage.Name, err)
+			}
+			whenRefs, whenAll := treeStageRefs(tmpl.Tree)
+			refs = append(refs, whenRefs...)
+			all = all || whenAll
+		}
+		if all {
+			for _, prior := range p.Stages[:i] {
+				deps[prior.Name] = struct{}{}
+			}
+		}
+		for _, ref := range refs {
+			if ref == stage.Name {
+				continue
+			}
+			if _, ok := index[ref]; ok {
+				deps[ref] = struct{}{}
+			}
+		}
+
+		if stage.Apply {
+			if lastApply != "" {
+				deps[lastApply] = struct{}{}
+			}
+			lastApply = stage.Name
+		}
+
+		list := make([]string, 0, len(deps))
+		for dep := range deps {
+			list = append(list, dep)
+		}
+		sort.Strings(list)
+		graph[stage.Name] = list
+	}
+
+	if cycle := findCycle(p.Stages, graph); len(cycle) > 0 {
+		return nil, fmt.Errorf("stage dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+	return graph, nil
+}
+
+// templateStageRefs lists stage names referenced through .Artifacts/.Stages in
+// a prompt template. all is true when the template uses the whole map (for
+// example via range), in which case every earlier stage is a dependency.
+func templateStageRefs(prompt string) (refs []string, all bool, err error) {
+	tmpl, err := template.New("deps").Parse(prompt)
+	if err != nil {
+		return nil, false, err
+	}
+	refs, all = treeStageRefs(tmpl.Tree)
+	return refs, all, nil
+}
+
+func treeStageRefs(tree *parse.Tree) (refs []string, all bool) {
+	if tree == nil {
+		return nil, false
+	}
+
+	seen := make(map[string]struct{})
+	addField := func(ident []string) {
+		if len(ident) > 0 && ident[0] == "$" {
+			ident = ident[1:]
+		}
+		if len(ident) == 0 {
+			return
+		}
+		if _, ok := artifactRoots[ident[0]]; !ok {
+			return
+		}
+		if len(ident) == 1 {
+			all = true
+			return
+		}
+		seen[ident[1]] = struct{}{}
+	}
+
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case nil:
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			if name, ok := indexedStage(n); ok {
+				seen[name] = struct{}{}
+				return
+			}
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			addField(n.Ident)
+		case *parse.VariableNode:
+			addField(n.Ident)
+		case *parse.ChainNode:
+			walk(n.Node)
+		}
+	}
+	walk(tree.Root)
+
+	for name := range seen {
+		refs = append(refs, name)
+	}
+	sort.Strings(refs)
+	return refs, all
+}
+
+// indexedStage recognizes `index .Artifacts "stage-name"` lookups, which are
+// required for stage names that are not valid template identifiers.
+func indexedStage(cmd *parse.CommandNode) (string, bool) {
+	if len(cmd.Args) < 3 {
+		return "", false
+	}
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	if !ok || ident.Ident != "index" {
+		return "", false
+	}
+	var fields []string
+	switch target := cmd.Args[1].(type) {
+	case *parse.FieldNode:
+		fields = target.Ident
+	case *parse.VariableNode:
+		fields = target.Ident
+		if len(fields) > 0 && fields[0] == "$" {
+			fields = fields[1:]
+		}
+	default:
+		return "", false
+	}
+	if len(fields) != 1 {
+		return "", false
+	}
+	if _, ok := artifactRoots[fields[0]]; !ok {
+		return "", false
+	}
+	key, ok := cmd.Args[2].(*parse.StringNode)
+	if !ok {
+		return "", false
+	}
+	return key.Text, true
+}
+
+func findCycle(stages []*Stage, graph map[string][]string) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(stages))
+	var path []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range graph[name] {
+			switch state[dep] {
+			case visiting:
+				start := 0
+				for i, entry := range path {
+					if entry == dep {
+						start = i
+						break
+					}
+				}
+				cycle := append([]string{}, path[start:]...)
+				return append(cycle, dep)
+			case unvisited:
+				if cycle := visit(dep); len(cycle) > 0 {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, stage := range stages {
+		if state[stage.Name] == unvisited {
+			if cycle := visit(stage.Name); len(cycle) > 0 {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// readyStages returns pending stages whose dependencies have all completed, in
+// manifest order.
+func readyStages(stages []*Stage, graph map[string][]string, started, completed map[string]bool) []*Stage {
+	var ready []*Stage
+	for _, stage := range stages {
+		if started[stage.Name] {
+			continue
+		}
+		ok := true
+		for _, dep := range graph[stage.Name] {
+			if !completed[dep] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			ready = append(ready, stage)
+		}
+	}
+	return ready
+}
+
+func copyArtifacts(artifacts map[string]ArtifactTemplateData) map[string]ArtifactTemplateData {
+	out := make(map[string]ArtifactTemplateData, len(artifacts))
+	for k, v := range artifacts {
+		out[k] = v
+	}
+	return out
+}
+
+func copyLegacyStages(stages map[string]map[string]string) map[string]map[string]string {
+	out := make(map[string]map[string]string, len(stages))
+	for k, v := range stages {
+		out[k] = v
+	}
+	return out
+}
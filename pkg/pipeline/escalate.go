@@ -0,0 +1,308 @@
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+	"github.com/zen-systems/flowgate/pkg/gate"
+	"github.com/zen-systems/flowgate/pkg/repair"
+	"gopkg.in/yaml.v3"
+)
+
+// Escalation actions.
+const (
+	escalateActionEscalate = "escalate"
+	escalateActionSwitch   = "switch"
+	escalateActionPrompt   = "prompt"
+	escalateActionAbort    = "abort"
+)
+
+// Escalation triggers.
+const (
+	escalateTriggerRepeatFingerprint = "repeat_fingerprint"
+	escalateTriggerScoreNotImproving = "score_not_improving"
+	escalateTriggerAttempt           = "attempt"
+	escalateTriggerRule              = "rule"
+	escalateTriggerCost              = "cost"
+	// escalateTriggerRepeatOutput is the built-in loop handling, which acts
+	// when an attempt repeats both the output and the violations.
+	escalateTriggerRepeatOutput = "repeat_output"
+)
+
+// EscalationPolicy changes how a stage repairs once its trigger fires.
+type EscalationPolicy struct {
+	// When is the trigger: repeat_fingerprint, score_not_improving,
+	// attempt>=N, rule:<name> or cost>=X (USD spent on the stage).
+	When string `yaml:"when"`
+	// Action is escalate (default), switch, prompt or abort.
+	Action string `yaml:"action,omitempty"`
+	// Adapter and Model are what switch moves the stage to.
+	Adapter string `yaml:"adapter,omitempty"`
+	Model   string `yaml:"model,omitempty"`
+	// Prompt is the template that prompt restarts the conversation with.
+	Prompt string `yaml:"prompt,omitempty"`
+}
+
+// UnmarshalYAML accepts a trigger or a mapping.
+func (p *EscalationPolicy) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*p = EscalationPolicy{When: node.Value}
+		return nil
+	}
+	type plain EscalationPolicy
+	return node.Decode((*plain)(p))
+}
+
+// EscalationPolicies are a stage's escalate_on policies, in the order they
+// are evaluated.
+type EscalationPolicies []EscalationPolicy
+
+// UnmarshalYAML accepts a single trigger or a list of policies.
+func (p *EscalationPolicies) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*p = EscalationPolicies{{When: node.Value}}
+		return nil
+	}
+	var policies []EscalationPolicy
+	if err := node.Decode(&policies); err != nil {
+		return err
+	}
+	*p = policies
+	return nil
+}
+
+func (p EscalationPolicy) action() string {
+	if p.Action == "" {
+		return escalateActionEscalate
+	}
+	return p.Action
+}
+
+// escalationTrigger is a parsed escalate_on trigger.
+type escalationTrigger struct {
+	kind    string
+	attempt int
+	rule    string
+	cost    float64
+}
+
+func parseEscalationTrigger(when string) (escalationTrigger, error) {
+	when = strings.Join(strings.Fields(when), "")
+	switch {
+	case when == escalateTriggerRepeatFingerprint, when == escalateTriggerScoreNotImproving:
+		return escalationTrigger{kind: when}, nil
+	case strings.HasPrefix(when, escalateTriggerRule+":"):
+		rule := strings.TrimPrefix(when, escalateTriggerRule+":")
+		if rule == "" {
+			return escalationTrigger{}, fmt.Errorf("rule trigger requires a rule name")
+		}
+		return escalationTrigger{kind: escalateTriggerRule, rule: rule}, nil
+	case strings.HasPrefix(when, escalateTriggerAttempt+">="):
+		n, err := strconv.Atoi(strings.TrimPrefix(when, escalateTriggerAttempt+">="))
+		if err != nil || n < 1 {
+			return escalationTrigger{}, fmt.Errorf("attempt trigger requires a positive attempt number")
+		}
+		return escalationTrigger{kind: escalateTriggerAttempt, attempt: n}, nil
+	case strings.HasPrefix(when, escalateTriggerCost+">="):
+		x, err := strconv.ParseFloat(strings.TrimPrefix(when, escalateTriggerCost+">="), 64)
+		if err != nil || x <= 0 {
+			return escalationTrigger{}, fmt.Errorf("cost trigger requires a positive amount")
+		}
+		return escalationTrigger{kind: escalateTriggerCost, cost: x}, nil
+	default:
+		return escalationTrigger{}, fmt.Errorf("unknown trigger %q", when)
+	}
+}
+
+func validateEscalationPolicy(policy EscalationPolicy) error {
+	if _, err := parseEscalationTrigger(policy.When); err != nil {
+		return err
+	}
+	switch policy.action() {
+	case escalateActionEscalate, escalateActionAbort:
+	case escalateActionSwitch:
+		if policy.Adapter == "" && policy.Model == "" {
+			return fmt.Errorf("switch requires an adapter or model")
+		}
+	case escalateActionPrompt:
+		if policy.Prompt == "" {
+			return fmt.Errorf("prompt action requires a prompt")
+		}
+	default:
+		return fmt.Errorf("action must be escalate, switch, prompt or abort")
+	}
+	return nil
+}
+
+// escalationCheck is what triggers are evaluated against after a failed
+// attempt. state already holds the attempt.
+type escalationCheck struct {
+	attempt int
+	state   *RepairState
+	failure *gate.GateResult
+	spent   float64
+}
+
+// fires reports whether the trigger fires, and why.
+func (t escalationTrigger) fires(check escalationCheck) (bool, string) {
+	attempts := check.state.Attempts
+	current := attempts[len(attempts)-1]
+	switch t.kind {
+	case escalateTriggerRepeatFingerprint:
+		if len(attempts) >= 2 && current.ViolationFingerprint == attempts[len(attempts)-2].ViolationFingerprint {
+			return true, fmt.Sprintf("attempt %d failed like attempt %d", check.attempt, check.attempt-1)
+		}
+	case escalateTriggerScoreNotImproving:
+		// Lower gate scores are better.
+		if len(attempts) >= 2 && current.Score >= attempts[len(attempts)-2].Score {
+			return true, fmt.Sprintf("gate score %.1f did not drop below %.1f", current.Score, attempts[len(attempts)-2].Score)
+		}
+	case escalateTriggerAttempt:
+		if check.attempt >= t.attempt {
+			return true, fmt.Sprintf("attempt %d failed", check.attempt)
+		}
+	case escalateTriggerRule:
+		for _, v := range check.failure.Violations {
+			if v.Rule == t.rule {
+				return true, fmt.Sprintf("rule %s violated", t.rule)
+			}
+		}
+	case escalateTriggerCost:
+		if check.spent >= t.cost {
+			return true, fmt.Sprintf("stage cost $%.4f reached $%.4f", check.spent, t.cost)
+		}
+	}
+	return false, ""
+}
+
+// escalation carries what a stage's repair loop can change when it
+// escalates: the target of the next attempt and its request.
+type escalation struct {
+	env    *stageEnv
+	stage  *Stage
+	data   map[string]any
+	target *callTarget
+	// base is the stage's first request, which prompt restarts from.
+	base adapter.Request
+	req  adapter.Request
+	// replaced is set when an action already wrote the next request, so the
+	// usual repair feedback is not appended.
+	replaced bool
+}
+
+// applyPolicies evaluates the stage's escalate_on policies after a failed
+// attempt that has another attempt after it. Each policy fires at most once.
+// It returns an event for every policy that fired and an error when one
+// aborts the stage.
+func (e *escalation) applyPolicies(check escalationCheck, art *artifact.Artifact) ([]evidence.EscalationRecord, error) {
+	if check.state.fired == nil {
+		check.state.fired = make(map[int]bool)
+	}
+	var events []evidence.EscalationRecord
+	for i, policy := range e.stage.EscalateOn {
+		if check.state.fired[i] {
+			continue
+		}
+		trigger, err := parseEscalationTrigger(policy.When)
+		if err != nil {
+			return events, fmt.Errorf("stage %s: escalate_on: %w", e.stage.Name, err)
+		}
+		ok, reason := trigger.fires(check)
+		if !ok {
+			continue
+		}
+		check.state.fired[i] = true
+		event := evidence.EscalationRecord{
+			Attempt: check.attempt,
+			Trigger: policy.When,
+			Reason:  reason,
+			Action:  policy.action(),
+		}
+		switch policy.action() {
+		case escalateActionAbort:
+			events = append(events, event)
+			return events, fmt.Errorf("stage %s aborted by escalate_on %s: %s", e.stage.Name, policy.When, reason)
+		case escalateActionEscalate:
+			e.escalate(check.failure, art)
+			check.state.Escalated = true
+		case escalateActionSwitch:
+			if err := e.switchTarget(policy); err != nil {
+				events = append(events, event)
+				return events, err
+			}
+		case escalateActionPrompt:
+			prompt, err := e.restart(policy, check, art)
+			if err != nil {
+				events = append(events, event)
+				return events, err
+			}
+			event.Prompt = truncateForEvidence(prompt, 4096)
+		}
+		event.Adapter = e.target.Adapter
+		event.Model = e.target.Model
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// escalate is the built-in escalation: the fallback model, if the stage has
+// one, and feedback that insists on a different approach.
+func (e *escalation) escalate(failure *gate.GateResult, art *artifact.Artifact) {
+	if e.stage.FallbackModel != "" {
+		e.target.Model = e.stage.FallbackModel
+	}
+	e.req = e.req.Append(
+		adapter.Message{Role: adapter.RoleAssistant, Content: art.Content},
+		adapter.Message{Role: adapter.RoleUser, Content: repair.GenerateEscalationFeedback(failure, e.stage.Apply)},
+	)
+	e.replaced = true
+}
+
+// switchTarget moves the stage to the policy's adapter and model. A new
+// adapter without a model uses the adapter's first model.
+func (e *escalation) switchTarget(policy EscalationPolicy) error {
+	next := *e.target
+	if policy.Adapter != "" {
+		next = callTarget{Adapter: policy.Adapter}
+	}
+	impl, ok := e.env.adapters[next.Adapter]
+	if !ok {
+		return fmt.Errorf("stage %s: escalate_on switch: adapter %s not found", e.stage.Name, next.Adapter)
+	}
+	if policy.Model != "" {
+		next.Model = e.env.aliases.Resolve(policy.Model)
+	}
+	if next.Model == "" {
+		if models := impl.Models(); len(models) > 0 {
+			next.Model = models[0]
+		}
+	}
+	*e.target = next
+	return nil
+}
+
+// restart replaces the conversation with the policy's prompt. The template
+// sees the stage's prompt data plus the failed .Output, the gate .Feedback
+// and the .Attempt that failed.
+func (e *escalation) restart(policy EscalationPolicy, check escalationCheck, art *artifact.Artifact) (string, error) {
+	data := make(map[string]any, len(e.data)+3)
+	for k, v := range e.data {
+		data[k] = v
+	}
+	data["Output"] = art.Content
+	data["Feedback"] = repair.GenerateRepairFeedback(check.failure)
+	data["Attempt"] = check.attempt
+	prompt, err := renderTemplate(policy.Prompt, data)
+	if err != nil {
+		return "", fmt.Errorf("render escalate_on prompt for stage %s: %w", e.stage.Name, err)
+	}
+	next := e.base.Append()
+	next.Messages = adapter.UserRequest(prompt).Messages
+	e.req = next
+	e.replaced = true
+	return prompt, nil
+}
@@ -0,0 +1,284 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+	"github.com/zen-systems/flowgate/pkg/workspace"
+)
+
+// ForEach runs a stage once per item taken from an earlier stage's output.
+type ForEach struct {
+	// Stage is the stage whose output holds the items.
+	Stage string `yaml:"stage"`
+	// Path selects a JSON array in the output, e.g. "plan.files"; "." is the
+	// whole output. Without a path, each non-empty line is an item.
+	Path string `yaml:"path,omitempty"`
+	// Parallel bounds how many items run at once (default 4).
+	Parallel int `yaml:"parallel,omitempty"`
+	// AllowFailures lets the stage succeed when some items fail.
+	AllowFailures bool `yaml:"allow_failures,omitempty"`
+}
+
+// stageItem is the item a for_each stage instance runs with.
+type stageItem struct {
+	Index int
+	Value any
+}
+
+// forEachResult is one entry of a for_each stage's aggregated output.
+type forEachResult struct {
+	Item   any    `json:"item"`
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// itemStageName names the record of one item of a for_each stage.
+func itemStageName(stageName string, index int) string {
+	return fmt.Sprintf("%s.%d", stageName, index)
+}
+
+// forEachItems extracts the items a for_each stage iterates over.
+func forEachItems(spec *ForEach, artifacts map[string]ArtifactTemplateData) ([]any, error) {
+	source, ok := artifacts[spec.Stage]
+	if !ok {
+		return nil, fmt.Errorf("stage %s has no output", spec.Stage)
+	}
+	if spec.Path == "" {
+		var items []any
+		for _, line := range strings.Split(source.Text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				items = append(items, line)
+			}
+		}
+		return items, nil
+	}
+
+	value := parseStructuredOutput(source.Text)
+	if value == nil {
+		return nil, fmt.Errorf("output of stage %s is not JSON", spec.Stage)
+	}
+	if spec.Path != "." {
+		for _, key := range strings.Split(spec.Path, ".") {
+			switch node := value.(type) {
+			case map[string]any:
+				value, ok = node[key]
+			case []any:
+				i, err := strconv.Atoi(key)
+				ok = err == nil && i >= 0 && i < len(node)
+				if ok {
+					value = node[i]
+				}
+			default:
+				ok = false
+			}
+			if !ok {
+				return nil, fmt.Errorf("path %s not found in output of stage %s", spec.Path, spec.Stage)
+			}
+		}
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("path %s in output of stage %s is not an array", spec.Path, spec.Stage)
+	}
+	return items, nil
+}
+
+// runForEach runs a for_each stage: each item runs as its own stage instance
+// with its own attempts and gates, recorded as <stage>.<index>. The results
+// are aggregated into a single result whose output is a JSON array with the
+// output or error of every item, and whose gate results are the items' gates
+// named <stage>.<index>/<gate>.
+func runForEach(
+	ctx context.Context,
+	env *stageEnv,
+	stage *Stage,
+	artifacts map[string]ArtifactTemplateData,
+	stagesLegacy map[string]map[string]string,
+) (*StageResult, *evidence.StageRecord, error) {
+	start := time.Now()
+	stageRecord := &evidence.StageRecord{}
+	items, err := forEachItems(stage.ForEach, artifacts)
+	if err != nil {
+		return nil, stageRecord, fmt.Errorf("stage %s for_each: %w", stage.Name, err)
+	}
+
+	parallel := stage.ForEach.Parallel
+	if parallel <= 0 {
+		parallel = defaultMaxParallel
+	}
+	if stage.Apply && env.applyForReal {
+		// Items write to the real workspace one at a time.
+		parallel = 1
+	}
+
+	type itemOutcome struct {
+		result *StageResult
+		record *evidence.StageRecord
+		err    error
+	}
+	outcomes := make([]itemOutcome, len(items))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func(i int, item any) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			itemStage := *stage
+			itemStage.Name = itemStageName(stage.Name, i)
+			itemStage.ForEach = nil
+			itemStage.item = &stageItem{Index: i, Value: item}
+			result, record, err := runStage(ctx, env, &itemStage, artifacts, stagesLegacy)
+			outcomes[i] = itemOutcome{result: result, record: record, err: err}
+		}(i, item)
+	}
+	wg.Wait()
+
+	writer := env.writer
+	definitionHash := stageDefinitionHash(env.pipeline, stage)
+	entries := make([]forEachResult, 0, len(items))
+	var failures []string
+	var gitFiles []workspace.GitFile
+	var conflicts []string
+	aggregate := &StageResult{Name: stage.Name}
+	for i, outcome := range outcomes {
+		name := itemStageName(stage.Name, i)
+		itemJSON, _ := json.Marshal(items[i])
+		itemRecord := evidence.ItemRecord{Index: i, Item: string(itemJSON), Stage: name}
+		entry := forEachResult{Item: items[i]}
+
+		if outcome.record != nil {
+			outcome.record.Name = name
+			outcome.record.DefinitionHash = definitionHash
+			if outcome.err != nil {
+				outcome.record.Error = outcome.err.Error()
+			}
+			if err := writer.WriteStage(*outcome.record); err != nil {
+				return nil, stageRecord, err
+			}
+			if stageRecord.Adapter == "" {
+				stageRecord.Adapter = outcome.record.Adapter
+				stageRecord.Model = outcome.record.Model
+			}
+			// A failed item has no stage gate results; its last attempt
+			// shows why it failed.
+			gates := outcome.record.GateResults
+			if outcome.err != nil && len(outcome.record.Attempts) > 0 {
+				gates = outcome.record.Attempts[len(outcome.record.Attempts)-1].GateResults
+			}
+			for _, g := range gates {
+				g.Name = name + "/" + g.Name
+				stageRecord.GateResults = append(stageRecord.GateResults, g)
+			}
+		}
+		if outcome.err != nil {
+			itemRecord.Error = outcome.err.Error()
+			entry.Error = outcome.err.Error()
+			failures = append(failures, fmt.Sprintf("%s: %v", name, outcome.err))
+		} else {
+			if err := writeGateLogs(writer, name, outcome.result.GateResults); err != nil {
+				return nil, stageRecord, err
+			}
+			itemRecord.Succeeded = true
+			entry.Output = outcome.result.Artifact.Content
+			for _, g := range outcome.result.GateResults {
+				g.Name = name + "/" + g.Name
+				aggregate.GateResults = append(aggregate.GateResults, g)
+			}
+			aggregate.ApplyResult = mergeApplyResults(aggregate.ApplyResult, outcome.result.ApplyResult)
+			var overlaps []string
+			gitFiles, overlaps = mergeGitFiles(gitFiles, outcome.result.gitFiles, env.applyForReal)
+			for _, path := range overlaps {
+				conflicts = append(conflicts, fmt.Sprintf("%s changes %s, which an earlier item also changed", name, path))
+			}
+		}
+		stageRecord.Items = append(stageRecord.Items, itemRecord)
+		entries = append(entries, entry)
+	}
+
+	if len(conflicts) > 0 {
+		stageRecord.DurationMillis = time.Since(start).Milliseconds()
+		return nil, stageRecord, fmt.Errorf("stage %s: items cannot be committed together: %s", stage.Name, strings.Join(conflicts, "; "))
+	}
+	if len(failures) > 0 && !stage.ForEach.AllowFailures {
+		stageRecord.DurationMillis = time.Since(start).Milliseconds()
+		return nil, stageRecord, fmt.Errorf("stage %s: %d of %d items failed: %s", stage.Name, len(failures), len(items), strings.Join(failures, "; "))
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return nil, stageRecord, err
+	}
+	output := string(data)
+	outputRef, outputSha, err := writer.WriteBlob("output", data)
+	if err != nil {
+		return nil, stageRecord, fmt.Errorf("write output blob for stage %s: %w", stage.Name, err)
+	}
+	art := artifact.New(output, stageRecord.Adapter, stageRecord.Model, "")
+	stageRecord.Output = truncateForEvidence(output, 4096)
+	stageRecord.OutputRef = outputRef
+	stageRecord.OutputHash = outputSha
+	stageRecord.OutputLen = len(output)
+	stageRecord.Artifacts = map[string]string{
+		"text": output,
+		"hash": art.Hash,
+	}
+	stageRecord.DurationMillis = time.Since(start).Milliseconds()
+
+	aggregate.Artifact = art
+	aggregate.Duration = time.Since(start)
+	aggregate.gitFiles = gitFiles
+	return aggregate, stageRecord, nil
+}
+
+// mergeGitFiles adds an item's stored files to those of the earlier items and
+// returns the paths both changed. Items applied to the real workspace run one
+// at a time, so a later item's file already holds the earlier changes and
+// replaces it. Otherwise every item applied to its own copy of the run branch
+// and one would silently overwrite the other in the commit.
+func mergeGitFiles(into, from []workspace.GitFile, stacked bool) ([]workspace.GitFile, []string) {
+	index := make(map[string]int, len(into))
+	for i, f := range into {
+		index[f.Path] = i
+	}
+	var overlaps []string
+	for _, f := range from {
+		i, ok := index[f.Path]
+		switch {
+		case !ok:
+			index[f.Path] = len(into)
+			into = append(into, f)
+		case stacked:
+			into[i] = f
+		default:
+			overlaps = append(overlaps, f.Path)
+		}
+	}
+	return into, overlaps
+}
+
+// mergeApplyResults combines the apply results of for_each items.
+func mergeApplyResults(into, from *workspace.ApplyResult) *workspace.ApplyResult {
+	if from == nil {
+		return into
+	}
+	if into == nil {
+		into = &workspace.ApplyResult{}
+	}
+	into.AppliedFiles = append(into.AppliedFiles, from.AppliedFiles...)
+	into.DeletedFiles = append(into.DeletedFiles, from.DeletedFiles...)
+	into.UsedUnifiedDiff = into.UsedUnifiedDiff || from.UsedUnifiedDiff
+	into.Adjustments = append(into.Adjustments, from.Adjustments...)
+	into.Changes = append(into.Changes, from.Changes...)
+	return into
+}
@@ -0,0 +1,371 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/gate"
+)
+
+// Gate severities. A failing warn gate is recorded but does not fail the
+// stage.
+const (
+	gateSeverityBlock = "block"
+	gateSeverityWarn  = "warn"
+)
+
+// Group modes.
+const (
+	gateModeAll    = "all"
+	gateModeAny    = "any"
+	gateModeQuorum = "quorum"
+)
+
+// gateGroupType is the gate type that combines other gates.
+const gateGroupType = "group"
+
+// gateMode is a parsed group mode: all members, any member, or at least n
+// members must pass.
+type gateMode struct {
+	kind   string
+	quorum int
+}
+
+func (m gateMode) String() string {
+	if m.kind == gateModeQuorum {
+		return fmt.Sprintf("quorum(%d)", m.quorum)
+	}
+	return m.kind
+}
+
+// parseGateMode parses `all`, `any` or `quorum(n)`. An empty mode means all.
+func parseGateMode(value string, members int) (gateMode, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "", gateModeAll:
+		return gateMode{kind: gateModeAll, quorum: members}, nil
+	case gateModeAny:
+		return gateMode{kind: gateModeAny, quorum: 1}, nil
+	}
+	if strings.HasPrefix(value, gateModeQuorum+"(") && strings.HasSuffix(value, ")") {
+		n, err := strconv.Atoi(strings.TrimSpace(value[len(gateModeQuorum)+1 : len(value)-1]))
+		if err != nil {
+			return gateMode{}, fmt.Errorf("invalid mode %q", value)
+		}
+		if n < 1 || n > members {
+			return gateMode{}, fmt.Errorf("mode %q needs between 1 and %d passing gates", value, members)
+		}
+		return gateMode{kind: gateModeQuorum, quorum: n}, nil
+	}
+	return gateMode{}, fmt.Errorf("invalid mode %q: expected all, any or quorum(n)", value)
+}
+
+// validateGateDefinition checks the composition fields of a gate.
+func (p *Pipeline) validateGateDefinition(name string, def GateDefinition) error {
+	switch def.Severity {
+	case "", gateSeverityBlock, gateSeverityWarn:
+	default:
+		return fmt.Errorf("gate %s: severity must be block or warn", name)
+	}
+	if def.Weight < 0 {
+		return fmt.Errorf("gate %s: weight must not be negative", name)
+	}
+	if def.MinScore != nil && def.MaxScore != nil && *def.MinScore > *def.MaxScore {
+		return fmt.Errorf("gate %s: min_score is greater than max_score", name)
+	}
+
+	if !strings.EqualFold(def.Type, gateGroupType) {
+		if def.Mode != "" || len(def.Gates) > 0 {
+			return fmt.Errorf("gate %s: mode and gates only apply to group gates", name)
+		}
+		return nil
+	}
+	if len(def.Gates) == 0 {
+		return fmt.Errorf("gate %s: group must list its gates", name)
+	}
+	if _, err := parseGateMode(def.Mode, len(def.Gates)); err != nil {
+		return fmt.Errorf("gate %s: %w", name, err)
+	}
+	for _, member := range def.Gates {
+		if _, ok := p.Gates[member]; !ok && member != "hollowcheck" && member != "stubcheck" {
+			return fmt.Errorf("gate %s references unknown gate %s", name, member)
+		}
+	}
+	return p.checkGateCycle(name, nil)
+}
+
+func (p *Pipeline) checkGateCycle(name string, path []string) error {
+	for _, seen := range path {
+		if seen == name {
+			return fmt.Errorf("gate group cycle: %s", strings.Join(append(path, name), " -> "))
+		}
+	}
+	def, ok := p.Gates[name]
+	if !ok || !strings.EqualFold(def.Type, gateGroupType) {
+		return nil
+	}
+	for _, member := range def.Gates {
+		if err := p.checkGateCycle(member, append(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gateEvaluator runs a stage's gates against one artifact.
+type gateEvaluator struct {
+	env           *stageEnv
+	stage         *Stage
+	art           *artifact.Artifact
+	workspacePath string
+}
+
+// blocks reports whether a gate outcome fails its stage.
+func (r GateResult) blocks() bool {
+	if r.Skipped || r.Severity == gateSeverityWarn {
+		return false
+	}
+	return !r.passed()
+}
+
+func (r GateResult) passed() bool {
+	return r.Error == nil && r.Result != nil && r.Result.Passed
+}
+
+// evaluateAll runs gates in order. Once a blocking gate fails the remaining
+// gates are skipped, so cheap gates listed first spare the expensive ones.
+// The returned error names the first blocking failure.
+func (e *gateEvaluator) evaluateAll(ctx context.Context, names []string, results []GateResult) ([]GateResult, error) {
+	var failure error
+	for _, name := range names {
+		if failure != nil {
+			results = append(results, e.skipped(name, failure.Error()))
+			continue
+		}
+		res, err := e.evaluate(ctx, name)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, res)
+		if res.blocks() {
+			if res.Error != nil {
+				failure = fmt.Errorf("gate %s error: %w", name, res.Error)
+			} else {
+				failure = fmt.Errorf("gate %s failed", name)
+			}
+		}
+	}
+	return results, failure
+}
+
+// evaluate runs one named gate or group. Errors are configuration problems;
+// gate failures and gate errors are reported in the result.
+func (e *gateEvaluator) evaluate(ctx context.Context, name string) (GateResult, error) {
+	def := e.env.pipeline.Gates[name]
+	// Validate has already checked the timeout.
+	limit, _ := parseTimeout(def.Timeout)
+	gateCtx, cancel := withTimeout(ctx, limit)
+	defer cancel()
+
+	if strings.EqualFold(def.Type, gateGroupType) {
+		return e.evaluateGroup(gateCtx, name, def)
+	}
+
+	instances, err := buildGateInstances(e.env, e.stage, []string{name}, e.workspacePath)
+	if err != nil {
+		return GateResult{}, err
+	}
+	start := time.Now()
+	res, err := instances[0].Evaluate(gateCtx, e.art)
+	if limit > 0 && timedOut(gateCtx, ctx) && !hasTimeoutViolation(res) {
+		res, err = gateTimeoutResult(res, limit), nil
+	}
+	if err == nil && !hasTimeoutViolation(res) {
+		applyScoreThreshold(res, def)
+	}
+	return GateResult{
+		Name:     instances[0].Name(),
+		Result:   res,
+		Error:    err,
+		Severity: gateSeverity(def),
+		Duration: time.Since(start),
+	}, nil
+}
+
+// evaluateGroup runs members in order and stops as soon as the outcome is
+// decided; members that no longer matter are recorded as skipped.
+func (e *gateEvaluator) evaluateGroup(ctx context.Context, name string, def GateDefinition) (GateResult, error) {
+	start := time.Now()
+	// Validate has already checked the mode.
+	mode, _ := parseGateMode(def.Mode, len(def.Gates))
+
+	var members []GateResult
+	var violations []gate.Violation
+	var hints []string
+	passed, evaluated := 0, 0
+	var weighted, totalWeight float64
+	reason := ""
+	for i, member := range def.Gates {
+		if reason != "" {
+			members = append(members, e.skipped(member, reason))
+			continue
+		}
+		res, err := e.evaluate(ctx, member)
+		if err != nil {
+			return GateResult{}, err
+		}
+		members = append(members, res)
+		evaluated++
+
+		weight := e.env.pipeline.Gates[member].Weight
+		if weight == 0 {
+			weight = 1
+		}
+		if res.Result != nil {
+			weighted += weight * float64(res.Result.Score)
+			totalWeight += weight
+		}
+
+		if res.passed() {
+			passed++
+		} else {
+			violations = append(violations, memberViolations(res)...)
+			if res.Severity != gateSeverityWarn && res.Result != nil {
+				hints = append(hints, res.Result.RepairHints...)
+			}
+		}
+
+		remaining := len(def.Gates) - i - 1
+		switch mode.kind {
+		case gateModeAll:
+			if res.blocks() {
+				reason = fmt.Sprintf("gate %s failed", member)
+			}
+		default:
+			if passed >= mode.quorum {
+				reason = fmt.Sprintf("%s reached", mode)
+			} else if passed+remaining < mode.quorum {
+				reason = fmt.Sprintf("%s unreachable", mode)
+			}
+		}
+	}
+
+	ok := passed >= mode.quorum
+	if mode.kind == gateModeAll {
+		ok = true
+		for _, member := range members {
+			if member.blocks() {
+				ok = false
+			}
+		}
+	}
+
+	score := 0
+	if totalWeight > 0 {
+		score = int(weighted/totalWeight + 0.5)
+	}
+	var result *gate.GateResult
+	if ok {
+		result = gate.NewPassingResult(score)
+		result.Violations = violations
+	} else {
+		result = gate.NewFailingResult(score, violations, hints)
+	}
+	result.Kind = gateGroupType
+	result.Diagnostics, _ = json.Marshal(map[string]any{
+		"mode":      mode.String(),
+		"passed":    passed,
+		"evaluated": evaluated,
+	})
+	applyScoreThreshold(result, def)
+
+	return GateResult{
+		Name:     name,
+		Result:   result,
+		Severity: gateSeverity(def),
+		Members:  members,
+		Duration: time.Since(start),
+	}, nil
+}
+
+func (e *gateEvaluator) skipped(name, reason string) GateResult {
+	return GateResult{
+		Name:       name,
+		Severity:   gateSeverity(e.env.pipeline.Gates[name]),
+		Skipped:    true,
+		SkipReason: reason,
+	}
+}
+
+// memberViolations returns the violations of a failed member. Advisory
+// members are downgraded to warnings.
+func memberViolations(res GateResult) []gate.Violation {
+	var out []gate.Violation
+	if res.Result != nil {
+		out = append(out, res.Result.Violations...)
+	}
+	if res.Error != nil {
+		out = append(out, gate.Violation{Rule: "gate_error", Severity: "error", Message: fmt.Sprintf("gate %s error: %v", res.Name, res.Error)})
+	}
+	if res.Severity == gateSeverityWarn {
+		for i := range out {
+			out[i].Severity = "warning"
+		}
+	}
+	return out
+}
+
+// applyScoreThreshold lets min_score/max_score decide the verdict from the
+// gate's score.
+func applyScoreThreshold(res *gate.GateResult, def GateDefinition) {
+	if res == nil || (def.MinScore == nil && def.MaxScore == nil) {
+		return
+	}
+	var problem string
+	switch {
+	case def.MinScore != nil && res.Score < *def.MinScore:
+		problem = fmt.Sprintf("score %d is below min_score %d", res.Score, *def.MinScore)
+	case def.MaxScore != nil && res.Score > *def.MaxScore:
+		problem = fmt.Sprintf("score %d is above max_score %d", res.Score, *def.MaxScore)
+	}
+	if problem == "" {
+		res.Passed = true
+		return
+	}
+	res.Passed = false
+	res.Violations = append(res.Violations, gate.Violation{
+		Rule:     "score_threshold",
+		Severity: "error",
+		Message:  problem,
+	})
+}
+
+func (def GateDefinition) parserConfig() gate.ParserConfig {
+	return gate.ParserConfig{Type: def.Parser, Pattern: def.Pattern, Report: def.Report}
+}
+
+func (s *Sandbox) config() gate.SandboxConfig {
+	return gate.SandboxConfig{
+		Network:        s.Network,
+		Env:            s.Env,
+		ReadOnly:       s.ReadOnly,
+		Writable:       s.Writable,
+		Hide:           s.Hide,
+		CPUSeconds:     s.CPUSeconds,
+		MemoryMB:       s.MemoryMB,
+		MaxProcesses:   s.MaxProcesses,
+		MaxOutputBytes: s.MaxOutputBytes,
+	}
+}
+
+func gateSeverity(def GateDefinition) string {
+	if def.Severity == gateSeverityWarn {
+		return gateSeverityWarn
+	}
+	return gateSeverityBlock
+}
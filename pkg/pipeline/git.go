@@ -0,0 +1,71 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/zen-systems/flowgate/pkg/attest"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+	"github.com/zen-systems/flowgate/pkg/workspace"
+)
+
+// runBranchPrefix names the branch each git-mode run commits to.
+const runBranchPrefix = "flowgate/"
+
+// openRunBranch opens the workspace repository and creates the run branch,
+// or continues the one recorded when the run is resumed.
+func openRunBranch(workspacePath string, record *evidence.RunRecord) (*workspace.GitRepo, error) {
+	repo, err := workspace.OpenGitRepo(workspacePath)
+	if err != nil {
+		return nil, err
+	}
+	if record.Git != nil {
+		if err := repo.UseBranch(record.Git.Branch); err != nil {
+			return nil, err
+		}
+		return repo, nil
+	}
+	branch := runBranchPrefix + record.ID
+	message := fmt.Sprintf("flowgate: snapshot workspace for run %s\n\nFlowgate-Run: %s\n", record.ID, record.ID)
+	base, err := repo.StartBranch(branch, message)
+	if err != nil {
+		return nil, err
+	}
+	record.Git = &evidence.GitRecord{Branch: branch, Base: base}
+	return repo, nil
+}
+
+// commitStage commits the files an apply stage changed to the run branch.
+// The stage's attestation is stored as a blob and its hash is named in the
+// commit trailer. It covers the stage's own evidence only, since run.json is
+// rewritten after the commit.
+func commitStage(writer *evidence.Writer, repo *workspace.GitRepo, record *evidence.RunRecord, stageName string, files []workspace.GitFile) error {
+	attestation, err := attest.BuildStageAttestation(writer.RunDir(), stageName)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(attestation, "", "  ")
+	if err != nil {
+		return err
+	}
+	ref, sha, err := writer.WriteBlob("attestation", data)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("flowgate: apply stage %s\n\nFlowgate-Run: %s\nFlowgate-Stage: %s\nFlowgate-Attestation: sha256:%s\n", stageName, record.ID, stageName, sha)
+	commit, err := repo.Commit(files, message)
+	if err != nil {
+		return err
+	}
+	if commit == "" {
+		return nil
+	}
+	record.Git.Commits = append(record.Git.Commits, evidence.GitCommit{
+		Stage:           stageName,
+		Commit:          commit,
+		AttestationRef:  ref,
+		AttestationHash: sha,
+	})
+	return writer.WriteRun(*record)
+}
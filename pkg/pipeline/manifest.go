@@ -3,7 +3,11 @@ package pipeline
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/zen-systems/flowgate/pkg/gate"
+	"github.com/zen-systems/flowgate/pkg/workspace"
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,6 +22,7 @@ func LoadManifest(path string) (*Pipeline, error) {
 	if err := yaml.Unmarshal(data, &pipeline); err != nil {
 		return nil, err
 	}
+	pipeline.baseDir = filepath.Dir(path)
 
 	return &pipeline, nil
 }
@@ -31,28 +36,147 @@ func (p *Pipeline) Validate() error {
 		return fmt.Errorf("pipeline must define at least one stage")
 	}
 
+	switch p.Workspace.Mode {
+	case "", workspace.ModeCopy, workspace.ModeGit:
+	default:
+		return fmt.Errorf("workspace mode must be copy or git")
+	}
+	switch p.Workspace.Clone {
+	case "", workspace.CloneCopy, workspace.CloneHardlink, workspace.CloneReflink, workspace.CloneOverlay:
+	default:
+		return fmt.Errorf("workspace clone must be copy, hardlink, reflink or overlay")
+	}
+	if p.Workspace.Clone != "" && p.Workspace.Mode == workspace.ModeGit {
+		return fmt.Errorf("workspace clone does not apply to git mode")
+	}
+
 	seen := make(map[string]struct{})
 	for _, stage := range p.Stages {
 		if stage.Name == "" {
 			return fmt.Errorf("stage name is required")
 		}
-		if stage.Prompt == "" {
-			return fmt.Errorf("stage %s must have a prompt", stage.Name)
+		switch stage.Type {
+		case "":
+			if stage.Prompt == "" {
+				return fmt.Errorf("stage %s must have a prompt", stage.Name)
+			}
+		case stageTypeApproval:
+			if len(stage.Gates) > 0 || stage.Apply || stage.OutputSchema != nil || stage.ForEach != nil || stageSamples(stage) > 0 || stage.RequireApproval {
+				return fmt.Errorf("stage %s: approval stages cannot have gates, apply, output_schema, for_each, samples or require_approval", stage.Name)
+			}
+		default:
+			return fmt.Errorf("stage %s: type must be approval when set", stage.Name)
 		}
 		if _, ok := seen[stage.Name]; ok {
 			return fmt.Errorf("duplicate stage name: %s", stage.Name)
 		}
 		seen[stage.Name] = struct{}{}
+		if _, err := parseTimeout(stage.Timeout); err != nil {
+			return fmt.Errorf("stage %s: %w", stage.Name, err)
+		}
+		switch stage.ApplyMode {
+		case "", workspace.ApplyStrict, workspace.ApplyFuzzy, workspace.ApplyMerge:
+		default:
+			return fmt.Errorf("stage %s: apply_mode must be strict, fuzzy or merge", stage.Name)
+		}
+		if stage.ForEach != nil {
+			if stage.ForEach.Stage == "" {
+				return fmt.Errorf("stage %s: for_each requires a stage", stage.Name)
+			}
+			if stage.ForEach.Parallel < 0 {
+				return fmt.Errorf("stage %s: for_each parallel must not be negative", stage.Name)
+			}
+		}
+		if stage.Samples < 0 {
+			return fmt.Errorf("stage %s: samples must not be negative", stage.Name)
+		}
+		for _, target := range stage.SampleModels {
+			if target.Adapter == "" {
+				return fmt.Errorf("stage %s: sample_models entries require an adapter", stage.Name)
+			}
+		}
+		for _, policy := range stage.EscalateOn {
+			if err := validateEscalationPolicy(policy); err != nil {
+				return fmt.Errorf("stage %s: escalate_on: %w", stage.Name, err)
+			}
+		}
+		if stage.ApplyFuzz != nil && *stage.ApplyFuzz < 0 {
+			return fmt.Errorf("stage %s: apply_fuzz must not be negative", stage.Name)
+		}
+		if (stage.ApplyMode != "" || stage.ApplyFuzz != nil) && !stage.Apply {
+			return fmt.Errorf("stage %s: apply_mode and apply_fuzz require apply: true", stage.Name)
+		}
+		if stage.OutputSchema != nil {
+			if err := stage.OutputSchema.load(p.baseDir); err != nil {
+				return fmt.Errorf("stage %s output_schema: %w", stage.Name, err)
+			}
+		}
 
 		for _, gateName := range stage.Gates {
 			if gateName == "" {
 				return fmt.Errorf("stage %s has empty gate name", stage.Name)
 			}
-			if _, ok := p.Gates[gateName]; !ok && gateName != "hollowcheck" {
+			if _, ok := p.Gates[gateName]; !ok && gateName != "hollowcheck" && gateName != "stubcheck" {
 				return fmt.Errorf("stage %s references unknown gate %s", stage.Name, gateName)
 			}
 		}
 	}
 
+	for name, def := range p.Gates {
+		if _, err := parseTimeout(def.Timeout); err != nil {
+			return fmt.Errorf("gate %s: %w", name, err)
+		}
+		if err := p.validateGateDefinition(name, def); err != nil {
+			return err
+		}
+		if strings.EqualFold(def.Type, "json_schema") {
+			if def.Schema == nil {
+				return fmt.Errorf("gate %s: json_schema gates require a schema", name)
+			}
+			if err := def.Schema.load(p.baseDir); err != nil {
+				return fmt.Errorf("gate %s schema: %w", name, err)
+			}
+		}
+		if def.Parser != "" || def.Pattern != "" || def.Report != "" {
+			if !strings.EqualFold(def.Type, "command") {
+				return fmt.Errorf("gate %s: parser only applies to command gates", name)
+			}
+			if _, err := gate.NewOutputParser(def.parserConfig()); err != nil {
+				return fmt.Errorf("gate %s: %w", name, err)
+			}
+		}
+		if def.Sandbox.enabled() {
+			if !strings.EqualFold(def.Type, "command") {
+				return fmt.Errorf("gate %s: sandbox only applies to command gates", name)
+			}
+			sb := def.Sandbox
+			if sb.CPUSeconds < 0 || sb.MemoryMB < 0 || sb.MaxProcesses < 0 || sb.MaxOutputBytes < 0 {
+				return fmt.Errorf("gate %s: sandbox limits must not be negative", name)
+			}
+		}
+		if strings.EqualFold(def.Type, "judge") {
+			if def.Rubric == "" {
+				return fmt.Errorf("gate %s: judge gates require a rubric", name)
+			}
+			path := def.Rubric
+			if !filepath.IsAbs(path) && p.baseDir != "" {
+				path = filepath.Join(p.baseDir, path)
+			}
+			rubric, err := gate.LoadRubric(path)
+			if err != nil {
+				return fmt.Errorf("gate %s: %w", name, err)
+			}
+			def.rubric = rubric
+			p.Gates[name] = def
+		}
+	}
+
+	if p.MaxParallel < 0 {
+		return fmt.Errorf("max_parallel must not be negative")
+	}
+	if _, err := buildStageGraph(p); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -2,7 +2,12 @@ package pipeline
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/config"
+	"gopkg.in/yaml.v3"
 )
 
 func TestLoadManifest(t *testing.T) {
@@ -42,3 +47,216 @@ stages:
 		t.Fatalf("validate: %v", err)
 	}
 }
+
+func TestLoadManifestSandbox(t *testing.T) {
+	content := `name: sandboxed
+gates:
+  plain:
+    type: command
+    command: ["go", "test", "./..."]
+    sandbox: true
+  off:
+    type: command
+    command: ["go", "vet", "./..."]
+    sandbox: false
+  limited:
+    type: command
+    command: ["go", "test", "./..."]
+    sandbox:
+      env: [GOFLAGS=-mod=mod]
+      read_only: ["~/go/pkg/mod"]
+      memory_mb: 2048
+stages:
+  - name: code
+    prompt: code
+    gates: [plain, off, limited]
+`
+	path := filepath.Join(t.TempDir(), "pipeline.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	p, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if !p.Gates["plain"].Sandbox.enabled() || p.Gates["off"].Sandbox.enabled() {
+		t.Fatalf("unexpected sandbox flags: %+v %+v", p.Gates["plain"].Sandbox, p.Gates["off"].Sandbox)
+	}
+	if cfg := p.Gates["limited"].Sandbox.config(); cfg.MemoryMB != 2048 || cfg.ReadOnly[0] != "~/go/pkg/mod" || cfg.Env[0] != "GOFLAGS=-mod=mod" {
+		t.Fatalf("unexpected sandbox config %+v", cfg)
+	}
+
+	p.Gates["stubs"] = GateDefinition{Type: "stubcheck", Sandbox: &Sandbox{}}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "sandbox only applies to command gates") {
+		t.Fatalf("expected sandbox validation error, got %v", err)
+	}
+}
+
+func TestValidateApplyMode(t *testing.T) {
+	p := &Pipeline{
+		Name:   "bad-apply",
+		Stages: []*Stage{{Name: "edit", Prompt: "edit", ApplyMode: "merge"}},
+	}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "require apply: true") {
+		t.Fatalf("expected apply_mode without apply to fail, got %v", err)
+	}
+
+	p.Stages[0].Apply = true
+	if err := p.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	p.Stages[0].ApplyMode = "overwrite"
+	if err := p.Validate(); err == nil {
+		t.Fatalf("expected unknown apply_mode to fail")
+	}
+}
+
+func TestValidateWorkspaceMode(t *testing.T) {
+	p := &Pipeline{
+		Name:      "workspace-mode",
+		Workspace: Workspace{Mode: "git"},
+		Stages:    []*Stage{{Name: "edit", Prompt: "edit"}},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	p.Workspace.Mode = "svn"
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "workspace mode") {
+		t.Fatalf("expected unknown workspace mode to fail, got %v", err)
+	}
+}
+
+func TestValidateWorkspaceClone(t *testing.T) {
+	p := &Pipeline{
+		Name:      "workspace-clone",
+		Workspace: Workspace{Clone: "reflink"},
+		Stages:    []*Stage{{Name: "edit", Prompt: "edit"}},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	p.Workspace.Clone = "rsync"
+	if err := p.Validate(); err == nil {
+		t.Fatalf("expected unknown clone strategy to fail")
+	}
+
+	p.Workspace = Workspace{Mode: "git", Clone: "hardlink"}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "git mode") {
+		t.Fatalf("expected clone with git mode to fail, got %v", err)
+	}
+}
+
+func TestValidateForEach(t *testing.T) {
+	p := &Pipeline{
+		Name: "fan-out",
+		Stages: []*Stage{
+			{Name: "plan", Prompt: "plan"},
+			{Name: "implement", Prompt: "{{ .Item }}", ForEach: &ForEach{Stage: "plan"}},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	p.Stages[1].ForEach.Stage = "missing"
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "unknown for_each stage") {
+		t.Fatalf("expected unknown for_each stage to fail, got %v", err)
+	}
+
+	p.Stages[1].ForEach = &ForEach{Stage: "plan", Parallel: -1}
+	if err := p.Validate(); err == nil {
+		t.Fatalf("expected negative parallel to fail")
+	}
+}
+
+func TestValidateSamples(t *testing.T) {
+	p := &Pipeline{
+		Name:   "best-of-n",
+		Stages: []*Stage{{Name: "code", Prompt: "code", Samples: -1}},
+	}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "samples must not be negative") {
+		t.Fatalf("expected negative samples to fail, got %v", err)
+	}
+
+	p.Stages[0].Samples = 2
+	p.Stages[0].SampleModels = []config.RouteTarget{{Model: "mock-1"}}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "require an adapter") {
+		t.Fatalf("expected sample model without adapter to fail, got %v", err)
+	}
+
+	p.Stages[0].SampleModels[0].Adapter = "mock"
+	if err := p.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+}
+
+func TestValidateApprovalStage(t *testing.T) {
+	p := &Pipeline{
+		Name: "reviewed",
+		Stages: []*Stage{
+			{Name: "plan", Prompt: "plan"},
+			{Name: "review", Type: "approval", DependsOn: []string{"plan"}},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	p.Stages[1].Gates = []string{"hollowcheck"}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "approval stages cannot") {
+		t.Fatalf("expected gated approval stage to fail, got %v", err)
+	}
+
+	p.Stages[1].Gates = nil
+	p.Stages[1].Type = "review"
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "type must be approval") {
+		t.Fatalf("expected unknown stage type to fail, got %v", err)
+	}
+}
+
+func TestValidateEscalateOn(t *testing.T) {
+	var policies EscalationPolicies
+	if err := yaml.Unmarshal([]byte("repeat_fingerprint"), &policies); err != nil || len(policies) != 1 || policies[0].When != "repeat_fingerprint" {
+		t.Fatalf("expected a single trigger to parse, got %+v (%v)", policies, err)
+	}
+	doc := "- cost >= 0.5\n- when: attempt>=3\n  action: switch\n  adapter: anthropic\n"
+	if err := yaml.Unmarshal([]byte(doc), &policies); err != nil || len(policies) != 2 || policies[1].Adapter != "anthropic" {
+		t.Fatalf("expected a list of policies to parse, got %+v (%v)", policies, err)
+	}
+
+	cases := []struct {
+		policy EscalationPolicy
+		err    string
+	}{
+		{EscalationPolicy{When: "score_not_improving", Action: "abort"}, ""},
+		{EscalationPolicy{When: "rule:no_todo", Action: "prompt", Prompt: "again"}, ""},
+		{EscalationPolicy{When: "attempt>=0"}, "positive attempt number"},
+		{EscalationPolicy{When: "cost>=free"}, "positive amount"},
+		{EscalationPolicy{When: "tired"}, "unknown trigger"},
+		{EscalationPolicy{When: "repeat_fingerprint", Action: "switch"}, "requires an adapter or model"},
+		{EscalationPolicy{When: "repeat_fingerprint", Action: "prompt"}, "requires a prompt"},
+		{EscalationPolicy{When: "repeat_fingerprint", Action: "retry"}, "action must be"},
+	}
+	for _, tc := range cases {
+		p := &Pipeline{
+			Name:   "escalating",
+			Stages: []*Stage{{Name: "plan", Prompt: "plan", EscalateOn: EscalationPolicies{tc.policy}}},
+		}
+		err := p.Validate()
+		if tc.err == "" {
+			if err != nil {
+				t.Fatalf("%+v: unexpected error %v", tc.policy, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%+v: expected error containing %q, got %v", tc.policy, tc.err, err)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/gate"
+	"gopkg.in/yaml.v3"
 )
 
 // Pipeline represents a multi-stage LLM workflow.
@@ -14,16 +16,25 @@ type Pipeline struct {
 	Workspace      Workspace                 `yaml:"workspace,omitempty"`
 	DefaultAdapter string                    `yaml:"default_adapter,omitempty"`
 	DefaultModel   string                    `yaml:"default_model,omitempty"`
+	MaxParallel    int                       `yaml:"max_parallel,omitempty"`
 	Gates          map[string]GateDefinition `yaml:"gates,omitempty"`
 	Stages         []*Stage                  `yaml:"stages"`
 
 	// Adapters is optional runtime configuration (not from YAML).
 	Adapters map[string]adapter.Adapter `yaml:"-"`
+
+	// baseDir is the manifest's directory, used to resolve schema paths.
+	baseDir string
 }
 
 // Workspace defines workspace configuration for a pipeline.
 type Workspace struct {
 	Path string `yaml:"path,omitempty"`
+	// Mode is "copy" (default) or "git".
+	Mode string `yaml:"mode,omitempty"`
+	// Clone is the copy mode clone strategy: copy (default), hardlink,
+	// reflink or overlay.
+	Clone string `yaml:"clone,omitempty"`
 }
 
 // GateDefinition defines a gate in the manifest.
@@ -37,6 +48,66 @@ type GateDefinition struct {
 	Workdir         string            `yaml:"workdir,omitempty"`
 	BinaryPath      string            `yaml:"binary_path,omitempty"`
 	ContractPath    string            `yaml:"contract_path,omitempty"`
+	Timeout         string            `yaml:"timeout,omitempty"`
+	Schema          *SchemaSpec       `yaml:"schema,omitempty"`
+	Severity        string            `yaml:"severity,omitempty"`
+	MinScore        *int              `yaml:"min_score,omitempty"`
+	MaxScore        *int              `yaml:"max_score,omitempty"`
+	Weight          float64           `yaml:"weight,omitempty"`
+	Mode            string            `yaml:"mode,omitempty"`
+	Gates           []string          `yaml:"gates,omitempty"`
+	Sandbox         *Sandbox          `yaml:"sandbox,omitempty"`
+	Parser          string            `yaml:"parser,omitempty"`
+	Pattern         string            `yaml:"pattern,omitempty"`
+	Report          string            `yaml:"report,omitempty"`
+	Rubric          string            `yaml:"rubric,omitempty"`
+	Adapter         string            `yaml:"adapter,omitempty"`
+	Model           string            `yaml:"model,omitempty"`
+
+	rubric *gate.Rubric
+}
+
+// Sandbox configures sandboxed execution for a command gate. `sandbox: true`
+// enables it with the defaults.
+type Sandbox struct {
+	Network        bool     `yaml:"network,omitempty"`
+	Env            []string `yaml:"env,omitempty"`
+	ReadOnly       []string `yaml:"read_only,omitempty"`
+	Writable       []string `yaml:"writable,omitempty"`
+	Hide           []string `yaml:"hide,omitempty"`
+	CPUSeconds     int      `yaml:"cpu_seconds,omitempty"`
+	MemoryMB       int      `yaml:"memory_mb,omitempty"`
+	MaxProcesses   int      `yaml:"max_processes,omitempty"`
+	MaxOutputBytes int      `yaml:"max_output_bytes,omitempty"`
+
+	disabled bool
+}
+
+// UnmarshalYAML accepts a boolean or a mapping.
+func (s *Sandbox) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var enabled bool
+		if err := node.Decode(&enabled); err != nil {
+			return fmt.Errorf("line %d: sandbox must be a boolean or a mapping", node.Line)
+		}
+		*s = Sandbox{disabled: !enabled}
+		return nil
+	}
+	type plain Sandbox
+	return node.Decode((*plain)(s))
+}
+
+// MarshalYAML writes a disabled sandbox back as false.
+func (s Sandbox) MarshalYAML() (any, error) {
+	if s.disabled {
+		return false, nil
+	}
+	type plain Sandbox
+	return plain(s), nil
+}
+
+func (s *Sandbox) enabled() bool {
+	return s != nil && !s.disabled
 }
 
 // CommandTemplate defines an allowed command template.
@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/cache"
+)
+
+// cachedResponse is the on-disk form of a cached adapter response.
+type cachedResponse struct {
+	Adapter   string         `json:"adapter"`
+	Model     string         `json:"model"`
+	Content   string         `json:"content"`
+	Usage     *adapter.Usage `json:"usage,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// responseCacheKey covers everything that affects the output: the target and
+// the full request including system prompt and sampling options.
+func responseCacheKey(target callTarget, req adapter.Request) string {
+	return cache.Key("response", target.Adapter, target.Model, adapter.RequestHash(req))
+}
+
+// lookupCachedResponse returns the first cached response among the targets.
+// Hits are reported with zero usage and cost since nothing was billed.
+func lookupCachedResponse(responses *cache.Cache, targets []callTarget, req adapter.Request) (*adapter.Response, adapter.CallReport, bool) {
+	if responses == nil {
+		return nil, adapter.CallReport{}, false
+	}
+	for idx, target := range targets {
+		data, ok := responses.Get(responseCacheKey(target, req))
+		if !ok {
+			continue
+		}
+		var entry cachedResponse
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		resp := &adapter.Response{
+			Artifact: artifact.New(entry.Content, target.Adapter, target.Model, req.Flatten()),
+		}
+		report := adapter.CallReport{
+			Adapter:      target.Adapter,
+			Model:        target.Model,
+			Cost:         adapter.Cost{Currency: "USD"},
+			FallbackUsed: idx > 0,
+			Cached:       true,
+		}
+		return resp, report, true
+	}
+	return nil, adapter.CallReport{}, false
+}
+
+// storeCachedResponse saves a successful response. Failures only cost a
+// future cache miss, so they are ignored.
+func storeCachedResponse(responses *cache.Cache, target callTarget, req adapter.Request, resp *adapter.Response) {
+	if responses == nil || resp == nil || resp.Artifact == nil {
+		return
+	}
+	data, err := json.Marshal(cachedResponse{
+		Adapter:   target.Adapter,
+		Model:     target.Model,
+		Content:   resp.Artifact.Content,
+		Usage:     resp.Usage,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return
+	}
+	_ = responses.Put(responseCacheKey(target, req), data)
+}
@@ -0,0 +1,280 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+	"github.com/zen-systems/flowgate/pkg/gate"
+	"gopkg.in/yaml.v3"
+)
+
+// Resume continues a run from its evidence bundle in runDir. Stages that
+// completed successfully are rehydrated from the blob store after their hashes
+// are verified; every other stage runs again and appends to the same bundle.
+//
+// opts.Input may be empty when the run recorded its input. If it is set, it
+// must match the recorded input hash.
+func Resume(ctx context.Context, pipeline *Pipeline, runDir string, opts RunOptions) (*RunResult, error) {
+	if pipeline == nil {
+		return nil, fmt.Errorf("pipeline is required")
+	}
+	if err := pipeline.Validate(); err != nil {
+		return nil, err
+	}
+	if len(pipeline.Adapters) == 0 {
+		return nil, fmt.Errorf("no adapters configured")
+	}
+
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	writer, err := evidence.OpenWriter(runDir)
+	if err != nil {
+		return nil, err
+	}
+	runRecord, err := evidence.ReadRun(runDir)
+	if err != nil {
+		return nil, fmt.Errorf("read run record: %w", err)
+	}
+	stageRecords, err := evidence.ReadStages(runDir)
+	if err != nil {
+		return nil, fmt.Errorf("read stage records: %w", err)
+	}
+
+	input, err := resumeInput(runDir, runRecord, opts.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	workspacePath := opts.WorkspacePath
+	if workspacePath == "" {
+		workspacePath = runRecord.Workspace
+	}
+	if workspacePath == "" {
+		workspacePath, err = resolveWorkspacePath(pipeline, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tracker := newCostTracker(opts.RoutingConfig, opts.MaxBudgetUSD)
+	tracker.restore(runRecord.CostReport)
+
+	state := newRunState(writer, *runRecord, input, workspacePath, tracker)
+	currentHash := pipelineHash(pipeline)
+	event := evidence.ResumeEvent{
+		Timestamp:       time.Now().UTC(),
+		PipelineHash:    currentHash,
+		PipelineChanged: runRecord.PipelineHash != "" && runRecord.PipelineHash != currentHash,
+	}
+
+	graph, err := buildStageGraph(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	// Manifest order is not necessarily topological, so iterate until no
+	// further stage can be restored.
+	for changed := true; changed; {
+		changed = false
+		for _, stage := range pipeline.Stages {
+			if _, ok := state.results[stage.Name]; ok {
+				continue
+			}
+			record := stageRecords[stage.Name]
+			if record == nil || !depsRestored(graph[stage.Name], state.results) {
+				continue
+			}
+			result, err := restoreStage(runDir, pipeline, stage, record)
+			if err != nil {
+				opts.logf("resume: re-running stage %s: %v", stage.Name, err)
+				continue
+			}
+			if result == nil {
+				continue
+			}
+			state.complete(stage, result)
+			state.records[stage.Name] = record
+			changed = true
+		}
+	}
+
+	for _, stage := range pipeline.Stages {
+		if _, ok := state.results[stage.Name]; ok {
+			event.ReusedStages = append(event.ReusedStages, stage.Name)
+			continue
+		}
+		event.RerunStages = append(event.RerunStages, stage.Name)
+		if record := stageRecords[stage.Name]; record != nil {
+			state.previous[stage.Name] = record
+		}
+	}
+
+	state.record.Resumes = append(state.record.Resumes, event)
+	if state.record.InputRef == "" {
+		ref, _, err := writer.WriteBlob("input", []byte(input))
+		if err != nil {
+			return nil, fmt.Errorf("write input blob: %w", err)
+		}
+		state.record.InputRef = ref
+	}
+	if state.record.PipelineHash == "" {
+		state.record.PipelineHash = currentHash
+	}
+	if err := writer.WriteRun(state.record); err != nil {
+		return nil, err
+	}
+
+	return executeStages(ctx, pipeline, opts, state)
+}
+
+func resumeInput(runDir string, record *evidence.RunRecord, provided string) (string, error) {
+	if provided != "" {
+		if record.InputHash != "" && hashString(provided) != record.InputHash {
+			return "", fmt.Errorf("input does not match the input hash recorded for run %s", record.ID)
+		}
+		return provided, nil
+	}
+	if record.InputRef == "" {
+		return "", fmt.Errorf("run %s did not record its input; pass the original input to resume", record.ID)
+	}
+	data, err := evidence.ReadBlob(runDir, record.InputRef, record.InputHash)
+	if err != nil {
+		return "", fmt.Errorf("read recorded input: %w", err)
+	}
+	return string(data), nil
+}
+
+// restoreStage rebuilds the result of a previously completed stage. It returns
+// nil without error when the stage did not complete, and an error when the
+// recorded output cannot be trusted.
+func restoreStage(runDir string, p *Pipeline, stage *Stage, record *evidence.StageRecord) (*StageResult, error) {
+	if record.OutputRef == "" {
+		return nil, nil
+	}
+	if stage.ForEach != nil || stage.Type == stageTypeApproval {
+		// For-each and approval stages have no attempts of their own.
+		if record.Error != "" {
+			return nil, nil
+		}
+	} else if len(record.Attempts) == 0 || !record.Attempts[len(record.Attempts)-1].Succeeded {
+		return nil, nil
+	}
+	if record.DefinitionHash != "" && record.DefinitionHash != stageDefinitionHash(p, stage) {
+		return nil, fmt.Errorf("stage definition changed")
+	}
+	data, err := evidence.ReadBlob(runDir, record.OutputRef, record.OutputHash)
+	if err != nil {
+		return nil, err
+	}
+	art := artifact.New(string(data), record.Adapter, record.Model, "")
+	if want := record.Artifacts["hash"]; want != "" && want != art.Hash {
+		return nil, fmt.Errorf("artifact hash mismatch: expected %s, got %s", want, art.Hash)
+	}
+	return &StageResult{
+		Name:        stage.Name,
+		Artifact:    art,
+		GateResults: restoredGateResults(record.GateResults),
+		Duration:    time.Duration(record.DurationMillis) * time.Millisecond,
+	}, nil
+}
+
+// restoredGateResults rebuilds gate results from their records, the inverse
+// of evidenceGateRecords.
+func restoredGateResults(records []evidence.GateRecord) []GateResult {
+	results := make([]GateResult, 0, len(records))
+	for _, record := range records {
+		result := GateResult{
+			Name:       record.Name,
+			Severity:   record.Severity,
+			Skipped:    record.Skipped,
+			SkipReason: record.SkipReason,
+			Members:    restoredGateResults(record.Members),
+			Duration:   time.Duration(record.DurationMillis) * time.Millisecond,
+		}
+		if record.Error != "" {
+			result.Error = errors.New(record.Error)
+		}
+		if !record.Skipped && record.Error == "" {
+			result.Result = &gate.GateResult{
+				Passed:      record.Passed,
+				Score:       record.Score,
+				RepairHints: record.RepairHints,
+				Kind:        record.Kind,
+				Diagnostics: record.Diagnostics,
+			}
+			for _, v := range record.Violations {
+				result.Result.Violations = append(result.Result.Violations, gate.Violation{
+					Rule:       v.Rule,
+					Severity:   v.Severity,
+					Message:    v.Message,
+					Location:   v.Location,
+					Suggestion: v.Suggestion,
+				})
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func depsRestored(deps []string, results map[string]*StageResult) bool {
+	for _, dep := range deps {
+		if _, ok := results[dep]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// pipelineHash fingerprints the manifest so resumes can report edits made
+// between executions.
+func pipelineHash(p *Pipeline) string {
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return ""
+	}
+	return hashString(string(data))
+}
+
+// stageDefinitionHash fingerprints a stage together with the gates it
+// references, including group members and judge rubrics, so that a resume
+// re-runs a stage whose gates changed.
+func stageDefinitionHash(p *Pipeline, stage *Stage) string {
+	gates := make(map[string]GateDefinition)
+	rubrics := make(map[string]*gate.Rubric)
+	var collect func(names []string)
+	collect = func(names []string) {
+		for _, name := range names {
+			def, ok := p.Gates[name]
+			if _, seen := gates[name]; !ok || seen {
+				continue
+			}
+			gates[name] = def
+			if def.rubric != nil {
+				rubrics[name] = def.rubric
+			}
+			collect(def.Gates)
+		}
+	}
+	collect(stage.Gates)
+
+	// A stage without gate definitions hashes as before, so records written
+	// by earlier versions still match.
+	var value any = stage
+	if len(gates) > 0 {
+		value = struct {
+			Stage   *Stage                    `yaml:"stage"`
+			Gates   map[string]GateDefinition `yaml:"gates"`
+			Rubrics map[string]*gate.Rubric   `yaml:"rubrics,omitempty"`
+		}{stage, gates, rubrics}
+	}
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return hashString(string(data))
+}
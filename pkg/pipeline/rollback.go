@@ -0,0 +1,118 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/zen-systems/flowgate/pkg/evidence"
+	"github.com/zen-systems/flowgate/pkg/workspace"
+)
+
+// RollbackResult lists the files a rollback restored or removed.
+type RollbackResult struct {
+	Workspace string
+	Restored  []string
+	Removed   []string
+}
+
+// Rollback undoes the changes a run applied to the real workspace, using the
+// apply journals in its evidence bundle. Every file must still match the
+// content the run left behind; if any was modified since, nothing is changed.
+// workspacePath defaults to the workspace recorded for the run.
+func Rollback(runDir, workspacePath string) (*RollbackResult, error) {
+	writer, err := evidence.OpenWriter(runDir)
+	if err != nil {
+		return nil, err
+	}
+	runRecord, err := evidence.ReadRun(runDir)
+	if err != nil {
+		return nil, fmt.Errorf("read run record: %w", err)
+	}
+	if len(runRecord.Rollbacks) > 0 {
+		return nil, fmt.Errorf("run was already rolled back")
+	}
+	if workspacePath == "" {
+		workspacePath = runRecord.Workspace
+	}
+	if workspacePath == "" {
+		return nil, fmt.Errorf("run does not record a workspace")
+	}
+	stageRecords, err := evidence.ReadStages(runDir)
+	if err != nil {
+		return nil, fmt.Errorf("read stage records: %w", err)
+	}
+
+	var journals []*evidence.ApplyJournal
+	for _, record := range stageRecords {
+		for _, attempt := range append(append([]evidence.AttemptRecord(nil), record.PriorAttempts...), record.Attempts...) {
+			if attempt.ApplyJournal != nil {
+				journals = append(journals, attempt.ApplyJournal)
+			}
+		}
+	}
+	if len(journals) == 0 {
+		return nil, fmt.Errorf("run did not apply changes to the real workspace")
+	}
+	sort.SliceStable(journals, func(i, j int) bool {
+		return journals[i].AppliedAt.Before(journals[j].AppliedAt)
+	})
+
+	// A file changed by several applies goes back to its state before the
+	// first one and must currently be in its state after the last one.
+	first := make(map[string]evidence.FileChange)
+	last := make(map[string]evidence.FileChange)
+	for _, journal := range journals {
+		for _, change := range journal.Changes {
+			if _, ok := first[change.Path]; !ok {
+				first[change.Path] = change
+			}
+			last[change.Path] = change
+		}
+	}
+
+	paths := make([]string, 0, len(first))
+	for path := range first {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	result := &RollbackResult{Workspace: workspacePath}
+	var restores []workspace.FileRestore
+	for _, path := range paths {
+		before, after := first[path], last[path]
+		if !before.Existed && after.Deleted {
+			continue
+		}
+		restore := workspace.FileRestore{
+			Path:   path,
+			Expect: workspace.FileState{Exists: !after.Deleted, Mode: after.NewMode, Hash: after.NewHash},
+		}
+		if before.Existed {
+			content, err := evidence.ReadBlob(runDir, before.OldRef, before.OldHash)
+			if err != nil {
+				return nil, fmt.Errorf("read original %s: %w", path, err)
+			}
+			restore.Target = workspace.FileState{Exists: true, Mode: before.OldMode, Hash: before.OldHash, Content: content}
+			result.Restored = append(result.Restored, path)
+		} else {
+			result.Removed = append(result.Removed, path)
+		}
+		restores = append(restores, restore)
+	}
+
+	if err := workspace.RestoreFiles(workspacePath, restores); err != nil {
+		return nil, err
+	}
+
+	runRecord.Rollbacks = append(runRecord.Rollbacks, evidence.RollbackEvent{
+		Timestamp: time.Now().UTC(),
+		Workspace: workspacePath,
+		Restored:  result.Restored,
+		Removed:   result.Removed,
+	})
+	if err := writer.WriteRun(*runRecord); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
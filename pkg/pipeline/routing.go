@@ -0,0 +1,91 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/router"
+)
+
+// routeStage picks the adapter and model for a stage that names neither an
+// adapter nor a model; a model alone belongs to the pipeline's default
+// adapter, and routing could pair it with another. A stage's task_type selects its route from the routing config; a stage
+// without one, in a pipeline without a default adapter, is classified from
+// its rendered prompt. The target is empty when the stage is not routed or
+// no adapter on its route is available, and the stage then falls back to
+// the pipeline defaults.
+func routeStage(ctx context.Context, env *stageEnv, stage *Stage, prompt string) (callTarget, *router.Decision) {
+	cfg := env.routing
+	if cfg == nil || stage.Adapter != "" || stage.Model != "" {
+		return callTarget{}, nil
+	}
+
+	var decision *router.Decision
+	switch {
+	case stage.TaskType != "":
+		decision = &router.Decision{
+			TaskType:   stage.TaskType,
+			Confidence: 1,
+			Reasons:    []string{"task_type set on stage"},
+		}
+	case env.pipeline.DefaultAdapter != "":
+		return callTarget{}, nil
+	default:
+		// A classifier error leaves the heuristic decision, with the error
+		// among its reasons.
+		decision, _ = router.NewClassifier(env.adapters, cfg, router.WithClassifierCall(env.classifierCall(stage))).Classify(ctx, prompt)
+		if decision == nil {
+			return callTarget{}, nil
+		}
+	}
+
+	target := callTarget{Adapter: cfg.Default.Adapter, Model: cfg.Default.Model}
+	if task, ok := cfg.TaskTypes[decision.TaskType]; ok {
+		target = callTarget{Adapter: task.Adapter, Model: task.Model}
+	} else {
+		decision.Reasons = append(decision.Reasons, fmt.Sprintf("no route for task type %q; using default", decision.TaskType))
+	}
+	target.Model = env.aliases.Resolve(target.Model)
+
+	candidates := []callTarget{target}
+	if cfg.Fallback.AllowFallback {
+		for _, entry := range resolveFallbackChain(cfg, target.Adapter, target.Model) {
+			candidates = append(candidates, callTarget{Adapter: entry.Adapter, Model: env.aliases.Resolve(entry.Model)})
+		}
+	}
+	for _, candidate := range candidates {
+		impl, ok := env.adapters[candidate.Adapter]
+		if !ok {
+			continue
+		}
+		if candidate != target {
+			decision.Reasons = append(decision.Reasons, fmt.Sprintf("adapter %s not available; using fallback %s", target.Adapter, candidate.Adapter))
+		}
+		if candidate.Model == "" {
+			if models := impl.Models(); len(models) > 0 {
+				candidate.Model = models[0]
+			}
+		}
+		return candidate, decision
+	}
+	decision.Reasons = append(decision.Reasons, fmt.Sprintf("adapter %s not available; using pipeline defaults", target.Adapter))
+	return callTarget{}, decision
+}
+
+// classifierCall sends a stage's routing tie-breaker through the call policy,
+// so that it is retried, cached, held to the budget and listed in the cost
+// report like any other call of the stage.
+func (env *stageEnv) classifierCall(stage *Stage) router.ClassifierCall {
+	return func(ctx context.Context, adapterName, model, prompt string) (string, error) {
+		resp, reports, err := callAdapterWithPolicy(ctx, env.adapters, adapterName, model, adapter.UserRequest(prompt), env.routing, env.tracker, env.cacheFor(stage), nil)
+		env.tracker.recordReports(reports)
+		if err != nil {
+			return "", err
+		}
+		if resp == nil || resp.Artifact == nil {
+			return "", fmt.Errorf("classifier returned empty response")
+		}
+		return resp.Artifact.Content, nil
+	}
+}
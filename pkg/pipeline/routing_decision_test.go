@@ -102,3 +102,138 @@ func TestRoutingDecisionRecordedWithLLM(t *testing.T) {
 		t.Fatalf("expected post-run feedback")
 	}
 }
+
+func TestStageTaskTypeRoutesThroughConfig(t *testing.T) {
+	disabled := false
+	cfg := &config.RoutingConfig{
+		TaskTypes: map[string]config.TaskType{
+			"review":    {Triggers: []string{"review"}, Adapter: "fileblock", Model: "careful"},
+			"summarize": {Triggers: []string{"summarize"}, Adapter: "offline", Model: "small"},
+		},
+		Default:             config.RouteTarget{Adapter: "mock", Model: "mock-1"},
+		EnableLLMTieBreaker: &disabled,
+		Fallback: config.FallbackConfig{
+			AllowFallback: true,
+			FallbackChain: map[string][]config.RouteTarget{"offline": {{Adapter: "mock", Model: "small"}}},
+		},
+	}
+	aliases := &config.ModelAliases{Aliases: map[string]string{"careful": "rev-2", "small": "mock-small"}}
+
+	p := &Pipeline{
+		Name: "routed",
+		Stages: []*Stage{
+			{Name: "review", TaskType: "review", Prompt: "look at {{ .Input }}"},
+			{Name: "digest", Prompt: "summarize {{ .Input }}"},
+			{Name: "pinned", TaskType: "review", Adapter: "mock", Model: "mock-1", Prompt: "hello"},
+		},
+		Adapters: map[string]adapter.Adapter{
+			"mock":      &simpleAdapter{content: "ok"},
+			"fileblock": &fileBlockAdapter{content: "reviewed"},
+		},
+	}
+
+	baseDir := t.TempDir()
+	if _, err := Run(context.Background(), p, RunOptions{Input: "the diff", EvidenceDir: baseDir, RoutingConfig: cfg, Aliases: aliases}); err != nil {
+		t.Fatalf("run pipeline: %v", err)
+	}
+	runDir := onlyRunDir(t, baseDir)
+
+	review := readStageRecord(t, runDir, "review")
+	if review.Adapter != "fileblock" || review.Model != "rev-2" {
+		t.Fatalf("expected task_type route with resolved alias, got %s/%s", review.Adapter, review.Model)
+	}
+	if review.RoutingDecision == nil || review.RoutingDecision.TaskType != "review" || review.RoutingDecision.Confidence != 1 {
+		t.Fatalf("expected task_type decision, got %+v", review.RoutingDecision)
+	}
+
+	// The classifier picks summarize from the prompt; its adapter is not
+	// configured, so the fallback chain supplies one.
+	digest := readStageRecord(t, runDir, "digest")
+	if digest.Adapter != "mock" || digest.Model != "mock-small" {
+		t.Fatalf("expected fallback route, got %s/%s", digest.Adapter, digest.Model)
+	}
+	if digest.RoutingDecision == nil || digest.RoutingDecision.TaskType != "summarize" {
+		t.Fatalf("expected classifier decision, got %+v", digest.RoutingDecision)
+	}
+
+	pinned := readStageRecord(t, runDir, "pinned")
+	if pinned.RoutingDecision != nil || pinned.Adapter != "mock" {
+		t.Fatalf("expected explicit adapter to bypass routing, got %s with %+v", pinned.Adapter, pinned.RoutingDecision)
+	}
+}
+
+func TestStageModelBypassesRouting(t *testing.T) {
+	disabled := false
+	cfg := &config.RoutingConfig{
+		TaskTypes: map[string]config.TaskType{
+			"review": {Triggers: []string{"review"}, Adapter: "fileblock", Model: "careful"},
+		},
+		Default:             config.RouteTarget{Adapter: "fileblock", Model: "careful"},
+		EnableLLMTieBreaker: &disabled,
+	}
+	// A model belongs to the default adapter; routing must not pair it with
+	// the review route's adapter.
+	p := &Pipeline{
+		Name:           "modeled",
+		DefaultAdapter: "mock",
+		Stages:         []*Stage{{Name: "review", TaskType: "review", Model: "mock-1", Prompt: "review {{ .Input }}"}},
+		Adapters: map[string]adapter.Adapter{
+			"mock":      &simpleAdapter{content: "ok"},
+			"fileblock": &fileBlockAdapter{content: "reviewed"},
+		},
+	}
+	baseDir := t.TempDir()
+	if _, err := Run(context.Background(), p, RunOptions{Input: "the diff", EvidenceDir: baseDir, RoutingConfig: cfg}); err != nil {
+		t.Fatalf("run pipeline: %v", err)
+	}
+	record := readStageRecord(t, onlyRunDir(t, baseDir), "review")
+	if record.Adapter != "mock" || record.Model != "mock-1" || record.RoutingDecision != nil {
+		t.Fatalf("expected the stage model on the default adapter, got %s/%s with %+v", record.Adapter, record.Model, record.RoutingDecision)
+	}
+}
+
+func TestStageClassifierCallIsTracked(t *testing.T) {
+	enabled := true
+	cfg := &config.RoutingConfig{
+		TaskTypes: map[string]config.TaskType{
+			"alpha": {Triggers: []string{"alpha"}, Adapter: "mock", Model: "mock-1"},
+			"beta":  {Triggers: []string{"beta"}, Adapter: "mock", Model: "mock-1"},
+		},
+		Default:                       config.RouteTarget{Adapter: "mock", Model: "mock-1"},
+		ClassifierAdapter:             "classifier",
+		ClassifierModel:               "cls-1",
+		EnableLLMTieBreaker:           &enabled,
+		ClassifierConfidenceThreshold: 0.65,
+	}
+	p := &Pipeline{
+		Name:   "classified",
+		Stages: []*Stage{{Name: "stage", Prompt: "alpha beta"}},
+		Adapters: map[string]adapter.Adapter{
+			"mock":       &simpleAdapter{content: "ok"},
+			"classifier": &classifierAdapter{response: `{"task_type":"beta","confidence":0.9,"reason":"tie breaker"}`},
+		},
+	}
+	baseDir := t.TempDir()
+	if _, err := Run(context.Background(), p, RunOptions{Input: "unrelated", EvidenceDir: baseDir, RoutingConfig: cfg}); err != nil {
+		t.Fatalf("run pipeline: %v", err)
+	}
+	runDir := onlyRunDir(t, baseDir)
+
+	record := readStageRecord(t, runDir, "stage")
+	if record.RoutingDecision == nil || !record.RoutingDecision.UsedLLM || record.RoutingDecision.TaskType != "beta" {
+		t.Fatalf("expected the tie-breaker to route the stage, got %+v", record.RoutingDecision)
+	}
+	run, err := evidence.ReadRun(runDir)
+	if err != nil {
+		t.Fatalf("read run: %v", err)
+	}
+	classifierCalls := 0
+	for _, call := range run.CostReport.Calls {
+		if call.Adapter == "classifier" {
+			classifierCalls++
+		}
+	}
+	if classifierCalls != 1 {
+		t.Fatalf("expected the stage's classifier call in the cost report, got %+v", run.CostReport.Calls)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/zen-systems/flowgate/pkg/adapter"
 	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/cache"
 	"github.com/zen-systems/flowgate/pkg/config"
 	"github.com/zen-systems/flowgate/pkg/evidence"
 	"github.com/zen-systems/flowgate/pkg/gate"
@@ -27,18 +29,56 @@ import (
 
 // RunOptions configures pipeline execution.
 type RunOptions struct {
-	Input           string
-	WorkspacePath   string
-	EvidenceDir     string
-	PipelinePath    string
-	RoutingConfig   *config.RoutingConfig
-	MaxBudgetUSD    float64
-	ApplyForReal    bool
-	ApplyApproved   bool
-	Logger          func(format string, args ...any)
+	Input         string
+	WorkspacePath string
+	EvidenceDir   string
+	PipelinePath  string
+	RoutingConfig *config.RoutingConfig
+	Aliases       *config.ModelAliases
+	MaxBudgetUSD  float64
+	ApplyForReal  bool
+	ApplyApproved bool
+	MaxParallel   int
+	Logger        func(format string, args ...any)
+	// OnStream receives output deltas while stages generate. It may be called
+	// concurrently when stages run in parallel.
+	OnStream func(StreamEvent)
+	// Timeout bounds the whole run, including stages that are retried or
+	// repaired. Zero means no limit.
+	Timeout time.Duration
+	// Cache serves repeated adapter calls from disk. Nil disables caching.
+	Cache *cache.Cache
+	// Approve asks for a decision on a stage awaiting approval. When it is
+	// nil or returns no decision, the run waits for one recorded with
+	// flowgate approve. It may be called concurrently.
+	Approve Approver
+	// Capabilities is the command gate capability policy. Nil uses the
+	// built-in capabilities.
+	Capabilities    *gate.CapabilitySet
 	VTPOrchestrator *orchestrator.Orchestrator
 }
 
+func (o RunOptions) logf(format string, args ...any) {
+	if o.Logger != nil {
+		o.Logger(format, args...)
+	}
+}
+
+// StreamEvent carries a chunk of stage output as it is generated. Attempt
+// starts at 1 and increases with each repair. Sample numbers the candidates
+// of a stage with samples, which stream concurrently; it is 0 otherwise.
+//
+// Reset is set, with no Delta, when the output streamed so far for the
+// attempt is discarded: the adapter call is retried or falls back, or the
+// attempt failed and the stage repairs it.
+type StreamEvent struct {
+	Stage   string
+	Attempt int
+	Sample  int
+	Delta   string
+	Reset   bool
+}
+
 // RunResult captures pipeline outputs.
 type RunResult struct {
 	RunID       string
@@ -53,6 +93,8 @@ type StageResult struct {
 	GateResults []GateResult
 	ApplyResult *workspace.ApplyResult
 	Duration    time.Duration
+
+	gitFiles []workspace.GitFile
 }
 
 // GateResult captures a gate evaluation with metadata.
@@ -61,12 +103,20 @@ type GateResult struct {
 	Result   *gate.GateResult
 	Error    error
 	Duration time.Duration
+	// Severity is "block" or "warn"; failing warn gates do not fail the stage.
+	Severity   string
+	Skipped    bool
+	SkipReason string
+	// Members holds the outcomes of a group's gates.
+	Members []GateResult
 }
 
 // RepairState tracks attempts and escalation decisions.
 type RepairState struct {
 	Attempts  []AttemptState
 	Escalated bool
+	// fired marks the escalate_on policies that have acted, by index.
+	fired map[int]bool
 }
 
 // AttemptState captures a single attempt fingerprint.
@@ -74,6 +124,8 @@ type AttemptState struct {
 	PromptHash           string
 	OutputHash           string
 	ViolationFingerprint string
+	// Score is the attempt's weighted mean gate score.
+	Score float64
 }
 
 // Run executes the pipeline with the given adapters and options.
@@ -90,16 +142,12 @@ func Run(ctx context.Context, pipeline *Pipeline, opts RunOptions) (*RunResult,
 		return nil, fmt.Errorf("no adapters configured")
 	}
 
-	workspacePath := opts.WorkspacePath
-	if workspacePath == "" {
-		workspacePath = pipeline.Workspace.Path
-	}
-	if workspacePath == "" {
-		cwd, err := os.Getwd()
-		if err != nil {
-			return nil, err
-		}
-		workspacePath = cwd
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	workspacePath, err := resolveWorkspacePath(pipeline, opts.WorkspacePath)
+	if err != nil {
+		return nil, err
 	}
 
 	writer, err := prepareEvidenceWriter(opts.EvidenceDir, workspacePath)
@@ -115,12 +163,19 @@ func Run(ctx context.Context, pipeline *Pipeline, opts RunOptions) (*RunResult,
 		routingDecision = decision
 	}
 
+	inputRef, inputSha, err := writer.WriteBlob("input", []byte(opts.Input))
+	if err != nil {
+		return nil, fmt.Errorf("write input blob: %w", err)
+	}
+
 	runID := filepath.Base(writer.RunDir())
 	runRecord := evidence.RunRecord{
 		ID:              runID,
 		Timestamp:       time.Now().UTC(),
 		PipelineFile:    opts.PipelinePath,
-		InputHash:       hashString(opts.Input),
+		InputHash:       inputSha,
+		InputRef:        inputRef,
+		PipelineHash:    pipelineHash(pipeline),
 		Workspace:       workspacePath,
 		ToolVersions:    map[string]string{"go": runtime.Version()},
 		RoutingDecision: routingDecision,
@@ -129,83 +184,334 @@ func Run(ctx context.Context, pipeline *Pipeline, opts RunOptions) (*RunResult,
 		return nil, err
 	}
 
+	return executeStages(ctx, pipeline, opts, newRunState(writer, runRecord, opts.Input, workspacePath, tracker))
+}
+
+// runState carries the evidence writer and accumulated stage outputs through
+// execution. Resumed runs start with the stages restored from disk.
+type runState struct {
+	writer        *evidence.Writer
+	record        evidence.RunRecord
+	input         string
+	workspacePath string
+	tracker       *costTracker
+
+	results      map[string]*StageResult
+	records      map[string]*evidence.StageRecord
+	artifacts    map[string]ArtifactTemplateData
+	stagesLegacy map[string]map[string]string
+	status       map[string]string
+	errors       map[string]string
+	// previous holds records of stages that are executed again on resume so
+	// their earlier attempts remain in the evidence bundle.
+	previous map[string]*evidence.StageRecord
+}
+
+func newRunState(writer *evidence.Writer, record evidence.RunRecord, input, workspacePath string, tracker *costTracker) *runState {
+	return &runState{
+		writer:        writer,
+		record:        record,
+		input:         input,
+		workspacePath: workspacePath,
+		tracker:       tracker,
+		results:       make(map[string]*StageResult),
+		records:       make(map[string]*evidence.StageRecord),
+		artifacts:     make(map[string]ArtifactTemplateData),
+		stagesLegacy:  make(map[string]map[string]string),
+		status:        make(map[string]string),
+		errors:        make(map[string]string),
+		previous:      make(map[string]*evidence.StageRecord),
+	}
+}
+
+// complete registers a finished stage so dependents can reference its output.
+func (s *runState) complete(stage *Stage, result *StageResult) {
+	name := stage.Name
+	data := ArtifactTemplateData{Text: result.Artifact.Content, Output: result.Artifact.Content, Hash: result.Artifact.Hash}
+	if stage.OutputSchema != nil || stage.ForEach != nil {
+		data.JSON = parseStructuredOutput(result.Artifact.Content)
+	}
+	s.results[name] = result
+	s.artifacts[name] = data
+	s.stagesLegacy[name] = map[string]string{"output": result.Artifact.Content}
+	s.status[name] = stageStatusSucceeded
+}
+
+func executeStages(ctx context.Context, pipeline *Pipeline, opts RunOptions, state *runState) (*RunResult, error) {
+	writer := state.writer
+	tracker := state.tracker
+	runRecord := &state.record
+
 	finalizeRun := func() error {
 		if tracker != nil {
 			runRecord.CostReport = tracker.report()
 		}
-		return writer.WriteRun(runRecord)
+		return writer.WriteRun(*runRecord)
 	}
 
-	results := make(map[string]*StageResult)
-	stageRecords := make(map[string]*evidence.StageRecord)
-	artifacts := make(map[string]ArtifactTemplateData)
-	stagesLegacy := make(map[string]map[string]string)
+	graph, err := buildStageGraph(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	parallel := opts.MaxParallel
+	if parallel <= 0 {
+		parallel = pipeline.MaxParallel
+	}
+	if parallel <= 0 {
+		parallel = defaultMaxParallel
+	}
+
+	type stageOutcome struct {
+		stage  *Stage
+		result *StageResult
+		record *evidence.StageRecord
+		err    error
+	}
 
-	for _, stage := range pipeline.Stages {
-		stageResult, stageRecord, err := runStage(ctx, writer, stage, adapters, pipeline, opts.Input, workspacePath, opts.ApplyForReal, opts.ApplyApproved, opts.RoutingConfig, tracker, artifacts, stagesLegacy)
-		if stageRecord != nil {
-			stageRecord.Name = stage.Name
-			if writeErr := writer.WriteStage(*stageRecord); writeErr != nil {
-				return nil, writeErr
+	var git *workspace.GitRepo
+	if pipeline.Workspace.Mode == workspace.ModeGit {
+		git, err = openRunBranch(state.workspacePath, runRecord)
+		if err != nil {
+			return nil, err
+		}
+		if err := writer.WriteRun(*runRecord); err != nil {
+			return nil, err
+		}
+	}
+
+	handlers := failureHandlers(pipeline.Stages)
+	env := &stageEnv{
+		writer:        writer,
+		pipeline:      pipeline,
+		adapters:      pipeline.Adapters,
+		input:         state.input,
+		workspacePath: state.workspacePath,
+		applyForReal:  opts.ApplyForReal,
+		applyApproved: opts.ApplyApproved,
+		routing:       opts.RoutingConfig,
+		tracker:       tracker,
+		cache:         opts.Cache,
+		capabilities:  opts.Capabilities,
+		onStream:      opts.OnStream,
+		git:           git,
+		graph:         graph,
+		approve:       opts.Approve,
+		aliases:       opts.Aliases,
+	}
+
+	// completed tracks finished stages regardless of outcome; state.status
+	// records whether each one succeeded, failed, or was skipped.
+	started := make(map[string]bool, len(pipeline.Stages))
+	completed := make(map[string]bool, len(pipeline.Stages))
+	for name := range state.results {
+		started[name] = true
+		completed[name] = true
+	}
+	outcomes := make(chan stageOutcome)
+	running := 0
+	var runErr error
+
+	for {
+		for rescan := true; rescan && runErr == nil; {
+			rescan = false
+			for _, stage := range readyStages(pipeline.Stages, graph, started, completed) {
+				if running >= parallel {
+					break
+				}
+				started[stage.Name] = true
+				reason, err := state.skipReason(stage, pipeline.Stages, graph[stage.Name], handlers)
+				if err != nil {
+					runErr = err
+					break
+				}
+				if reason != "" {
+					record := &evidence.StageRecord{
+						Name:           stage.Name,
+						DefinitionHash: stageDefinitionHash(pipeline, stage),
+						Skipped:        true,
+						SkipReason:     reason,
+					}
+					if err := writer.WriteStage(*record); err != nil {
+						runErr = err
+						break
+					}
+					opts.logf("stage %s skipped: %s", stage.Name, reason)
+					state.records[stage.Name] = record
+					state.status[stage.Name] = stageStatusSkipped
+					completed[stage.Name] = true
+					// Skipping may make further stages ready.
+					rescan = true
+					continue
+				}
+				running++
+				stageArtifacts := copyArtifacts(state.artifacts)
+				stageLegacy := copyLegacyStages(state.stagesLegacy)
+				go func(stage *Stage) {
+					// Validate has already checked the timeout.
+					limit, _ := parseTimeout(stage.Timeout)
+					stageCtx, cancel := withTimeout(ctx, limit)
+					defer cancel()
+					run := runStage
+					switch {
+					case stage.Type == stageTypeApproval:
+						run = runApproval
+					case stage.ForEach != nil:
+						run = runForEach
+					}
+					stageResult, stageRecord, err := run(stageCtx, env, stage, stageArtifacts, stageLegacy)
+					err = timeoutError(stageCtx, ctx, "stage "+stage.Name, limit, err)
+					outcomes <- stageOutcome{stage: stage, result: stageResult, record: stageRecord, err: err}
+				}(stage)
 			}
-			stageRecords[stage.Name] = stageRecord
 		}
-		if err != nil {
-			if writeErr := finalizeRun(); writeErr != nil {
-				return nil, writeErr
+		if running == 0 {
+			break
+		}
+
+		outcome := <-outcomes
+		running--
+		stage := outcome.stage
+
+		if outcome.record != nil {
+			outcome.record.Name = stage.Name
+			outcome.record.DefinitionHash = stageDefinitionHash(pipeline, stage)
+			if outcome.err != nil {
+				outcome.record.Error = outcome.err.Error()
 			}
-			return nil, err
+			if prev := state.previous[stage.Name]; prev != nil {
+				outcome.record.PriorAttempts = append(append([]evidence.AttemptRecord{}, prev.PriorAttempts...), prev.Attempts...)
+			}
+			if writeErr := writer.WriteStage(*outcome.record); writeErr != nil && runErr == nil {
+				runErr = writeErr
+			}
+			state.records[stage.Name] = outcome.record
+		}
+		if outcome.err != nil {
+			completed[stage.Name] = true
+			state.status[stage.Name] = stageStatusFailed
+			state.errors[stage.Name] = outcome.err.Error()
+			if stage.OnFailure != "" {
+				opts.logf("stage %s failed, continuing with %s: %v", stage.Name, stage.OnFailure, outcome.err)
+				continue
+			}
+			if runErr == nil {
+				runErr = outcome.err
+			}
+			continue
 		}
 
-		if err := writeGateLogs(writer, stage.Name, stageResult.GateResults); err != nil {
-			return nil, err
+		// for_each items wrote their own gate logs.
+		if stage.ForEach == nil {
+			if err := writeGateLogs(writer, stage.Name, outcome.result.GateResults); err != nil && runErr == nil {
+				runErr = err
+			}
+		}
+		if env.git != nil && len(outcome.result.gitFiles) > 0 && runErr == nil {
+			if err := commitStage(writer, env.git, runRecord, stage.Name, outcome.result.gitFiles); err != nil {
+				runErr = fmt.Errorf("commit stage %s: %w", stage.Name, err)
+			}
 		}
 
-		results[stage.Name] = stageResult
-		artifacts[stage.Name] = ArtifactTemplateData{Text: stageResult.Artifact.Content, Output: stageResult.Artifact.Content, Hash: stageResult.Artifact.Hash}
-		stagesLegacy[stage.Name] = map[string]string{"output": stageResult.Artifact.Content}
+		completed[stage.Name] = true
+		state.complete(stage, outcome.result)
 	}
 
-	if routingDecision != nil {
-		routingDecision.Feedback = buildRoutingFeedback(stageRecords, opts.Input, opts.RoutingConfig, routingDecision.TaskType)
+	if runErr != nil {
+		if opts.Timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			runErr = fmt.Errorf("run timed out after %s: %w", opts.Timeout, runErr)
+		}
+		if writeErr := finalizeRun(); writeErr != nil {
+			return nil, writeErr
+		}
+		return nil, runErr
+	}
+
+	if runRecord.RoutingDecision != nil {
+		runRecord.RoutingDecision.Feedback = buildRoutingFeedback(state.records, state.input, opts.RoutingConfig, runRecord.RoutingDecision.TaskType)
 	}
 	if err := finalizeRun(); err != nil {
 		return nil, err
 	}
 
 	return &RunResult{
-		RunID:       runID,
+		RunID:       runRecord.ID,
 		EvidenceDir: writer.RunDir(),
-		Stages:      results,
+		Stages:      state.results,
 	}, nil
 }
 
+func resolveWorkspacePath(pipeline *Pipeline, override string) (string, error) {
+	workspacePath := override
+	if workspacePath == "" {
+		workspacePath = pipeline.Workspace.Path
+	}
+	if workspacePath == "" {
+		return os.Getwd()
+	}
+	return workspacePath, nil
+}
+
+// stageEnv carries the run-wide settings every stage executes with.
+type stageEnv struct {
+	writer        *evidence.Writer
+	pipeline      *Pipeline
+	adapters      map[string]adapter.Adapter
+	input         string
+	workspacePath string
+	applyForReal  bool
+	applyApproved bool
+	routing       *config.RoutingConfig
+	tracker       *costTracker
+	cache         *cache.Cache
+	capabilities  *gate.CapabilitySet
+	onStream      func(StreamEvent)
+	// git is set in git workspace mode.
+	git *workspace.GitRepo
+	// graph maps each stage to the stages it depends on.
+	graph   map[string][]string
+	approve Approver
+	aliases *config.ModelAliases
+}
+
 func runStage(
 	ctx context.Context,
-	writer *evidence.Writer,
+	env *stageEnv,
 	stage *Stage,
-	adapters map[string]adapter.Adapter,
-	pipeline *Pipeline,
-	input string,
-	workspacePath string,
-	applyForReal bool,
-	applyApproved bool,
-	routing *config.RoutingConfig,
-	tracker *costTracker,
 	artifacts map[string]ArtifactTemplateData,
 	stagesLegacy map[string]map[string]string,
 ) (*StageResult, *evidence.StageRecord, error) {
 	if stage == nil {
 		return nil, nil, fmt.Errorf("stage is nil")
 	}
-	if writer == nil {
+	if env == nil || env.writer == nil {
 		return nil, nil, fmt.Errorf("evidence writer is nil")
 	}
+	writer := env.writer
+	pipeline := env.pipeline
+	adapters := env.adapters
+	input := env.input
 
 	start := time.Now()
 	stageRecord := &evidence.StageRecord{}
 
+	data := promptData(input, artifacts, stagesLegacy)
+	if stage.item != nil {
+		data["Item"] = stage.item.Value
+		data["ItemIndex"] = stage.item.Index
+	}
+	prompt, err := renderTemplate(stage.Prompt, data)
+	if err != nil {
+		return nil, stageRecord, fmt.Errorf("render prompt for stage %s: %w", stage.Name, err)
+	}
+
+	// Routing needs the rendered prompt to classify stages that name
+	// neither an adapter nor a task type.
+	route, routingDecision := routeStage(ctx, env, stage, prompt)
+	stageRecord.RoutingDecision = routingDecision
 	adapterName := stage.Adapter
+	if adapterName == "" {
+		adapterName = route.Adapter
+	}
 	if adapterName == "" {
 		adapterName = pipeline.DefaultAdapter
 	}
@@ -218,6 +524,9 @@ func runStage(
 	}
 
 	model := stage.Model
+	if model == "" {
+		model = route.Model
+	}
 	if model == "" {
 		model = pipeline.DefaultModel
 	}
@@ -231,10 +540,27 @@ func runStage(
 		return nil, stageRecord, fmt.Errorf("model not specified for stage %s", stage.Name)
 	}
 
-	prompt, err := renderPrompt(stage.Prompt, input, artifacts, stagesLegacy)
-	if err != nil {
-		return nil, stageRecord, fmt.Errorf("render prompt for stage %s: %w", stage.Name, err)
+	system := ""
+	if stage.System != "" {
+		system, err = renderTemplate(stage.System, data)
+		if err != nil {
+			return nil, stageRecord, fmt.Errorf("render system prompt for stage %s: %w", stage.Name, err)
+		}
 	}
+	if stage.OutputSchema != nil {
+		// Validate compiles the schema before any stage runs.
+		if system != "" {
+			system += "\n\n"
+		}
+		system += schemaInstruction(stage.OutputSchema.schema)
+	}
+	req := adapter.UserRequest(prompt)
+	req.System = system
+	if stage.OutputSchema != nil {
+		req.ResponseSchema = stage.OutputSchema.schema.Raw()
+	}
+	req.MaxTokens = stage.MaxTokens
+	req.Temperature = stage.Temperature
 
 	promptRef, promptSha, err := writer.WriteBlob("prompt", []byte(prompt))
 	if err != nil {
@@ -243,6 +569,7 @@ func runStage(
 
 	stageRecord.Adapter = adapterName
 	stageRecord.Model = model
+	stageRecord.System = truncateForEvidence(system, 4096)
 	stageRecord.Prompt = truncateForEvidence(prompt, 4096)
 	stageRecord.PromptRef = promptRef
 	stageRecord.PromptHash = promptSha
@@ -256,94 +583,125 @@ func runStage(
 	var lastArtifact *artifact.Artifact
 	var lastGateResults []GateResult
 	var lastApplyResult *workspace.ApplyResult
+	var lastApplyPath string
 	var lastErr error
 	state := RepairState{}
 
+	applyOpts, cleanupSnapshot, err := stageApplyOptions(env, stage)
+	if err != nil {
+		return nil, stageRecord, fmt.Errorf("stage %s: %w", stage.Name, err)
+	}
+	if cleanupSnapshot != nil {
+		defer cleanupSnapshot()
+	}
+	clone := &stageClone{env: env}
+	defer clone.cleanup()
+
+	attemptEnv := env
+	if stage.RequireApproval && stage.Apply && env.applyForReal {
+		// Attempts run in a clone; the output is applied for real once it
+		// is approved.
+		dry := *env
+		dry.applyForReal = false
+		attemptEnv = &dry
+	}
+	target := callTarget{Adapter: adapterName, Model: model}
+	esc := &escalation{env: env, stage: stage, data: data, target: &target, base: req}
+	// spent is what the stage's attempts have cost so far.
+	var spent float64
 	for attempt := 1; attempt <= attempts; attempt++ {
-		attemptStart := time.Now()
-		resp, reports, err := callAdapterWithPolicy(ctx, adapters, adapterName, model, prompt, routing, tracker)
-		if tracker != nil {
-			tracker.recordReports(reports)
+		var outcome *attemptOutcome
+		if attempt == 1 && stageSamples(stage) > 0 {
+			var records []evidence.AttemptRecord
+			outcome, records, err = drawSamples(ctx, attemptEnv, stage, target, req, applyOpts, clone)
+			stageRecord.Attempts = append(stageRecord.Attempts, records...)
+			for _, record := range records {
+				if record.Cost != nil {
+					spent += record.Cost.Amount
+				}
+			}
+		} else {
+			if prev := env.streamSink(stage.Name, attempt-1, 0); prev != nil && attempt > 1 {
+				prev.onReset()
+			}
+			outcome, err = runAttempt(ctx, attemptEnv, stage, target, req, attempt, applyOpts, clone, env.cacheFor(stage), env.streamSink(stage.Name, attempt, 0))
+			if outcome != nil {
+				stageRecord.Attempts = append(stageRecord.Attempts, outcome.record)
+				spent += outcome.cost.Amount
+			}
 		}
 		if err != nil {
-			lastErr = fmt.Errorf("stage %s adapter error: %w", stage.Name, err)
-			return nil, stageRecord, lastErr
+			return nil, stageRecord, err
 		}
-		if resp == nil || resp.Artifact == nil {
-			lastErr = fmt.Errorf("stage %s adapter returned empty response", stage.Name)
-			return nil, stageRecord, lastErr
-		}
-		art := resp.Artifact
+		art := outcome.art
+		applyErr, gateErr := outcome.applyErr, outcome.gateErr
 		lastArtifact = art
+		lastApplyResult = outcome.applyResult
+		lastApplyPath = outcome.applyPath
+		lastGateResults = outcome.gateResults
 
-		attemptPromptRef, attemptPromptSha, err := writer.WriteBlob("attempt-prompt", []byte(prompt))
-		if err != nil {
-			attemptPromptSha = hashString(prompt)
-			attemptPromptRef = ""
-		}
-		attemptOutputRef, attemptOutputSha, err := writer.WriteBlob("attempt-output", []byte(art.Content))
-		if err != nil {
-			attemptOutputSha = hashString(art.Content)
-			attemptOutputRef = ""
-		}
-
-		applyResult, applyWorkspacePath, applyMode, cleanup, applyErr := applyIfNeeded(stage, workspacePath, art, applyForReal, applyApproved)
-		if cleanup != nil {
-			defer cleanup()
-		}
-		lastApplyResult = applyResult
-
-		gateResults, gateErr := evaluateGates(ctx, stage, pipeline, art, applyWorkspacePath, applyApproved)
-		lastGateResults = gateResults
-
-		succeeded := applyErr == nil && gateErr == nil
-		attemptRecord := evidence.AttemptRecord{
-			Attempt:        attempt,
-			PromptHash:     attemptPromptSha,
-			PromptRef:      attemptPromptRef,
-			OutputRef:      attemptOutputRef,
-			OutputHash:     attemptOutputSha,
-			OutputLen:      len(art.Content),
-			WorkspaceUsed:  applyWorkspacePath,
-			WorkspaceMode:  applyMode,
-			GateResults:    evidenceGateRecords(gateResults),
-			Succeeded:      succeeded,
-			DurationMillis: time.Since(attemptStart).Milliseconds(),
-		}
-		if applyErr != nil {
-			attemptRecord.ApplyError = applyErr.Error()
-		}
-		stageRecord.Attempts = append(stageRecord.Attempts, attemptRecord)
-
-		if succeeded {
+		if outcome.succeeded() {
 			lastErr = nil
 			break
 		}
 
-		failureResult := consolidateGateFailures(gateResults, applyErr)
+		failureResult := consolidateGateFailures(outcome.gateResults, applyErr)
 		outputHash := art.Hash
 		if outputHash == "" {
 			outputHash = hashString(art.Content)
 		}
 		fingerprint := fingerprintViolations(failureResult.Violations, applyErr)
+		_, score := sampleScore(env, outcome)
 		state.Attempts = append(state.Attempts, AttemptState{
-			PromptHash:           attemptPromptSha,
+			PromptHash:           outcome.record.PromptHash,
 			OutputHash:           outputHash,
 			ViolationFingerprint: fingerprint,
+			Score:                score,
 		})
 
-		if len(state.Attempts) >= 2 {
+		// escalate_on policies act first; the built-in loop handling only
+		// sees attempts no policy acted on.
+		acted := false
+		if attempt < attempts && len(stage.EscalateOn) > 0 {
+			esc.req, esc.replaced = req, false
+			events, err := esc.applyPolicies(escalationCheck{attempt: attempt, state: &state, failure: failureResult, spent: spent}, art)
+			stageRecord.Escalations = append(stageRecord.Escalations, events...)
+			if err != nil {
+				return nil, stageRecord, err
+			}
+			req = esc.req
+			if esc.replaced {
+				continue
+			}
+			acted = len(events) > 0
+		}
+
+		if !acted && len(state.Attempts) >= 2 {
 			prev := state.Attempts[len(state.Attempts)-2]
 			if fingerprint == prev.ViolationFingerprint && outputHash == prev.OutputHash {
-				if !state.Escalated {
+				event := evidence.EscalationRecord{
+					Attempt: attempt,
+					Trigger: escalateTriggerRepeatOutput,
+					Reason:  fmt.Sprintf("attempt %d repeated attempt %d", attempt, attempt-1),
+				}
+				if state.Escalated {
+					event.Action = escalateActionAbort
+					stageRecord.Escalations = append(stageRecord.Escalations, event)
+					return nil, stageRecord, fmt.Errorf("repair loop detected for stage %s: fingerprint=%s outputHash=%s promptRef=%s outputRef=%s", stage.Name, fingerprint, outputHash, outcome.record.PromptRef, outcome.record.OutputRef)
+				}
+				// Escalating on the last attempt would leave nothing to
+				// escalate to.
+				if attempt < attempts {
 					state.Escalated = true
-					if stage.FallbackModel != "" {
-						model = stage.FallbackModel
-					}
-					prompt = repair.GenerateEscalationPrompt(art, failureResult, stage.Apply)
+					esc.req = req
+					esc.escalate(failureResult, art)
+					req = esc.req
+					event.Action = escalateActionEscalate
+					event.Adapter = target.Adapter
+					event.Model = target.Model
+					stageRecord.Escalations = append(stageRecord.Escalations, event)
 					continue
 				}
-				return nil, stageRecord, fmt.Errorf("repair loop detected for stage %s: fingerprint=%s outputHash=%s promptRef=%s outputRef=%s", stage.Name, fingerprint, outputHash, attemptPromptRef, attemptOutputRef)
 			}
 		}
 
@@ -358,9 +716,11 @@ func runStage(
 			break
 		}
 
-		prompt = repair.GenerateRepairPrompt(art, failureResult)
+		req = req.Append(
+			adapter.Message{Role: adapter.RoleAssistant, Content: art.Content},
+			adapter.Message{Role: adapter.RoleUser, Content: repair.GenerateRepairFeedback(failureResult)},
+		)
 	}
-
 	if lastErr != nil {
 		return nil, stageRecord, lastErr
 	}
@@ -382,11 +742,37 @@ func runStage(
 		"text": output,
 		"hash": lastArtifact.Hash,
 	}
-	if lastApplyResult != nil {
-		stageRecord.ApplyResult = &evidence.ApplyRecord{
-			AppliedFiles:    lastApplyResult.AppliedFiles,
-			DeletedFiles:    lastApplyResult.DeletedFiles,
-			UsedUnifiedDiff: lastApplyResult.UsedUnifiedDiff,
+	stageRecord.ApplyResult = applyRecord(lastApplyResult, applyOpts.Mode)
+	if stage.RequireApproval {
+		decision, err := awaitApproval(ctx, env, stage.Name, "", []evidence.ApprovalSubject{approvalSubject(stage.Name, stageRecord)})
+		stageRecord.Approval = decision
+		if err != nil {
+			return nil, stageRecord, err
+		}
+		if attemptEnv != env && stage.Apply {
+			result, path, mode, journal, err := applyAttempt(env, stage, lastArtifact, applyOpts, clone)
+			last := &stageRecord.Attempts[len(stageRecord.Attempts)-1]
+			last.WorkspaceUsed = path
+			last.WorkspaceMode = mode
+			last.ApplyJournal = journal
+			if err != nil {
+				last.ApplyError = err.Error()
+				last.ApplyRejects = evidenceApplyRejects(err)
+				last.Succeeded = false
+				return nil, stageRecord, fmt.Errorf("stage %s: apply approved output: %w", stage.Name, err)
+			}
+			lastApplyResult = result
+			lastApplyPath = path
+			stageRecord.ApplyResult = applyRecord(lastApplyResult, applyOpts.Mode)
+		}
+	}
+	var gitFiles []workspace.GitFile
+	if env.git != nil && lastApplyResult != nil && len(lastApplyResult.Changes) > 0 {
+		// Store the applied files before a temporary worktree is removed; the
+		// commit is made once the stage record is written.
+		gitFiles, err = env.git.StoreFiles(lastApplyPath, lastApplyResult.Changes)
+		if err != nil {
+			return nil, stageRecord, fmt.Errorf("stage %s: %w", stage.Name, err)
 		}
 	}
 	stageRecord.DurationMillis = time.Since(start).Milliseconds()
@@ -397,73 +783,349 @@ func runStage(
 		GateResults: lastGateResults,
 		ApplyResult: lastApplyResult,
 		Duration:    time.Since(start),
+		gitFiles:    gitFiles,
 	}, stageRecord, nil
 }
 
-func applyIfNeeded(stage *Stage, workspacePath string, art *artifact.Artifact, applyForReal bool, applyApproved bool) (*workspace.ApplyResult, string, string, func() error, error) {
+// attemptOutcome is one call to a stage's adapter with the apply and gates
+// that followed it.
+type attemptOutcome struct {
+	art         *artifact.Artifact
+	applyResult *workspace.ApplyResult
+	applyPath   string
+	applyErr    error
+	gateResults []GateResult
+	gateErr     error
+	cost        adapter.Cost
+	record      evidence.AttemptRecord
+}
+
+func (o *attemptOutcome) succeeded() bool {
+	return o.applyErr == nil && o.gateErr == nil
+}
+
+// runAttempt calls the adapter with req, applies the output when the stage
+// applies, and evaluates the stage's gates. Only a failed adapter call is
+// returned as an error.
+func runAttempt(
+	ctx context.Context,
+	env *stageEnv,
+	stage *Stage,
+	target callTarget,
+	req adapter.Request,
+	attempt int,
+	opts workspace.ApplyOptions,
+	clone *stageClone,
+	responses *cache.Cache,
+	stream *streamSink,
+) (*attemptOutcome, error) {
+	writer := env.writer
+	attemptStart := time.Now()
+	resp, reports, err := callAdapterWithPolicy(ctx, env.adapters, target.Adapter, target.Model, req, env.routing, env.tracker, responses, stream)
+	if env.tracker != nil {
+		env.tracker.recordReports(reports)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stage %s adapter error: %w", stage.Name, err)
+	}
+	if resp == nil || resp.Artifact == nil {
+		return nil, fmt.Errorf("stage %s adapter returned empty response", stage.Name)
+	}
+	art := resp.Artifact
+	cost := adapter.Cost{Currency: "USD"}
+	for _, report := range reports {
+		if report.Error == "" {
+			cost.Amount += report.Cost.Amount
+			cost.IsEstimate = cost.IsEstimate || report.Cost.IsEstimate
+		}
+	}
+
+	transcript := req.Flatten()
+	attemptPromptRef, attemptPromptSha, err := writer.WriteBlob("attempt-prompt", []byte(transcript))
+	if err != nil {
+		attemptPromptSha = hashString(transcript)
+		attemptPromptRef = ""
+	}
+	attemptOutputRef, attemptOutputSha, err := writer.WriteBlob("attempt-output", []byte(art.Content))
+	if err != nil {
+		attemptOutputSha = hashString(art.Content)
+		attemptOutputRef = ""
+	}
+
+	applyResult, applyWorkspacePath, applyMode, journal, applyErr := applyAttempt(env, stage, art, opts, clone)
+	gateResults, gateErr := evaluateGates(ctx, env, stage, art, applyWorkspacePath)
+
+	outcome := &attemptOutcome{
+		art:         art,
+		applyResult: applyResult,
+		applyPath:   applyWorkspacePath,
+		applyErr:    applyErr,
+		gateResults: gateResults,
+		gateErr:     gateErr,
+		cost:        cost,
+	}
+	outcome.record = evidence.AttemptRecord{
+		Attempt:        attempt,
+		PromptHash:     attemptPromptSha,
+		PromptRef:      attemptPromptRef,
+		OutputRef:      attemptOutputRef,
+		OutputHash:     attemptOutputSha,
+		OutputLen:      len(art.Content),
+		WorkspaceUsed:  applyWorkspacePath,
+		WorkspaceMode:  applyMode,
+		GateResults:    evidenceGateRecords(gateResults),
+		ApplyJournal:   journal,
+		Succeeded:      outcome.succeeded(),
+		DurationMillis: time.Since(attemptStart).Milliseconds(),
+	}
+	if applyErr != nil {
+		outcome.record.ApplyError = applyErr.Error()
+		outcome.record.ApplyRejects = evidenceApplyRejects(applyErr)
+	}
+	return outcome, nil
+}
+
+// applyAttempt applies an attempt's output. Real applies are journaled so
+// that the run can be rolled back.
+func applyAttempt(env *stageEnv, stage *Stage, art *artifact.Artifact, opts workspace.ApplyOptions, clone *stageClone) (*workspace.ApplyResult, string, string, *evidence.ApplyJournal, error) {
+	var journal *evidence.ApplyJournal
+	if env.applyForReal {
+		opts.Journal = func(changes []workspace.FileChange) error {
+			var err error
+			journal, err = writeApplyJournal(env.writer, changes)
+			return err
+		}
+	}
+	result, path, mode, err := applyIfNeeded(env, stage, art, opts, clone)
+	if err != nil {
+		// A failed apply leaves the workspace unchanged.
+		journal = nil
+	}
+	return result, path, mode, journal, err
+}
+
+// applyRecord summarizes an apply result for the stage record.
+func applyRecord(result *workspace.ApplyResult, mode string) *evidence.ApplyRecord {
+	if result == nil {
+		return nil
+	}
+	record := &evidence.ApplyRecord{
+		AppliedFiles:    result.AppliedFiles,
+		DeletedFiles:    result.DeletedFiles,
+		UsedUnifiedDiff: result.UsedUnifiedDiff,
+		Mode:            mode,
+	}
+	for _, adj := range result.Adjustments {
+		record.Adjustments = append(record.Adjustments, evidence.ApplyAdjustment{
+			File:   adj.File,
+			Hunk:   adj.Hunk,
+			Offset: adj.Offset,
+			Fuzz:   adj.Fuzz,
+			Merged: adj.Merged,
+		})
+	}
+	return record
+}
+
+// cacheFor returns the response cache for a stage, or nil when the stage
+// opts out with cache: false.
+func (env *stageEnv) cacheFor(stage *Stage) *cache.Cache {
+	if stage.Cache != nil && !*stage.Cache {
+		return nil
+	}
+	return env.cache
+}
+
+// judgeFunc returns the call used by a judge gate. The call goes through the
+// same retry, fallback, cache and budget policy as stage calls and is
+// recorded in the run's cost report.
+func (env *stageEnv) judgeFunc(stage *Stage, def GateDefinition) gate.JudgeFunc {
+	return func(ctx context.Context, req adapter.Request) (string, error) {
+		adapterName := def.Adapter
+		if adapterName == "" {
+			adapterName = env.pipeline.DefaultAdapter
+		}
+		if adapterName == "" {
+			adapterName = pickSingleAdapter(env.adapters)
+		}
+		adapterImpl, ok := env.adapters[adapterName]
+		if !ok {
+			return "", fmt.Errorf("adapter %s not found", adapterName)
+		}
+		model := def.Model
+		if model == "" {
+			model = env.pipeline.DefaultModel
+		}
+		if model == "" {
+			if models := adapterImpl.Models(); len(models) > 0 {
+				model = models[0]
+			}
+		}
+		if model == "" {
+			return "", fmt.Errorf("model not specified for judge")
+		}
+
+		resp, reports, err := callAdapterWithPolicy(ctx, env.adapters, adapterName, model, req, env.routing, env.tracker, env.cacheFor(stage), nil)
+		env.tracker.recordReports(reports)
+		if err != nil {
+			return "", err
+		}
+		if resp == nil || resp.Artifact == nil {
+			return "", fmt.Errorf("judge adapter returned empty response")
+		}
+		return resp.Artifact.Content, nil
+	}
+}
+
+// streamSink returns the stream receiver for a stage attempt, or nil when the
+// caller did not ask for streaming. sample is 0 outside of sampling.
+func (env *stageEnv) streamSink(stageName string, attempt, sample int) *streamSink {
+	if env.onStream == nil {
+		return nil
+	}
+	return &streamSink{
+		onDelta: func(delta string) {
+			env.onStream(StreamEvent{Stage: stageName, Attempt: attempt, Sample: sample, Delta: delta})
+		},
+		onReset: func() {
+			env.onStream(StreamEvent{Stage: stageName, Attempt: attempt, Sample: sample, Reset: true})
+		},
+	}
+}
+
+// cloneWorkspace returns a temporary copy of the workspace made with the
+// manifest's clone strategy. In git mode it is a worktree of the run branch,
+// so ignored files are left out and earlier applied stages are included.
+func (env *stageEnv) cloneWorkspace() (*workspace.Clone, error) {
+	if env.git != nil {
+		return env.git.Worktree()
+	}
+	return workspace.NewClone(env.workspacePath, env.pipeline.Workspace.Clone)
+}
+
+// stageClone is the temporary workspace a stage's dry-run attempts apply to.
+// It is made on first use and reset to the workspace's state before each
+// later attempt instead of being cloned again.
+type stageClone struct {
+	env   *stageEnv
+	clone *workspace.Clone
+}
+
+func (c *stageClone) prepare() (string, error) {
+	if c.clone == nil {
+		clone, err := c.env.cloneWorkspace()
+		if err != nil {
+			return "", err
+		}
+		c.clone = clone
+		return clone.Dir, nil
+	}
+	if err := c.clone.Reset(); err != nil {
+		return "", fmt.Errorf("reset workspace clone: %w", err)
+	}
+	return c.clone.Dir, nil
+}
+
+func (c *stageClone) cleanup() {
+	if c.clone != nil {
+		c.clone.Cleanup()
+	}
+}
+
+// stageApplyOptions returns how a stage's output is applied. Merge mode
+// snapshots the workspace first, since that is what the model sees; the
+// returned cleanup removes the snapshot.
+func stageApplyOptions(env *stageEnv, stage *Stage) (workspace.ApplyOptions, func() error, error) {
+	opts := workspace.ApplyOptions{Mode: stage.ApplyMode, Fuzz: workspace.DefaultFuzz}
+	if opts.Mode == "" {
+		opts.Mode = workspace.ApplyFuzzy
+	}
+	if stage.ApplyFuzz != nil {
+		opts.Fuzz = *stage.ApplyFuzz
+	}
+	if !stage.Apply || opts.Mode != workspace.ApplyMerge {
+		return opts, nil, nil
+	}
+	snapshot, err := env.cloneWorkspace()
+	if err != nil {
+		return opts, nil, fmt.Errorf("snapshot workspace: %w", err)
+	}
+	opts.Base = snapshot.Dir
+	return opts, snapshot.Cleanup, nil
+}
+
+func applyIfNeeded(env *stageEnv, stage *Stage, art *artifact.Artifact, opts workspace.ApplyOptions, clone *stageClone) (*workspace.ApplyResult, string, string, error) {
+	workspacePath := env.workspacePath
 	if !stage.Apply {
-		return nil, workspacePath, "real", nil, nil
+		return nil, workspacePath, "real", nil
 	}
-	if applyForReal && !applyApproved {
-		return nil, workspacePath, "real", nil, fmt.Errorf("apply for real requires explicit approval")
+	if env.applyForReal && !env.applyApproved {
+		return nil, workspacePath, "real", fmt.Errorf("apply for real requires explicit approval")
 	}
 	applyPath := workspacePath
 	mode := "real"
-	var cleanup func() error
-	if !applyForReal {
-		tempDir, tempCleanup, err := workspace.CloneToTemp(workspacePath)
+	if !env.applyForReal {
+		tempDir, err := clone.prepare()
 		if err != nil {
-			return nil, workspacePath, "temp", nil, err
+			return nil, workspacePath, "temp", err
 		}
 		applyPath = tempDir
 		mode = "temp"
-		cleanup = tempCleanup
 	}
-	result, err := workspace.ApplyOutput(applyPath, art.Content)
+	result, err := workspace.ApplyOutputWithOptions(applyPath, art.Content, opts)
 	if err != nil {
-		return nil, applyPath, mode, cleanup, err
+		return nil, applyPath, mode, err
 	}
-	return result, applyPath, mode, cleanup, nil
+	return result, applyPath, mode, nil
 }
 
-func evaluateGates(ctx context.Context, stage *Stage, pipeline *Pipeline, art *artifact.Artifact, workspacePath string, applyApproved bool) ([]GateResult, error) {
-	if len(stage.Gates) == 0 {
+func evaluateGates(ctx context.Context, env *stageEnv, stage *Stage, art *artifact.Artifact, workspacePath string) ([]GateResult, error) {
+	if len(stage.Gates) == 0 && stage.OutputSchema == nil {
 		return nil, nil
 	}
 
-	gateInstances, err := buildGateInstances(pipeline, stage.Gates, workspacePath, applyApproved)
-	if err != nil {
-		return nil, err
-	}
-
+	evaluator := &gateEvaluator{env: env, stage: stage, art: art, workspacePath: workspacePath}
 	var results []GateResult
-	for _, gateInstance := range gateInstances {
+	if stage.OutputSchema != nil {
+		// The schema check is cheap and later gates usually assume valid
+		// output, so it runs first.
 		start := time.Now()
-		res, err := gateInstance.Evaluate(ctx, art)
+		res, err := gate.NewJSONSchemaGate(outputSchemaGate, stage.OutputSchema.schema).Evaluate(ctx, art)
 		results = append(results, GateResult{
-			Name:     gateInstance.Name(),
+			Name:     outputSchemaGate,
 			Result:   res,
 			Error:    err,
+			Severity: gateSeverityBlock,
 			Duration: time.Since(start),
 		})
 		if err != nil {
-			return results, fmt.Errorf("gate %s error: %w", gateInstance.Name(), err)
+			return results, fmt.Errorf("gate %s error: %w", outputSchemaGate, err)
 		}
-		if res != nil && !res.Passed {
-			return results, fmt.Errorf("gate %s failed", gateInstance.Name())
+		if !res.Passed {
+			for _, name := range stage.Gates {
+				results = append(results, evaluator.skipped(name, fmt.Sprintf("gate %s failed", outputSchemaGate)))
+			}
+			return results, fmt.Errorf("gate %s failed", outputSchemaGate)
 		}
 	}
 
-	return results, nil
+	return evaluator.evaluateAll(ctx, stage.Gates, results)
 }
 
-func buildGateInstances(pipeline *Pipeline, gateNames []string, workspacePath string, applyApproved bool) ([]gate.Gate, error) {
+func buildGateInstances(env *stageEnv, stage *Stage, gateNames []string, workspacePath string) ([]gate.Gate, error) {
+	pipeline := env.pipeline
 	instances := make([]gate.Gate, 0, len(gateNames))
 	for _, name := range gateNames {
 		if name == "hollowcheck" {
 			instances = append(instances, gate.NewHollowCheckGate("", ""))
 			continue
 		}
+		if name == "stubcheck" {
+			if _, ok := pipeline.Gates[name]; !ok {
+				instances = append(instances, gate.NewStubCheckGate(name, workspacePath))
+				continue
+			}
+		}
 
 		def, ok := pipeline.Gates[name]
 		if !ok {
@@ -472,6 +1134,8 @@ func buildGateInstances(pipeline *Pipeline, gateNames []string, workspacePath st
 		switch strings.ToLower(def.Type) {
 		case "hollowcheck":
 			instances = append(instances, gate.NewHollowCheckGate(def.BinaryPath, def.ContractPath))
+		case "stubcheck":
+			instances = append(instances, gate.NewStubCheckGate(name, workspacePath))
 		case "command":
 			workdir := def.Workdir
 			if workdir == "" {
@@ -490,7 +1154,11 @@ func buildGateInstances(pipeline *Pipeline, gateNames []string, workspacePath st
 			var templates []gate.CommandTemplate
 			var allowed []string
 			if def.Capability != "" {
-				resolved, ok := gate.TemplatesForCapability(def.Capability)
+				capabilities := env.capabilities
+				if capabilities == nil {
+					capabilities = gate.DefaultCapabilities()
+				}
+				resolved, ok := capabilities.TemplatesFor(def.Capability)
 				if !ok {
 					return nil, fmt.Errorf("unknown capability %s", def.Capability)
 				}
@@ -514,11 +1182,29 @@ func buildGateInstances(pipeline *Pipeline, gateNames []string, workspacePath st
 				}
 			}
 
-			g, err := gate.NewCommandGate(name, def.Command, workdir, allowed, denyShell, workspacePath, templates, policyMode, capability, applyApproved)
+			g, err := gate.NewCommandGate(name, def.Command, workdir, allowed, denyShell, workspacePath, templates, policyMode, capability, env.applyApproved)
 			if err != nil {
 				return nil, err
 			}
+			if def.Sandbox.enabled() {
+				g.SetSandbox(def.Sandbox.config())
+			}
+			if def.Parser != "" {
+				if err := g.SetParser(def.parserConfig()); err != nil {
+					return nil, fmt.Errorf("gate %s: %w", name, err)
+				}
+			}
 			instances = append(instances, g)
+		case "json_schema":
+			if def.Schema == nil || def.Schema.schema == nil {
+				return nil, fmt.Errorf("gate %s has no compiled schema", name)
+			}
+			instances = append(instances, gate.NewJSONSchemaGate(name, def.Schema.schema))
+		case "judge":
+			if def.rubric == nil {
+				return nil, fmt.Errorf("gate %s has no loaded rubric", name)
+			}
+			instances = append(instances, gate.NewJudgeGate(name, def.rubric, env.judgeFunc(stage, def)))
 		default:
 			return nil, fmt.Errorf("unsupported gate type %s", def.Type)
 		}
@@ -528,6 +1214,14 @@ func buildGateInstances(pipeline *Pipeline, gateNames []string, workspacePath st
 }
 
 func consolidateGateFailures(results []GateResult, applyErr error) *gate.GateResult {
+	var rejected *workspace.ApplyError
+	if errors.As(applyErr, &rejected) {
+		violations := make([]gate.Violation, 0, len(rejected.Rejects))
+		for _, r := range rejected.Rejects {
+			violations = append(violations, applyRejectViolation(r))
+		}
+		return gate.NewFailingResult(100, violations, nil)
+	}
 	if applyErr != nil {
 		return gate.NewFailingResult(100, []gate.Violation{
 			{
@@ -538,10 +1232,25 @@ func consolidateGateFailures(results []GateResult, applyErr error) *gate.GateRes
 		}, nil)
 	}
 
-	var violations []gate.Violation
+	var violations, warnings []gate.Violation
 	var hints []string
 	for _, result := range results {
-		if result.Result == nil || result.Result.Passed {
+		if result.Skipped || result.Result == nil {
+			continue
+		}
+		if result.Severity == gateSeverityWarn {
+			if !result.Result.Passed {
+				warnings = append(warnings, memberViolations(result)...)
+			}
+			continue
+		}
+		if result.Result.Passed {
+			// Passing groups can still carry warnings from advisory members.
+			for _, v := range result.Result.Violations {
+				if v.Severity == "warning" {
+					warnings = append(warnings, v)
+				}
+			}
 			continue
 		}
 		violations = append(violations, result.Result.Violations...)
@@ -556,11 +1265,73 @@ func consolidateGateFailures(results []GateResult, applyErr error) *gate.GateRes
 		})
 	}
 
-	return gate.NewFailingResult(100, violations, hints)
+	return gate.NewFailingResult(100, append(violations, warnings...), hints)
+}
+
+// applyRejectViolation turns a rejected hunk into a violation located at the
+// file and line, so the repair prompt can ask for just that hunk again.
+func applyRejectViolation(r workspace.HunkReject) gate.Violation {
+	location := r.File
+	if r.Line > 0 {
+		location = fmt.Sprintf("%s:%d", r.File, r.Line)
+	}
+	what := "file"
+	if r.Hunk > 0 {
+		what = fmt.Sprintf("hunk %d", r.Hunk)
+	}
+	return gate.Violation{
+		Rule:       "apply_failed",
+		Severity:   "error",
+		Message:    fmt.Sprintf("%s of %s did not apply: %s", what, r.File, r.Reason),
+		Location:   location,
+		Suggestion: fmt.Sprintf("Regenerate only this %s of %s against the current file contents.", what, r.File),
+	}
+}
+
+// writeApplyJournal stores the previous contents of files about to be
+// changed in the real workspace, so the apply can be rolled back.
+func writeApplyJournal(writer *evidence.Writer, changes []workspace.FileChange) (*evidence.ApplyJournal, error) {
+	journal := &evidence.ApplyJournal{AppliedAt: time.Now().UTC()}
+	for _, c := range changes {
+		record := evidence.FileChange{
+			Path:    c.Path,
+			Existed: c.Existed,
+			OldMode: c.OldMode,
+			OldHash: c.OldHash,
+			Deleted: c.Deleted,
+			NewMode: c.NewMode,
+			NewHash: c.NewHash,
+		}
+		if c.Existed {
+			ref, _, err := writer.WriteBlob("apply-original", c.OldContent)
+			if err != nil {
+				return nil, err
+			}
+			record.OldRef = ref
+		}
+		journal.Changes = append(journal.Changes, record)
+	}
+	return journal, nil
+}
+
+func evidenceApplyRejects(applyErr error) []evidence.ApplyReject {
+	var rejected *workspace.ApplyError
+	if !errors.As(applyErr, &rejected) {
+		return nil
+	}
+	records := make([]evidence.ApplyReject, 0, len(rejected.Rejects))
+	for _, r := range rejected.Rejects {
+		records = append(records, evidence.ApplyReject{File: r.File, Hunk: r.Hunk, Line: r.Line, Reason: r.Reason})
+	}
+	return records
 }
 
 func renderPrompt(prompt string, input string, artifacts map[string]ArtifactTemplateData, stages map[string]map[string]string) (string, error) {
-	data := map[string]any{
+	return renderTemplate(prompt, promptData(input, artifacts, stages))
+}
+
+func promptData(input string, artifacts map[string]ArtifactTemplateData, stages map[string]map[string]string) map[string]any {
+	return map[string]any{
 		"Input":     input,
 		"input":     input,
 		"Artifacts": artifacts,
@@ -568,7 +1339,9 @@ func renderPrompt(prompt string, input string, artifacts map[string]ArtifactTemp
 		"Stages":    stages,
 		"stages":    stages,
 	}
+}
 
+func renderTemplate(prompt string, data map[string]any) (string, error) {
 	tmpl, err := template.New("prompt").Parse(prompt)
 	if err != nil {
 		return "", err
@@ -605,6 +1378,9 @@ func prepareEvidenceWriter(baseDir, workspacePath string) (*evidence.Writer, err
 
 func writeGateLogs(writer *evidence.Writer, stageName string, results []GateResult) error {
 	for _, result := range results {
+		if err := writeGateLogs(writer, stageName, result.Members); err != nil {
+			return err
+		}
 		if result.Result == nil || result.Result.Kind != "command" || len(result.Result.Diagnostics) == 0 {
 			continue
 		}
@@ -632,6 +1408,10 @@ func evidenceGateRecords(results []GateResult) []evidence.GateRecord {
 	for _, result := range results {
 		record := evidence.GateRecord{
 			Name:           result.Name,
+			Severity:       result.Severity,
+			Skipped:        result.Skipped,
+			SkipReason:     result.SkipReason,
+			Members:        evidenceGateRecords(result.Members),
 			DurationMillis: result.Duration.Milliseconds(),
 		}
 		if result.Error != nil {
@@ -663,6 +1443,8 @@ type ArtifactTemplateData struct {
 	Text   string
 	Output string
 	Hash   string
+	// JSON holds the parsed output of stages with an output_schema.
+	JSON any
 }
 
 func hashString(value string) string {
@@ -708,6 +1490,9 @@ func buildRoutingFeedback(stageRecords map[string]*evidence.StageRecord, input s
 	for _, record := range stageRecords {
 		feedback.AttemptsNeeded += len(record.Attempts)
 		for _, gateResult := range record.GateResults {
+			if gateResult.Skipped || gateResult.Severity == gateSeverityWarn {
+				continue
+			}
 			if !gateResult.Passed || gateResult.Error != "" {
 				feedback.GatesPassed = false
 				break
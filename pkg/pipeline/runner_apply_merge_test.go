@@ -0,0 +1,114 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+)
+
+func TestApplyRejectsBecomeLocatedViolations(t *testing.T) {
+	workspacePath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspacePath, "notes.txt"), []byte("header\nalpha\nbeta\ngamma\n"), 0644); err != nil {
+		t.Fatalf("write workspace: %v", err)
+	}
+
+	chat := &chatRecorder{outputs: []string{
+		"--- a/notes.txt\n+++ b/notes.txt\n@@ -2,2 +2,2 @@\n alpha\n-delta\n+DELTA\n",
+		// Shifted by one line: fuzzy apply still finds it.
+		"--- a/notes.txt\n+++ b/notes.txt\n@@ -1,2 +1,2 @@\n alpha\n-beta\n+BETA\n",
+	}}
+	p := &Pipeline{
+		Name: "apply-rejects",
+		Stages: []*Stage{
+			{Name: "edit", Prompt: "edit", Adapter: "chat", Model: "mock-1", Apply: true, MaxRetries: 1},
+		},
+		Adapters: map[string]adapter.Adapter{"chat": chat},
+	}
+
+	base := t.TempDir()
+	if _, err := Run(context.Background(), p, RunOptions{Input: "input", WorkspacePath: workspacePath, EvidenceDir: base}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if len(chat.requests) != 2 {
+		t.Fatalf("expected a repair attempt, got %d requests", len(chat.requests))
+	}
+	feedback := chat.requests[1].Messages[len(chat.requests[1].Messages)-1].Content
+	for _, want := range []string{"hunk 1 of notes.txt did not apply", "Location: notes.txt:2", `expected "delta", found "beta"`} {
+		if !strings.Contains(feedback, want) {
+			t.Fatalf("repair feedback missing %q:\n%s", want, feedback)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(onlyRunDir(t, base), "stages", "edit.json"))
+	if err != nil {
+		t.Fatalf("read stage record: %v", err)
+	}
+	var record evidence.StageRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("unmarshal stage record: %v", err)
+	}
+	rejects := record.Attempts[0].ApplyRejects
+	if len(rejects) != 1 || rejects[0].File != "notes.txt" || rejects[0].Hunk != 1 || rejects[0].Line != 2 {
+		t.Fatalf("unexpected rejects: %+v", rejects)
+	}
+	apply := record.ApplyResult
+	if apply == nil || apply.Mode != "fuzzy" || len(apply.Adjustments) != 1 || apply.Adjustments[0].Offset != 1 {
+		t.Fatalf("unexpected apply record: %+v", apply)
+	}
+}
+
+func TestApplyMergeUsesStageSnapshot(t *testing.T) {
+	if _, err := exec.LookPath("grep"); err != nil {
+		t.Skip("grep not available")
+	}
+
+	workspacePath := t.TempDir()
+	path := filepath.Join(workspacePath, "notes.txt")
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree\nfour\n"), 0644); err != nil {
+		t.Fatalf("write workspace: %v", err)
+	}
+
+	// The first attempt lands in the real workspace and changes line 1; the
+	// repair is still written against the snapshot the model saw.
+	chat := &chatRecorder{outputs: []string{
+		"--- a/notes.txt\n+++ b/notes.txt\n@@ -1,2 +1,2 @@\n-one\n+ONE\n two\n",
+		"--- a/notes.txt\n+++ b/notes.txt\n@@ -1,4 +1,4 @@\n one\n two\n three\n-four\n+FOUR\n",
+	}}
+	p := &Pipeline{
+		Name: "apply-merge",
+		Gates: map[string]GateDefinition{
+			"four": {Type: "command", Command: []string{"grep", "-q", "FOUR", "notes.txt"}},
+		},
+		Stages: []*Stage{
+			{Name: "edit", Prompt: "edit", Adapter: "chat", Model: "mock-1", Apply: true, ApplyMode: "merge", Gates: []string{"four"}, MaxRetries: 1},
+		},
+		Adapters: map[string]adapter.Adapter{"chat": chat},
+	}
+
+	_, err := Run(context.Background(), p, RunOptions{
+		Input:         "input",
+		WorkspacePath: workspacePath,
+		EvidenceDir:   t.TempDir(),
+		ApplyForReal:  true,
+		ApplyApproved: true,
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read workspace: %v", err)
+	}
+	if string(data) != "ONE\ntwo\nthree\nFOUR\n" {
+		t.Fatalf("unexpected merged content: %q", string(data))
+	}
+}
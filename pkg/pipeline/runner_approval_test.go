@@ -0,0 +1,173 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/attest"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+)
+
+func TestApprovalStageWaitsForRecordedDecision(t *testing.T) {
+	defer func(interval time.Duration) { approvalPollInterval = interval }(approvalPollInterval)
+	approvalPollInterval = 10 * time.Millisecond
+
+	script := &scriptAdapter{respond: func(_, prompt string) (string, error) {
+		return "echo " + prompt, nil
+	}}
+	p := &Pipeline{
+		Name: "reviewed",
+		Stages: []*Stage{
+			{Name: "plan", Prompt: "plan"},
+			{Name: "review", Type: stageTypeApproval, Prompt: "Is this plan safe?", DependsOn: []string{"plan"}},
+			{Name: "code", Prompt: "code with {{ .Artifacts.review.Text }}"},
+		},
+		Adapters: map[string]adapter.Adapter{"script": script},
+	}
+	base := t.TempDir()
+	done := make(chan error, 1)
+	go func() {
+		_, err := Run(context.Background(), p, RunOptions{Input: "input", EvidenceDir: base})
+		done <- err
+	}()
+
+	var runDir string
+	var req *evidence.ApprovalRequest
+	for deadline := time.Now().Add(5 * time.Second); req == nil; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("run never asked for approval")
+		}
+		matches, _ := filepath.Glob(filepath.Join(base, "*", "approvals", "review.request.json"))
+		if len(matches) == 0 {
+			continue
+		}
+		runDir = filepath.Dir(filepath.Dir(matches[0]))
+		requests, err := evidence.ReadApprovalRequests(runDir)
+		if err != nil {
+			t.Fatalf("read approval requests: %v", err)
+		}
+		req = requests["review"]
+	}
+	if req.Message != "Is this plan safe?" || len(req.Subjects) != 1 || req.Subjects[0].Stage != "plan" || req.Subjects[0].Output != "echo plan" {
+		t.Fatalf("unexpected approval request: %+v", req)
+	}
+
+	// A decision on a different request is ignored.
+	stale := evidence.ApprovalRecord{Decision: evidence.ApprovalRejected, Approver: "bob", RequestHash: "stale"}
+	if err := evidence.WriteApproval(runDir, "review", stale); err != nil {
+		t.Fatalf("write stale decision: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	decision := evidence.ApprovalRecord{
+		Decision:    evidence.ApprovalApproved,
+		Approver:    "alice",
+		Timestamp:   time.Now().UTC(),
+		Comment:     "ship it",
+		Source:      "cli",
+		RequestHash: req.Hash,
+	}
+	if err := evidence.WriteApproval(runDir, "review", decision); err != nil {
+		t.Fatalf("write decision: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if last := script.prompts[len(script.prompts)-1]; last != "code with ship it" {
+		t.Fatalf("expected the comment to be the approval stage's output, got %q", last)
+	}
+	record := readStageRecord(t, runDir, "review")
+	if record.Approval == nil || record.Approval.Approver != "alice" || record.Approval.Decision != evidence.ApprovalApproved || record.Approval.RequestHash != req.Hash {
+		t.Fatalf("expected the decision in the stage record: %+v", record.Approval)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(runDir, "approvals")); len(entries) != 0 {
+		t.Fatalf("expected approval files to be removed once decided, got %d", len(entries))
+	}
+
+	att, err := attest.BuildAttestation(runDir, "review")
+	if err != nil {
+		t.Fatalf("build attestation: %v", err)
+	}
+	if !att.Claim.Passed || att.Claim.Approval == nil || att.Claim.Approval.Approver != "alice" || att.Claim.Approval.Comment != "ship it" {
+		t.Fatalf("expected the approval in the attestation: %+v", att.Claim)
+	}
+}
+
+func TestRequireApprovalHoldsRealApply(t *testing.T) {
+	for _, decision := range []string{evidence.ApprovalRejected, evidence.ApprovalApproved} {
+		t.Run(decision, func(t *testing.T) {
+			realWorkspace := t.TempDir()
+			if err := os.WriteFile(filepath.Join(realWorkspace, "hello.txt"), []byte("original"), 0644); err != nil {
+				t.Fatalf("write workspace: %v", err)
+			}
+			var asked *evidence.ApprovalRequest
+			p := &Pipeline{
+				Name: "held",
+				Stages: []*Stage{{
+					Name:            "stage",
+					Prompt:          "apply",
+					Model:           "fileblock-1",
+					Apply:           true,
+					RequireApproval: true,
+				}},
+				Adapters: map[string]adapter.Adapter{"fileblock": &fileBlockAdapter{content: "// file: hello.txt\nmodified\n"}},
+			}
+			base := t.TempDir()
+			_, err := Run(context.Background(), p, RunOptions{
+				Input:         "input",
+				WorkspacePath: realWorkspace,
+				EvidenceDir:   base,
+				ApplyForReal:  true,
+				ApplyApproved: true,
+				Approve: func(_ context.Context, req evidence.ApprovalRequest) (*evidence.ApprovalRecord, error) {
+					asked = &req
+					data, _ := os.ReadFile(filepath.Join(realWorkspace, "hello.txt"))
+					if string(data) != "original" {
+						t.Errorf("workspace changed before approval: %q", data)
+					}
+					return &evidence.ApprovalRecord{Decision: decision, Approver: "alice", Comment: "checked", Source: "tty"}, nil
+				},
+			})
+
+			if asked == nil || len(asked.Subjects) != 1 || len(asked.Subjects[0].AppliedFiles) != 1 {
+				t.Fatalf("expected the pending apply in the request: %+v", asked)
+			}
+			data, readErr := os.ReadFile(filepath.Join(realWorkspace, "hello.txt"))
+			if readErr != nil {
+				t.Fatalf("read workspace: %v", readErr)
+			}
+			record := readStageRecord(t, onlyRunDir(t, base), "stage")
+			if record.Approval == nil || record.Approval.Decision != decision || record.Approval.Timestamp.IsZero() {
+				t.Fatalf("expected the decision in the stage record: %+v", record.Approval)
+			}
+			last := record.Attempts[len(record.Attempts)-1]
+
+			if decision == evidence.ApprovalRejected {
+				if err == nil || !strings.Contains(err.Error(), "rejected by alice: checked") {
+					t.Fatalf("expected the rejection to fail the run, got %v", err)
+				}
+				if string(data) != "original" {
+					t.Fatalf("rejected output was applied: %q", data)
+				}
+				if last.WorkspaceMode != "temp" {
+					t.Fatalf("expected the attempt to run in a clone: %+v", last)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if strings.TrimSpace(string(data)) != "modified" {
+				t.Fatalf("approved output was not applied: %q", data)
+			}
+			if last.WorkspaceMode != "real" || last.ApplyJournal == nil {
+				t.Fatalf("expected the approved apply to be journaled: %+v", last)
+			}
+		})
+	}
+}
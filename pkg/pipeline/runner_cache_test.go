@@ -0,0 +1,70 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/cache"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+)
+
+func TestRunServesRepeatedCallsFromCache(t *testing.T) {
+	responses, err := cache.New(t.TempDir(), cache.Options{})
+	if err != nil {
+		t.Fatalf("new cache: %v", err)
+	}
+	chat := &chatRecorder{outputs: []string{"plan", "code", "code again"}}
+	noCache := false
+	newPipeline := func() *Pipeline {
+		return &Pipeline{
+			Name: "cache",
+			Stages: []*Stage{
+				{Name: "plan", Prompt: "plan {{ .Input }}", Adapter: "chat", Model: "mock-1"},
+				{Name: "code", Prompt: "code {{ .Artifacts.plan.Text }}", Adapter: "chat", Model: "mock-1", Cache: &noCache},
+			},
+			Adapters: map[string]adapter.Adapter{"chat": chat},
+		}
+	}
+	run := func() *evidence.RunRecord {
+		result, err := Run(context.Background(), newPipeline(), RunOptions{
+			Input:         "feature",
+			EvidenceDir:   t.TempDir(),
+			WorkspacePath: t.TempDir(),
+			Cache:         responses,
+		})
+		if err != nil {
+			t.Fatalf("run pipeline: %v", err)
+		}
+		record, err := evidence.ReadRun(result.EvidenceDir)
+		if err != nil {
+			t.Fatalf("read run: %v", err)
+		}
+		return record
+	}
+
+	run()
+	record := run()
+
+	if len(chat.requests) != 3 {
+		t.Fatalf("expected plan to be cached and code to run twice, got %d calls", len(chat.requests))
+	}
+	report := record.CostReport
+	if report == nil || len(report.Calls) != 2 {
+		t.Fatalf("expected 2 call reports, got %+v", report)
+	}
+	var cached, live int
+	for _, call := range report.Calls {
+		if call.Cached {
+			cached++
+			if call.Cost.Amount != 0 || call.Usage.TotalTokens != 0 {
+				t.Fatalf("cache hit should be free: %+v", call)
+			}
+		} else {
+			live++
+		}
+	}
+	if cached != 1 || live != 1 {
+		t.Fatalf("expected one cached and one live call, got %d/%d", cached, live)
+	}
+}
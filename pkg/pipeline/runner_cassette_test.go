@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+)
+
+func TestRunReplaysRecordedRepairLoop(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	newPipeline := func(adapters map[string]adapter.Adapter) *Pipeline {
+		return &Pipeline{
+			Name: "cassette",
+			Gates: map[string]GateDefinition{
+				"needs_fix": {
+					Type:      "command",
+					Command:   []string{"sh", "-c", "if [ -f .attempt ]; then exit 0; else touch .attempt; exit 1; fi"},
+					DenyShell: boolPtr(false),
+				},
+			},
+			Stages: []*Stage{{
+				Name:       "stage",
+				Prompt:     "fix {{ .Input }}",
+				Adapter:    "chat",
+				Model:      "mock-1",
+				Gates:      []string{"needs_fix"},
+				MaxRetries: 1,
+			}},
+			Adapters: adapters,
+		}
+	}
+	run := func(adapters map[string]adapter.Adapter) (*RunResult, error) {
+		return Run(context.Background(), newPipeline(adapters), RunOptions{
+			Input:         "bug",
+			EvidenceDir:   t.TempDir(),
+			WorkspacePath: t.TempDir(),
+			ApplyApproved: true,
+		})
+	}
+
+	cassette := adapter.NewCassette()
+	chat := &chatRecorder{outputs: []string{"draft", "fixed"}}
+	recorded, err := run(map[string]adapter.Adapter{"chat": adapter.NewRecordingAdapter(chat, cassette)})
+	if err != nil {
+		t.Fatalf("record run: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := cassette.Save(path); err != nil {
+		t.Fatalf("save cassette: %v", err)
+	}
+
+	loaded, err := adapter.LoadCassette(path)
+	if err != nil {
+		t.Fatalf("load cassette: %v", err)
+	}
+	replayed, err := run(loaded.ReplayAdapters())
+	if err != nil {
+		t.Fatalf("replay run: %v", err)
+	}
+	got := replayed.Stages["stage"]
+	want := recorded.Stages["stage"]
+	if got.Artifact.Content != "fixed" || got.Artifact.Hash != want.Artifact.Hash {
+		t.Fatalf("replay diverged: got %q want %q", got.Artifact.Content, want.Artifact.Content)
+	}
+
+	loaded, err = adapter.LoadCassette(path)
+	if err != nil {
+		t.Fatalf("load cassette: %v", err)
+	}
+	p := newPipeline(loaded.ReplayAdapters())
+	p.Stages[0].Prompt = "a prompt that was never recorded"
+	_, err = Run(context.Background(), p, RunOptions{Input: "bug", EvidenceDir: t.TempDir(), WorkspacePath: t.TempDir()})
+	if !errors.Is(err, adapter.ErrCassetteMiss) {
+		t.Fatalf("expected cassette miss, got %v", err)
+	}
+}
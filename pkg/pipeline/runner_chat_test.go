@@ -0,0 +1,110 @@
+package pipeline
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+)
+
+// chatRecorder implements adapter.ChatAdapter and records every request.
+type chatRecorder struct {
+	outputs  []string
+	requests []adapter.Request
+}
+
+func (a *chatRecorder) Generate(ctx context.Context, model string, prompt string) (*adapter.Response, error) {
+	return a.Chat(ctx, model, adapter.UserRequest(prompt))
+}
+
+func (a *chatRecorder) Chat(_ context.Context, model string, req adapter.Request) (*adapter.Response, error) {
+	a.requests = append(a.requests, req)
+	content := a.outputs[len(a.requests)-1]
+	return &adapter.Response{Artifact: artifact.New(content, "chat", model, req.Flatten())}, nil
+}
+
+func (a *chatRecorder) Name() string { return "chat" }
+
+func (a *chatRecorder) Models() []string { return []string{"mock-1"} }
+
+func TestRepairAppendsToConversation(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	writer, err := evidence.NewWriter(t.TempDir(), "run-chat")
+	if err != nil {
+		t.Fatalf("new writer: %v", err)
+	}
+
+	temperature := 0.2
+	p := &Pipeline{
+		Name: "chat",
+		Gates: map[string]GateDefinition{
+			"needs_fix": {
+				Type:      "command",
+				Command:   []string{"sh", "-c", "if [ -f .attempt ]; then exit 0; else touch .attempt; exit 1; fi"},
+				DenyShell: boolPtr(false),
+			},
+		},
+	}
+	stage := &Stage{
+		Name:        "stage",
+		System:      "You review {{ .Input }} changes.",
+		Prompt:      "hello",
+		Adapter:     "chat",
+		Model:       "mock-1",
+		MaxTokens:   512,
+		Temperature: &temperature,
+		Gates:       []string{"needs_fix"},
+		MaxRetries:  1,
+	}
+	chat := &chatRecorder{outputs: []string{"draft", "fixed"}}
+
+	result, record, err := runStage(
+		context.Background(),
+		&stageEnv{
+			writer:        writer,
+			pipeline:      p,
+			adapters:      map[string]adapter.Adapter{"chat": chat},
+			input:         "go",
+			workspacePath: t.TempDir(),
+			applyApproved: true,
+			tracker:       newCostTracker(nil, 0),
+		},
+		stage,
+		map[string]ArtifactTemplateData{},
+		map[string]map[string]string{},
+	)
+	if err != nil {
+		t.Fatalf("run stage: %v", err)
+	}
+	if result.Artifact.Content != "fixed" {
+		t.Fatalf("unexpected output %q", result.Artifact.Content)
+	}
+	if record.System != "You review go changes." {
+		t.Fatalf("unexpected recorded system prompt %q", record.System)
+	}
+
+	if len(chat.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(chat.requests))
+	}
+	first := chat.requests[0]
+	if first.System != "You review go changes." || first.MaxTokens != 512 || first.Temperature == nil || *first.Temperature != 0.2 {
+		t.Fatalf("stage options not forwarded: %+v", first)
+	}
+	repairReq := chat.requests[1]
+	if len(repairReq.Messages) != 3 {
+		t.Fatalf("expected repair to extend the conversation, got %+v", repairReq.Messages)
+	}
+	if repairReq.Messages[1].Role != adapter.RoleAssistant || repairReq.Messages[1].Content != "draft" {
+		t.Fatalf("expected previous output as assistant turn, got %+v", repairReq.Messages[1])
+	}
+	if repairReq.Messages[2].Role != adapter.RoleUser || strings.Contains(repairReq.Messages[2].Content, "draft") {
+		t.Fatalf("repair feedback should not re-paste the output: %+v", repairReq.Messages[2])
+	}
+}
@@ -0,0 +1,69 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+)
+
+func TestDryRunAttemptsReuseResetClone(t *testing.T) {
+	if _, err := exec.LookPath("grep"); err != nil {
+		t.Skip("grep not available")
+	}
+
+	workspacePath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspacePath, "notes.txt"), []byte("one\n"), 0644); err != nil {
+		t.Fatalf("write workspace: %v", err)
+	}
+
+	// The repair is a strict diff against the original file, so it only
+	// applies if the first attempt's write was reset.
+	chat := &chatRecorder{outputs: []string{
+		"// file: notes.txt\nbad\n",
+		"--- a/notes.txt\n+++ b/notes.txt\n@@ -1,1 +1,1 @@\n-one\n+good\n",
+	}}
+	p := &Pipeline{
+		Name:      "clone-reuse",
+		Workspace: Workspace{Clone: "hardlink"},
+		Gates: map[string]GateDefinition{
+			"good": {Type: "command", Command: []string{"grep", "-q", "good", "notes.txt"}},
+		},
+		Stages: []*Stage{
+			{Name: "edit", Prompt: "edit", Adapter: "chat", Model: "mock-1", Apply: true, ApplyMode: "strict", Gates: []string{"good"}, MaxRetries: 1},
+		},
+		Adapters: map[string]adapter.Adapter{"chat": chat},
+	}
+
+	base := t.TempDir()
+	if _, err := Run(context.Background(), p, RunOptions{Input: "input", WorkspacePath: workspacePath, EvidenceDir: base}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(onlyRunDir(t, base), "stages", "edit.json"))
+	if err != nil {
+		t.Fatalf("read stage record: %v", err)
+	}
+	var record evidence.StageRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("unmarshal stage record: %v", err)
+	}
+	if len(record.Attempts) != 2 || !record.Attempts[1].Succeeded {
+		t.Fatalf("expected the repair to succeed: %+v", record.Attempts)
+	}
+	if record.Attempts[0].WorkspaceUsed != record.Attempts[1].WorkspaceUsed {
+		t.Fatalf("expected attempts to share a clone: %s != %s", record.Attempts[0].WorkspaceUsed, record.Attempts[1].WorkspaceUsed)
+	}
+	if _, err := os.Stat(record.Attempts[0].WorkspaceUsed); !os.IsNotExist(err) {
+		t.Fatalf("expected the clone to be removed: %v", err)
+	}
+	data, err = os.ReadFile(filepath.Join(workspacePath, "notes.txt"))
+	if err != nil || string(data) != "one\n" {
+		t.Fatalf("expected hardlinked clone to leave the workspace alone, got %q (%v)", string(data), err)
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/zen-systems/flowgate/pkg/adapter"
 	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/gate"
 )
 
 type staticAdapter struct {
@@ -110,3 +112,55 @@ func TestCommandGatePolicyAllowsGofmt(t *testing.T) {
 		t.Fatalf("expected gofmt to modify file")
 	}
 }
+
+func TestCommandGateUsesLoadedCapabilities(t *testing.T) {
+	if _, err := exec.LookPath("touch"); err != nil {
+		t.Skip("touch not available")
+	}
+
+	workspace := t.TempDir()
+	repoFile := filepath.Join(t.TempDir(), "capabilities.yaml")
+	content := `
+capabilities:
+  gofmt:
+    disabled: true
+  touch:
+    templates:
+      - exec: touch
+        args: ["{path}"]
+`
+	if err := os.WriteFile(repoFile, []byte(content), 0644); err != nil {
+		t.Fatalf("write capabilities: %v", err)
+	}
+	capabilities, err := gate.LoadCapabilities("", repoFile)
+	if err != nil {
+		t.Fatalf("load capabilities: %v", err)
+	}
+
+	newPipeline := func(capability string, command []string) *Pipeline {
+		return &Pipeline{
+			Name: "policy-loaded",
+			Gates: map[string]GateDefinition{
+				"check": {Type: "command", Capability: capability, Command: command},
+			},
+			Stages: []*Stage{
+				{Name: "stage", Prompt: "hello", Adapter: "static", Model: "mock-1", Gates: []string{"check"}},
+			},
+			Adapters: map[string]adapter.Adapter{"static": &staticAdapter{content: "ok"}},
+		}
+	}
+
+	opts := RunOptions{WorkspacePath: workspace, EvidenceDir: t.TempDir(), Input: "input", Capabilities: capabilities}
+	if _, err := Run(context.Background(), newPipeline("touch", []string{"touch", "marker"}), opts); err != nil {
+		t.Fatalf("expected touch capability to pass: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "marker")); err != nil {
+		t.Fatalf("expected touch to run: %v", err)
+	}
+
+	opts.EvidenceDir = t.TempDir()
+	_, err = Run(context.Background(), newPipeline("gofmt", []string{"gofmt", "-w", "main.go"}), opts)
+	if err == nil || !strings.Contains(err.Error(), "unknown capability gofmt") {
+		t.Fatalf("expected disabled capability to be unknown, got %v", err)
+	}
+}
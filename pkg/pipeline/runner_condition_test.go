@@ -0,0 +1,116 @@
+package pipeline
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+)
+
+func TestRunSkipsStagesWhenConditionIsFalse(t *testing.T) {
+	echo := &flakyAdapter{}
+	p := &Pipeline{
+		Name: "conditional",
+		Stages: []*Stage{
+			{Name: "implement", Prompt: "implement {{ .Input }}"},
+			{Name: "docs", Prompt: "document {{ .Artifacts.implement.Text }}", When: `not (contains .Input "hotfix")`},
+			{Name: "publish", Prompt: "publish {{ .Artifacts.docs.Text }}"},
+		},
+		Adapters: map[string]adapter.Adapter{"flaky": echo},
+	}
+
+	result, err := Run(context.Background(), p, RunOptions{Input: "hotfix: nil deref", EvidenceDir: t.TempDir(), WorkspacePath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("run pipeline: %v", err)
+	}
+	if len(echo.calls) != 1 {
+		t.Fatalf("expected only implement to run, got %v", echo.calls)
+	}
+
+	stages, err := evidence.ReadStages(result.EvidenceDir)
+	if err != nil {
+		t.Fatalf("read stages: %v", err)
+	}
+	docs := stages["docs"]
+	if docs == nil || !docs.Skipped || !strings.Contains(docs.SkipReason, "when condition is false") {
+		t.Fatalf("expected docs to be skipped by its condition: %+v", docs)
+	}
+	publish := stages["publish"]
+	if publish == nil || !publish.Skipped || publish.SkipReason != "dependency docs skipped" {
+		t.Fatalf("expected publish to be skipped after docs: %+v", publish)
+	}
+}
+
+func TestRunBranchesToOnFailureTarget(t *testing.T) {
+	echo := &flakyAdapter{failOn: "implement"}
+	p := &Pipeline{
+		Name: "remediation",
+		Stages: []*Stage{
+			{Name: "implement", Prompt: "implement {{ .Input }}", OnFailure: "remediate"},
+			{Name: "remediate", Prompt: "remediate {{ .Input }}"},
+			{Name: "report", Prompt: "report", When: `eq .Status.implement "failed"`},
+		},
+		Adapters: map[string]adapter.Adapter{"flaky": echo},
+	}
+
+	result, err := Run(context.Background(), p, RunOptions{Input: "task", EvidenceDir: t.TempDir(), WorkspacePath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("expected remediation to keep the run alive: %v", err)
+	}
+	if result.Stages["remediate"] == nil || result.Stages["report"] == nil {
+		t.Fatalf("expected remediate and report to run, got %v", echo.calls)
+	}
+
+	stages, err := evidence.ReadStages(result.EvidenceDir)
+	if err != nil {
+		t.Fatalf("read stages: %v", err)
+	}
+	if implement := stages["implement"]; implement == nil || !strings.Contains(implement.Error, "provider unavailable") {
+		t.Fatalf("expected implement failure to be recorded: %+v", implement)
+	}
+}
+
+func TestRunSkipsUntriggeredOnFailureTarget(t *testing.T) {
+	echo := &flakyAdapter{}
+	p := &Pipeline{
+		Name: "remediation",
+		Stages: []*Stage{
+			{Name: "implement", Prompt: "implement", OnFailure: "remediate"},
+			{Name: "remediate", Prompt: "remediate"},
+		},
+		Adapters: map[string]adapter.Adapter{"flaky": echo},
+	}
+
+	result, err := Run(context.Background(), p, RunOptions{Input: "task", EvidenceDir: t.TempDir(), WorkspacePath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("run pipeline: %v", err)
+	}
+	if _, ok := result.Stages["remediate"]; ok {
+		t.Fatalf("remediate should not run when implement succeeds")
+	}
+	stages, err := evidence.ReadStages(result.EvidenceDir)
+	if err != nil {
+		t.Fatalf("read stages: %v", err)
+	}
+	if !stages["remediate"].Skipped {
+		t.Fatalf("expected skipped record for remediate: %+v", stages["remediate"])
+	}
+}
+
+func TestValidateRejectsUnknownOnFailureTarget(t *testing.T) {
+	p := &Pipeline{
+		Name:   "bad",
+		Stages: []*Stage{{Name: "a", Prompt: "a", OnFailure: "missing"}},
+	}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "on_failure") {
+		t.Fatalf("expected on_failure validation error, got %v", err)
+	}
+
+	p.Stages[0].OnFailure = ""
+	p.Stages[0].When = "{{ if }}"
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "when") {
+		t.Fatalf("expected when parse error, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package pipeline
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/artifact"
+)
+
+// barrierAdapter blocks each call until `want` calls are in flight, proving
+// that independent stages run concurrently.
+type barrierAdapter struct {
+	mu      sync.Mutex
+	want    int
+	waiting int
+	release chan struct{}
+}
+
+func (a *barrierAdapter) Generate(ctx context.Context, model string, prompt string) (*adapter.Response, error) {
+	a.mu.Lock()
+	a.waiting++
+	if a.waiting == a.want {
+		close(a.release)
+	}
+	a.mu.Unlock()
+
+	select {
+	case <-a.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	art := artifact.New("out:"+prompt, "barrier", model, prompt)
+	return &adapter.Response{Artifact: art}, nil
+}
+
+func (a *barrierAdapter) Name() string { return "barrier" }
+
+func (a *barrierAdapter) Models() []string { return []string{"mock-1"} }
+
+func TestBuildStageGraphInfersDependencies(t *testing.T) {
+	p := &Pipeline{
+		Name: "graph",
+		Stages: []*Stage{
+			{Name: "research", Prompt: "{{ .Input }}"},
+			{Name: "style-guide", Prompt: "style"},
+			{Name: "outline", Prompt: "{{ .Artifacts.research.Text }} {{ index .Artifacts \"style-guide\" }}"},
+			{Name: "legacy", Prompt: "{{ .stages.outline.output }}", DependsOn: []string{"research"}},
+			{Name: "summary", Prompt: "{{ range $name, $a := .Artifacts }}{{ $a.Text }}{{ end }}"},
+			{Name: "review", Prompt: "review", System: "Hold the draft to {{ .Artifacts.outline.Text }}"},
+		},
+	}
+
+	graph, err := buildStageGraph(p)
+	if err != nil {
+		t.Fatalf("build graph: %v", err)
+	}
+
+	expected := map[string][]string{
+		"research":    {},
+		"style-guide": {},
+		"outline":     {"research", "style-guide"},
+		"legacy":      {"outline", "research"},
+		"summary":     {"legacy", "outline", "research", "style-guide"},
+		"review":      {"outline"},
+	}
+	for name, want := range expected {
+		if got := graph[name]; !reflect.DeepEqual(got, want) {
+			t.Fatalf("deps for %s = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestValidateRejectsDependencyCycle(t *testing.T) {
+	p := &Pipeline{
+		Name: "cycle",
+		Stages: []*Stage{
+			{Name: "a", Prompt: "{{ .Artifacts.b.Text }}"},
+			{Name: "b", Prompt: "b", DependsOn: []string{"a"}},
+		},
+	}
+	err := p.Validate()
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+
+	p.Stages[1].DependsOn = []string{"missing"}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "unknown stage") {
+		t.Fatalf("expected unknown dependency error, got %v", err)
+	}
+}
+
+func TestRunExecutesIndependentStagesConcurrently(t *testing.T) {
+	barrier := &barrierAdapter{want: 2, release: make(chan struct{})}
+	p := &Pipeline{
+		Name: "fanout",
+		Stages: []*Stage{
+			{Name: "left", Prompt: "left"},
+			{Name: "right", Prompt: "right"},
+			{Name: "join", Prompt: "{{ .Artifacts.left.Text }}|{{ .Artifacts.right.Text }}"},
+		},
+		Adapters: map[string]adapter.Adapter{"barrier": barrier},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := Run(ctx, p, RunOptions{Input: "input", EvidenceDir: t.TempDir(), WorkspacePath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("run pipeline: %v", err)
+	}
+	join := result.Stages["join"]
+	if join == nil || join.Artifact.Content != "out:out:left|out:right" {
+		t.Fatalf("unexpected join output: %+v", join)
+	}
+}
+
+func TestRunSequentialWhenParallelismIsOne(t *testing.T) {
+	barrier := &barrierAdapter{want: 2, release: make(chan struct{})}
+	p := &Pipeline{
+		Name: "serial",
+		Stages: []*Stage{
+			{Name: "left", Prompt: "left"},
+			{Name: "right", Prompt: "right"},
+		},
+		Adapters: map[string]adapter.Adapter{"barrier": barrier},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := Run(ctx, p, RunOptions{Input: "input", EvidenceDir: t.TempDir(), WorkspacePath: t.TempDir(), MaxParallel: 1})
+	if err == nil {
+		t.Fatalf("expected serial execution to block on the barrier")
+	}
+}
@@ -0,0 +1,123 @@
+package pipeline
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+	"github.com/zen-systems/flowgate/pkg/gate"
+)
+
+func escalatePipeline(policies EscalationPolicies, strong *chatRecorder) *Pipeline {
+	return &Pipeline{
+		Name: "escalating",
+		Stages: []*Stage{{
+			Name:         "plan",
+			Prompt:       "plan {{ .Input }}",
+			Adapter:      "fixed",
+			Model:        "mock-1",
+			MaxRetries:   3,
+			OutputSchema: &SchemaSpec{Inline: map[string]any{"type": "object"}},
+			EscalateOn:   policies,
+		}},
+		Adapters: map[string]adapter.Adapter{
+			"fixed":  &fixedAdapter{content: "not json"},
+			"strong": strong,
+		},
+	}
+}
+
+func TestEscalateOnSwitchesAdapterAndRestartsPrompt(t *testing.T) {
+	strong := &chatRecorder{outputs: []string{`{"ok": true}`}}
+	p := escalatePipeline(EscalationPolicies{
+		{When: "attempt >= 2", Action: escalateActionSwitch, Adapter: "strong"},
+		{When: escalateTriggerRepeatFingerprint, Action: escalateActionPrompt, Prompt: "Start over for {{ .Input }} after attempt {{ .Attempt }}; {{ .Output }} failed."},
+	}, strong)
+	base := t.TempDir()
+	if _, err := Run(context.Background(), p, RunOptions{Input: "feature", EvidenceDir: base, WorkspacePath: t.TempDir()}); err != nil {
+		t.Fatalf("run pipeline: %v", err)
+	}
+
+	if len(strong.requests) != 1 {
+		t.Fatalf("expected one call after the switch, got %d", len(strong.requests))
+	}
+	messages := strong.requests[0].Messages
+	if len(messages) != 1 || messages[0].Content != "Start over for feature after attempt 2; not json failed." {
+		t.Fatalf("expected the conversation to restart with the escalation prompt, got %+v", messages)
+	}
+	if len(strong.requests[0].ResponseSchema) == 0 {
+		t.Fatal("expected the restarted request to keep the output schema")
+	}
+
+	record := readStageRecord(t, onlyRunDir(t, base), "plan")
+	if record.Adapter != "chat" || len(record.Attempts) != 3 {
+		t.Fatalf("expected the third attempt to pass on the switched adapter: %s, %d attempts", record.Adapter, len(record.Attempts))
+	}
+	if len(record.Escalations) != 2 {
+		t.Fatalf("expected two escalation events, got %+v", record.Escalations)
+	}
+	switched, restarted := record.Escalations[0], record.Escalations[1]
+	if switched.Attempt != 2 || switched.Action != escalateActionSwitch || switched.Adapter != "strong" || switched.Model != "mock-1" {
+		t.Fatalf("unexpected switch event: %+v", switched)
+	}
+	if restarted.Attempt != 2 || restarted.Trigger != escalateTriggerRepeatFingerprint || !strings.HasPrefix(restarted.Prompt, "Start over") {
+		t.Fatalf("unexpected prompt event: %+v", restarted)
+	}
+}
+
+func TestEscalateOnAbort(t *testing.T) {
+	p := escalatePipeline(EscalationPolicies{
+		{When: "rule:" + gate.InvalidJSONRule, Action: escalateActionAbort},
+	}, &chatRecorder{})
+	base := t.TempDir()
+	_, err := Run(context.Background(), p, RunOptions{Input: "feature", EvidenceDir: base, WorkspacePath: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "aborted by escalate_on rule:invalid_json") {
+		t.Fatalf("expected the policy to abort the stage, got %v", err)
+	}
+
+	record := readStageRecord(t, onlyRunDir(t, base), "plan")
+	if len(record.Attempts) != 1 {
+		t.Fatalf("expected the stage to stop after one attempt, got %d", len(record.Attempts))
+	}
+	want := evidence.EscalationRecord{Attempt: 1, Trigger: "rule:invalid_json", Reason: "rule invalid_json violated", Action: escalateActionAbort}
+	if len(record.Escalations) != 1 || record.Escalations[0] != want {
+		t.Fatalf("unexpected escalation events: %+v", record.Escalations)
+	}
+}
+
+func TestEscalationTriggerFires(t *testing.T) {
+	failure := &gate.GateResult{Violations: []gate.Violation{{Rule: gate.InvalidJSONRule}}}
+	scores := func(values ...float64) *RepairState {
+		state := &RepairState{}
+		for _, v := range values {
+			state.Attempts = append(state.Attempts, AttemptState{Score: v})
+		}
+		return state
+	}
+	tests := []struct {
+		name  string
+		when  string
+		check escalationCheck
+		want  bool
+	}{
+		{"score decreasing", "score_not_improving", escalationCheck{attempt: 2, state: scores(60, 40), failure: failure}, false},
+		{"score rising", "score_not_improving", escalationCheck{attempt: 2, state: scores(40, 60), failure: failure}, true},
+		{"score unchanged", "score_not_improving", escalationCheck{attempt: 2, state: scores(40, 40), failure: failure}, true},
+		{"score first attempt", "score_not_improving", escalationCheck{attempt: 1, state: scores(40), failure: failure}, false},
+		{"attempt reached", "attempt >= 2", escalationCheck{attempt: 2, state: scores(0, 0), failure: failure}, true},
+		{"attempt not reached", "attempt >= 3", escalationCheck{attempt: 2, state: scores(0, 0), failure: failure}, false},
+		{"rule violated", "rule:" + gate.InvalidJSONRule, escalationCheck{attempt: 1, state: scores(0), failure: failure}, true},
+		{"rule not violated", "rule:other", escalationCheck{attempt: 1, state: scores(0), failure: failure}, false},
+	}
+	for _, tt := range tests {
+		trigger, err := parseEscalationTrigger(tt.when)
+		if err != nil {
+			t.Fatalf("%s: parse trigger: %v", tt.name, err)
+		}
+		if got, reason := trigger.fires(tt.check); got != tt.want {
+			t.Errorf("%s: expected fires=%v, got %v (%s)", tt.name, tt.want, got, reason)
+		}
+	}
+}
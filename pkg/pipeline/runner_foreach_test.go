@@ -0,0 +1,172 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+)
+
+// scriptAdapter answers each prompt with respond(model, prompt). It is safe
+// to use from concurrent stages.
+type scriptAdapter struct {
+	mu      sync.Mutex
+	prompts []string
+	respond func(model, prompt string) (string, error)
+}
+
+func (a *scriptAdapter) Generate(_ context.Context, model string, prompt string) (*adapter.Response, error) {
+	a.mu.Lock()
+	a.prompts = append(a.prompts, prompt)
+	a.mu.Unlock()
+	content, err := a.respond(model, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return &adapter.Response{Artifact: artifact.New(content, "script", model, prompt)}, nil
+}
+
+func (a *scriptAdapter) Name() string { return "script" }
+
+func (a *scriptAdapter) Models() []string { return []string{"mock-1"} }
+
+func forEachPipeline(script *scriptAdapter, allowFailures bool) *Pipeline {
+	return &Pipeline{
+		Name: "fan-out",
+		Stages: []*Stage{
+			{Name: "plan", Prompt: "plan"},
+			{
+				Name:    "implement",
+				Prompt:  "implement {{ .Item.path }} ({{ .ItemIndex }})",
+				ForEach: &ForEach{Stage: "plan", Path: "files", Parallel: 2, AllowFailures: allowFailures},
+			},
+			{Name: "summary", Prompt: "{{ range .Artifacts.implement.JSON }}{{ .item.path }}={{ or .output .error }};{{ end }}"},
+		},
+		Adapters: map[string]adapter.Adapter{"script": script},
+	}
+}
+
+func forEachScript() *scriptAdapter {
+	return &scriptAdapter{respond: func(_, prompt string) (string, error) {
+		switch {
+		case prompt == "plan":
+			return `{"files": [{"path": "a.go"}, {"path": "b.go"}, {"path": "c.go"}]}`, nil
+		case strings.HasPrefix(prompt, "implement b.go"):
+			return "", fmt.Errorf("model refused")
+		case strings.HasPrefix(prompt, "implement "):
+			return "done " + strings.TrimPrefix(prompt, "implement "), nil
+		}
+		return "summary", nil
+	}}
+}
+
+func readStageRecord(t *testing.T, runDir, name string) evidence.StageRecord {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(runDir, "stages", name+".json"))
+	if err != nil {
+		t.Fatalf("read stage record %s: %v", name, err)
+	}
+	var record evidence.StageRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("unmarshal stage record %s: %v", name, err)
+	}
+	return record
+}
+
+func TestForEachRunsStagePerItem(t *testing.T) {
+	script := forEachScript()
+	base := t.TempDir()
+	if _, err := Run(context.Background(), forEachPipeline(script, true), RunOptions{Input: "input", EvidenceDir: base}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	runDir := onlyRunDir(t, base)
+
+	last := script.prompts[len(script.prompts)-1]
+	if last != "a.go=done a.go (0);b.go=stage implement.1 adapter error: model refused;c.go=done c.go (2);" {
+		t.Fatalf("unexpected aggregated output in summary prompt: %q", last)
+	}
+
+	record := readStageRecord(t, runDir, "implement")
+	if record.Error != "" || len(record.Items) != 3 {
+		t.Fatalf("unexpected for_each record: %+v", record)
+	}
+	if !record.Items[0].Succeeded || record.Items[1].Succeeded || record.Items[1].Error == "" || record.Items[1].Item != `{"path":"b.go"}` {
+		t.Fatalf("unexpected item records: %+v", record.Items)
+	}
+	item := readStageRecord(t, runDir, record.Items[2].Stage)
+	if item.Name != "implement.2" || len(item.Attempts) != 1 || item.Prompt != "implement c.go (2)" {
+		t.Fatalf("unexpected item stage record: %+v", item)
+	}
+}
+
+func TestForEachItemFailureFailsStageButKeepsItems(t *testing.T) {
+	base := t.TempDir()
+	_, err := Run(context.Background(), forEachPipeline(forEachScript(), false), RunOptions{Input: "input", EvidenceDir: base})
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 items failed") {
+		t.Fatalf("expected item failure to fail the run, got %v", err)
+	}
+	runDir := onlyRunDir(t, base)
+
+	record := readStageRecord(t, runDir, "implement")
+	if record.Error == "" || len(record.Items) != 3 || !record.Items[0].Succeeded || !record.Items[2].Succeeded {
+		t.Fatalf("expected successful items to be recorded: %+v", record)
+	}
+	if item := readStageRecord(t, runDir, "implement.0"); item.OutputRef == "" {
+		t.Fatalf("expected successful item output to be kept: %+v", item)
+	}
+}
+
+func TestForEachItemsFromLines(t *testing.T) {
+	artifacts := map[string]ArtifactTemplateData{
+		"list": {Text: "a.go\n\n  b.go  \n"},
+		"json": {Text: "```json\n[1, 2]\n```"},
+	}
+	items, err := forEachItems(&ForEach{Stage: "list"}, artifacts)
+	if err != nil || len(items) != 2 || items[0] != "a.go" || items[1] != "b.go" {
+		t.Fatalf("unexpected line items: %v %v", items, err)
+	}
+	items, err = forEachItems(&ForEach{Stage: "json", Path: "."}, artifacts)
+	if err != nil || len(items) != 2 {
+		t.Fatalf("unexpected root items: %v %v", items, err)
+	}
+	if _, err := forEachItems(&ForEach{Stage: "json", Path: "files"}, artifacts); err == nil {
+		t.Fatalf("expected missing path to fail")
+	}
+}
+
+func TestForEachRollsUpItemGates(t *testing.T) {
+	script := &scriptAdapter{respond: func(_, prompt string) (string, error) {
+		switch {
+		case prompt == "plan":
+			return `{"files": [{"path": "a.go"}, {"path": "b.go"}]}`, nil
+		case strings.HasPrefix(prompt, "implement b.go"):
+			return "package b\n\nfunc B() {\n\tpanic(\"not implemented\")\n}\n", nil
+		}
+		return "package a\n\nfunc A() int {\n\treturn 1\n}\n", nil
+	}}
+	p := forEachPipeline(script, true)
+	p.Stages = p.Stages[:2]
+	p.Stages[1].Gates = []string{"stubcheck"}
+	base := t.TempDir()
+	result, err := Run(context.Background(), p, RunOptions{Input: "input", EvidenceDir: base, WorkspacePath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	gates := result.Stages["implement"].GateResults
+	if len(gates) != 1 || gates[0].Name != "implement.0/stubcheck" || !gates[0].Result.Passed {
+		t.Fatalf("expected the passing item's gates on the stage result, got %+v", gates)
+	}
+	record := readStageRecord(t, onlyRunDir(t, base), "implement")
+	if len(record.GateResults) != 2 || record.GateResults[1].Name != "implement.1/stubcheck" || record.GateResults[1].Passed {
+		t.Fatalf("expected the failed item's last gates in the stage record, got %+v", record.GateResults)
+	}
+}
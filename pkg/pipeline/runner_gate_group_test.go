@@ -0,0 +1,204 @@
+package pipeline
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+)
+
+func shellGate(script string) GateDefinition {
+	return GateDefinition{Type: "command", Command: []string{"sh", "-c", script}, DenyShell: boolPtr(false)}
+}
+
+func TestGateSeverityAndSkipping(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	lint := shellGate("echo style >&2; exit 1")
+	lint.Severity = "warn"
+	chat := &chatRecorder{outputs: []string{"first", "second"}}
+	p := &Pipeline{
+		Name: "severity",
+		Gates: map[string]GateDefinition{
+			"lint":      lint,
+			"unit":      shellGate(`if [ -f .attempt ]; then exit 0; else touch .attempt; exit 1; fi`),
+			"expensive": shellGate("exit 0"),
+		},
+		Stages: []*Stage{{
+			Name:       "code",
+			Prompt:     "write code",
+			Adapter:    "chat",
+			Model:      "mock-1",
+			MaxRetries: 1,
+			Gates:      []string{"lint", "unit", "expensive"},
+		}},
+		Adapters: map[string]adapter.Adapter{"chat": chat},
+	}
+	evidenceDir := t.TempDir()
+	if _, err := Run(context.Background(), p, RunOptions{
+		Input:         "input",
+		EvidenceDir:   evidenceDir,
+		WorkspacePath: t.TempDir(),
+		ApplyApproved: true,
+	}); err != nil {
+		t.Fatalf("run pipeline: %v", err)
+	}
+
+	stages, err := evidence.ReadStages(onlyRunDir(t, evidenceDir))
+	if err != nil {
+		t.Fatalf("read stages: %v", err)
+	}
+	first := stages["code"].Attempts[0].GateResults
+	if len(first) != 3 {
+		t.Fatalf("expected 3 gate records, got %+v", first)
+	}
+	if first[0].Passed || first[0].Severity != "warn" {
+		t.Fatalf("expected failing advisory lint record, got %+v", first[0])
+	}
+	if first[1].Passed || first[1].Severity != "block" {
+		t.Fatalf("expected blocking unit failure, got %+v", first[1])
+	}
+	if !first[2].Skipped || !strings.Contains(first[2].SkipReason, "gate unit failed") {
+		t.Fatalf("expected expensive gate to be skipped, got %+v", first[2])
+	}
+
+	final := stages["code"].GateResults
+	if final[0].Passed || !final[1].Passed || !final[2].Passed {
+		t.Fatalf("expected warn failure to leave the stage passing, got %+v", final)
+	}
+
+	messages := chat.requests[1].Messages
+	feedback := messages[len(messages)-1].Content
+	issues := strings.Index(feedback, "Issues found:")
+	warnings := strings.Index(feedback, "Warnings (non-blocking")
+	if issues < 0 || warnings < issues || !strings.Contains(feedback[warnings:], "[warning]") {
+		t.Fatalf("expected warnings listed after blocking issues:\n%s", feedback)
+	}
+}
+
+func TestGateGroupModes(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	pass, fail := shellGate("exit 0"), shellGate("exit 1")
+	tests := []struct {
+		name    string
+		mode    string
+		members []string
+		passed  bool
+		skipped []bool
+	}{
+		{name: "all", mode: "all", members: []string{"pass", "fail", "pass2"}, skipped: []bool{false, false, true}},
+		{name: "any", mode: "any", members: []string{"fail", "pass", "pass2"}, passed: true, skipped: []bool{false, false, true}},
+		{name: "quorum reached", mode: "quorum(2)", members: []string{"pass", "pass2", "fail"}, passed: true, skipped: []bool{false, false, true}},
+		{name: "quorum unreachable", mode: "quorum(2)", members: []string{"fail", "fail2", "pass"}, skipped: []bool{false, false, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pipeline{
+				Name: "groups",
+				Gates: map[string]GateDefinition{
+					"pass": pass, "pass2": pass, "fail": fail, "fail2": fail,
+					"checks": {Type: "group", Mode: tt.mode, Gates: tt.members},
+				},
+				Stages: []*Stage{{Name: "stage", Prompt: "hello", Gates: []string{"checks"}}},
+			}
+			if err := p.Validate(); err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			results, err := evaluateGates(context.Background(), &stageEnv{pipeline: p, applyApproved: true}, p.Stages[0], nil, t.TempDir())
+			if (err == nil) != tt.passed {
+				t.Fatalf("expected passed=%v, got err=%v", tt.passed, err)
+			}
+			if len(results) != 1 || len(results[0].Members) != len(tt.members) {
+				t.Fatalf("expected one group result with members, got %+v", results)
+			}
+			for i, member := range results[0].Members {
+				if member.Skipped != tt.skipped[i] {
+					t.Fatalf("member %s: expected skipped=%v, got %+v", member.Name, tt.skipped[i], member)
+				}
+			}
+		})
+	}
+}
+
+func TestGateScoreThresholds(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// Command gates score 0 on success and 100 on failure, so a weighted
+	// group of one advisory failure (weight 1) and one success (weight 4)
+	// scores 20.
+	light := shellGate("exit 1")
+	light.Weight = 1
+	light.Severity = "warn"
+	heavy := shellGate("exit 0")
+	heavy.Weight = 4
+	maxScore := 20
+	p := &Pipeline{
+		Name: "thresholds",
+		Gates: map[string]GateDefinition{
+			"light":  light,
+			"heavy":  heavy,
+			"scored": {Type: "group", Gates: []string{"light", "heavy"}, MaxScore: &maxScore},
+		},
+		Stages: []*Stage{{Name: "stage", Prompt: "hello", Gates: []string{"scored"}}},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	results, err := evaluateGates(context.Background(), &stageEnv{pipeline: p, applyApproved: true}, p.Stages[0], nil, t.TempDir())
+	if err != nil {
+		t.Fatalf("expected aggregate score within max_score to pass, got %v", err)
+	}
+	if results[0].Result.Score != 20 {
+		t.Fatalf("expected weighted score 20, got %d", results[0].Result.Score)
+	}
+
+	maxScore = 10
+	results, err = evaluateGates(context.Background(), &stageEnv{pipeline: p, applyApproved: true}, p.Stages[0], nil, t.TempDir())
+	if err == nil {
+		t.Fatal("expected score above max_score to fail")
+	}
+	violations := results[0].Result.Violations
+	if last := violations[len(violations)-1]; last.Rule != "score_threshold" {
+		t.Fatalf("expected score_threshold violation, got %+v", violations)
+	}
+}
+
+func TestValidateGateGroups(t *testing.T) {
+	tests := []struct {
+		name  string
+		gates map[string]GateDefinition
+		want  string
+	}{
+		{name: "bad severity", gates: map[string]GateDefinition{"g": {Type: "stubcheck", Severity: "fatal"}}, want: "severity"},
+		{name: "bad mode", gates: map[string]GateDefinition{"g": {Type: "group", Mode: "most", Gates: []string{"stubcheck"}}}, want: "invalid mode"},
+		{name: "quorum too large", gates: map[string]GateDefinition{"g": {Type: "group", Mode: "quorum(3)", Gates: []string{"stubcheck"}}}, want: "between 1 and 1"},
+		{name: "unknown member", gates: map[string]GateDefinition{"g": {Type: "group", Gates: []string{"missing"}}}, want: "unknown gate missing"},
+		{name: "cycle", gates: map[string]GateDefinition{
+			"a": {Type: "group", Gates: []string{"b"}},
+			"b": {Type: "group", Gates: []string{"a"}},
+		}, want: "cycle"},
+		{name: "mode on leaf", gates: map[string]GateDefinition{"g": {Type: "stubcheck", Mode: "any"}}, want: "only apply to group"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pipeline{
+				Name:   "invalid",
+				Gates:  tt.gates,
+				Stages: []*Stage{{Name: "stage", Prompt: "hello"}},
+			}
+			if err := p.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q error, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/attest"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+)
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestGitWorkspaceCommitsAppliedStages(t *testing.T) {
+	for _, tool := range []string{"git", "grep"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+
+	workspacePath := t.TempDir()
+	gitOutput(t, workspacePath, "init", "--quiet")
+	if err := os.WriteFile(filepath.Join(workspacePath, "notes.txt"), []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatalf("write workspace: %v", err)
+	}
+	gitOutput(t, workspacePath, "add", "-A")
+	gitOutput(t, workspacePath, "commit", "--quiet", "-m", "initial")
+	head := gitOutput(t, workspacePath, "rev-parse", "HEAD")
+
+	chat := &chatRecorder{outputs: []string{
+		"--- a/notes.txt\n+++ b/notes.txt\n@@ -1,2 +1,2 @@\n-one\n+ONE\n two\n",
+		"--- a/notes.txt\n+++ b/notes.txt\n@@ -1,2 +1,2 @@\n ONE\n-two\n+TWO\n",
+	}}
+	p := &Pipeline{
+		Name:      "git-mode",
+		Workspace: Workspace{Mode: "git"},
+		Gates: map[string]GateDefinition{
+			"upper": {Type: "command", Command: []string{"grep", "-q", "ONE", "notes.txt"}},
+		},
+		Stages: []*Stage{
+			{Name: "first", Prompt: "edit", Adapter: "chat", Model: "mock-1", Apply: true},
+			// The second stage sees the first stage's commit.
+			{Name: "second", Prompt: "edit", Adapter: "chat", Model: "mock-1", Apply: true, Gates: []string{"upper"}, DependsOn: []string{"first"}},
+		},
+		Adapters: map[string]adapter.Adapter{"chat": chat},
+	}
+
+	base := t.TempDir()
+	if _, err := Run(context.Background(), p, RunOptions{Input: "input", WorkspacePath: workspacePath, EvidenceDir: base}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	runDir := onlyRunDir(t, base)
+
+	runRecord, err := evidence.ReadRun(runDir)
+	if err != nil {
+		t.Fatalf("read run: %v", err)
+	}
+	git := runRecord.Git
+	if git == nil || git.Branch != "flowgate/"+runRecord.ID || git.Base != head || len(git.Commits) != 2 {
+		t.Fatalf("unexpected git record: %+v", git)
+	}
+	if tip := gitOutput(t, workspacePath, "rev-parse", git.Branch); tip != git.Commits[1].Commit {
+		t.Fatalf("expected branch at the last stage commit, got %s", tip)
+	}
+	if got := gitOutput(t, workspacePath, "show", git.Branch+":notes.txt"); got != "ONE\nTWO" {
+		t.Fatalf("unexpected branch content: %q", got)
+	}
+
+	message := gitOutput(t, workspacePath, "log", "-1", "--format=%B", git.Commits[0].Commit)
+	for _, want := range []string{"Flowgate-Run: " + runRecord.ID, "Flowgate-Stage: first", "Flowgate-Attestation: sha256:" + git.Commits[0].AttestationHash} {
+		if !strings.Contains(message, want) {
+			t.Fatalf("commit message missing %q:\n%s", want, message)
+		}
+	}
+	for _, commit := range git.Commits {
+		message := gitOutput(t, workspacePath, "log", "-1", "--format=%B", commit.Commit)
+		_, trailer, ok := strings.Cut(message, "Flowgate-Attestation: sha256:")
+		if !ok {
+			t.Fatalf("commit %s has no attestation trailer:\n%s", commit.Commit, message)
+		}
+		sha := strings.TrimSpace(trailer)
+		data, err := evidence.ReadBlob(runDir, commit.AttestationRef, sha)
+		if err != nil {
+			t.Fatalf("read attestation: %v", err)
+		}
+		var att attest.AttestationV0
+		if err := json.Unmarshal(data, &att); err != nil {
+			t.Fatalf("parse attestation: %v", err)
+		}
+		// The run record was rewritten after the commit; the attestation
+		// must still verify against the finished run.
+		if err := attest.VerifyAttestation(&att, runDir); err != nil {
+			t.Fatalf("verify attestation of stage %s: %v", commit.Stage, err)
+		}
+	}
+
+	if got := gitOutput(t, workspacePath, "rev-parse", "HEAD"); got != head {
+		t.Fatalf("expected HEAD to be untouched")
+	}
+	data, err := os.ReadFile(filepath.Join(workspacePath, "notes.txt"))
+	if err != nil || string(data) != "one\ntwo\n" {
+		t.Fatalf("expected dry run to leave the workspace alone, got %q (%v)", string(data), err)
+	}
+	if worktrees := gitOutput(t, workspacePath, "worktree", "list"); strings.Count(worktrees, "\n") != 0 {
+		t.Fatalf("expected temporary worktrees to be removed:\n%s", worktrees)
+	}
+}
+
+func TestGitWorkspaceForEachRejectsOverlappingItems(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	workspacePath := t.TempDir()
+	gitOutput(t, workspacePath, "init", "--quiet")
+	if err := os.WriteFile(filepath.Join(workspacePath, "notes.txt"), []byte("one\n"), 0644); err != nil {
+		t.Fatalf("write workspace: %v", err)
+	}
+	gitOutput(t, workspacePath, "add", "-A")
+	gitOutput(t, workspacePath, "commit", "--quiet", "-m", "initial")
+
+	// Each item rewrites the same file in its own worktree.
+	script := &scriptAdapter{respond: func(_, prompt string) (string, error) {
+		if prompt == "plan" {
+			return "first\nsecond", nil
+		}
+		return "// file: notes.txt\n" + strings.TrimPrefix(prompt, "write ") + "\n", nil
+	}}
+	p := &Pipeline{
+		Name:      "git-fan-out",
+		Workspace: Workspace{Mode: "git"},
+		Stages: []*Stage{
+			{Name: "plan", Prompt: "plan"},
+			{Name: "write", Prompt: "write {{ .Item }}", Apply: true, ForEach: &ForEach{Stage: "plan"}},
+		},
+		Adapters: map[string]adapter.Adapter{"script": script},
+	}
+	base := t.TempDir()
+	_, err := Run(context.Background(), p, RunOptions{Input: "input", WorkspacePath: workspacePath, EvidenceDir: base})
+	if err == nil || !strings.Contains(err.Error(), "write.1 changes notes.txt, which an earlier item also changed") {
+		t.Fatalf("expected overlapping items to fail the stage, got %v", err)
+	}
+
+	runRecord, err := evidence.ReadRun(onlyRunDir(t, base))
+	if err != nil {
+		t.Fatalf("read run: %v", err)
+	}
+	if runRecord.Git == nil || len(runRecord.Git.Commits) != 0 {
+		t.Fatalf("expected nothing to be committed, got %+v", runRecord.Git)
+	}
+}
@@ -0,0 +1,119 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+	"github.com/zen-systems/flowgate/pkg/gate"
+)
+
+func TestJudgeGateDrivesRepairAndRecordsCost(t *testing.T) {
+	rubric := filepath.Join(t.TempDir(), "research.yaml")
+	if err := os.WriteFile(rubric, []byte(`pass_score: 70
+criteria:
+  - name: accuracy
+    description: Claims are correct.
+    weight: 2
+  - name: sources
+    description: Claims cite sources.
+`), 0644); err != nil {
+		t.Fatalf("write rubric: %v", err)
+	}
+
+	author := &chatRecorder{outputs: []string{"draft", "final"}}
+	judge := &chatRecorder{outputs: []string{
+		`{"criteria": [{"name": "accuracy", "score": 30, "reason": "the dates are wrong", "suggestion": "check the release dates"}, {"name": "sources", "score": 90, "reason": "cited"}]}`,
+		`{"criteria": [{"name": "accuracy", "score": 85, "reason": "correct"}, {"name": "sources", "score": 90, "reason": "cited"}]}`,
+	}}
+	p := &Pipeline{
+		Name: "judged",
+		Gates: map[string]GateDefinition{
+			"review": {Type: "judge", Rubric: rubric, Adapter: "judge", Model: "mock-1"},
+		},
+		Stages: []*Stage{{
+			Name:       "research",
+			Prompt:     "research {{ .Input }}",
+			Adapter:    "chat",
+			Model:      "mock-1",
+			MaxRetries: 1,
+			Gates:      []string{"review"},
+		}},
+		Adapters: map[string]adapter.Adapter{"chat": author, "judge": judge},
+	}
+
+	result, err := Run(context.Background(), p, RunOptions{
+		Input:         "releases",
+		EvidenceDir:   t.TempDir(),
+		WorkspacePath: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("run pipeline: %v", err)
+	}
+
+	if len(judge.requests) != 2 {
+		t.Fatalf("expected 2 judge calls, got %d", len(judge.requests))
+	}
+	first := judge.requests[0].Messages[0].Content
+	if !strings.Contains(first, "accuracy (weight 2)") || !strings.Contains(first, "<work>\ndraft\n</work>") {
+		t.Fatalf("judge prompt missing rubric or artifact:\n%s", first)
+	}
+	repair := author.requests[1].Messages[len(author.requests[1].Messages)-1].Content
+	if !strings.Contains(repair, "accuracy scored 30/100") || !strings.Contains(repair, "check the release dates") {
+		t.Fatalf("expected judge verdict in repair feedback:\n%s", repair)
+	}
+
+	record, err := evidence.ReadRun(result.EvidenceDir)
+	if err != nil {
+		t.Fatalf("read run: %v", err)
+	}
+	judgeCalls := 0
+	for _, call := range record.CostReport.Calls {
+		if call.Adapter == "judge" {
+			judgeCalls++
+		}
+	}
+	if len(record.CostReport.Calls) != 4 || judgeCalls != 2 {
+		t.Fatalf("expected judge calls in the cost report, got %+v", record.CostReport.Calls)
+	}
+
+	stages, err := evidence.ReadStages(result.EvidenceDir)
+	if err != nil {
+		t.Fatalf("read stages: %v", err)
+	}
+	violations := stages["research"].Attempts[0].GateResults[0].Violations
+	if len(violations) != 1 || violations[0].Rule != gate.JudgeCriterionRule || violations[0].Location != "accuracy" {
+		t.Fatalf("unexpected violations %+v", violations)
+	}
+}
+
+func TestValidateLoadsJudgeRubric(t *testing.T) {
+	p := &Pipeline{
+		Name:    "judged",
+		baseDir: t.TempDir(),
+		Gates: map[string]GateDefinition{
+			"review": {Type: "judge", Rubric: "rubrics/missing.yaml"},
+		},
+		Stages: []*Stage{{Name: "research", Prompt: "research", Gates: []string{"review"}}},
+	}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "read rubric") {
+		t.Fatalf("expected missing rubric error, got %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(p.baseDir, "rubrics"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(p.baseDir, "rubrics", "missing.yaml"), []byte("criteria:\n  - name: clarity\n    description: Easy to follow.\n"), 0644); err != nil {
+		t.Fatalf("write rubric: %v", err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if r := p.Gates["review"].rubric; r == nil || r.PassScore != 70 || r.Criteria[0].Weight != 1 {
+		t.Fatalf("expected rubric defaults to be applied, got %+v", r)
+	}
+}
@@ -76,16 +76,16 @@ func TestRepairLoopDetection(t *testing.T) {
 
 	_, stageRecord, err := runStage(
 		context.Background(),
-		writer,
+		&stageEnv{
+			writer:        writer,
+			pipeline:      p,
+			adapters:      map[string]adapter.Adapter{"fixed": &fixedAdapter{content: "same"}},
+			input:         "input",
+			workspacePath: t.TempDir(),
+			applyApproved: true,
+			tracker:       newCostTracker(nil, 0),
+		},
 		stage,
-		map[string]adapter.Adapter{"fixed": &fixedAdapter{content: "same"}},
-		p,
-		"input",
-		t.TempDir(),
-		false,
-		true,
-		nil,
-		newCostTracker(nil, 0),
 		map[string]ArtifactTemplateData{},
 		map[string]map[string]string{},
 	)
@@ -129,16 +129,16 @@ func TestRepairLoopAllowsChangedOutput(t *testing.T) {
 
 	_, stageRecord, err := runStage(
 		context.Background(),
-		writer,
+		&stageEnv{
+			writer:        writer,
+			pipeline:      p,
+			adapters:      map[string]adapter.Adapter{"changing": &changingAdapter{contents: []string{"one", "two"}}},
+			input:         "input",
+			workspacePath: t.TempDir(),
+			applyApproved: true,
+			tracker:       newCostTracker(nil, 0),
+		},
 		stage,
-		map[string]adapter.Adapter{"changing": &changingAdapter{contents: []string{"one", "two"}}},
-		p,
-		"input",
-		t.TempDir(),
-		false,
-		true,
-		nil,
-		newCostTracker(nil, 0),
 		map[string]ArtifactTemplateData{},
 		map[string]map[string]string{},
 	)
@@ -152,3 +152,50 @@ func TestRepairLoopAllowsChangedOutput(t *testing.T) {
 		t.Fatalf("expected 2 attempts")
 	}
 }
+
+func TestRepeatOnLastAttemptFailsStage(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	writer, err := evidence.NewWriter(t.TempDir(), "run3")
+	if err != nil {
+		t.Fatalf("new writer: %v", err)
+	}
+
+	p := &Pipeline{
+		Name:  "loop",
+		Gates: map[string]GateDefinition{"fail": shellGate("exit 1")},
+	}
+
+	stage := &Stage{
+		Name:       "stage",
+		Prompt:     "hello",
+		Adapter:    "fixed",
+		Model:      "mock-1",
+		Gates:      []string{"fail"},
+		MaxRetries: 1,
+	}
+
+	_, stageRecord, err := runStage(
+		context.Background(),
+		&stageEnv{
+			writer:        writer,
+			pipeline:      p,
+			adapters:      map[string]adapter.Adapter{"fixed": &fixedAdapter{content: "same"}},
+			input:         "input",
+			workspacePath: t.TempDir(),
+			applyApproved: true,
+			tracker:       newCostTracker(nil, 0),
+		},
+		stage,
+		map[string]ArtifactTemplateData{},
+		map[string]map[string]string{},
+	)
+	if err == nil {
+		t.Fatalf("expected the repeated failure on the last attempt to fail the stage")
+	}
+	if stageRecord == nil || len(stageRecord.Attempts) != 2 || len(stageRecord.Escalations) != 0 {
+		t.Fatalf("expected 2 attempts and no escalation")
+	}
+}
@@ -0,0 +1,66 @@
+package pipeline
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+)
+
+func TestCommandGateParserFeedsRepair(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	vet := shellGate("if [ -f .attempt ]; then exit 0; fi; touch .attempt; echo './main.go:3:1: unreachable code' >&2; exit 1")
+	vet.Parser = "go_vet"
+	chat := &chatRecorder{outputs: []string{"first", "second"}}
+	p := &Pipeline{
+		Name:  "parsed",
+		Gates: map[string]GateDefinition{"vet": vet},
+		Stages: []*Stage{{
+			Name:       "code",
+			Prompt:     "code",
+			Adapter:    "chat",
+			Model:      "mock-1",
+			MaxRetries: 1,
+			Gates:      []string{"vet"},
+		}},
+		Adapters: map[string]adapter.Adapter{"chat": chat},
+	}
+	if _, err := Run(context.Background(), p, RunOptions{
+		Input:         "feature",
+		EvidenceDir:   t.TempDir(),
+		WorkspacePath: t.TempDir(),
+		ApplyApproved: true,
+	}); err != nil {
+		t.Fatalf("run pipeline: %v", err)
+	}
+
+	repair := chat.requests[1].Messages[len(chat.requests[1].Messages)-1].Content
+	if !strings.Contains(repair, "[error] vet: unreachable code") || !strings.Contains(repair, "Location: main.go:3") {
+		t.Fatalf("expected parsed vet finding in repair feedback:\n%s", repair)
+	}
+}
+
+func TestValidateGateParser(t *testing.T) {
+	for _, tt := range []struct {
+		def  GateDefinition
+		want string
+	}{
+		{GateDefinition{Type: "command", Command: []string{"go", "vet"}, Parser: "pylint"}, "unknown parser"},
+		{GateDefinition{Type: "command", Command: []string{"make"}, Parser: "regex"}, "requires a pattern"},
+		{GateDefinition{Type: "stubcheck", Parser: "go_vet"}, "only applies to command gates"},
+	} {
+		p := &Pipeline{
+			Name:   "parsed",
+			Gates:  map[string]GateDefinition{"check": tt.def},
+			Stages: []*Stage{{Name: "code", Prompt: "code", Gates: []string{"check"}}},
+		}
+		if err := p.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("expected %q error, got %v", tt.want, err)
+		}
+	}
+}
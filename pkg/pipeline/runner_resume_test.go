@@ -0,0 +1,195 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+)
+
+// flakyAdapter echoes prompts and fails any prompt containing failOn.
+type flakyAdapter struct {
+	mu     sync.Mutex
+	failOn string
+	calls  []string
+}
+
+func (a *flakyAdapter) Generate(ctx context.Context, model string, prompt string) (*adapter.Response, error) {
+	a.mu.Lock()
+	a.calls = append(a.calls, prompt)
+	a.mu.Unlock()
+	if a.failOn != "" && strings.Contains(prompt, a.failOn) {
+		return nil, fmt.Errorf("provider unavailable")
+	}
+	return &adapter.Response{Artifact: artifact.New("out:"+prompt, "flaky", model, prompt)}, nil
+}
+
+func (a *flakyAdapter) Name() string { return "flaky" }
+
+func (a *flakyAdapter) Models() []string { return []string{"mock-1"} }
+
+func resumePipeline(a adapter.Adapter) *Pipeline {
+	return &Pipeline{
+		Name: "resume",
+		Stages: []*Stage{
+			{Name: "research", Prompt: "research {{ .Input }}"},
+			{Name: "draft", Prompt: "draft {{ .Artifacts.research.Text }}"},
+		},
+		Adapters: map[string]adapter.Adapter{"flaky": a},
+	}
+}
+
+func onlyRunDir(t *testing.T, base string) string {
+	t.Helper()
+	entries, err := os.ReadDir(base)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one run directory in %s: %v", base, err)
+	}
+	return filepath.Join(base, entries[0].Name())
+}
+
+func TestResumeReusesCompletedStages(t *testing.T) {
+	base := t.TempDir()
+	first := &flakyAdapter{failOn: "draft"}
+	_, err := Run(context.Background(), resumePipeline(first), RunOptions{Input: "topic", EvidenceDir: base, WorkspacePath: t.TempDir()})
+	if err == nil {
+		t.Fatalf("expected first run to fail")
+	}
+	runDir := onlyRunDir(t, base)
+
+	second := &flakyAdapter{}
+	result, err := Resume(context.Background(), resumePipeline(second), runDir, RunOptions{WorkspacePath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if len(second.calls) != 1 || second.calls[0] != "draft out:research topic" {
+		t.Fatalf("expected only draft to run again, got %v", second.calls)
+	}
+	if got := result.Stages["draft"].Artifact.Content; got != "out:draft out:research topic" {
+		t.Fatalf("unexpected draft output %q", got)
+	}
+	if result.EvidenceDir != runDir {
+		t.Fatalf("expected resume to reuse %s, got %s", runDir, result.EvidenceDir)
+	}
+
+	run, err := evidence.ReadRun(runDir)
+	if err != nil {
+		t.Fatalf("read run: %v", err)
+	}
+	if len(run.Resumes) != 1 {
+		t.Fatalf("expected one resume event, got %d", len(run.Resumes))
+	}
+	event := run.Resumes[0]
+	if strings.Join(event.ReusedStages, ",") != "research" || strings.Join(event.RerunStages, ",") != "draft" {
+		t.Fatalf("unexpected resume event: %+v", event)
+	}
+
+	stages, err := evidence.ReadStages(runDir)
+	if err != nil {
+		t.Fatalf("read stages: %v", err)
+	}
+	if stages["draft"].OutputRef == "" || len(stages["draft"].PriorAttempts) != 0 {
+		t.Fatalf("unexpected draft record: %+v", stages["draft"])
+	}
+}
+
+func TestResumeRejectsTamperedOutput(t *testing.T) {
+	base := t.TempDir()
+	first := &flakyAdapter{failOn: "draft"}
+	if _, err := Run(context.Background(), resumePipeline(first), RunOptions{Input: "topic", EvidenceDir: base, WorkspacePath: t.TempDir()}); err == nil {
+		t.Fatalf("expected first run to fail")
+	}
+	runDir := onlyRunDir(t, base)
+
+	stages, err := evidence.ReadStages(runDir)
+	if err != nil {
+		t.Fatalf("read stages: %v", err)
+	}
+	blob := filepath.Join(runDir, filepath.FromSlash(stages["research"].OutputRef))
+	if err := os.WriteFile(blob, []byte("tampered"), 0600); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+
+	second := &flakyAdapter{}
+	if _, err := Resume(context.Background(), resumePipeline(second), runDir, RunOptions{WorkspacePath: t.TempDir()}); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if len(second.calls) != 2 {
+		t.Fatalf("expected both stages to run again, got %v", second.calls)
+	}
+}
+
+func TestResumeRejectsDifferentInput(t *testing.T) {
+	base := t.TempDir()
+	first := &flakyAdapter{failOn: "draft"}
+	if _, err := Run(context.Background(), resumePipeline(first), RunOptions{Input: "topic", EvidenceDir: base, WorkspacePath: t.TempDir()}); err == nil {
+		t.Fatalf("expected first run to fail")
+	}
+
+	_, err := Resume(context.Background(), resumePipeline(&flakyAdapter{}), onlyRunDir(t, base), RunOptions{Input: "other"})
+	if err == nil || !strings.Contains(err.Error(), "input") {
+		t.Fatalf("expected input mismatch error, got %v", err)
+	}
+}
+
+func gatedResumePipeline(a adapter.Adapter, check string) *Pipeline {
+	p := resumePipeline(a)
+	p.Gates = map[string]GateDefinition{
+		"checks": {Type: "group", Gates: []string{"check"}},
+		"check":  shellGate(check),
+	}
+	p.Stages[0].Gates = []string{"checks"}
+	return p
+}
+
+func TestResumeRestoresGateResults(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	base := t.TempDir()
+	workspacePath := t.TempDir()
+	if _, err := Run(context.Background(), gatedResumePipeline(&flakyAdapter{failOn: "draft"}, "true"), RunOptions{Input: "topic", EvidenceDir: base, WorkspacePath: workspacePath, ApplyApproved: true}); err == nil {
+		t.Fatalf("expected first run to fail")
+	}
+
+	second := &flakyAdapter{}
+	result, err := Resume(context.Background(), gatedResumePipeline(second, "true"), onlyRunDir(t, base), RunOptions{WorkspacePath: workspacePath, ApplyApproved: true})
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if len(second.calls) != 1 {
+		t.Fatalf("expected research to be reused, got %v", second.calls)
+	}
+	gates := result.Stages["research"].GateResults
+	if len(gates) != 1 || !gates[0].passed() || gates[0].Severity != "block" || len(gates[0].Members) != 1 || !gates[0].Members[0].passed() {
+		t.Fatalf("expected the reused stage's gates to be restored as passed, got %+v", gates)
+	}
+}
+
+func TestResumeRerunsStageWhenGateChanges(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	base := t.TempDir()
+	workspacePath := t.TempDir()
+	if _, err := Run(context.Background(), gatedResumePipeline(&flakyAdapter{failOn: "draft"}, "true"), RunOptions{Input: "topic", EvidenceDir: base, WorkspacePath: workspacePath, ApplyApproved: true}); err == nil {
+		t.Fatalf("expected first run to fail")
+	}
+
+	// Only the group member's command changes.
+	second := &flakyAdapter{}
+	if _, err := Resume(context.Background(), gatedResumePipeline(second, "test -d ."), onlyRunDir(t, base), RunOptions{WorkspacePath: workspacePath, ApplyApproved: true}); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if len(second.calls) != 2 {
+		t.Fatalf("expected both stages to run again, got %v", second.calls)
+	}
+}
@@ -0,0 +1,91 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+	"github.com/zen-systems/flowgate/pkg/workspace"
+)
+
+func TestRollbackRestoresRealWorkspace(t *testing.T) {
+	workspacePath := t.TempDir()
+	notes := filepath.Join(workspacePath, "notes.txt")
+	if err := os.WriteFile(notes, []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatalf("write workspace: %v", err)
+	}
+
+	chat := &chatRecorder{outputs: []string{
+		"// file: notes.txt\nONE\ntwo\n// file: added.txt\nnew\n",
+		"--- a/notes.txt\n+++ b/notes.txt\n@@ -1,2 +1,2 @@\n ONE\n-two\n+TWO\n",
+	}}
+	p := &Pipeline{
+		Name: "rollback",
+		Stages: []*Stage{
+			{Name: "first", Prompt: "edit", Adapter: "chat", Model: "mock-1", Apply: true},
+			{Name: "second", Prompt: "edit", Adapter: "chat", Model: "mock-1", Apply: true, DependsOn: []string{"first"}},
+		},
+		Adapters: map[string]adapter.Adapter{"chat": chat},
+	}
+
+	base := t.TempDir()
+	_, err := Run(context.Background(), p, RunOptions{
+		Input:         "input",
+		WorkspacePath: workspacePath,
+		EvidenceDir:   base,
+		ApplyForReal:  true,
+		ApplyApproved: true,
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	runDir := onlyRunDir(t, base)
+	applied, err := os.ReadFile(notes)
+	if err != nil {
+		t.Fatalf("read workspace: %v", err)
+	}
+
+	if err := os.WriteFile(notes, []byte("edited by hand\n"), 0644); err != nil {
+		t.Fatalf("edit workspace: %v", err)
+	}
+	var restoreErr *workspace.RestoreError
+	if _, err := Rollback(runDir, ""); !errors.As(err, &restoreErr) {
+		t.Fatalf("expected modified file to block rollback, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workspacePath, "added.txt")); err != nil {
+		t.Fatalf("expected blocked rollback to leave added.txt: %v", err)
+	}
+
+	if err := os.WriteFile(notes, applied, 0644); err != nil {
+		t.Fatalf("reset workspace: %v", err)
+	}
+	result, err := Rollback(runDir, "")
+	if err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if len(result.Restored) != 1 || result.Restored[0] != "notes.txt" || len(result.Removed) != 1 || result.Removed[0] != "added.txt" {
+		t.Fatalf("unexpected rollback result: %+v", result)
+	}
+	data, err := os.ReadFile(notes)
+	if err != nil || string(data) != "one\ntwo\n" {
+		t.Fatalf("expected notes.txt to be restored, got %q (%v)", string(data), err)
+	}
+	if _, err := os.Stat(filepath.Join(workspacePath, "added.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected added.txt to be removed: %v", err)
+	}
+
+	runRecord, err := evidence.ReadRun(runDir)
+	if err != nil {
+		t.Fatalf("read run: %v", err)
+	}
+	if len(runRecord.Rollbacks) != 1 {
+		t.Fatalf("expected a rollback event, got %+v", runRecord.Rollbacks)
+	}
+	if _, err := Rollback(runDir, ""); err == nil {
+		t.Fatalf("expected a second rollback to be rejected")
+	}
+}
@@ -0,0 +1,187 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/config"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+)
+
+func TestSamplesApplyOnlyTheCandidateThatPasses(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	realWorkspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(realWorkspace, "out.txt"), []byte("original\n"), 0644); err != nil {
+		t.Fatalf("write workspace: %v", err)
+	}
+	var calls atomic.Int32
+	script := &scriptAdapter{respond: func(_, _ string) (string, error) {
+		if calls.Add(1) == 2 {
+			return "// file: out.txt\ngood\n", nil
+		}
+		return "// file: out.txt\nbad\n", nil
+	}}
+	p := &Pipeline{
+		Name:  "best-of-n",
+		Gates: map[string]GateDefinition{"good": shellGate("grep -q good out.txt")},
+		Stages: []*Stage{{
+			Name:    "code",
+			Prompt:  "code",
+			Apply:   true,
+			Gates:   []string{"good"},
+			Samples: 3,
+		}},
+		Adapters: map[string]adapter.Adapter{"script": script},
+	}
+	base := t.TempDir()
+	if _, err := Run(context.Background(), p, RunOptions{
+		Input:         "input",
+		WorkspacePath: realWorkspace,
+		EvidenceDir:   base,
+		ApplyForReal:  true,
+		ApplyApproved: true,
+	}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	runDir := onlyRunDir(t, base)
+
+	data, err := os.ReadFile(filepath.Join(realWorkspace, "out.txt"))
+	if err != nil || strings.TrimSpace(string(data)) != "good" {
+		t.Fatalf("expected only the passing sample to be applied, got %q %v", data, err)
+	}
+	record := readStageRecord(t, runDir, "code")
+	if len(record.Attempts) != 3 {
+		t.Fatalf("expected 3 sample attempts, got %+v", record.Attempts)
+	}
+	selected := 0
+	for i, attempt := range record.Attempts {
+		if attempt.Attempt != 1 || attempt.Sample != i+1 || attempt.Cost == nil {
+			t.Fatalf("unexpected sample attempt: %+v", attempt)
+		}
+		if attempt.Selected {
+			selected++
+			if !attempt.Succeeded || attempt.WorkspaceMode != "real" || attempt.ApplyJournal == nil {
+				t.Fatalf("expected the selected sample to be applied for real: %+v", attempt)
+			}
+		} else if attempt.Succeeded || attempt.WorkspaceMode != "temp" {
+			t.Fatalf("expected the other samples to fail in clones: %+v", attempt)
+		}
+	}
+	if selected != 1 {
+		t.Fatalf("expected exactly one selected sample, got %d", selected)
+	}
+
+	data, err = os.ReadFile(filepath.Join(runDir, "run.json"))
+	if err != nil {
+		t.Fatalf("read run record: %v", err)
+	}
+	var run evidence.RunRecord
+	if err := json.Unmarshal(data, &run); err != nil {
+		t.Fatalf("unmarshal run record: %v", err)
+	}
+	if run.CostReport == nil || len(run.CostReport.Calls) != 3 {
+		t.Fatalf("expected every sample in the cost report: %+v", run.CostReport)
+	}
+}
+
+func TestSamplesAcrossModelsPickPassingCandidate(t *testing.T) {
+	script := &scriptAdapter{respond: func(model, _ string) (string, error) {
+		switch model {
+		case "broken":
+			return "", fmt.Errorf("unavailable")
+		case "loose":
+			return "not json", nil
+		}
+		return `{"steps": ["parse"]}`, nil
+	}}
+	p := &Pipeline{
+		Name: "best-of-n",
+		Stages: []*Stage{{
+			Name:   "plan",
+			Prompt: "plan",
+			OutputSchema: &SchemaSpec{Inline: map[string]any{
+				"type":     "object",
+				"required": []any{"steps"},
+			}},
+			SampleModels: []config.RouteTarget{
+				{Adapter: "script", Model: "broken"},
+				{Adapter: "script", Model: "loose"},
+				{Adapter: "script", Model: "strict"},
+			},
+		}},
+		Adapters: map[string]adapter.Adapter{"script": script},
+	}
+	base := t.TempDir()
+	result, err := Run(context.Background(), p, RunOptions{Input: "input", EvidenceDir: base})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got := result.Stages["plan"].Artifact.Model; got != "strict" {
+		t.Fatalf("expected the strict model's output to be kept, got %s", got)
+	}
+
+	record := readStageRecord(t, onlyRunDir(t, base), "plan")
+	if record.Model != "strict" || len(record.Attempts) != 3 {
+		t.Fatalf("unexpected stage record: %+v", record)
+	}
+	broken, loose, strict := record.Attempts[0], record.Attempts[1], record.Attempts[2]
+	if broken.Model != "broken" || broken.Error == "" || broken.Selected {
+		t.Fatalf("expected the failed call to be recorded: %+v", broken)
+	}
+	if loose.Model != "loose" || loose.Succeeded || loose.Selected {
+		t.Fatalf("expected the invalid sample to lose: %+v", loose)
+	}
+	if strict.Model != "strict" || !strict.Succeeded || !strict.Selected {
+		t.Fatalf("expected the valid sample to be selected: %+v", strict)
+	}
+}
+
+func TestSamplesPreferTheLowerGateScore(t *testing.T) {
+	script := &scriptAdapter{respond: func(model, _ string) (string, error) {
+		if model == "mocked" {
+			return "package users\n\nfunc Name() string {\n\treturn \"John Doe\"\n}\n", nil
+		}
+		return "package users\n\nfunc Name(id string) string {\n\treturn lookup(id)\n}\n", nil
+	}}
+	p := &Pipeline{
+		Name: "best-of-n",
+		Stages: []*Stage{{
+			Name:   "code",
+			Prompt: "code",
+			Gates:  []string{"stubcheck"},
+			SampleModels: []config.RouteTarget{
+				{Adapter: "script", Model: "mocked"},
+				{Adapter: "script", Model: "clean"},
+			},
+		}},
+		Adapters: map[string]adapter.Adapter{"script": script},
+	}
+	base := t.TempDir()
+	result, err := Run(context.Background(), p, RunOptions{Input: "input", EvidenceDir: base, WorkspacePath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got := result.Stages["code"].Artifact.Model; got != "clean" {
+		t.Fatalf("expected the sample without stub warnings to be kept, got %s", got)
+	}
+
+	record := readStageRecord(t, onlyRunDir(t, base), "code")
+	mocked, clean := record.Attempts[0], record.Attempts[1]
+	if !mocked.Succeeded || mocked.Selected {
+		t.Fatalf("expected the mocked sample to pass with a worse score and lose: %+v", mocked)
+	}
+	if !clean.Succeeded || !clean.Selected {
+		t.Fatalf("expected the clean sample to be selected: %+v", clean)
+	}
+}
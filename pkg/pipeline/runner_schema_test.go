@@ -0,0 +1,128 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+	"github.com/zen-systems/flowgate/pkg/gate"
+)
+
+func TestOutputSchemaRepairsAndExposesJSON(t *testing.T) {
+	chat := &chatRecorder{outputs: []string{
+		`{"steps": ["parse", 2]}`,
+		"```json\n{\"steps\": [\"parse\", \"emit\"]}\n```",
+		"done",
+	}}
+	p := &Pipeline{
+		Name: "structured",
+		Stages: []*Stage{
+			{
+				Name:       "plan",
+				Prompt:     "plan {{ .Input }}",
+				Adapter:    "chat",
+				Model:      "mock-1",
+				MaxRetries: 1,
+				OutputSchema: &SchemaSpec{Inline: map[string]any{
+					"type":     "object",
+					"required": []any{"steps"},
+					"properties": map[string]any{
+						"steps": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+					},
+				}},
+			},
+			{
+				Name:    "code",
+				Prompt:  "{{ range .Artifacts.plan.JSON.steps }}[{{ . }}]{{ end }}",
+				Adapter: "chat",
+				Model:   "mock-1",
+			},
+		},
+		Adapters: map[string]adapter.Adapter{"chat": chat},
+	}
+	evidenceDir := t.TempDir()
+	if _, err := Run(context.Background(), p, RunOptions{
+		Input:         "feature",
+		EvidenceDir:   evidenceDir,
+		WorkspacePath: t.TempDir(),
+	}); err != nil {
+		t.Fatalf("run pipeline: %v", err)
+	}
+
+	if len(chat.requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(chat.requests))
+	}
+	first := chat.requests[0]
+	if len(first.ResponseSchema) == 0 || !strings.Contains(first.System, "JSON Schema") {
+		t.Fatalf("expected schema to be requested, got %+v", first)
+	}
+	repair := chat.requests[1].Messages[len(chat.requests[1].Messages)-1].Content
+	if !strings.Contains(repair, "Location: /steps/1") {
+		t.Fatalf("expected repair feedback to point at the bad item:\n%s", repair)
+	}
+	if got := chat.requests[2].Messages[0].Content; got != "[parse][emit]" {
+		t.Fatalf("expected prompt rendered from parsed JSON, got %q", got)
+	}
+	if chat.requests[2].ResponseSchema != nil {
+		t.Fatal("stages without output_schema should not request structured output")
+	}
+
+	stages, err := evidence.ReadStages(onlyRunDir(t, evidenceDir))
+	if err != nil {
+		t.Fatalf("read stages: %v", err)
+	}
+	violations := stages["plan"].Attempts[0].GateResults[0].Violations
+	if len(violations) != 1 || violations[0].Rule != gate.JSONSchemaRule || violations[0].Location != "/steps/1" {
+		t.Fatalf("unexpected violations %+v", violations)
+	}
+}
+
+func TestLoadManifestResolvesSchemas(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "schemas"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "schemas", "plan.json"), []byte(`{"type": "object"}`), 0644); err != nil {
+		t.Fatalf("write schema: %v", err)
+	}
+	manifest := filepath.Join(dir, "pipeline.yaml")
+	content := `name: schemas
+gates:
+  outline:
+    type: json_schema
+    schema:
+      type: array
+      minItems: 1
+stages:
+  - name: plan
+    prompt: plan
+    output_schema: schemas/plan.json
+  - name: outline
+    prompt: outline
+    output_schema: '{"type": "array"}'
+    gates: [outline]
+`
+	if err := os.WriteFile(manifest, []byte(content), 0644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	p, err := LoadManifest(manifest)
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if p.Stages[0].OutputSchema.schema == nil || p.Stages[1].OutputSchema.schema == nil || p.Gates["outline"].Schema.schema == nil {
+		t.Fatal("expected schemas to be compiled")
+	}
+
+	p.Stages[0].OutputSchema = &SchemaSpec{Path: "schemas/missing.json"}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "stage plan output_schema") {
+		t.Fatalf("expected missing schema error, got %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/artifact"
+	"github.com/zen-systems/flowgate/pkg/config"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+)
+
+func TestRunStreamsStageOutput(t *testing.T) {
+	mock := adapter.NewMockAdapterWithResponses(map[string]string{
+		"hello": "a streamed response that spans several mock chunks",
+	}, "")
+	p := &Pipeline{
+		Name:     "stream",
+		Stages:   []*Stage{{Name: "answer", Prompt: "hello"}},
+		Adapters: map[string]adapter.Adapter{"mock": mock},
+	}
+
+	var mu sync.Mutex
+	var events []StreamEvent
+	result, err := Run(context.Background(), p, RunOptions{
+		Input:         "input",
+		EvidenceDir:   t.TempDir(),
+		WorkspacePath: t.TempDir(),
+		OnStream: func(event StreamEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event)
+		},
+	})
+	if err != nil {
+		t.Fatalf("run pipeline: %v", err)
+	}
+
+	if len(events) < 2 {
+		t.Fatalf("expected output in several deltas, got %d", len(events))
+	}
+	var sb strings.Builder
+	for _, event := range events {
+		if event.Stage != "answer" || event.Attempt != 1 {
+			t.Fatalf("unexpected event metadata: %+v", event)
+		}
+		sb.WriteString(event.Delta)
+	}
+	want := result.Stages["answer"].Artifact.Content
+	if sb.String() != want {
+		t.Fatalf("streamed %q, artifact %q", sb.String(), want)
+	}
+
+	stages, err := evidence.ReadStages(result.EvidenceDir)
+	if err != nil {
+		t.Fatalf("read stages: %v", err)
+	}
+	if stages["answer"].OutputHash != hashString(want) {
+		t.Fatalf("unexpected output hash %s", stages["answer"].OutputHash)
+	}
+}
+
+// droppingStreamAdapter streams part of its output and then fails with a
+// transient error on its first call.
+type droppingStreamAdapter struct {
+	calls int
+}
+
+func (a *droppingStreamAdapter) Generate(ctx context.Context, model string, prompt string) (*adapter.Response, error) {
+	return a.Stream(ctx, model, adapter.UserRequest(prompt), func(string) {})
+}
+
+func (a *droppingStreamAdapter) Stream(_ context.Context, model string, req adapter.Request, onDelta adapter.StreamFunc) (*adapter.Response, error) {
+	a.calls++
+	if a.calls == 1 {
+		onDelta("partial ")
+		return nil, &adapter.AdapterError{Status: 503, Temporary: true, Err: fmt.Errorf("connection reset")}
+	}
+	onDelta("complete answer")
+	return &adapter.Response{Artifact: artifact.New("complete answer", "dropping", model, req.Flatten())}, nil
+}
+
+func (a *droppingStreamAdapter) Name() string { return "dropping" }
+
+func (a *droppingStreamAdapter) Models() []string { return []string{"mock-1"} }
+
+func TestStreamResetsBeforeRetry(t *testing.T) {
+	cfg := &config.RoutingConfig{Retry: config.RetryConfig{MaxRetries: 1, BaseBackoffMs: 1, MaxBackoffMs: 1}}
+	var events []string
+	stream := &streamSink{
+		onDelta: func(delta string) { events = append(events, delta) },
+		onReset: func() { events = append(events, "<reset>") },
+	}
+	resp, _, err := callAdapterWithPolicy(
+		context.Background(),
+		map[string]adapter.Adapter{"dropping": &droppingStreamAdapter{}},
+		"dropping",
+		"mock-1",
+		adapter.UserRequest("prompt"),
+		cfg,
+		newCostTracker(cfg, 0),
+		nil,
+		stream,
+	)
+	if err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+	want := []string{"partial ", "<reset>", "complete answer"}
+	if strings.Join(events, "|") != strings.Join(want, "|") || resp.Artifact.Content != "complete answer" {
+		t.Fatalf("expected a reset between the dropped and the retried output, got %q", events)
+	}
+}
+
+func TestStreamResetsBeforeRepair(t *testing.T) {
+	chat := &chatRecorder{outputs: []string{"not json", `{"ok": true}`}}
+	p := &Pipeline{
+		Name: "repaired",
+		Stages: []*Stage{{
+			Name:         "answer",
+			Prompt:       "answer",
+			Adapter:      "chat",
+			Model:        "mock-1",
+			MaxRetries:   1,
+			OutputSchema: &SchemaSpec{Inline: map[string]any{"type": "object"}},
+		}},
+		Adapters: map[string]adapter.Adapter{"chat": chat},
+	}
+	var events []string
+	onStream := func(event StreamEvent) {
+		if event.Reset {
+			events = append(events, fmt.Sprintf("<reset %d>", event.Attempt))
+		} else {
+			events = append(events, fmt.Sprintf("%d:%s", event.Attempt, event.Delta))
+		}
+	}
+	if _, err := Run(context.Background(), p, RunOptions{Input: "input", EvidenceDir: t.TempDir(), OnStream: onStream}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	want := []string{"1:not json", "<reset 1>", `2:{"ok": true}`}
+	if strings.Join(events, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected the failed attempt's output to be discarded before the repair, got %q", events)
+	}
+}
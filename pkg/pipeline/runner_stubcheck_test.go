@@ -0,0 +1,67 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+)
+
+func TestStubCheckGateDrivesRepair(t *testing.T) {
+	chat := &chatRecorder{outputs: []string{
+		"package calc\n\nfunc Add(a, b int) int {\n\tpanic(\"not implemented\")\n}\n",
+		"package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n",
+	}}
+	p := &Pipeline{
+		Name:     "stubcheck",
+		Stages:   []*Stage{{Name: "code", Prompt: "write Add", Adapter: "chat", Model: "mock-1", MaxRetries: 1, Gates: []string{"stubcheck"}}},
+		Adapters: map[string]adapter.Adapter{"chat": chat},
+	}
+	result, err := Run(context.Background(), p, RunOptions{
+		Input:         "input",
+		EvidenceDir:   t.TempDir(),
+		WorkspacePath: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("run pipeline: %v", err)
+	}
+
+	if len(chat.requests) != 2 {
+		t.Fatalf("expected one repair, got %d requests", len(chat.requests))
+	}
+	messages := chat.requests[1].Messages
+	feedback := messages[len(messages)-1].Content
+	if !strings.Contains(feedback, `Replace panic("not implemented") with real implementation at artifact.go:4`) {
+		t.Fatalf("expected stub hint in repair feedback:\n%s", feedback)
+	}
+	gates := result.Stages["code"].GateResults
+	if len(gates) != 1 || gates[0].Name != "stubcheck" || !gates[0].Result.Passed {
+		t.Fatalf("expected passing stubcheck on the repaired output, got %+v", gates)
+	}
+}
+
+func TestStubCheckGateScansUnappliedDiff(t *testing.T) {
+	workspacePath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspacePath, "calc.go"), []byte("package calc\n\nfunc A() {}\n"), 0644); err != nil {
+		t.Fatalf("write workspace: %v", err)
+	}
+	chat := &chatRecorder{outputs: []string{
+		"--- a/calc.go\n+++ b/calc.go\n@@ -1,3 +1,5 @@\n package calc\n \n func A() {}\n+\n+func B() { panic(\"not implemented\") }\n",
+		"--- a/calc.go\n+++ b/calc.go\n@@ -1,3 +1,5 @@\n package calc\n \n func A() {}\n+\n+func B() int { return 1 }\n",
+	}}
+	// Without apply, the diff is gated against the untouched workspace.
+	p := &Pipeline{
+		Name:     "stubcheck-diff",
+		Stages:   []*Stage{{Name: "code", Prompt: "write B", Adapter: "chat", Model: "mock-1", MaxRetries: 1, Gates: []string{"stubcheck"}}},
+		Adapters: map[string]adapter.Adapter{"chat": chat},
+	}
+	if _, err := Run(context.Background(), p, RunOptions{Input: "input", EvidenceDir: t.TempDir(), WorkspacePath: workspacePath}); err != nil {
+		t.Fatalf("run pipeline: %v", err)
+	}
+	if len(chat.requests) != 2 {
+		t.Fatalf("expected the stub in the diff to force a repair, got %d requests", len(chat.requests))
+	}
+}
@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+	"github.com/zen-systems/flowgate/pkg/gate"
+)
+
+// stallingAdapter never answers; it returns once the context is done.
+type stallingAdapter struct{}
+
+func (stallingAdapter) Generate(ctx context.Context, _ string, _ string) (*adapter.Response, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (stallingAdapter) Name() string { return "stall" }
+
+func (stallingAdapter) Models() []string { return []string{"stall-1"} }
+
+func TestGateTimeoutRecordsViolation(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	p := &Pipeline{
+		Name: "gate-timeout",
+		Gates: map[string]GateDefinition{
+			"hang": {
+				Type:      "command",
+				Command:   []string{"sh", "-c", "sleep 30"},
+				DenyShell: boolPtr(false),
+				Timeout:   "200ms",
+			},
+		},
+		Stages:   []*Stage{{Name: "stage", Prompt: "hello", Gates: []string{"hang"}}},
+		Adapters: map[string]adapter.Adapter{"mock": adapter.NewMockAdapter()},
+	}
+	evidenceDir := t.TempDir()
+	start := time.Now()
+	_, err := Run(context.Background(), p, RunOptions{
+		Input:         "input",
+		EvidenceDir:   evidenceDir,
+		WorkspacePath: t.TempDir(),
+		ApplyApproved: true,
+	})
+	if err == nil {
+		t.Fatal("expected gate timeout to fail the run")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("gate was not stopped at its timeout, took %s", elapsed)
+	}
+
+	stages, err := evidence.ReadStages(onlyRunDir(t, evidenceDir))
+	if err != nil {
+		t.Fatalf("read stages: %v", err)
+	}
+	record := stages["stage"]
+	if len(record.Attempts) != 1 || len(record.Attempts[0].GateResults) != 1 {
+		t.Fatalf("expected one attempt with one gate record, got %+v", record.Attempts)
+	}
+	violations := record.Attempts[0].GateResults[0].Violations
+	if len(violations) != 1 || violations[0].Rule != gate.TimeoutRule {
+		t.Fatalf("expected timeout violation, got %+v", violations)
+	}
+}
+
+func TestStageAndRunTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		stage   string
+		run     time.Duration
+		wantErr string
+	}{
+		{name: "stage", stage: "100ms", wantErr: "stage slow timed out after 100ms"},
+		{name: "run", run: 100 * time.Millisecond, wantErr: "run timed out after 100ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pipeline{
+				Name:     "timeout",
+				Stages:   []*Stage{{Name: "slow", Prompt: "hello", Adapter: "stall", Model: "stall-1", Timeout: tt.stage}},
+				Adapters: map[string]adapter.Adapter{"stall": stallingAdapter{}},
+			}
+			_, err := Run(context.Background(), p, RunOptions{
+				Input:         "input",
+				EvidenceDir:   t.TempDir(),
+				WorkspacePath: t.TempDir(),
+				Timeout:       tt.run,
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsInvalidTimeout(t *testing.T) {
+	p := &Pipeline{
+		Name:   "invalid",
+		Stages: []*Stage{{Name: "stage", Prompt: "hello", Timeout: "soon"}},
+	}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "invalid timeout") {
+		t.Fatalf("expected invalid timeout error, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/zen-systems/flowgate/pkg/adapter"
+	"github.com/zen-systems/flowgate/pkg/evidence"
+	"github.com/zen-systems/flowgate/pkg/workspace"
+)
+
+// stageSamples returns how many candidates a stage draws for its first
+// attempt, or 0 when it does not sample.
+func stageSamples(stage *Stage) int {
+	n := stage.Samples
+	if n == 0 {
+		n = len(stage.SampleModels)
+	}
+	if n <= 1 && len(stage.SampleModels) == 0 {
+		return 0
+	}
+	return n
+}
+
+// sampleTarget returns the adapter and model candidate i is drawn from.
+func sampleTarget(env *stageEnv, stage *Stage, target callTarget, i int) callTarget {
+	if len(stage.SampleModels) == 0 {
+		return target
+	}
+	entry := stage.SampleModels[i%len(stage.SampleModels)]
+	model := entry.Model
+	if model == "" {
+		if impl, ok := env.adapters[entry.Adapter]; ok {
+			if models := impl.Models(); len(models) > 0 {
+				model = models[0]
+			}
+		}
+	}
+	return callTarget{Adapter: entry.Adapter, Model: model}
+}
+
+// drawSamples runs a stage's first attempt as several candidates at once and
+// returns the best one along with a record for every candidate.
+//
+// Each candidate is applied and gated in its own clone, even when the stage
+// applies for real; only the winner is then applied to the real workspace,
+// without running its gates again. Candidates bypass the response cache,
+// which would otherwise hand all of them the same output.
+func drawSamples(
+	ctx context.Context,
+	env *stageEnv,
+	stage *Stage,
+	target callTarget,
+	req adapter.Request,
+	opts workspace.ApplyOptions,
+	clone *stageClone,
+) (*attemptOutcome, []evidence.AttemptRecord, error) {
+	n := stageSamples(stage)
+	dry := *env
+	dry.applyForReal = false
+
+	outcomes := make([]*attemptOutcome, n)
+	errs := make([]error, n)
+	clones := make([]*stageClone, n)
+	var wg sync.WaitGroup
+	for i := range outcomes {
+		clones[i] = &stageClone{env: env}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			outcomes[i], errs[i] = runAttempt(ctx, &dry, stage, sampleTarget(env, stage, target, i), req, 1, opts, clones[i], nil, env.streamSink(stage.Name, 1, i+1))
+		}(i)
+	}
+	wg.Wait()
+
+	best := -1
+	for i, outcome := range outcomes {
+		if outcome != nil && (best < 0 || betterSample(env, outcome, outcomes[best])) {
+			best = i
+		}
+	}
+	for i, c := range clones {
+		if i != best {
+			c.cleanup()
+		}
+	}
+
+	if best >= 0 {
+		winner := outcomes[best]
+		if env.applyForReal && stage.Apply && winner.succeeded() {
+			clones[best].cleanup()
+			result, path, mode, journal, err := applyAttempt(env, stage, winner.art, opts, clone)
+			winner.applyResult, winner.applyPath, winner.applyErr = result, path, err
+			winner.record.WorkspaceUsed = path
+			winner.record.WorkspaceMode = mode
+			winner.record.ApplyJournal = journal
+			if err != nil {
+				winner.record.ApplyError = err.Error()
+				winner.record.ApplyRejects = evidenceApplyRejects(err)
+				winner.record.Succeeded = false
+			}
+		} else {
+			// Repairs continue in the winner's clone.
+			*clone = *clones[best]
+		}
+	}
+
+	records := make([]evidence.AttemptRecord, n)
+	for i, outcome := range outcomes {
+		if outcome == nil {
+			t := sampleTarget(env, stage, target, i)
+			records[i] = evidence.AttemptRecord{Attempt: 1, Sample: i + 1, Adapter: t.Adapter, Model: t.Model, Error: errs[i].Error()}
+			continue
+		}
+		cost := outcome.cost
+		record := outcome.record
+		record.Sample = i + 1
+		record.Selected = i == best
+		record.Adapter = outcome.art.Adapter
+		record.Model = outcome.art.Model
+		record.Cost = &cost
+		records[i] = record
+	}
+	if best < 0 {
+		return nil, records, fmt.Errorf("stage %s: all %d samples failed: %w", stage.Name, n, errs[0])
+	}
+	return outcomes[best], records, nil
+}
+
+// betterSample reports whether candidate a beats b. A candidate that passes
+// beats one that does not; after that, more passing gates, a lower mean
+// gate score and a lower cost win, in that order. Gate scores measure what is
+// wrong with the output, so lower is better.
+func betterSample(env *stageEnv, a, b *attemptOutcome) bool {
+	if a.succeeded() != b.succeeded() {
+		return a.succeeded()
+	}
+	passedA, scoreA := sampleScore(env, a)
+	passedB, scoreB := sampleScore(env, b)
+	if passedA != passedB {
+		return passedA > passedB
+	}
+	if scoreA != scoreB {
+		return scoreA < scoreB
+	}
+	return a.cost.Amount < b.cost.Amount
+}
+
+// sampleScore counts a candidate's passing gates and averages their scores,
+// weighted like the members of a gate group.
+func sampleScore(env *stageEnv, outcome *attemptOutcome) (int, float64) {
+	passed := 0
+	var weighted, totalWeight float64
+	for _, res := range outcome.gateResults {
+		if res.passed() {
+			passed++
+		}
+		if res.Result == nil {
+			continue
+		}
+		weight := env.pipeline.Gates[res.Name].Weight
+		if weight == 0 {
+			weight = 1
+		}
+		weighted += weight * float64(res.Result.Score)
+		totalWeight += weight
+	}
+	if totalWeight == 0 {
+		return passed, 0
+	}
+	return passed, weighted / totalWeight
+}
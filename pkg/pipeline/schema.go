@@ -0,0 +1,110 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zen-systems/flowgate/pkg/gate"
+	"github.com/zen-systems/flowgate/pkg/jsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// outputSchemaGate names the implicit gate that checks stages with an
+// output_schema.
+const outputSchemaGate = "output_schema"
+
+// SchemaSpec is a JSON Schema written inline in the manifest, either as YAML
+// or as a JSON string, or given as a path to a schema file. Relative paths
+// resolve against the manifest's directory.
+type SchemaSpec struct {
+	Path   string
+	Inline any
+
+	schema *jsonschema.Schema
+}
+
+// UnmarshalYAML accepts a mapping (inline schema) or a scalar (path or JSON).
+func (s *SchemaSpec) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		value := strings.TrimSpace(node.Value)
+		if strings.HasPrefix(value, "{") {
+			var inline any
+			if err := json.Unmarshal([]byte(value), &inline); err != nil {
+				return fmt.Errorf("line %d: invalid inline schema: %w", node.Line, err)
+			}
+			s.Inline = inline
+			return nil
+		}
+		s.Path = value
+		return nil
+	case yaml.MappingNode:
+		var inline map[string]any
+		if err := node.Decode(&inline); err != nil {
+			return err
+		}
+		s.Inline = inline
+		return nil
+	default:
+		return fmt.Errorf("line %d: schema must be a mapping or a file path", node.Line)
+	}
+}
+
+// MarshalYAML writes the schema back in the form it was given.
+func (s SchemaSpec) MarshalYAML() (any, error) {
+	if s.Path != "" {
+		return s.Path, nil
+	}
+	return s.Inline, nil
+}
+
+// load reads and compiles the schema.
+func (s *SchemaSpec) load(baseDir string) error {
+	var data []byte
+	switch {
+	case s.Path != "":
+		path := s.Path
+		if !filepath.IsAbs(path) && baseDir != "" {
+			path = filepath.Join(baseDir, path)
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read schema: %w", err)
+		}
+		data = raw
+	case s.Inline != nil:
+		raw, err := json.Marshal(s.Inline)
+		if err != nil {
+			return fmt.Errorf("encode schema: %w", err)
+		}
+		data = raw
+	default:
+		return fmt.Errorf("schema is empty")
+	}
+
+	schema, err := jsonschema.Compile(data)
+	if err != nil {
+		return err
+	}
+	s.schema = schema
+	return nil
+}
+
+// schemaInstruction is appended to the system prompt of structured stages.
+// Providers without native structured output rely on it entirely.
+func schemaInstruction(schema *jsonschema.Schema) string {
+	return "Respond with a single JSON document, without prose or code fences, that conforms to this JSON Schema:\n" + string(schema.Raw())
+}
+
+// parseStructuredOutput decodes the JSON document in a structured stage's
+// output for use in templates. It returns nil when the output is not JSON.
+func parseStructuredOutput(content string) any {
+	var value any
+	if err := json.Unmarshal([]byte(gate.ExtractJSON(content)), &value); err != nil {
+		return nil
+	}
+	return value
+}
@@ -1,19 +1,51 @@
 package pipeline
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/zen-systems/flowgate/pkg/config"
+)
 
 // Stage represents a single step in a pipeline.
 type Stage struct {
-	Name          string   `yaml:"name"`
-	TaskType      string   `yaml:"task_type"`
-	Adapter       string   `yaml:"adapter,omitempty"`
-	Model         string   `yaml:"model,omitempty"`
-	FallbackModel string   `yaml:"fallback_model,omitempty"`
-	Prompt        string   `yaml:"prompt"`
-	Gates         []string `yaml:"gates,omitempty"`
-	MaxRetries    int      `yaml:"max_retries,omitempty"`
-	Apply         bool     `yaml:"apply,omitempty"`
-	EscalateOn    string   `yaml:"escalate_on,omitempty"`
+	Name          string      `yaml:"name"`
+	TaskType      string      `yaml:"task_type"`
+	Adapter       string      `yaml:"adapter,omitempty"`
+	Model         string      `yaml:"model,omitempty"`
+	FallbackModel string      `yaml:"fallback_model,omitempty"`
+	System        string      `yaml:"system,omitempty"`
+	Prompt        string      `yaml:"prompt"`
+	MaxTokens     int         `yaml:"max_tokens,omitempty"`
+	Temperature   *float64    `yaml:"temperature,omitempty"`
+	Cache         *bool       `yaml:"cache,omitempty"`
+	OutputSchema  *SchemaSpec `yaml:"output_schema,omitempty"`
+	Gates         []string    `yaml:"gates,omitempty"`
+	MaxRetries    int         `yaml:"max_retries,omitempty"`
+	Apply         bool        `yaml:"apply,omitempty"`
+	ApplyMode     string      `yaml:"apply_mode,omitempty"`
+	ApplyFuzz     *int        `yaml:"apply_fuzz,omitempty"`
+	Timeout       string      `yaml:"timeout,omitempty"`
+	DependsOn     []string    `yaml:"depends_on,omitempty"`
+	When          string      `yaml:"when,omitempty"`
+	OnFailure     string      `yaml:"on_failure,omitempty"`
+	ForEach       *ForEach    `yaml:"for_each,omitempty"`
+	// Type is "approval" for a stage that waits for a human decision on its
+	// dependencies instead of calling an adapter.
+	Type string `yaml:"type,omitempty"`
+	// RequireApproval holds the stage's output for a human decision before
+	// dependents see it or it is applied to the real workspace.
+	RequireApproval bool `yaml:"require_approval,omitempty"`
+	// Samples draws that many candidates for the first attempt and keeps the
+	// best; SampleModels spreads them over adapters and models in turn.
+	Samples      int                  `yaml:"samples,omitempty"`
+	SampleModels []config.RouteTarget `yaml:"sample_models,omitempty"`
+
+	// EscalateOn changes how the stage repairs once a trigger fires, e.g.
+	// switching to a stronger model after repeated failures.
+	EscalateOn EscalationPolicies `yaml:"escalate_on,omitempty"`
+
+	// item is set on the instances a for_each stage runs per item.
+	item *stageItem
 }
 
 // Execute runs a stage directly. Prefer running via the pipeline runner.
@@ -0,0 +1,73 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zen-systems/flowgate/pkg/gate"
+)
+
+// parseTimeout parses a manifest duration such as "90s" or "10m". An empty
+// value means no limit.
+func parseTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", value, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("timeout %q must be positive", value)
+	}
+	return d, nil
+}
+
+// withTimeout derives a context bounded by limit, or returns ctx unchanged
+// when limit is zero.
+func withTimeout(ctx context.Context, limit time.Duration) (context.Context, context.CancelFunc) {
+	if limit <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, limit)
+}
+
+// timedOut reports whether ctx hit its own deadline rather than inheriting
+// cancellation from parent.
+func timedOut(ctx, parent context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded) && parent.Err() == nil
+}
+
+// timeoutError annotates err when ctx expired on its own deadline.
+func timeoutError(ctx, parent context.Context, what string, limit time.Duration, err error) error {
+	if err == nil || !timedOut(ctx, parent) {
+		return err
+	}
+	return fmt.Errorf("%s timed out after %s: %w", what, limit, err)
+}
+
+// gateTimeoutResult is recorded for a gate that ran out of time without
+// reporting a timeout itself.
+func gateTimeoutResult(prev *gate.GateResult, limit time.Duration) *gate.GateResult {
+	result := &gate.GateResult{Score: 100}
+	if prev != nil {
+		result.Kind = prev.Kind
+		result.Diagnostics = prev.Diagnostics
+	}
+	result.Violations = []gate.Violation{gate.TimeoutViolation("gate", limit)}
+	return result
+}
+
+func hasTimeoutViolation(result *gate.GateResult) bool {
+	if result == nil {
+		return false
+	}
+	for _, v := range result.Violations {
+		if v.Rule == gate.TimeoutRule {
+			return true
+		}
+	}
+	return false
+}
@@ -18,24 +18,81 @@ func GenerateRepairPrompt(original *artifact.Artifact, result *gate.GateResult)
 	sb.WriteString(original.Content)
 	sb.WriteString("\n---\n\n")
 
+	writeIssues(&sb, result)
+	sb.WriteString("\nPlease fix all issues and provide the corrected output.")
+
+	return sb.String()
+}
+
+// GenerateRepairFeedback creates a follow-up message for a conversation in
+// which the failing output is already the previous assistant turn.
+func GenerateRepairFeedback(result *gate.GateResult) string {
+	var sb strings.Builder
+
+	sb.WriteString("Your previous response failed quality checks.\n\n")
+	writeIssues(&sb, result)
+	sb.WriteString("\nPlease fix all issues and provide the complete corrected output.")
+
+	return sb.String()
+}
+
+// GenerateEscalationFeedback is the conversational form of
+// GenerateEscalationPrompt.
+func GenerateEscalationFeedback(result *gate.GateResult, requireDiff bool) string {
+	var sb strings.Builder
+
+	sb.WriteString("Your responses are repeating and still fail quality checks.\n")
+	sb.WriteString("Do NOT repeat the previous output; change the implementation.\n\n")
+
 	sb.WriteString("Issues found:\n")
 	for _, v := range result.Violations {
-		sb.WriteString(fmt.Sprintf("- [%s] %s: %s\n", v.Severity, v.Rule, v.Message))
-		if v.Suggestion != "" {
-			sb.WriteString(fmt.Sprintf("  Suggestion: %s\n", v.Suggestion))
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", v.Rule, v.Message))
+	}
+
+	if requireDiff {
+		sb.WriteString("\nReturn a unified diff only.\n")
+	}
+
+	sb.WriteString("\nProvide a corrected implementation that addresses the issues above.\n")
+
+	return sb.String()
+}
+
+func writeIssues(sb *strings.Builder, result *gate.GateResult) {
+	var blocking, warnings []gate.Violation
+	for _, v := range result.Violations {
+		if v.Severity == "warning" {
+			warnings = append(warnings, v)
+		} else {
+			blocking = append(blocking, v)
 		}
 	}
 
+	sb.WriteString("Issues found:\n")
+	writeViolations(sb, blocking)
+	if len(warnings) > 0 {
+		sb.WriteString("\nWarnings (non-blocking, fix if it does not risk the issues above):\n")
+		writeViolations(sb, warnings)
+	}
+
 	if len(result.RepairHints) > 0 {
 		sb.WriteString("\nRepair hints:\n")
 		for _, hint := range result.RepairHints {
 			sb.WriteString(fmt.Sprintf("- %s\n", hint))
 		}
 	}
+}
 
-	sb.WriteString("\nPlease fix all issues and provide the corrected output.")
-
-	return sb.String()
+func writeViolations(sb *strings.Builder, violations []gate.Violation) {
+	for _, v := range violations {
+		sb.WriteString(fmt.Sprintf("- [%s] %s: %s\n", v.Severity, v.Rule, v.Message))
+		if v.Location != "" {
+			sb.WriteString(fmt.Sprintf("  Location: %s\n", v.Location))
+		}
+		if v.Suggestion != "" {
+			sb.WriteString(fmt.Sprintf("  Suggestion: %s\n", v.Suggestion))
+		}
+	}
 }
 
 // GenerateEscalationPrompt creates a stronger prompt when the repair loop is stuck.
@@ -25,3 +25,40 @@ func TestGenerateEscalationPromptRequestsDiff(t *testing.T) {
 		t.Fatalf("missing unified diff request")
 	}
 }
+
+func TestGenerateRepairFeedbackOmitsOutput(t *testing.T) {
+	result := gate.NewFailingResult(100, []gate.Violation{
+		{
+			Rule:       "rule",
+			Severity:   "error",
+			Message:    "message",
+			Location:   "/steps/1",
+			Suggestion: "suggestion",
+		},
+	}, []string{"hint"})
+
+	feedback := GenerateRepairFeedback(result)
+	for _, want := range []string{"[error] rule: message", "Location: /steps/1", "Suggestion: suggestion", "- hint"} {
+		if !strings.Contains(feedback, want) {
+			t.Fatalf("feedback missing %q:\n%s", want, feedback)
+		}
+	}
+	if strings.Contains(feedback, "---") {
+		t.Fatalf("feedback should not re-paste the output:\n%s", feedback)
+	}
+}
+
+func TestGenerateRepairFeedbackSeparatesWarnings(t *testing.T) {
+	result := gate.NewFailingResult(100, []gate.Violation{
+		{Rule: "lint", Severity: "warning", Message: "line too long"},
+		{Rule: "test", Severity: "error", Message: "TestAdd failed"},
+	}, nil)
+
+	feedback := GenerateRepairFeedback(result)
+	issues := strings.Index(feedback, "[error] test: TestAdd failed")
+	header := strings.Index(feedback, "Warnings (non-blocking")
+	warning := strings.Index(feedback, "[warning] lint: line too long")
+	if issues < 0 || header < issues || warning < header {
+		t.Fatalf("expected warnings after blocking issues:\n%s", feedback)
+	}
+}
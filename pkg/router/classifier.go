@@ -15,11 +15,31 @@ import (
 type Classifier struct {
 	adapters map[string]adapter.Adapter
 	config   *config.RoutingConfig
+	call     ClassifierCall
+}
+
+// ClassifierCall sends the LLM tie-breaker prompt to an adapter and model and
+// returns the response text.
+type ClassifierCall func(ctx context.Context, adapterName, model, prompt string) (string, error)
+
+// ClassifierOption configures a Classifier.
+type ClassifierOption func(*Classifier)
+
+// WithClassifierCall makes the LLM tie-breaker go through call instead of
+// the adapter's Generate, e.g. to apply retries and track its cost.
+func WithClassifierCall(call ClassifierCall) ClassifierOption {
+	return func(c *Classifier) {
+		c.call = call
+	}
 }
 
 // NewClassifier creates a new classifier with adapters and routing config.
-func NewClassifier(adapters map[string]adapter.Adapter, cfg *config.RoutingConfig) *Classifier {
-	return &Classifier{adapters: adapters, config: cfg}
+func NewClassifier(adapters map[string]adapter.Adapter, cfg *config.RoutingConfig, opts ...ClassifierOption) *Classifier {
+	c := &Classifier{adapters: adapters, config: cfg}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // Classify determines the task type for a prompt.
@@ -46,17 +66,13 @@ func (c *Classifier) Classify(ctx context.Context, prompt string) (*Decision, er
 	}
 
 	promptText := buildClassifierPrompt(prompt, decision.Candidates)
-	resp, err := adapterImpl.Generate(ctx, model, promptText)
+	content, err := c.generate(ctx, adapterImpl, adapterName, model, promptText)
 	if err != nil {
 		decision.Reasons = append(decision.Reasons, fmt.Sprintf("classifier error: %v", err))
 		return decision, err
 	}
-	if resp == nil || resp.Artifact == nil {
-		decision.Reasons = append(decision.Reasons, "classifier returned empty response")
-		return decision, fmt.Errorf("classifier returned empty response")
-	}
 
-	picked, err := parseClassifierResponse(resp.Artifact.Content)
+	picked, err := parseClassifierResponse(content)
 	if err != nil {
 		decision.Reasons = append(decision.Reasons, fmt.Sprintf("classifier response invalid: %v", err))
 		return decision, err
@@ -81,6 +97,22 @@ func (c *Classifier) Classify(ctx context.Context, prompt string) (*Decision, er
 	return decision, nil
 }
 
+// generate sends the tie-breaker prompt, through the configured call if there
+// is one.
+func (c *Classifier) generate(ctx context.Context, adapterImpl adapter.Adapter, adapterName, model, prompt string) (string, error) {
+	if c.call != nil {
+		return c.call(ctx, adapterName, model, prompt)
+	}
+	resp, err := adapterImpl.Generate(ctx, model, prompt)
+	if err != nil {
+		return "", err
+	}
+	if resp == nil || resp.Artifact == nil {
+		return "", fmt.Errorf("classifier returned empty response")
+	}
+	return resp.Artifact.Content, nil
+}
+
 type classifierPick struct {
 	TaskType   string  `json:"task_type"`
 	Confidence float64 `json:"confidence"`
@@ -1,6 +1,7 @@
 package workspace
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,18 +12,108 @@ const (
 	fileModeDefault = 0644
 )
 
+// Apply modes.
+const (
+	// ApplyStrict requires every hunk to match at its header line with all
+	// of its context.
+	ApplyStrict = "strict"
+	// ApplyFuzzy searches for each hunk near its header line and may ignore
+	// up to Fuzz context lines at each end, like patch.
+	ApplyFuzzy = "fuzzy"
+	// ApplyMerge is ApplyFuzzy plus a three-way merge against the snapshot
+	// in Base when a hunk or file block no longer fits the current file.
+	ApplyMerge = "merge"
+)
+
+// DefaultFuzz is the fuzz factor used by patch.
+const DefaultFuzz = 2
+
+// ApplyOptions control how output is applied.
+type ApplyOptions struct {
+	// Mode is ApplyStrict, ApplyFuzzy or ApplyMerge. Empty means strict.
+	Mode string
+	// Fuzz is the number of context lines that may be ignored at each end
+	// of a hunk in fuzzy and merge modes.
+	Fuzz int
+	// Base is a copy of the workspace as the model saw it, used by merge
+	// mode.
+	Base string
+	// Journal, when set, receives the planned changes after they are staged
+	// and before any file is replaced. An error aborts the apply.
+	Journal func([]FileChange) error
+}
+
 // ApplyResult describes changes made to the workspace.
 type ApplyResult struct {
-	AppliedFiles    []string `json:"applied_files"`
-	DeletedFiles    []string `json:"deleted_files,omitempty"`
-	UsedUnifiedDiff bool     `json:"used_unified_diff"`
+	AppliedFiles    []string         `json:"applied_files"`
+	DeletedFiles    []string         `json:"deleted_files,omitempty"`
+	UsedUnifiedDiff bool             `json:"used_unified_diff"`
+	Adjustments     []HunkAdjustment `json:"adjustments,omitempty"`
+	// Changes records each file's previous state. Writes are committed as
+	// one transaction, so a failed apply leaves the workspace unchanged.
+	Changes []FileChange `json:"changes,omitempty"`
+}
+
+// HunkAdjustment records a hunk or file that was not applied verbatim.
+type HunkAdjustment struct {
+	File string `json:"file"`
+	// Hunk is the 1-based hunk index, or 0 for a file block.
+	Hunk   int  `json:"hunk,omitempty"`
+	Offset int  `json:"offset,omitempty"`
+	Fuzz   int  `json:"fuzz,omitempty"`
+	Merged bool `json:"merged,omitempty"`
 }
 
-// ApplyOutput applies either a unified diff or file-block output to the workspace.
+// HunkReject describes a hunk or file block that could not be applied.
+type HunkReject struct {
+	File string `json:"file"`
+	// Hunk is the 1-based hunk index, or 0 for a file block.
+	Hunk int `json:"hunk,omitempty"`
+	// Line is the line in the current file the reject refers to.
+	Line   int    `json:"line,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// ApplyError is returned when hunks or file blocks are rejected. Nothing is
+// written to the workspace.
+type ApplyError struct {
+	Rejects []HunkReject
+}
+
+func (e *ApplyError) Error() string {
+	parts := make([]string, 0, len(e.Rejects))
+	for _, r := range e.Rejects {
+		where := r.File
+		if r.Hunk > 0 {
+			where = fmt.Sprintf("%s hunk %d", where, r.Hunk)
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", where, r.Reason))
+	}
+	return fmt.Sprintf("apply rejected %d change(s): %s", len(e.Rejects), strings.Join(parts, "; "))
+}
+
+// ApplyOutput applies either a unified diff or file-block output to the
+// workspace in strict mode.
 func ApplyOutput(workspacePath, output string) (*ApplyResult, error) {
+	return ApplyOutputWithOptions(workspacePath, output, ApplyOptions{})
+}
+
+// ApplyOutputWithOptions applies either a unified diff or file-block output
+// to the workspace.
+func ApplyOutputWithOptions(workspacePath, output string, opts ApplyOptions) (*ApplyResult, error) {
+	switch opts.Mode {
+	case "", ApplyStrict, ApplyFuzzy:
+	case ApplyMerge:
+		if opts.Base == "" {
+			return nil, fmt.Errorf("merge apply requires a base snapshot")
+		}
+	default:
+		return nil, fmt.Errorf("unknown apply mode %q", opts.Mode)
+	}
+
 	patches, err := ParseUnifiedDiff(output)
 	if err == nil {
-		return applyPatches(workspacePath, patches)
+		return applyPatches(workspacePath, patches, opts)
 	}
 
 	files := ParseFileBlocks(output)
@@ -30,52 +121,60 @@ func ApplyOutput(workspacePath, output string) (*ApplyResult, error) {
 		return nil, fmt.Errorf("unable to parse output as unified diff or file blocks: %w", err)
 	}
 
-	return applyFileBlocks(workspacePath, files)
+	return applyFileBlocks(workspacePath, files, opts)
 }
 
-func applyPatches(workspacePath string, patches []FilePatch) (*ApplyResult, error) {
+func applyPatches(workspacePath string, patches []FilePatch, opts ApplyOptions) (*ApplyResult, error) {
 	if len(patches) == 0 {
 		return nil, fmt.Errorf("no patches to apply")
 	}
 
+	result := &ApplyResult{UsedUnifiedDiff: true}
+	var rejects []HunkReject
 	opPlans := make([]fileOp, 0, len(patches))
 	for _, patch := range patches {
-		plan, err := buildFileOp(workspacePath, patch)
+		plan, adjustments, err := buildFileOp(workspacePath, patch, opts)
+		var applyErr *ApplyError
+		if errors.As(err, &applyErr) {
+			rejects = append(rejects, applyErr.Rejects...)
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
+		result.Adjustments = append(result.Adjustments, adjustments...)
 		opPlans = append(opPlans, plan)
 	}
+	if len(rejects) > 0 {
+		return nil, &ApplyError{Rejects: rejects}
+	}
 
-	result := &ApplyResult{UsedUnifiedDiff: true}
+	changes, err := commitOps(opPlans, opts.Journal)
+	if err != nil {
+		return nil, err
+	}
 	for _, plan := range opPlans {
 		if plan.delete {
-			if err := os.Remove(plan.path); err != nil && !os.IsNotExist(err) {
-				return nil, err
-			}
 			result.DeletedFiles = append(result.DeletedFiles, plan.relative)
 			continue
 		}
-
-		if err := os.MkdirAll(filepath.Dir(plan.path), 0755); err != nil {
-			return nil, err
-		}
-		if err := os.WriteFile(plan.path, []byte(plan.content), plan.mode); err != nil {
-			return nil, err
-		}
 		result.AppliedFiles = append(result.AppliedFiles, plan.relative)
 	}
+	result.Changes = changes
 
 	return result, nil
 }
 
-func applyFileBlocks(workspacePath string, files map[string]string) (*ApplyResult, error) {
+func applyFileBlocks(workspacePath string, files map[string]string, opts ApplyOptions) (*ApplyResult, error) {
 	if len(files) == 0 {
 		return nil, fmt.Errorf("no file blocks to apply")
 	}
 
+	result := &ApplyResult{UsedUnifiedDiff: false}
+	var rejects []HunkReject
 	opPlans := make([]fileOp, 0, len(files))
-	for rel, content := range files {
+	for _, rel := range sortedKeys(files) {
+		content := files[rel]
 		path, err := safeJoin(workspacePath, rel)
 		if err != nil {
 			return nil, err
@@ -86,6 +185,21 @@ func applyFileBlocks(workspacePath string, files map[string]string) (*ApplyResul
 			mode = info.Mode().Perm()
 		}
 
+		if opts.Mode == ApplyMerge {
+			merged, changed, conflicts, err := mergeFileBlock(workspacePath, opts.Base, rel, content)
+			if err != nil {
+				return nil, err
+			}
+			if len(conflicts) > 0 {
+				rejects = append(rejects, conflicts...)
+				continue
+			}
+			if changed {
+				result.Adjustments = append(result.Adjustments, HunkAdjustment{File: rel, Merged: true})
+			}
+			content = merged
+		}
+
 		opPlans = append(opPlans, fileOp{
 			path:     path,
 			relative: rel,
@@ -93,17 +207,18 @@ func applyFileBlocks(workspacePath string, files map[string]string) (*ApplyResul
 			mode:     mode,
 		})
 	}
+	if len(rejects) > 0 {
+		return nil, &ApplyError{Rejects: rejects}
+	}
 
-	result := &ApplyResult{UsedUnifiedDiff: false}
+	changes, err := commitOps(opPlans, opts.Journal)
+	if err != nil {
+		return nil, err
+	}
 	for _, plan := range opPlans {
-		if err := os.MkdirAll(filepath.Dir(plan.path), 0755); err != nil {
-			return nil, err
-		}
-		if err := os.WriteFile(plan.path, []byte(plan.content), plan.mode); err != nil {
-			return nil, err
-		}
 		result.AppliedFiles = append(result.AppliedFiles, plan.relative)
 	}
+	result.Changes = changes
 
 	return result, nil
 }
@@ -116,45 +231,53 @@ type fileOp struct {
 	delete   bool
 }
 
-func buildFileOp(workspacePath string, patch FilePatch) (fileOp, error) {
+func buildFileOp(workspacePath string, patch FilePatch, opts ApplyOptions) (fileOp, []HunkAdjustment, error) {
 	oldPath := normalizeDiffPath(patch.OldPath)
 	newPath := normalizeDiffPath(patch.NewPath)
 
 	if newPath == "/dev/null" {
 		if oldPath == "/dev/null" {
-			return fileOp{}, fmt.Errorf("invalid patch with both paths /dev/null")
+			return fileOp{}, nil, fmt.Errorf("invalid patch with both paths /dev/null")
 		}
 		path, err := safeJoin(workspacePath, oldPath)
 		if err != nil {
-			return fileOp{}, err
+			return fileOp{}, nil, err
 		}
-		return fileOp{path: path, relative: oldPath, delete: true}, nil
+		return fileOp{path: path, relative: oldPath, delete: true}, nil, nil
 	}
 
 	path, err := safeJoin(workspacePath, newPath)
 	if err != nil {
-		return fileOp{}, err
+		return fileOp{}, nil, err
 	}
 
 	mode := os.FileMode(fileModeDefault)
 	if info, err := os.Stat(path); err == nil {
 		mode = info.Mode().Perm()
 	}
+	if patch.NewMode != 0 {
+		mode = patch.NewMode
+	}
 
 	var original string
 	if oldPath != "/dev/null" {
 		data, err := os.ReadFile(path)
 		if err != nil && !os.IsNotExist(err) {
-			return fileOp{}, err
+			return fileOp{}, nil, err
 		}
 		if err == nil {
 			original = string(data)
 		}
 	}
 
-	updated, err := applyHunks(original, patch.Hunks)
-	if err != nil {
-		return fileOp{}, fmt.Errorf("apply patch %s: %w", newPath, err)
+	updated, adjustments := original, []HunkAdjustment(nil)
+	if len(patch.Hunks) > 0 {
+		updated, adjustments, err = patchFile(newPath, oldPath, original, patch.Hunks, opts)
+		if err != nil {
+			return fileOp{}, nil, err
+		}
+	} else if patch.NewMode == 0 {
+		return fileOp{}, nil, fmt.Errorf("patch %s has no hunks", newPath)
 	}
 
 	return fileOp{
@@ -162,7 +285,7 @@ func buildFileOp(workspacePath string, patch FilePatch) (fileOp, error) {
 		relative: newPath,
 		content:  updated,
 		mode:     mode,
-	}, nil
+	}, adjustments, nil
 }
 
 func normalizeDiffPath(path string) string {
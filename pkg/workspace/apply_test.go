@@ -1,8 +1,10 @@
 package workspace
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -42,3 +44,149 @@ func TestApplyOutputFileBlocks(t *testing.T) {
 		t.Fatalf("unexpected content: %q", string(data))
 	}
 }
+
+func writeWorkspaceFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+}
+
+func readWorkspaceFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("read file: %v", err)
+	}
+	return string(data)
+}
+
+func TestApplyOutputFuzzyOffset(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkspaceFile(t, dir, "file.txt", "new1\nnew2\na\nb\nc\n")
+	diff := "--- a/file.txt\n+++ b/file.txt\n@@ -1,3 +1,3 @@\n a\n-b\n+bee\n c\n"
+
+	if _, err := ApplyOutputWithOptions(dir, diff, ApplyOptions{Mode: ApplyStrict}); err == nil {
+		t.Fatalf("expected strict apply to reject shifted hunk")
+	}
+
+	result, err := ApplyOutputWithOptions(dir, diff, ApplyOptions{Mode: ApplyFuzzy, Fuzz: DefaultFuzz})
+	if err != nil {
+		t.Fatalf("fuzzy apply: %v", err)
+	}
+	if got := readWorkspaceFile(t, dir, "file.txt"); got != "new1\nnew2\na\nbee\nc\n" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+	if len(result.Adjustments) != 1 || result.Adjustments[0].Offset != 2 || result.Adjustments[0].Fuzz != 0 {
+		t.Fatalf("unexpected adjustments: %+v", result.Adjustments)
+	}
+}
+
+func TestApplyOutputFuzzyIgnoresEdgeContext(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkspaceFile(t, dir, "file.txt", "one\ntwo\nthree\nfour\nfive\n")
+	diff := "--- a/file.txt\n+++ b/file.txt\n@@ -2,3 +2,3 @@\n TWO\n-three\n+3\n four\n"
+
+	result, err := ApplyOutputWithOptions(dir, diff, ApplyOptions{Mode: ApplyFuzzy, Fuzz: 1})
+	if err != nil {
+		t.Fatalf("fuzzy apply: %v", err)
+	}
+	if got := readWorkspaceFile(t, dir, "file.txt"); got != "one\ntwo\n3\nfour\nfive\n" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+	if len(result.Adjustments) != 1 || result.Adjustments[0].Fuzz != 1 {
+		t.Fatalf("unexpected adjustments: %+v", result.Adjustments)
+	}
+}
+
+func TestApplyOutputReportsRejectedHunks(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkspaceFile(t, dir, "a.txt", "a\nb\nc\n")
+	writeWorkspaceFile(t, dir, "b.txt", "x\ny\nz\n")
+	diff := "--- a/a.txt\n+++ b/a.txt\n@@ -1,3 +1,3 @@\n a\n-b\n+bee\n c\n" +
+		"--- a/b.txt\n+++ b/b.txt\n@@ -1,2 +1,2 @@\n x\n-missing\n+why\n@@ -3,1 +3,1 @@\n-z\n+zed\n"
+
+	_, err := ApplyOutputWithOptions(dir, diff, ApplyOptions{Mode: ApplyFuzzy, Fuzz: DefaultFuzz})
+	var applyErr *ApplyError
+	if !errors.As(err, &applyErr) {
+		t.Fatalf("expected ApplyError, got %v", err)
+	}
+	if len(applyErr.Rejects) != 1 {
+		t.Fatalf("expected one reject, got %+v", applyErr.Rejects)
+	}
+	reject := applyErr.Rejects[0]
+	if reject.File != "b.txt" || reject.Hunk != 1 || reject.Line != 1 || !strings.Contains(reject.Reason, `expected "missing"`) {
+		t.Fatalf("unexpected reject: %+v", reject)
+	}
+	if got := readWorkspaceFile(t, dir, "a.txt"); got != "a\nb\nc\n" {
+		t.Fatalf("expected nothing to be written, got %q", got)
+	}
+}
+
+func TestApplyOutputMergeWithSnapshot(t *testing.T) {
+	base := t.TempDir()
+	writeWorkspaceFile(t, base, "file.txt", "a\nb\nc\nd\ne\n")
+	writeWorkspaceFile(t, base, "block.txt", "one\ntwo\nthree\n")
+
+	dir := t.TempDir()
+	// The workspace moved on after the snapshot the model saw.
+	writeWorkspaceFile(t, dir, "file.txt", "a\nB\nc\nd\ne\n")
+	writeWorkspaceFile(t, dir, "block.txt", "ONE\ntwo\nthree\n")
+
+	diff := "--- a/file.txt\n+++ b/file.txt\n@@ -1,3 +1,3 @@\n a\n-b\n+bee\n c\n"
+	opts := ApplyOptions{Mode: ApplyMerge, Fuzz: DefaultFuzz, Base: base}
+	_, err := ApplyOutputWithOptions(dir, diff, opts)
+	var applyErr *ApplyError
+	if !errors.As(err, &applyErr) || len(applyErr.Rejects) != 1 || applyErr.Rejects[0].Line != 2 || applyErr.Rejects[0].Hunk != 1 {
+		t.Fatalf("expected conflict on line 2, got %v", err)
+	}
+
+	// The context still names the old line 2, so only the snapshot fits.
+	diff = "--- a/file.txt\n+++ b/file.txt\n@@ -1,4 +1,4 @@\n a\n b\n c\n-d\n+dee\n"
+	result, err := ApplyOutputWithOptions(dir, diff, ApplyOptions{Mode: ApplyMerge, Base: base})
+	if err != nil {
+		t.Fatalf("merge apply: %v", err)
+	}
+	if got := readWorkspaceFile(t, dir, "file.txt"); got != "a\nB\nc\ndee\ne\n" {
+		t.Fatalf("unexpected merged content: %q", got)
+	}
+	if len(result.Adjustments) != 1 || !result.Adjustments[0].Merged {
+		t.Fatalf("unexpected adjustments: %+v", result.Adjustments)
+	}
+
+	if _, err := ApplyOutputWithOptions(dir, "// file: block.txt\none\ntwo\n3\n", opts); err != nil {
+		t.Fatalf("merge file block: %v", err)
+	}
+	if got := readWorkspaceFile(t, dir, "block.txt"); got != "ONE\ntwo\n3\n" {
+		t.Fatalf("unexpected merged block: %q", got)
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		name, base, ours, theirs, want string
+		conflicts                      int
+	}{
+		{"theirs only", "a\nb\nc\n", "a\nb\nc\n", "a\nB\nc\n", "a\nB\nc\n", 0},
+		{"ours only", "a\nb\nc\n", "a\nB\nc\n", "a\nb\nc\n", "a\nB\nc\n", 0},
+		{"both same", "a\nb\nc\n", "a\nB\nc\n", "a\nB\nc\n", "a\nB\nc\n", 0},
+		{"separate regions", "a\nb\nc\nd\ne\n", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", 0},
+		{"insertions", "a\nb\n", "a\nx\nb\n", "a\nb\ny\n", "a\nx\nb\ny\n", 0},
+		{"conflict", "a\nb\nc\n", "a\nX\nc\n", "a\nY\nc\n", "a\nX\nc\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := merge3(tt.base, tt.ours, tt.theirs)
+			if len(conflicts) != tt.conflicts {
+				t.Fatalf("conflicts = %+v, want %d", conflicts, tt.conflicts)
+			}
+			if got != tt.want {
+				t.Fatalf("merge3 = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
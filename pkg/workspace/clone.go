@@ -1,6 +1,7 @@
 package workspace
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -9,58 +10,229 @@ import (
 	"strings"
 )
 
+// Clone strategies.
+const (
+	CloneCopy     = "copy"
+	CloneHardlink = "hardlink"
+	CloneReflink  = "reflink"
+	CloneOverlay  = "overlay"
+)
+
+// Clone is a temporary copy of a workspace that can be reset to the state
+// it had when it was made, so one clone can serve several attempts.
+type Clone struct {
+	Dir string
+
+	reset   func() error
+	cleanup func() error
+}
+
+// Reset discards every change made in the clone since it was created.
+func (c *Clone) Reset() error {
+	return c.reset()
+}
+
+// Cleanup removes the clone.
+func (c *Clone) Cleanup() error {
+	return c.cleanup()
+}
+
 // CloneToTemp copies a workspace directory tree into a temp directory.
 func CloneToTemp(src string) (tempDir string, cleanup func() error, err error) {
-	info, err := os.Stat(src)
+	clone, err := NewClone(src, CloneCopy)
 	if err != nil {
 		return "", nil, err
 	}
+	return clone.Dir, clone.Cleanup, nil
+}
+
+// NewClone clones the workspace at src into a temp directory using strategy:
+//
+//   - copy (the default) copies every file.
+//   - hardlink links every file. Applies replace files rather than writing
+//     them in place, but a gate that edits a file in place also edits the
+//     workspace.
+//   - reflink shares file data copy-on-write where the filesystem supports
+//     it (btrfs, XFS) and copies otherwise.
+//   - overlay mounts an overlayfs over the workspace; it needs Linux and
+//     permission to mount.
+//
+// Paths matched by the workspace's .flowgateignore are left out, except by
+// overlay clones, which always show the whole workspace.
+func NewClone(src, strategy string) (*Clone, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
 	if !info.IsDir() {
-		return "", nil, fmt.Errorf("workspace path is not a directory")
+		return nil, fmt.Errorf("workspace path is not a directory")
 	}
 
-	tempDir, err = os.MkdirTemp("", "flowgate-workspace-*")
+	var place func(src, dest string, mode fs.FileMode) error
+	switch strategy {
+	case "", CloneCopy:
+		place = copyFile
+	case CloneHardlink:
+		place = linkFile
+	case CloneReflink:
+		place = reflinkFile
+	case CloneOverlay:
+		return overlayClone(src)
+	default:
+		return nil, fmt.Errorf("unknown clone strategy %q", strategy)
+	}
+
+	ignore, err := loadIgnoreRules(src)
 	if err != nil {
-		return "", nil, err
+		return nil, fmt.Errorf("read %s: %w", IgnoreFile, err)
 	}
-	cleanup = func() error { return os.RemoveAll(tempDir) }
+	tempDir, err := os.MkdirTemp("", "flowgate-workspace-*")
+	if err != nil {
+		return nil, err
+	}
+	t := &treeClone{src: src, dir: tempDir, place: place, ignore: ignore, files: make(map[string]fs.FileInfo)}
+	if err := t.populate(); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, err
+	}
+	return &Clone{
+		Dir:     tempDir,
+		reset:   t.reset,
+		cleanup: func() error { return os.RemoveAll(tempDir) },
+	}, nil
+}
+
+// treeClone is a clone built file by file. It remembers each file it placed
+// so a reset only redoes the files that changed.
+type treeClone struct {
+	src    string
+	dir    string
+	place  func(src, dest string, mode fs.FileMode) error
+	ignore *ignoreRules
+	files  map[string]fs.FileInfo
+}
 
-	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, walkErr error) error {
+func (t *treeClone) populate() error {
+	return filepath.WalkDir(t.src, func(path string, d fs.DirEntry, walkErr error) error {
 		if walkErr != nil {
 			return walkErr
 		}
 
-		rel, err := filepath.Rel(src, path)
+		rel, err := filepath.Rel(t.src, path)
 		if err != nil {
 			return err
 		}
 		if rel == "." {
 			return nil
 		}
-		if shouldSkip(rel, d) {
+		if shouldSkip(rel, d) || t.ignore.ignored(filepath.ToSlash(rel), d.IsDir()) {
 			if d.IsDir() {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
-		destPath := filepath.Join(tempDir, rel)
 		if d.IsDir() {
-			return os.MkdirAll(destPath, 0755)
+			return os.MkdirAll(filepath.Join(t.dir, rel), 0755)
 		}
+		return t.placeFile(rel)
+	})
+}
 
-		info, err := d.Info()
+// placeFile clones one file or symlink and records what it placed.
+func (t *treeClone) placeFile(rel string) error {
+	srcPath := filepath.Join(t.src, rel)
+	destPath := filepath.Join(t.dir, rel)
+	info, err := os.Lstat(srcPath)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(srcPath)
 		if err != nil {
 			return err
 		}
-		return copyFile(path, destPath, info.Mode())
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return err
+		}
+		err = os.Symlink(target, destPath)
+		if err != nil {
+			return err
+		}
+	} else if info.Mode().IsRegular() {
+		if err := t.place(srcPath, destPath, info.Mode()); err != nil {
+			return err
+		}
+	} else {
+		return nil
+	}
+	placed, err := os.Lstat(destPath)
+	if err != nil {
+		return err
+	}
+	t.files[rel] = placed
+	return nil
+}
+
+// reset removes files added to the clone and re-places files that were
+// changed, replaced or removed.
+func (t *treeClone) reset() error {
+	seen := make(map[string]bool, len(t.files))
+	err := filepath.WalkDir(t.dir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(t.dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if d.IsDir() {
+			if _, err := os.Stat(filepath.Join(t.src, rel)); err != nil {
+				if err := os.RemoveAll(path); err != nil {
+					return err
+				}
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		placed, ok := t.files[rel]
+		if ok {
+			current, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if unchanged(placed, current) {
+				seen[rel] = true
+				return nil
+			}
+		}
+		return os.Remove(path)
 	})
 	if err != nil {
-		cleanup()
-		return "", nil, err
+		return err
+	}
+	for rel := range t.files {
+		if seen[rel] {
+			continue
+		}
+		if err := t.placeFile(rel); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
 	}
+	return nil
+}
 
-	return tempDir, cleanup, nil
+// unchanged reports whether a file is still the one placed by the clone.
+// A rename-based write replaces the inode, so SameFile catches it even when
+// size and modification time match.
+func unchanged(placed, current fs.FileInfo) bool {
+	return os.SameFile(placed, current) &&
+		placed.Size() == current.Size() &&
+		placed.Mode() == current.Mode() &&
+		placed.ModTime().Equal(current.ModTime())
 }
 
 func shouldSkip(rel string, d fs.DirEntry) bool {
@@ -93,3 +265,10 @@ func copyFile(src, dest string, mode fs.FileMode) error {
 	}
 	return nil
 }
+
+func linkFile(src, dest string, _ fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return os.Link(src, dest)
+}
@@ -36,3 +36,73 @@ func TestCloneToTemp(t *testing.T) {
 		t.Fatalf("expected .flowgate/runs to be skipped")
 	}
 }
+
+func TestCloneStrategiesResetToBaseline(t *testing.T) {
+	for _, strategy := range []string{CloneCopy, CloneHardlink, CloneReflink, CloneOverlay} {
+		t.Run(strategy, func(t *testing.T) {
+			root := t.TempDir()
+			writeWorkspaceFile(t, root, "keep.txt", "keep\n")
+			writeWorkspaceFile(t, root, "edit.txt", "original\n")
+			writeWorkspaceFile(t, root, "gone.txt", "gone\n")
+
+			clone, err := NewClone(root, strategy)
+			if strategy == CloneOverlay && err != nil {
+				t.Skipf("overlay not available: %v", err)
+			}
+			if err != nil {
+				t.Fatalf("clone: %v", err)
+			}
+			defer clone.Cleanup()
+
+			if _, err := ApplyOutput(clone.Dir, "// file: edit.txt\nchanged\n// file: sub/new.txt\nnew\n"); err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			if err := os.Remove(filepath.Join(clone.Dir, "gone.txt")); err != nil {
+				t.Fatalf("remove: %v", err)
+			}
+			if got := readWorkspaceFile(t, root, "edit.txt"); got != "original\n" {
+				t.Fatalf("apply to clone changed the workspace: %q", got)
+			}
+
+			if err := clone.Reset(); err != nil {
+				t.Fatalf("reset: %v", err)
+			}
+			for name, want := range map[string]string{"keep.txt": "keep\n", "edit.txt": "original\n", "gone.txt": "gone\n"} {
+				if got := readWorkspaceFile(t, clone.Dir, name); got != want {
+					t.Fatalf("%s after reset: %q", name, got)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(clone.Dir, "sub")); !os.IsNotExist(err) {
+				t.Fatalf("expected added directory to be removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestCloneHonorsIgnoreFile(t *testing.T) {
+	root := t.TempDir()
+	writeWorkspaceFile(t, root, IgnoreFile, "# build output\nnode_modules/\n*.log\n/docs/generated\n!keep.log\n")
+	writeWorkspaceFile(t, root, "main.go", "package main\n")
+	writeWorkspaceFile(t, root, "node_modules/pkg/index.js", "x\n")
+	writeWorkspaceFile(t, root, "logs/run.log", "x\n")
+	writeWorkspaceFile(t, root, "keep.log", "x\n")
+	writeWorkspaceFile(t, root, "docs/generated/api.md", "x\n")
+	writeWorkspaceFile(t, root, "web/docs/generated/page.md", "x\n")
+
+	clone, cleanup, err := CloneToTemp(root)
+	if err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	defer cleanup()
+
+	for _, rel := range []string{"main.go", "keep.log", IgnoreFile, "web/docs/generated/page.md"} {
+		if _, err := os.Stat(filepath.Join(clone, rel)); err != nil {
+			t.Fatalf("expected %s in clone: %v", rel, err)
+		}
+	}
+	for _, rel := range []string{"node_modules", "logs/run.log", "docs/generated"} {
+		if _, err := os.Stat(filepath.Join(clone, rel)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be ignored: %v", rel, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package workspace
 
 import (
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 )
@@ -11,6 +12,9 @@ type FilePatch struct {
 	OldPath string
 	NewPath string
 	Hunks   []Hunk
+	// NewMode is the permission set by a git "new mode" or "new file mode"
+	// header, or zero.
+	NewMode os.FileMode
 }
 
 // Hunk represents a unified diff hunk.
@@ -27,8 +31,29 @@ func ParseUnifiedDiff(input string) ([]FilePatch, error) {
 	lines := strings.Split(input, "\n")
 	var patches []FilePatch
 
+	// git headers precede the ---/+++ lines and carry mode changes. A header
+	// that is not followed by ---/+++ is a mode-only change.
+	var header *FilePatch
+	flushHeader := func() {
+		if header != nil && header.NewMode != 0 {
+			patches = append(patches, *header)
+		}
+		header = nil
+	}
+
 	for i := 0; i < len(lines); {
 		line := lines[i]
+		if strings.HasPrefix(line, "diff --git ") {
+			flushHeader()
+			header = parseGitHeader(line)
+			i++
+			continue
+		}
+		if header != nil {
+			if mode, ok := parseModeLine(line); ok {
+				header.NewMode = mode
+			}
+		}
 		if !strings.HasPrefix(line, "--- ") {
 			i++
 			continue
@@ -43,6 +68,10 @@ func ParseUnifiedDiff(input string) ([]FilePatch, error) {
 		i++
 
 		patch := FilePatch{OldPath: oldPath, NewPath: newPath}
+		if header != nil {
+			patch.NewMode = header.NewMode
+			header = nil
+		}
 		for i < len(lines) && strings.HasPrefix(lines[i], "@@") {
 			hunk, next, err := parseHunk(lines, i)
 			if err != nil {
@@ -54,6 +83,7 @@ func ParseUnifiedDiff(input string) ([]FilePatch, error) {
 
 		patches = append(patches, patch)
 	}
+	flushHeader()
 
 	if len(patches) == 0 {
 		return nil, fmt.Errorf("no unified diff content found")
@@ -70,6 +100,31 @@ func parseDiffPath(line string) string {
 	return fields[0]
 }
 
+// parseGitHeader reads the paths from a "diff --git a/x b/y" line.
+func parseGitHeader(line string) *FilePatch {
+	fields := strings.Fields(strings.TrimPrefix(line, "diff --git "))
+	if len(fields) != 2 {
+		return nil
+	}
+	return &FilePatch{OldPath: fields[0], NewPath: fields[1]}
+}
+
+// parseModeLine reads the permission bits from a "new mode" or "new file
+// mode" line.
+func parseModeLine(line string) (os.FileMode, bool) {
+	for _, prefix := range []string{"new mode ", "new file mode "} {
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		mode, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, prefix)), 8, 32)
+		if err != nil {
+			return 0, false
+		}
+		return os.FileMode(mode) & os.ModePerm, true
+	}
+	return 0, false
+}
+
 func parseHunk(lines []string, start int) (Hunk, int, error) {
 	line := lines[start]
 	oldStart, oldLines, newStart, newLines, err := parseHunkHeader(line)
@@ -86,7 +141,7 @@ func parseHunk(lines []string, start int) (Hunk, int, error) {
 
 	i := start + 1
 	for i < len(lines) {
-		if strings.HasPrefix(lines[i], "@@") || strings.HasPrefix(lines[i], "--- ") {
+		if strings.HasPrefix(lines[i], "@@") || strings.HasPrefix(lines[i], "--- ") || strings.HasPrefix(lines[i], "diff --git ") {
 			break
 		}
 		if strings.HasPrefix(lines[i], "\\") {
@@ -151,58 +206,179 @@ func parseHunkRange(value string) (int, int, error) {
 	return start, lines, nil
 }
 
-func applyHunks(original string, hunks []Hunk) (string, error) {
+// hunkPlacement records where a hunk was applied relative to its header.
+type hunkPlacement struct {
+	offset int
+	fuzz   int
+}
+
+// hunkFailure describes a hunk that did not apply.
+type hunkFailure struct {
+	hunk   int
+	line   int
+	reason string
+}
+
+// applyHunks applies hunks the way patch does: each hunk is searched for near
+// its expected position, shifted by the offset of the previous hunk, and up
+// to fuzz context lines may be ignored at each end. With strict set only the
+// exact position and full context are accepted. Every hunk is tried so that
+// all failures are reported together.
+func applyHunks(original string, hunks []Hunk, fuzz int, strict bool) (string, []hunkPlacement, []hunkFailure) {
 	oldLines := splitLines(original)
-	var newLines []string
+	var out []string
+	var placements []hunkPlacement
+	var failures []hunkFailure
 
 	index := 0
-	for _, hunk := range hunks {
-		if hunk.OldStart < 0 {
-			return "", fmt.Errorf("invalid hunk start")
+	delta := 0
+	for i, hunk := range hunks {
+		before, after, leading, trailing, err := splitHunk(hunk)
+		if err != nil {
+			failures = append(failures, hunkFailure{hunk: i + 1, line: hunk.OldStart, reason: err.Error()})
+			continue
 		}
 
-		targetIndex := hunk.OldStart - 1
-		if targetIndex < 0 {
-			targetIndex = 0
+		// Unified diffs number pure insertions after the line they follow.
+		anchor := hunk.OldStart - 1
+		if len(before) == 0 && hunk.OldLines == 0 {
+			anchor = hunk.OldStart
 		}
-
-		if targetIndex > len(oldLines) {
-			return "", fmt.Errorf("hunk starts beyond file length")
+		if anchor < 0 {
+			anchor = 0
 		}
+		expected := anchor + delta
 
-		newLines = append(newLines, oldLines[index:targetIndex]...)
-		index = targetIndex
-
-		for _, line := range hunk.Lines {
-			if line == "" {
-				newLines = append(newLines, "")
+		maxFuzz := fuzz
+		if strict {
+			maxFuzz = 0
+		}
+		pos := -1
+		for f := 0; f <= maxFuzz && pos < 0; f++ {
+			head := minInt(f, leading)
+			tail := minInt(f, trailing)
+			if f > 0 && head == 0 && tail == 0 {
+				break
+			}
+			want := before[head : len(before)-tail]
+			if strict {
+				if matchAt(oldLines, want, expected, index) {
+					pos = expected
+				}
+			} else {
+				pos = searchLines(oldLines, want, expected+head, index)
+			}
+			if pos < 0 {
 				continue
 			}
+			out = append(out, oldLines[index:pos]...)
+			out = append(out, after[head:len(after)-tail]...)
+			index = pos + len(want)
+			delta = pos - head - anchor
+			placements = append(placements, hunkPlacement{offset: delta, fuzz: f})
+		}
+		if pos < 0 {
+			failures = append(failures, hunkFailure{
+				hunk:   i + 1,
+				line:   hunk.OldStart,
+				reason: describeMismatch(oldLines, before, expected),
+			})
+		}
+	}
+	if len(failures) > 0 {
+		return "", nil, failures
+	}
 
-			switch line[0] {
-			case ' ': // context
-				text := line[1:]
-				if index >= len(oldLines) || oldLines[index] != text {
-					return "", fmt.Errorf("context mismatch: %s", text)
-				}
-				newLines = append(newLines, text)
-				index++
-			case '-':
-				text := line[1:]
-				if index >= len(oldLines) || oldLines[index] != text {
-					return "", fmt.Errorf("delete mismatch: %s", text)
-				}
-				index++
-			case '+':
-				newLines = append(newLines, line[1:])
-			default:
-				return "", fmt.Errorf("invalid hunk line: %s", line)
+	out = append(out, oldLines[index:]...)
+	return joinLines(out, original == "" || strings.HasSuffix(original, "\n")), placements, nil
+}
+
+// splitHunk returns the lines a hunk expects and produces, and how many
+// context lines it has at each end.
+func splitHunk(hunk Hunk) (before, after []string, leading, trailing int, err error) {
+	lines := hunk.Lines
+	// Blank lines after the hunk are separators, not context.
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	inLeading := true
+	for _, line := range lines {
+		kind, text := byte(' '), ""
+		if line != "" {
+			kind, text = line[0], line[1:]
+		}
+		switch kind {
+		case ' ':
+			before = append(before, text)
+			after = append(after, text)
+			if inLeading {
+				leading++
 			}
+			trailing++
+		case '-':
+			before = append(before, text)
+			inLeading = false
+			trailing = 0
+		case '+':
+			after = append(after, text)
+			inLeading = false
+			trailing = 0
+		default:
+			return nil, nil, 0, 0, fmt.Errorf("invalid hunk line: %s", line)
+		}
+	}
+	if inLeading {
+		// A hunk with only context changes nothing; treat it all as leading.
+		trailing = 0
+	}
+	return before, after, leading, trailing, nil
+}
+
+// searchLines finds want in lines at or after min, preferring positions
+// closest to expected.
+func searchLines(lines, want []string, expected, min int) int {
+	if expected < min {
+		expected = min
+	}
+	last := len(lines) - len(want)
+	for d := 0; expected-d >= min || expected+d <= last; d++ {
+		if p := expected + d; p <= last && matchAt(lines, want, p, min) {
+			return p
+		}
+		if p := expected - d; d > 0 && p >= min && p <= last && matchAt(lines, want, p, min) {
+			return p
 		}
 	}
+	return -1
+}
 
-	newLines = append(newLines, oldLines[index:]...)
-	return strings.Join(newLines, "\n"), nil
+func matchAt(lines, want []string, pos, min int) bool {
+	if pos < min || pos+len(want) > len(lines) {
+		return false
+	}
+	for i, text := range want {
+		if lines[pos+i] != text {
+			return false
+		}
+	}
+	return true
+}
+
+// describeMismatch explains why want does not match at the expected position.
+func describeMismatch(lines, want []string, expected int) string {
+	if expected > len(lines) {
+		return fmt.Sprintf("hunk starts at line %d beyond end of file (%d lines)", expected+1, len(lines))
+	}
+	for i, text := range want {
+		at := expected + i
+		if at >= len(lines) {
+			return fmt.Sprintf("line %d: expected %q, found end of file", at+1, text)
+		}
+		if lines[at] != text {
+			return fmt.Sprintf("line %d: expected %q, found %q", at+1, text, lines[at])
+		}
+	}
+	return "hunk context not found"
 }
 
 func splitLines(content string) []string {
@@ -214,3 +390,13 @@ func splitLines(content string) []string {
 	}
 	return strings.Split(content, "\n")
 }
+
+// joinLines joins lines, ending with a newline when newline is set and there
+// is content.
+func joinLines(lines []string, newline bool) string {
+	content := strings.Join(lines, "\n")
+	if newline && len(lines) > 0 {
+		content += "\n"
+	}
+	return content
+}
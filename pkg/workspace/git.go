@@ -0,0 +1,277 @@
+package workspace
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Workspace modes.
+const (
+	ModeCopy = "copy"
+	ModeGit  = "git"
+)
+
+// GitRepo is the git repository containing a workspace. In git mode, dry
+// runs use worktrees of a run branch and each applied stage is committed to
+// that branch. Commits are built in a private index, so the checkout's HEAD,
+// index and working tree are never touched.
+type GitRepo struct {
+	path   string
+	root   string
+	prefix string
+	branch string
+	env    []string
+
+	mu sync.Mutex
+}
+
+// GitFile is a file stored in the repository's object database, ready to be
+// committed. Path is relative to the workspace.
+type GitFile struct {
+	Path    string
+	Mode    string
+	Object  string
+	Deleted bool
+}
+
+// OpenGitRepo opens the repository containing workspacePath using the local
+// git binary.
+func OpenGitRepo(workspacePath string) (*GitRepo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git workspace mode requires git: %w", err)
+	}
+	r := &GitRepo{path: workspacePath}
+	root, err := r.git(workspacePath, nil, "", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("workspace is not a git repository: %w", err)
+	}
+	prefix, err := r.git(workspacePath, nil, "", "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	r.root = root
+	r.prefix = prefix
+
+	// commit-tree needs an identity; fall back to a fixed one when the user
+	// has not configured theirs.
+	if name, _ := r.git(root, nil, "", "config", "user.name"); name == "" && os.Getenv("GIT_AUTHOR_NAME") == "" {
+		r.env = append(r.env, "GIT_AUTHOR_NAME=flowgate", "GIT_COMMITTER_NAME=flowgate")
+	}
+	if email, _ := r.git(root, nil, "", "config", "user.email"); email == "" && os.Getenv("GIT_AUTHOR_EMAIL") == "" {
+		r.env = append(r.env, "GIT_AUTHOR_EMAIL=flowgate@localhost", "GIT_COMMITTER_EMAIL=flowgate@localhost")
+	}
+	return r, nil
+}
+
+// Branch returns the run branch.
+func (r *GitRepo) Branch() string {
+	return r.branch
+}
+
+// StartBranch creates branch at a snapshot of the workspace and returns the
+// commit it points to. Uncommitted changes and untracked files that are not
+// ignored are committed on top of HEAD first, so the branch matches what is
+// on disk; evidence under .flowgate/runs is left out.
+func (r *GitRepo) StartBranch(branch, message string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	head, err := r.git(r.root, nil, "", "rev-parse", "--verify", "HEAD^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("workspace repository has no commits: %w", err)
+	}
+	index, cleanup, err := r.tempIndex()
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	if _, err := r.git(r.root, index, "", "read-tree", head); err != nil {
+		return "", err
+	}
+	if _, err := r.git(r.path, index, "", "add", "-A", "--", ".", ":(exclude).flowgate/runs"); err != nil {
+		return "", err
+	}
+	base, err := r.commitIndex(index, head, message)
+	if err != nil {
+		return "", err
+	}
+	if base == "" {
+		base = head
+	}
+	if _, err := r.git(r.root, nil, "", "branch", branch, base); err != nil {
+		return "", err
+	}
+	r.branch = branch
+	return base, nil
+}
+
+// UseBranch continues an existing run branch.
+func (r *GitRepo) UseBranch(branch string) error {
+	if _, err := r.git(r.root, nil, "", "rev-parse", "--verify", "refs/heads/"+branch); err != nil {
+		return fmt.Errorf("run branch %s: %w", branch, err)
+	}
+	r.branch = branch
+	return nil
+}
+
+// Worktree checks out the tip of the run branch into a temporary detached
+// worktree. The clone's Dir is the workspace inside it; a reset checks the
+// same commit out again and removes untracked and ignored files.
+func (r *GitRepo) Worktree() (*Clone, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tip, err := r.git(r.root, nil, "", "rev-parse", "--verify", "refs/heads/"+r.branch)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "flowgate-worktree-*")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.git(r.root, nil, "", "worktree", "add", "--detach", "--quiet", dir, tip); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &Clone{
+		Dir: filepath.Join(dir, filepath.FromSlash(r.prefix)),
+		reset: func() error {
+			if _, err := r.git(dir, nil, "", "checkout", "--quiet", "--force", "--detach", tip); err != nil {
+				return err
+			}
+			_, err := r.git(dir, nil, "", "clean", "-ffdxq")
+			return err
+		},
+		cleanup: func() error {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			_, err := r.git(r.root, nil, "", "worktree", "remove", "--force", dir)
+			if removeErr := os.RemoveAll(dir); err == nil {
+				err = removeErr
+			}
+			return err
+		},
+	}, nil
+}
+
+// StoreFiles writes the files an apply changed in dir to the object database.
+func (r *GitRepo) StoreFiles(dir string, changes []FileChange) ([]GitFile, error) {
+	files := make([]GitFile, 0, len(changes))
+	for _, c := range changes {
+		if c.Deleted {
+			files = append(files, GitFile{Path: c.Path, Deleted: true})
+			continue
+		}
+		path, err := safeJoin(dir, c.Path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		object, err := r.git(r.root, nil, "", "hash-object", "-w", "--path="+r.prefix+filepath.ToSlash(c.Path), path)
+		if err != nil {
+			return nil, err
+		}
+		mode := "100644"
+		if info.Mode().Perm()&0111 != 0 {
+			mode = "100755"
+		}
+		files = append(files, GitFile{Path: c.Path, Mode: mode, Object: object})
+	}
+	return files, nil
+}
+
+// Commit records files as a commit on top of the run branch and advances the
+// branch. It returns "" when the files do not change the branch.
+func (r *GitRepo) Commit(files []GitFile, message string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tip, err := r.git(r.root, nil, "", "rev-parse", "--verify", "refs/heads/"+r.branch)
+	if err != nil {
+		return "", err
+	}
+	index, cleanup, err := r.tempIndex()
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	if _, err := r.git(r.root, index, "", "read-tree", tip); err != nil {
+		return "", err
+	}
+	var info strings.Builder
+	for _, f := range files {
+		path := r.prefix + filepath.ToSlash(f.Path)
+		if f.Deleted {
+			fmt.Fprintf(&info, "0 %s\t%s\n", strings.Repeat("0", len(tip)), path)
+			continue
+		}
+		fmt.Fprintf(&info, "%s %s\t%s\n", f.Mode, f.Object, path)
+	}
+	if _, err := r.git(r.root, index, info.String(), "update-index", "--index-info"); err != nil {
+		return "", err
+	}
+	commit, err := r.commitIndex(index, tip, message)
+	if err != nil || commit == "" {
+		return "", err
+	}
+	if _, err := r.git(r.root, nil, "", "update-ref", "refs/heads/"+r.branch, commit, tip); err != nil {
+		return "", err
+	}
+	return commit, nil
+}
+
+// commitIndex commits the tree in index with parent, or returns "" when the
+// tree is unchanged.
+func (r *GitRepo) commitIndex(index []string, parent, message string) (string, error) {
+	tree, err := r.git(r.root, index, "", "write-tree")
+	if err != nil {
+		return "", err
+	}
+	parentTree, err := r.git(r.root, nil, "", "rev-parse", parent+"^{tree}")
+	if err != nil {
+		return "", err
+	}
+	if tree == parentTree {
+		return "", nil
+	}
+	return r.git(r.root, nil, message, "commit-tree", tree, "-p", parent, "-F", "-")
+}
+
+// tempIndex returns the environment for a private index file.
+func (r *GitRepo) tempIndex() ([]string, func(), error) {
+	dir, err := os.MkdirTemp("", "flowgate-index-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(dir, "index")}
+	return env, func() { os.RemoveAll(dir) }, nil
+}
+
+func (r *GitRepo) git(dir string, env []string, stdin string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(append(os.Environ(), r.env...), env...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
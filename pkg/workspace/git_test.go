@@ -0,0 +1,106 @@
+package workspace
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func initGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	runGit(t, dir, "init", "--quiet")
+	writeWorkspaceFile(t, dir, "tracked.txt", "committed\n")
+	writeWorkspaceFile(t, dir, ".gitignore", "ignored.txt\n")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "--quiet", "-m", "initial")
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestGitRepoRunBranch(t *testing.T) {
+	dir := initGitRepo(t)
+	head := runGit(t, dir, "rev-parse", "HEAD")
+	writeWorkspaceFile(t, dir, "tracked.txt", "uncommitted\n")
+	writeWorkspaceFile(t, dir, "untracked.txt", "new\n")
+	writeWorkspaceFile(t, dir, "ignored.txt", "secret\n")
+	writeWorkspaceFile(t, dir, ".flowgate/runs/r1/run.json", "{}\n")
+
+	repo, err := OpenGitRepo(dir)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	base, err := repo.StartBranch("flowgate/r1", "snapshot\n")
+	if err != nil {
+		t.Fatalf("start branch: %v", err)
+	}
+	if base == head {
+		t.Fatalf("expected uncommitted changes to be snapshotted")
+	}
+	files := runGit(t, dir, "ls-tree", "-r", "--name-only", "flowgate/r1")
+	if files != ".gitignore\ntracked.txt\nuntracked.txt" {
+		t.Fatalf("unexpected snapshot files:\n%s", files)
+	}
+
+	clone, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("worktree: %v", err)
+	}
+	wt := clone.Dir
+	if got := readWorkspaceFile(t, wt, "tracked.txt"); got != "uncommitted\n" {
+		t.Fatalf("worktree does not match snapshot: %q", got)
+	}
+	result, err := ApplyOutput(wt, "// file: tracked.txt\napplied\n")
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	stored, err := repo.StoreFiles(wt, result.Changes)
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	if err := clone.Cleanup(); err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+	if _, err := os.Stat(wt); !os.IsNotExist(err) {
+		t.Fatalf("expected worktree to be removed: %v", err)
+	}
+
+	commit, err := repo.Commit(stored, "apply\n\nFlowgate-Run: r1\n")
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if tip := runGit(t, dir, "rev-parse", "flowgate/r1"); tip != commit {
+		t.Fatalf("branch not advanced: %s != %s", tip, commit)
+	}
+	if got := runGit(t, dir, "show", "flowgate/r1:tracked.txt"); got != "applied" {
+		t.Fatalf("unexpected committed content: %q", got)
+	}
+	if got := runGit(t, dir, "rev-parse", "HEAD"); got != head {
+		t.Fatalf("expected HEAD to be untouched")
+	}
+	if got := readWorkspaceFile(t, dir, "tracked.txt"); got != "uncommitted\n" {
+		t.Fatalf("expected working tree to be untouched, got %q", got)
+	}
+
+	again, err := repo.Commit(stored, "apply again\n")
+	if err != nil || again != "" {
+		t.Fatalf("expected no commit for unchanged files, got %q %v", again, err)
+	}
+}
@@ -0,0 +1,109 @@
+package workspace
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile lists paths left out of workspace clones, in .gitignore syntax.
+const IgnoreFile = ".flowgateignore"
+
+type ignoreRule struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreRules is a parsed ignore file. The last matching rule wins; a rule
+// starting with "!" re-includes a path.
+type ignoreRules struct {
+	rules []ignoreRule
+}
+
+// loadIgnoreRules reads the ignore file at the workspace root, if any.
+func loadIgnoreRules(root string) (*ignoreRules, error) {
+	f, err := os.Open(filepath.Join(root, IgnoreFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &ignoreRules{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rules := &ignoreRules{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		rules.rules = append(rules.rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// ignored reports whether rel, a slash-separated path, is excluded.
+func (r *ignoreRules) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range r.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		var matched bool
+		if rule.anchored {
+			matched = matchGlob(strings.Split(rule.pattern, "/"), strings.Split(rel, "/"))
+		} else {
+			matched, _ = path.Match(rule.pattern, path.Base(rel))
+		}
+		if matched {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matchGlob matches path segments against pattern segments, where "**"
+// matches any number of segments.
+func matchGlob(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchGlob(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchGlob(pattern[1:], segments[1:])
+}
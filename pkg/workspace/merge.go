@@ -0,0 +1,287 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// conflictReason is reported for changes that overlap edits made to the
+// workspace since the snapshot.
+const conflictReason = "conflicts with changes made since the snapshot"
+
+// patchFile applies hunks to the current content of rel. In merge mode,
+// hunks that no longer fit are applied to the snapshot instead and the
+// result is merged with the current content.
+func patchFile(rel, oldPath, original string, hunks []Hunk, opts ApplyOptions) (string, []HunkAdjustment, error) {
+	strict := opts.Mode == "" || opts.Mode == ApplyStrict
+	updated, placements, failures := applyHunks(original, hunks, opts.Fuzz, strict)
+	if len(failures) == 0 {
+		var adjustments []HunkAdjustment
+		for i, p := range placements {
+			if p.offset != 0 || p.fuzz != 0 {
+				adjustments = append(adjustments, HunkAdjustment{File: rel, Hunk: i + 1, Offset: p.offset, Fuzz: p.fuzz})
+			}
+		}
+		return updated, adjustments, nil
+	}
+
+	if opts.Mode == ApplyMerge && oldPath != "/dev/null" {
+		base, err := readSnapshot(opts.Base, oldPath)
+		if err != nil {
+			return "", nil, err
+		}
+		theirs, basePlacements, baseFailures := applyHunks(base, hunks, opts.Fuzz, false)
+		if len(baseFailures) == 0 {
+			merged, conflicts := merge3(base, original, theirs)
+			if len(conflicts) == 0 {
+				return merged, []HunkAdjustment{{File: rel, Merged: true}}, nil
+			}
+			rejects := make([]HunkReject, 0, len(conflicts))
+			for _, c := range conflicts {
+				rejects = append(rejects, HunkReject{
+					File:   rel,
+					Hunk:   hunkForLine(hunks, basePlacements, c.baseLine),
+					Line:   c.line,
+					Reason: conflictReason,
+				})
+			}
+			return "", nil, &ApplyError{Rejects: rejects}
+		}
+		failures = baseFailures
+	}
+
+	rejects := make([]HunkReject, 0, len(failures))
+	for _, f := range failures {
+		rejects = append(rejects, HunkReject{File: rel, Hunk: f.hunk, Line: f.line, Reason: f.reason})
+	}
+	return "", nil, &ApplyError{Rejects: rejects}
+}
+
+// mergeFileBlock merges a file block with edits made to the file since the
+// snapshot. changed reports whether a merge was needed.
+func mergeFileBlock(workspacePath, basePath, rel, content string) (string, bool, []HunkReject, error) {
+	path, err := safeJoin(workspacePath, rel)
+	if err != nil {
+		return "", false, nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return content, false, nil, nil
+	}
+	if err != nil {
+		return "", false, nil, err
+	}
+	current := string(data)
+	base, err := readSnapshot(basePath, rel)
+	if err != nil {
+		return "", false, nil, err
+	}
+	if current == base || current == content {
+		return content, false, nil, nil
+	}
+
+	merged, conflicts := merge3(base, current, content)
+	if len(conflicts) == 0 {
+		return merged, true, nil, nil
+	}
+	rejects := make([]HunkReject, 0, len(conflicts))
+	for _, c := range conflicts {
+		rejects = append(rejects, HunkReject{File: rel, Line: c.line, Reason: conflictReason})
+	}
+	return "", false, rejects, nil
+}
+
+// readSnapshot returns the content of rel in the snapshot, or "" when the
+// file did not exist.
+func readSnapshot(basePath, rel string) (string, error) {
+	path, err := safeJoin(basePath, rel)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read snapshot: %w", err)
+	}
+	return string(data), nil
+}
+
+// hunkForLine returns the 1-based index of the last hunk placed at or before
+// line in the snapshot.
+func hunkForLine(hunks []Hunk, placements []hunkPlacement, line int) int {
+	index := 1
+	for i, hunk := range hunks {
+		if i >= len(placements) {
+			break
+		}
+		start := hunk.OldStart + placements[i].offset
+		if start > line {
+			break
+		}
+		index = i + 1
+	}
+	return index
+}
+
+func sortedKeys(files map[string]string) []string {
+	keys := make([]string, 0, len(files))
+	for k := range files {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mergeConflict is a region both sides changed differently. Line is the
+// first line of the region in the current file.
+type mergeConflict struct {
+	line     int
+	baseLine int
+}
+
+// merge3 merges the changes from base to theirs into ours, line by line.
+func merge3(base, ours, theirs string) (string, []mergeConflict) {
+	o, a, b := splitLines(base), splitLines(ours), splitLines(theirs)
+	ma, mb := matchLines(o, a), matchLines(o, b)
+
+	var out []string
+	var conflicts []mergeConflict
+	i, j, k := 0, 0, 0
+	for {
+		for i < len(o) && j < len(a) && k < len(b) && ma[i] == j && mb[i] == k {
+			out = append(out, o[i])
+			i, j, k = i+1, j+1, k+1
+		}
+		if i >= len(o) && j >= len(a) && k >= len(b) {
+			break
+		}
+
+		ni := i
+		for ni < len(o) && (ma[ni] < 0 || mb[ni] < 0) {
+			ni++
+		}
+		nj, nk := len(a), len(b)
+		if ni < len(o) {
+			nj, nk = ma[ni], mb[ni]
+		}
+
+		baseChunk, oursChunk, theirsChunk := o[i:ni], a[j:nj], b[k:nk]
+		switch {
+		case equalLines(oursChunk, baseChunk):
+			out = append(out, theirsChunk...)
+		case equalLines(theirsChunk, baseChunk), equalLines(oursChunk, theirsChunk):
+			out = append(out, oursChunk...)
+		default:
+			conflicts = append(conflicts, mergeConflict{line: j + 1, baseLine: i + 1})
+			out = append(out, oursChunk...)
+		}
+		i, j, k = ni, nj, nk
+	}
+	newline := strings.HasSuffix(ours, "\n") || strings.HasSuffix(theirs, "\n")
+	return joinLines(out, newline), conflicts
+}
+
+// matchLines pairs each line of a with a line of b along a shortest edit
+// script (Myers), returning -1 for lines of a that were removed.
+func matchLines(a, b []string) []int {
+	m := make([]int, len(a))
+	for i := range m {
+		m[i] = -1
+	}
+
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		m[pre] = pre
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		m[len(a)-1-suf] = len(b) - 1 - suf
+		suf++
+	}
+	x0, y0 := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	n, nm := len(x0), len(y0)
+	if n == 0 || nm == 0 {
+		return m
+	}
+
+	max := n + nm
+	off := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < nm && x0[x] == y0[y] {
+				x, y = x+1, y+1
+			}
+			v[off+k] = x
+			if x >= n && y >= nm {
+				backtrackMatches(trace, off, d, n, nm, pre, m)
+				return m
+			}
+		}
+	}
+	return m
+}
+
+func backtrackMatches(trace [][]int, off, d, x, y, pre int, m []int) {
+	for ; d > 0; d-- {
+		prev := trace[d]
+		k := x - y
+		var pk int
+		if k == -d || (k != d && prev[off+k-1] < prev[off+k+1]) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		px := prev[off+pk]
+		py := px - pk
+		// The edit moves from (px, py) to the start of the snake ending at
+		// (x, y); the snake's lines are matches.
+		sx := px
+		if pk == k-1 {
+			sx = px + 1
+		}
+		for x > sx {
+			x, y = x-1, y-1
+			m[pre+x] = pre + y
+		}
+		x, y = px, py
+	}
+	for x > 0 && y > 0 {
+		x, y = x-1, y-1
+		m[pre+x] = pre + y
+	}
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
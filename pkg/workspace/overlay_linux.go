@@ -0,0 +1,69 @@
+//go:build linux
+
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// overlayClone mounts an overlayfs with src as the read-only lower layer.
+// Writes land in an upper directory, so a reset only has to empty it.
+func overlayClone(src string) (*Clone, error) {
+	lower, err := filepath.Abs(src)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ContainsAny(lower, ",:") {
+		return nil, fmt.Errorf("overlay clone: workspace path %s contains ',' or ':'", lower)
+	}
+	root, err := os.MkdirTemp("", "flowgate-overlay-*")
+	if err != nil {
+		return nil, err
+	}
+	upper := filepath.Join(root, "upper")
+	work := filepath.Join(root, "work")
+	merged := filepath.Join(root, "merged")
+
+	mount := func() error {
+		for _, dir := range []string{upper, work, merged} {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+		options := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", lower, upper, work)
+		if err := syscall.Mount("overlay", merged, "overlay", 0, options); err != nil {
+			return fmt.Errorf("overlay clone: mount: %w", err)
+		}
+		return nil
+	}
+	if err := mount(); err != nil {
+		os.RemoveAll(root)
+		return nil, err
+	}
+
+	return &Clone{
+		Dir: merged,
+		reset: func() error {
+			if err := syscall.Unmount(merged, 0); err != nil {
+				return fmt.Errorf("overlay clone: unmount: %w", err)
+			}
+			if err := os.RemoveAll(upper); err != nil {
+				return err
+			}
+			if err := os.RemoveAll(work); err != nil {
+				return err
+			}
+			return mount()
+		},
+		cleanup: func() error {
+			if err := syscall.Unmount(merged, 0); err != nil {
+				return fmt.Errorf("overlay clone: unmount: %w", err)
+			}
+			return os.RemoveAll(root)
+		},
+	}, nil
+}
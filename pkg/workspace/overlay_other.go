@@ -0,0 +1,10 @@
+//go:build !linux
+
+package workspace
+
+import "fmt"
+
+// overlayClone is unavailable: overlayfs is Linux-only.
+func overlayClone(_ string) (*Clone, error) {
+	return nil, fmt.Errorf("overlay clones require Linux")
+}
@@ -0,0 +1,43 @@
+//go:build linux
+
+package workspace
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// ficlone is the FICLONE ioctl request number.
+const ficlone = 0x40049409
+
+// reflinkFile clones src into dest with FICLONE, sharing data blocks until
+// either side is written. Filesystems without reflink support get a copy.
+func reflinkFile(src, dest string, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+	if err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, out.Fd(), ficlone, in.Fd())
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if errno == 0 {
+		return nil
+	}
+	if errors.Is(errno, syscall.EOPNOTSUPP) || errors.Is(errno, syscall.EXDEV) || errors.Is(errno, syscall.EINVAL) || errors.Is(errno, syscall.ENOTTY) {
+		return copyFile(src, dest, mode)
+	}
+	return &os.PathError{Op: "reflink", Path: dest, Err: errno}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package workspace
+
+import "io/fs"
+
+// reflinkFile copies: FICLONE is Linux-only.
+func reflinkFile(src, dest string, mode fs.FileMode) error {
+	return copyFile(src, dest, mode)
+}
@@ -0,0 +1,268 @@
+package workspace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileChange records how an apply changed one file, with enough of the
+// original to undo it.
+type FileChange struct {
+	Path    string      `json:"path"`
+	Existed bool        `json:"existed"`
+	OldMode os.FileMode `json:"old_mode,omitempty"`
+	OldHash string      `json:"old_hash,omitempty"`
+	// OldContent is the original content; it is stored separately.
+	OldContent []byte      `json:"-"`
+	Deleted    bool        `json:"deleted,omitempty"`
+	NewMode    os.FileMode `json:"new_mode,omitempty"`
+	NewHash    string      `json:"new_hash,omitempty"`
+}
+
+// FileState is the state of a workspace file. A missing file has Exists
+// false.
+type FileState struct {
+	Exists  bool
+	Mode    os.FileMode
+	Hash    string
+	Content []byte
+}
+
+// FileRestore replaces a file in state Expect with Target.
+type FileRestore struct {
+	Path   string
+	Expect FileState
+	Target FileState
+}
+
+// RestoreError lists files that no longer match the state recorded when the
+// changes were applied.
+type RestoreError struct {
+	Modified []string
+}
+
+func (e *RestoreError) Error() string {
+	return fmt.Sprintf("files changed since apply: %s", strings.Join(e.Modified, ", "))
+}
+
+// HashContent returns the hex SHA-256 of content.
+func HashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// RestoreFiles verifies that every file is in its Expect state and then
+// replaces all of them with their Target state in one transaction. Nothing
+// is written if any file was modified.
+func RestoreFiles(workspacePath string, files []FileRestore) error {
+	var modified []string
+	ops := make([]fileOp, 0, len(files))
+	for _, f := range files {
+		path, err := safeJoin(workspacePath, f.Path)
+		if err != nil {
+			return err
+		}
+		current, err := readFileState(path)
+		if err != nil {
+			return err
+		}
+		if !sameState(current, f.Expect) {
+			modified = append(modified, f.Path)
+			continue
+		}
+		if !f.Target.Exists {
+			ops = append(ops, fileOp{path: path, relative: f.Path, delete: true})
+			continue
+		}
+		if HashContent(f.Target.Content) != f.Target.Hash {
+			return fmt.Errorf("restore %s: content does not match recorded hash", f.Path)
+		}
+		ops = append(ops, fileOp{path: path, relative: f.Path, content: string(f.Target.Content), mode: f.Target.Mode})
+	}
+	if len(modified) > 0 {
+		sort.Strings(modified)
+		return &RestoreError{Modified: modified}
+	}
+	_, err := commitOps(ops, nil)
+	return err
+}
+
+func sameState(current, expect FileState) bool {
+	if current.Exists != expect.Exists {
+		return false
+	}
+	if !current.Exists {
+		return true
+	}
+	return current.Hash == expect.Hash && (expect.Mode == 0 || current.Mode == expect.Mode)
+}
+
+func readFileState(path string) (FileState, error) {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return FileState{}, nil
+	}
+	if err != nil {
+		return FileState{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return FileState{}, fmt.Errorf("%s is not a regular file", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FileState{}, err
+	}
+	return FileState{Exists: true, Mode: info.Mode().Perm(), Hash: HashContent(data), Content: data}, nil
+}
+
+// commitOps applies ops as one transaction. New contents are first written
+// to temporary files next to their targets and journal, when set, is given
+// the planned changes; once both succeed, each target is moved aside and the
+// staged file renamed into place. If any step fails, files already replaced
+// are put back, so the workspace is either fully updated or unchanged.
+func commitOps(ops []fileOp, journal func([]FileChange) error) ([]FileChange, error) {
+	type staged struct {
+		op     fileOp
+		before FileState
+		temp   string
+		backup string
+	}
+
+	items := make([]*staged, 0, len(ops))
+	cleanup := func() {
+		for _, item := range items {
+			if item.temp != "" {
+				os.Remove(item.temp)
+			}
+		}
+	}
+
+	for _, op := range ops {
+		before, err := readFileState(op.path)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		item := &staged{op: op, before: before}
+		items = append(items, item)
+		if op.delete {
+			continue
+		}
+		temp, err := stageFile(op.path, op.content, op.mode)
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("stage %s: %w", op.relative, err)
+		}
+		item.temp = temp
+	}
+
+	changes := make([]FileChange, 0, len(items))
+	for _, item := range items {
+		change := FileChange{
+			Path:       item.op.relative,
+			Existed:    item.before.Exists,
+			OldMode:    item.before.Mode,
+			OldHash:    item.before.Hash,
+			OldContent: item.before.Content,
+			Deleted:    item.op.delete,
+		}
+		if !item.op.delete {
+			change.NewMode = item.op.mode
+			change.NewHash = HashContent([]byte(item.op.content))
+		}
+		changes = append(changes, change)
+	}
+	if journal != nil {
+		if err := journal(changes); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("journal changes: %w", err)
+		}
+	}
+
+	var committed []*staged
+	undo := func() {
+		for i := len(committed) - 1; i >= 0; i-- {
+			item := committed[i]
+			if item.temp != "" {
+				os.Remove(item.op.path)
+			}
+			if item.backup != "" {
+				os.Rename(item.backup, item.op.path)
+			}
+		}
+	}
+
+	for _, item := range items {
+		if item.before.Exists {
+			backup, err := reserveTemp(item.op.path, ".flowgate-backup-")
+			if err == nil {
+				err = os.Rename(item.op.path, backup)
+			}
+			if err != nil {
+				os.Remove(backup)
+				undo()
+				cleanup()
+				return nil, fmt.Errorf("commit %s: %w", item.op.relative, err)
+			}
+			item.backup = backup
+		}
+		committed = append(committed, item)
+		if item.temp != "" {
+			if err := os.Rename(item.temp, item.op.path); err != nil {
+				undo()
+				cleanup()
+				return nil, fmt.Errorf("commit %s: %w", item.op.relative, err)
+			}
+		}
+	}
+
+	for _, item := range items {
+		if item.backup != "" {
+			os.Remove(item.backup)
+		}
+	}
+	return changes, nil
+}
+
+// stageFile writes content to a temporary file in the target's directory.
+func stageFile(path, content string, mode os.FileMode) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".flowgate-apply-*")
+	if err != nil {
+		return "", err
+	}
+	name := f.Name()
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		os.Remove(name)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(name)
+		return "", err
+	}
+	if err := os.Chmod(name, mode); err != nil {
+		os.Remove(name)
+		return "", err
+	}
+	return name, nil
+}
+
+// reserveTemp returns an unused path next to path.
+func reserveTemp(path, prefix string) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), prefix+"*")
+	if err != nil {
+		return "", err
+	}
+	name := f.Name()
+	f.Close()
+	return name, nil
+}
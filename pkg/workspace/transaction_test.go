@@ -0,0 +1,90 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyOutputIsAllOrNothing(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkspaceFile(t, dir, "a.txt", "original\n")
+	// A regular file where a directory is needed makes staging fail.
+	writeWorkspaceFile(t, dir, "blocker", "not a directory\n")
+
+	_, err := ApplyOutput(dir, "// file: a.txt\nchanged\n// file: blocker/b.txt\nnew\n")
+	if err == nil {
+		t.Fatalf("expected apply to fail")
+	}
+	if got := readWorkspaceFile(t, dir, "a.txt"); got != "original\n" {
+		t.Fatalf("expected a.txt to be untouched, got %q", got)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected no leftover temporary files, got %d entries", len(entries))
+	}
+}
+
+func TestApplyRecordsChangesAndRestores(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkspaceFile(t, dir, "edit.txt", "a\nb\n")
+	writeWorkspaceFile(t, dir, "gone.txt", "bye\n")
+	writeWorkspaceFile(t, dir, "run.sh", "echo hi\n")
+
+	diff := "--- a/edit.txt\n+++ b/edit.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+B\n" +
+		"--- a/gone.txt\n+++ /dev/null\n@@ -1,1 +0,0 @@\n-bye\n" +
+		"--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1,1 @@\n+hello\n" +
+		"diff --git a/run.sh b/run.sh\nold mode 100644\nnew mode 100755\n"
+	result, err := ApplyOutput(dir, diff)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if len(result.Changes) != 4 {
+		t.Fatalf("expected 4 changes, got %+v", result.Changes)
+	}
+	info, err := os.Stat(filepath.Join(dir, "run.sh"))
+	if err != nil || info.Mode().Perm() != 0755 {
+		t.Fatalf("expected run.sh to be executable: %v %v", info.Mode(), err)
+	}
+
+	var restores []FileRestore
+	for _, c := range result.Changes {
+		restore := FileRestore{
+			Path:   c.Path,
+			Expect: FileState{Exists: !c.Deleted, Mode: c.NewMode, Hash: c.NewHash},
+			Target: FileState{Exists: c.Existed, Mode: c.OldMode, Hash: c.OldHash, Content: c.OldContent},
+		}
+		restores = append(restores, restore)
+	}
+
+	writeWorkspaceFile(t, dir, "new.txt", "edited after apply\n")
+	var restoreErr *RestoreError
+	if err := RestoreFiles(dir, restores); !errors.As(err, &restoreErr) || len(restoreErr.Modified) != 1 || restoreErr.Modified[0] != "new.txt" {
+		t.Fatalf("expected new.txt to be reported as modified, got %v", err)
+	}
+	if got := readWorkspaceFile(t, dir, "edit.txt"); got != "a\nB\n" {
+		t.Fatalf("expected failed restore to leave files alone, got %q", got)
+	}
+
+	writeWorkspaceFile(t, dir, "new.txt", "hello\n")
+	if err := RestoreFiles(dir, restores); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if got := readWorkspaceFile(t, dir, "edit.txt"); got != "a\nb\n" {
+		t.Fatalf("edit.txt not restored: %q", got)
+	}
+	if got := readWorkspaceFile(t, dir, "gone.txt"); got != "bye\n" {
+		t.Fatalf("gone.txt not restored: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected new.txt to be removed: %v", err)
+	}
+	info, err = os.Stat(filepath.Join(dir, "run.sh"))
+	if err != nil || info.Mode().Perm() != 0644 {
+		t.Fatalf("expected run.sh mode to be restored: %v %v", info.Mode(), err)
+	}
+}